@@ -0,0 +1,71 @@
+package harpy
+
+import (
+	"context"
+	"fmt"
+)
+
+// BatchMethodLimiter is an implementation of RequestSetReader that rejects
+// batches in which too many requests target the same method.
+//
+// It prevents a single batch from invoking an expensive method an excessive
+// number of times. A batch that exceeds the limit is rejected as a whole with a
+// JSON-RPC "invalid request" error, before any of its requests are passed to
+// the exchanger.
+type BatchMethodLimiter struct {
+	// Next is the reader from which request sets are read.
+	Next RequestSetReader
+
+	// MaxRequestsPerMethod is the maximum number of requests within a batch
+	// that may target the same method, including notifications. If it is
+	// zero, there is no limit.
+	MaxRequestsPerMethod int
+}
+
+var _ RequestSetReader = (*BatchMethodLimiter)(nil)
+
+// Read reads the next RequestSet that is to be processed.
+//
+// It returns a native JSON-RPC Error if the request set is a batch that
+// exceeds the limit.
+func (l *BatchMethodLimiter) Read(ctx context.Context) (RequestSet, error) {
+	rs, err := l.Next.Read(ctx)
+	if err != nil {
+		return RequestSet{}, err
+	}
+
+	if err, ok := l.validate(rs); !ok {
+		return RequestSet{}, err
+	}
+
+	return rs, nil
+}
+
+// validate returns an error if any method is targeted by more than the
+// permitted number of requests within rs.
+func (l *BatchMethodLimiter) validate(rs RequestSet) (Error, bool) {
+	if !rs.IsBatch || l.MaxRequestsPerMethod <= 0 {
+		return Error{}, true
+	}
+
+	counts := map[string]int{}
+
+	for _, req := range rs.Requests {
+		counts[req.Method]++
+
+		if counts[req.Method] > l.MaxRequestsPerMethod {
+			return NewErrorWithReservedCode(
+				InvalidRequestCode,
+				WithMessage(
+					fmt.Sprintf(
+						"batch contains more than %d requests to the %q method",
+						l.MaxRequestsPerMethod,
+						req.Method,
+					),
+				),
+			), false
+		}
+	}
+
+	return Error{}, true
+}
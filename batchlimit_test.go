@@ -0,0 +1,88 @@
+package harpy_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type BatchMethodLimiter", func() {
+	var (
+		requestSet RequestSet
+		reader     *BatchMethodLimiter
+	)
+
+	BeforeEach(func() {
+		requestSet = RequestSet{
+			Requests: []Request{
+				{Version: "2.0", Method: "<a>"},
+				{Version: "2.0", Method: "<b>"},
+				{Version: "2.0", Method: "<a>"},
+			},
+			IsBatch: true,
+		}
+
+		reader = &BatchMethodLimiter{
+			Next: &RequestSetReaderStub{
+				ReadFunc: func(context.Context) (RequestSet, error) {
+					return requestSet, nil
+				},
+			},
+			MaxRequestsPerMethod: 2,
+		}
+	})
+
+	Describe("func Read()", func() {
+		It("returns a batch that does not exceed the limit", func() {
+			rs, err := reader.Read(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs).To(Equal(requestSet))
+		})
+
+		It("returns an error if the batch exceeds the limit for any method", func() {
+			reader.MaxRequestsPerMethod = 1
+
+			_, err := reader.Read(context.Background())
+			Expect(err).To(Equal(
+				NewErrorWithReservedCode(
+					InvalidRequestCode,
+					WithMessage(`batch contains more than 1 requests to the "<a>" method`),
+				),
+			))
+		})
+
+		It("does not apply the limit if it is zero", func() {
+			reader.MaxRequestsPerMethod = 0
+
+			_, err := reader.Read(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("does not apply the limit to non-batch request sets", func() {
+			reader.MaxRequestsPerMethod = 1
+			requestSet = RequestSet{
+				Requests: []Request{
+					{Version: "2.0", Method: "<a>"},
+				},
+			}
+
+			_, err := reader.Read(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("returns errors from the next reader", func() {
+			reader.Next = &RequestSetReaderStub{
+				ReadFunc: func(context.Context) (RequestSet, error) {
+					return RequestSet{}, errors.New("<error>")
+				},
+			}
+
+			_, err := reader.Read(context.Background())
+			Expect(err).To(MatchError("<error>"))
+		})
+	})
+})
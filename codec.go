@@ -0,0 +1,141 @@
+package harpy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/dogmatiq/harpy/internal/jsonx"
+)
+
+// Codec encodes and decodes JSON-RPC request and response sets using a specific
+// wire format, such as MessagePack.
+//
+// The codec only determines the wire format of the request or response set as
+// a whole. The raw values within the Request and Response types, such as
+// request IDs, parameters, results and error data, are always represented as
+// JSON. A codec for any other format is responsible for converting these
+// values to and from JSON when decoding and encoding.
+type Codec = jsonx.Codec
+
+// Encoder writes encoded values to a stream.
+type Encoder = jsonx.Encoder
+
+// Decoder reads encoded values from a stream.
+type Decoder = jsonx.Decoder
+
+// JSONCodec is the default Codec, which encodes request and response sets as
+// JSON using the encoding/json package.
+var JSONCodec Codec = jsonCodec{}
+
+// DecodeWith is a RequestSetOption that sets the codec used to decode request
+// sets by UnmarshalRequestSet() and UnmarshalRequestSetBytes().
+//
+// The parameters of each request are always represented as JSON once decoded.
+//
+// Request sets are decoded using JSONCodec by default.
+func DecodeWith(c Codec) RequestSetOption {
+	return func(opts *requestSetOptions) {
+		opts.Codec = c
+	}
+}
+
+// DecodeResponsesWith is a ResponseSetOption that sets the codec used to decode
+// response sets by UnmarshalResponseSet().
+//
+// Response sets are decoded using JSONCodec by default.
+func DecodeResponsesWith(c Codec) ResponseSetOption {
+	return func(opts *responseSetOptions) {
+		opts.Codec = c
+	}
+}
+
+// jsonCodec is the implementation of JSONCodec.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder {
+	return json.NewEncoder(w)
+}
+
+func (jsonCodec) NewDecoder(r io.Reader) Decoder {
+	return json.NewDecoder(r)
+}
+
+// isJSONCodec returns true if c is nil or JSONCodec, in which case the content
+// is already JSON and does not need to be converted.
+func isJSONCodec(c Codec) bool {
+	return c == nil || c == JSONCodec
+}
+
+// transcodeToJSON decodes a single value from r using c and returns its JSON
+// representation.
+//
+// If the value can not be decoded, the returned error wraps a codecError,
+// unless the failure was caused by an error reading from r, in which case that
+// error is returned unchanged.
+func transcodeToJSON(r io.Reader, c Codec) (json.RawMessage, error) {
+	rr := &readErrorRecorder{r: r}
+
+	var data json.RawMessage
+	if err := c.NewDecoder(rr).Decode(&data); err != nil {
+		if rr.err != nil && errors.Is(err, rr.err) {
+			return nil, err
+		}
+
+		return nil, codecError{err}
+	}
+
+	return data, nil
+}
+
+// transcodeBytesToJSON decodes a single value from data using c and returns its
+// JSON representation.
+func transcodeBytesToJSON(data []byte, c Codec) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := c.Unmarshal(data, &raw); err != nil {
+		return nil, codecError{err}
+	}
+
+	return raw, nil
+}
+
+// codecError indicates that content could not be decoded by a Codec.
+type codecError struct {
+	cause error
+}
+
+func (e codecError) Error() string {
+	return e.cause.Error()
+}
+
+func (e codecError) Unwrap() error {
+	return e.cause
+}
+
+// readErrorRecorder is an io.Reader that records the last error returned by
+// the underlying reader, so that IO errors can be distinguished from decoding
+// errors.
+type readErrorRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,146 @@
+package harpy_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing/iotest"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// hexString returns the hexadecimal encoding of s.
+func hexString(s string) string {
+	return hex.EncodeToString([]byte(s))
+}
+
+var _ = Describe("var JSONCodec", func() {
+	It("marshals and unmarshals values as JSON", func() {
+		data, err := JSONCodec.Marshal(map[string]int{"value": 123})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"value": 123}`))
+
+		var v map[string]int
+		err = JSONCodec.Unmarshal(data, &v)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(v).To(Equal(map[string]int{"value": 123}))
+	})
+
+	It("is used by default", func() {
+		r := strings.NewReader(`{"jsonrpc":"2.0","id":123,"method":"<method>"}`)
+
+		rs, err := UnmarshalRequestSet(r, DecodeWith(JSONCodec))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs.Requests).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("func DecodeWith()", func() {
+	It("causes UnmarshalRequestSet() to decode the request set using the codec", func() {
+		r := strings.NewReader(hexString(`[{"jsonrpc":"2.0","id":123,"method":"<method>","params":[1,2,3]}]`))
+
+		rs, err := UnmarshalRequestSet(r, DecodeWith(HexCodec{}))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs).To(Equal(RequestSet{
+			Requests: []Request{
+				{
+					Version:    "2.0",
+					ID:         json.RawMessage(`123`),
+					Method:     "<method>",
+					Parameters: json.RawMessage(`[1,2,3]`),
+				},
+			},
+			IsBatch: true,
+		}))
+	})
+
+	It("causes UnmarshalRequestSetBytes() to decode the request set using the codec", func() {
+		data := []byte(hexString(`{"jsonrpc":"2.0","id":123,"method":"<method>"}`))
+
+		rs, err := UnmarshalRequestSetBytes(data, DecodeWith(HexCodec{}))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs).To(Equal(RequestSet{
+			Requests: []Request{
+				{
+					Version: "2.0",
+					ID:      json.RawMessage(`123`),
+					Method:  "<method>",
+				},
+			},
+			IsBatch: false,
+		}))
+	})
+
+	It("applies the other options to the decoded request set", func() {
+		r := strings.NewReader(hexString(`{"jsonrpc":"2.0","id":123,"method":"<method>","params":[[1]]}`))
+
+		_, err := UnmarshalRequestSet(r, DecodeWith(HexCodec{}), MaxRequestSetNestingDepth(2))
+
+		var rpcErr Error
+		ok := errors.As(err, &rpcErr)
+		Expect(ok).To(BeTrue())
+		Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+		Expect(rpcErr.Unwrap()).To(MatchError("unable to parse request: json: exceeded maximum nesting depth of 2"))
+	})
+
+	It("returns a parse error if the codec can not decode the request set", func() {
+		r := strings.NewReader(`<not hex>`)
+
+		_, err := UnmarshalRequestSet(r, DecodeWith(HexCodec{}))
+
+		var rpcErr Error
+		ok := errors.As(err, &rpcErr)
+		Expect(ok).To(BeTrue())
+		Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+		Expect(rpcErr.Unwrap()).To(MatchError(HavePrefix("unable to parse request: encoding/hex: ")))
+	})
+
+	It("returns io.EOF if there is no content", func() {
+		_, err := UnmarshalRequestSet(strings.NewReader(``), DecodeWith(HexCodec{}))
+		Expect(err).To(Equal(io.EOF))
+
+		_, err = UnmarshalRequestSetBytes(nil, DecodeWith(HexCodec{}))
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("returns IO errors unchanged", func() {
+		r := iotest.ErrReader(errors.New("<error>"))
+
+		_, err := UnmarshalRequestSet(r, DecodeWith(HexCodec{}))
+		Expect(err).To(MatchError("<error>"))
+
+		var rpcErr Error
+		Expect(errors.As(err, &rpcErr)).To(BeFalse())
+	})
+})
+
+var _ = Describe("func DecodeResponsesWith()", func() {
+	It("causes UnmarshalResponseSet() to decode the response set using the codec", func() {
+		r := strings.NewReader(hexString(`{"jsonrpc":"2.0","id":123,"result":[1,2,3]}`))
+
+		rs, err := UnmarshalResponseSet(r, DecodeResponsesWith(HexCodec{}))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs).To(Equal(ResponseSet{
+			Responses: []Response{
+				SuccessResponse{
+					Version:   "2.0",
+					RequestID: json.RawMessage(`123`),
+					Result:    json.RawMessage(`[1,2,3]`),
+				},
+			},
+			IsBatch: false,
+		}))
+	})
+
+	It("returns an error if the codec can not decode the response set", func() {
+		r := strings.NewReader(`<not hex>`)
+
+		_, err := UnmarshalResponseSet(r, DecodeResponsesWith(HexCodec{}))
+		Expect(err).To(MatchError(HavePrefix("unable to parse response: encoding/hex: ")))
+	})
+})
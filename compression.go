@@ -0,0 +1,71 @@
+package harpy
+
+// CompressionHint describes a response in terms that allow a transport to
+// decide whether it is worth compressing.
+type CompressionHint struct {
+	// Size is the approximate size of the response's content, in bytes. A
+	// negative value indicates that the size is not known in advance, such as
+	// when the result is streamed.
+	Size int
+
+	// Incompressible is true if the response is unlikely to benefit from
+	// compression, such as when it contains data that is already compressed.
+	Incompressible bool
+}
+
+// ShouldCompress returns true if a response with this hint should be
+// compressed, given the minimum size, in bytes, at which compression is
+// worthwhile.
+//
+// Responses of an unknown size are compressed unless they are incompressible.
+func (h CompressionHint) ShouldCompress(minSize int) bool {
+	if h.Incompressible {
+		return false
+	}
+
+	return h.Size < 0 || h.Size >= minSize
+}
+
+// CompressionHinter is an interface for values that provide a hint about
+// whether a response should be compressed.
+//
+// It is an optional interface. SuccessResponse and ErrorResponse do not
+// implement it, but it may be implemented by the reader of a StreamResult, such
+// as to avoid re-compressing data that is already compressed, or by a type that
+// embeds a SuccessResponse or ErrorResponse.
+type CompressionHinter interface {
+	CompressionHint() CompressionHint
+}
+
+// CompressionHintOf returns a hint about whether res should be compressed.
+//
+// If res, or the result stream of a SuccessResponse, implements
+// CompressionHinter, its hint is returned. Otherwise, the size is that of the
+// result of a SuccessResponse, or unknown if the result is streamed, or that
+// of the error message and any user-defined error data of an ErrorResponse.
+func CompressionHintOf(res Response) CompressionHint {
+	if h, ok := res.(CompressionHinter); ok {
+		return h.CompressionHint()
+	}
+
+	switch res := res.(type) {
+	case SuccessResponse:
+		if res.ResultStream != nil {
+			if h, ok := res.ResultStream.(CompressionHinter); ok {
+				return h.CompressionHint()
+			}
+
+			return CompressionHint{Size: -1}
+		}
+
+		return CompressionHint{Size: len(res.Result)}
+
+	case ErrorResponse:
+		return CompressionHint{
+			Size: len(res.Error.Message) + len(res.Error.Data),
+		}
+
+	default:
+		return CompressionHint{Size: -1}
+	}
+}
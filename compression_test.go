@@ -0,0 +1,96 @@
+package harpy_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// hintedReader is an io.Reader that provides a compression hint.
+type hintedReader struct {
+	*strings.Reader
+	Hint CompressionHint
+}
+
+func (r hintedReader) CompressionHint() CompressionHint {
+	return r.Hint
+}
+
+// hintedResponse is a SuccessResponse that provides a compression hint.
+type hintedResponse struct {
+	SuccessResponse
+	Hint CompressionHint
+}
+
+func (r hintedResponse) CompressionHint() CompressionHint {
+	return r.Hint
+}
+
+var _ = Describe("type CompressionHint", func() {
+	DescribeTable(
+		"func ShouldCompress()",
+		func(hint CompressionHint, expect bool) {
+			Expect(hint.ShouldCompress(100)).To(Equal(expect))
+		},
+		Entry("smaller than the minimum size", CompressionHint{Size: 99}, false),
+		Entry("equal to the minimum size", CompressionHint{Size: 100}, true),
+		Entry("larger than the minimum size", CompressionHint{Size: 101}, true),
+		Entry("unknown size", CompressionHint{Size: -1}, true),
+		Entry("incompressible", CompressionHint{Size: 1000, Incompressible: true}, false),
+		Entry("incompressible with unknown size", CompressionHint{Size: -1, Incompressible: true}, false),
+	)
+})
+
+var _ = Describe("func CompressionHintOf()", func() {
+	It("returns the size of the result of a success response", func() {
+		res := NewSuccessResponse(json.RawMessage(`123`), []int{1, 2, 3})
+		Expect(CompressionHintOf(res)).To(Equal(CompressionHint{Size: 7}))
+	})
+
+	It("returns an unknown size for a success response with a result stream", func() {
+		res := NewSuccessResponse(
+			json.RawMessage(`123`),
+			StreamResult{Reader: strings.NewReader(`[1, 2, 3]`)},
+		)
+
+		Expect(CompressionHintOf(res)).To(Equal(CompressionHint{Size: -1}))
+	})
+
+	It("returns the hint provided by the result stream", func() {
+		hint := CompressionHint{Size: 9, Incompressible: true}
+		res := NewSuccessResponse(
+			json.RawMessage(`123`),
+			StreamResult{
+				Reader: hintedReader{
+					Reader: strings.NewReader(`[1, 2, 3]`),
+					Hint:   hint,
+				},
+			},
+		)
+
+		Expect(CompressionHintOf(res)).To(Equal(hint))
+	})
+
+	It("returns the size of the message and data of an error response", func() {
+		res := NewErrorResponse(
+			json.RawMessage(`123`),
+			NewError(100, WithMessage("<message>"), WithData([]int{1, 2, 3})),
+		)
+
+		Expect(CompressionHintOf(res)).To(Equal(CompressionHint{Size: 16}))
+	})
+
+	It("returns the hint provided by the response itself", func() {
+		hint := CompressionHint{Size: 3, Incompressible: true}
+		res := hintedResponse{
+			SuccessResponse: NewSuccessResponse(json.RawMessage(`123`), []int{1, 2, 3}).(SuccessResponse),
+			Hint:            hint,
+		}
+
+		Expect(CompressionHintOf(res)).To(Equal(hint))
+	})
+})
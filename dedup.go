@@ -0,0 +1,164 @@
+package harpy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// Deduplicator is an implementation of Exchanger that avoids invoking the next
+// exchanger more than once for identical calls within the same batch.
+//
+// Two calls are considered identical if they have the same method name and
+// parameters. The first such call is passed to the next exchanger, and its
+// response is shared with each of the duplicates. The request ID within each
+// response is always that of the request it answers. If the next exchanger
+// panics, the panic is propagated to the caller and each of the duplicates
+// receives an "internal error" response.
+//
+// Calls that are not part of a batch and notifications are always passed to
+// the next exchanger.
+type Deduplicator struct {
+	// Next is the next exchanger in the middleware stack.
+	Next Exchanger
+}
+
+var _ Exchanger = (*Deduplicator)(nil)
+
+// Call handles a call request and returns the response.
+func (d *Deduplicator) Call(ctx context.Context, req Request) Response {
+	b, ok := ctx.Value(batchKey{}).(*batch)
+	if !ok {
+		return d.Next.Call(ctx, req)
+	}
+
+	c, isDuplicate := b.call(req)
+
+	if isDuplicate {
+		select {
+		case <-ctx.Done():
+			return NewErrorResponse(req.ID, ctx.Err())
+		case <-c.done:
+			return withRequestID(c.res, req.ID)
+		}
+	}
+
+	defer c.complete(req)
+	c.res = d.Next.Call(ctx, req)
+
+	// A streamed result can only be read once, so it is buffered so that it
+	// may be shared with any duplicate calls.
+	if res, ok := c.res.(SuccessResponse); ok {
+		buffered, err := res.BufferResult()
+		if err != nil {
+			c.res = NewErrorResponse(req.ID, err)
+		} else {
+			c.res = buffered
+		}
+	}
+
+	return c.res
+}
+
+// Notify handles a notification request.
+func (d *Deduplicator) Notify(ctx context.Context, req Request) error {
+	return d.Next.Notify(ctx, req)
+}
+
+// batchKey is the context key used to associate a batch with the context
+// passed to an exchanger.
+type batchKey struct{}
+
+// batch holds state that is shared between the requests in a single batch.
+type batch struct {
+	m     sync.Mutex
+	calls map[[sha256.Size]byte]*sharedCall
+}
+
+// sharedCall is the result of a call that may be shared by identical calls
+// within the same batch.
+type sharedCall struct {
+	done chan struct{}
+	res  Response
+}
+
+// errSharedCallPanicked is the error used to build the response shared with
+// identical calls if the exchanger that handles the original call panics.
+var errSharedCallPanicked = errors.New("the exchanger panicked while handling an identical call")
+
+// complete marks c as complete, notifying any identical calls that are waiting
+// for its response.
+//
+// It must be deferred by the caller responsible for populating c.res. If the
+// response has not been populated, because the exchanger panicked, the
+// identical calls receive an error response instead.
+func (c *sharedCall) complete(req Request) {
+	if c.res == nil {
+		c.res = NewErrorResponse(req.ID, errSharedCallPanicked)
+	}
+
+	close(c.done)
+}
+
+// call returns the sharedCall for requests that are identical to req.
+//
+// If isDuplicate is false, req is the first such request and the caller is
+// responsible for populating the response and closing c.done.
+func (b *batch) call(req Request) (c *sharedCall, isDuplicate bool) {
+	key := callKey(req)
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if c, ok := b.calls[key]; ok {
+		return c, true
+	}
+
+	if b.calls == nil {
+		b.calls = map[[sha256.Size]byte]*sharedCall{}
+	}
+
+	c = &sharedCall{
+		done: make(chan struct{}),
+	}
+	b.calls[key] = c
+
+	return c, false
+}
+
+// callKey returns a hash of the method and parameters of req.
+func callKey(req Request) [sha256.Size]byte {
+	params := req.Parameters
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, params); err == nil {
+		params = compact.Bytes()
+	}
+
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte{0})
+	h.Write(params)
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+
+	return key
+}
+
+// withRequestID returns a copy of res with its request ID set to id.
+func withRequestID(res Response, id json.RawMessage) Response {
+	switch res := res.(type) {
+	case SuccessResponse:
+		res.RequestID = id
+		return res
+	case ErrorResponse:
+		res.RequestID = id
+		return res
+	default:
+		return res
+	}
+}
@@ -0,0 +1,255 @@
+package harpy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("type Deduplicator", func() {
+	var (
+		next      *ExchangerStub
+		calls     int32
+		exchanger *Deduplicator
+	)
+
+	BeforeEach(func() {
+		calls = 0
+
+		next = &ExchangerStub{
+			CallFunc: func(
+				_ context.Context,
+				req Request,
+			) Response {
+				atomic.AddInt32(&calls, 1)
+
+				if req.Method == "<error>" {
+					return NewErrorResponse(req.ID, errors.New("<error>"))
+				}
+
+				if req.Method == "<stream>" {
+					return NewSuccessResponse(
+						req.ID,
+						StreamResult{Reader: bytes.NewReader(req.Parameters)},
+					)
+				}
+
+				return SuccessResponse{
+					Version:   "2.0",
+					RequestID: req.ID,
+					Result:    req.Parameters,
+				}
+			},
+		}
+
+		exchanger = &Deduplicator{Next: next}
+	})
+
+	exchange := func(requests ...Request) []Response {
+		var (
+			m         sync.Mutex
+			responses []Response
+		)
+
+		err := Exchange(
+			context.Background(),
+			exchanger,
+			&RequestSetReaderStub{
+				ReadFunc: func(context.Context) (RequestSet, error) {
+					return RequestSet{
+						Requests: requests,
+						IsBatch:  true,
+					}, nil
+				},
+			},
+			&ResponseWriterStub{
+				WriteBatchedFunc: func(res Response) error {
+					m.Lock()
+					defer m.Unlock()
+					responses = append(responses, res)
+					return nil
+				},
+			},
+			NewZapExchangeLogger(zap.NewNop()),
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		return responses
+	}
+
+	Describe("func Call()", func() {
+		It("invokes the next exchanger once for identical calls within a batch", func() {
+			responses := exchange(
+				Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>", Parameters: json.RawMessage(`{"a":1}`)},
+				Request{Version: "2.0", ID: json.RawMessage(`2`), Method: "<method>", Parameters: json.RawMessage(`{"a":1}`)},
+				Request{Version: "2.0", ID: json.RawMessage(`"three"`), Method: "<method>", Parameters: json.RawMessage(`{ "a": 1 }`)},
+			)
+
+			Expect(atomic.LoadInt32(&calls)).To(BeNumerically("==", 1))
+			Expect(responses).To(HaveLen(3))
+
+			var ids []string
+			for _, res := range responses {
+				Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+				ids = append(ids, string(res.(SuccessResponse).RequestID))
+			}
+			Expect(ids).To(ConsistOf("1", "2", `"three"`))
+		})
+
+		It("shares error responses between identical calls", func() {
+			responses := exchange(
+				Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<error>"},
+				Request{Version: "2.0", ID: json.RawMessage(`2`), Method: "<error>"},
+			)
+
+			Expect(atomic.LoadInt32(&calls)).To(BeNumerically("==", 1))
+			Expect(responses).To(HaveLen(2))
+
+			var ids []string
+			for _, res := range responses {
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				ids = append(ids, string(res.(ErrorResponse).RequestID))
+			}
+			Expect(ids).To(ConsistOf("1", "2"))
+		})
+
+		It("shares streamed results between identical calls", func() {
+			responses := exchange(
+				Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<stream>", Parameters: json.RawMessage(`[1, 2, 3]`)},
+				Request{Version: "2.0", ID: json.RawMessage(`2`), Method: "<stream>", Parameters: json.RawMessage(`[1, 2, 3]`)},
+			)
+
+			Expect(atomic.LoadInt32(&calls)).To(BeNumerically("==", 1))
+			Expect(responses).To(HaveLen(2))
+
+			for _, res := range responses {
+				Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+				Expect(res.(SuccessResponse).Result).To(MatchJSON(`[1, 2, 3]`))
+				Expect(res.(SuccessResponse).ResultStream).To(BeNil())
+			}
+		})
+
+		It("invokes the next exchanger for calls with different methods or parameters", func() {
+			responses := exchange(
+				Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>", Parameters: json.RawMessage(`[1]`)},
+				Request{Version: "2.0", ID: json.RawMessage(`2`), Method: "<method>", Parameters: json.RawMessage(`[2]`)},
+				Request{Version: "2.0", ID: json.RawMessage(`3`), Method: "<other>", Parameters: json.RawMessage(`[1]`)},
+			)
+
+			Expect(atomic.LoadInt32(&calls)).To(BeNumerically("==", 3))
+			Expect(responses).To(HaveLen(3))
+		})
+
+		It("propagates a panic and returns an error response to identical calls", func() {
+			next.CallFunc = func(context.Context, Request) Response {
+				atomic.AddInt32(&calls, 1)
+
+				// Give the identical calls a chance to join this one.
+				time.Sleep(20 * time.Millisecond)
+				panic("<panic>")
+			}
+
+			var (
+				m         sync.Mutex
+				panics    []any
+				responses []Response
+			)
+
+			err := Exchange(
+				context.Background(),
+				&ExchangerStub{
+					CallFunc: func(ctx context.Context, req Request) (res Response) {
+						defer func() {
+							if v := recover(); v != nil {
+								m.Lock()
+								panics = append(panics, v)
+								m.Unlock()
+								res = NewErrorResponse(req.ID, errors.New("<recovered>"))
+							}
+						}()
+						return exchanger.Call(ctx, req)
+					},
+				},
+				&RequestSetReaderStub{
+					ReadFunc: func(context.Context) (RequestSet, error) {
+						return RequestSet{
+							Requests: []Request{
+								{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>", Parameters: json.RawMessage(`[1]`)},
+								{Version: "2.0", ID: json.RawMessage(`2`), Method: "<method>", Parameters: json.RawMessage(`[1]`)},
+							},
+							IsBatch: true,
+						}, nil
+					},
+				},
+				&ResponseWriterStub{
+					WriteBatchedFunc: func(res Response) error {
+						m.Lock()
+						defer m.Unlock()
+						responses = append(responses, res)
+						return nil
+					},
+				},
+				NewZapExchangeLogger(zap.NewNop()),
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(atomic.LoadInt32(&calls)).To(BeNumerically("==", 1))
+			Expect(panics).To(ConsistOf("<panic>"))
+			Expect(responses).To(HaveLen(2))
+
+			var errs []error
+			for _, res := range responses {
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				Expect(res.(ErrorResponse).Error.Code).To(Equal(InternalErrorCode))
+				errs = append(errs, res.(ErrorResponse).ServerError)
+			}
+			Expect(errs).To(ContainElement(MatchError("<recovered>")))
+		})
+
+		It("does not share responses between batches", func() {
+			req := Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>", Parameters: json.RawMessage(`[1]`)}
+			dup := Request{Version: "2.0", ID: json.RawMessage(`2`), Method: "<method>", Parameters: json.RawMessage(`[1]`)}
+
+			exchange(req, dup)
+			exchange(req, dup)
+
+			Expect(atomic.LoadInt32(&calls)).To(BeNumerically("==", 2))
+		})
+
+		It("invokes the next exchanger for calls that are not part of a batch", func() {
+			req := Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>", Parameters: json.RawMessage(`[1]`)}
+
+			exchanger.Call(context.Background(), req)
+			exchanger.Call(context.Background(), req)
+
+			Expect(atomic.LoadInt32(&calls)).To(BeNumerically("==", 2))
+		})
+	})
+
+	Describe("func Notify()", func() {
+		It("always invokes the next exchanger", func() {
+			var notifications int32
+			next.NotifyFunc = func(context.Context, Request) error {
+				atomic.AddInt32(&notifications, 1)
+				return nil
+			}
+
+			exchange(
+				Request{Version: "2.0", Method: "<method>", Parameters: json.RawMessage(`[1]`)},
+				Request{Version: "2.0", Method: "<method>", Parameters: json.RawMessage(`[1]`)},
+			)
+
+			Expect(atomic.LoadInt32(&notifications)).To(BeNumerically("==", 2))
+		})
+	})
+})
@@ -0,0 +1,26 @@
+package harpy
+
+import (
+	"context"
+	"time"
+)
+
+// requestDurationKey is the context key used to associate the time taken to
+// handle a request with a context.
+type requestDurationKey struct{}
+
+// WithRequestDuration returns a copy of ctx that is associated with the time
+// taken to handle a request.
+//
+// Exchange() uses it to provide the duration to the ExchangeLogger, via the
+// context passed to ExchangeLogger.LogCall() and LogNotification().
+func WithRequestDuration(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, requestDurationKey{}, d)
+}
+
+// RequestDurationFromContext returns the time taken to handle a request, as
+// associated with ctx by WithRequestDuration(), if any.
+func RequestDurationFromContext(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(requestDurationKey{}).(time.Duration)
+	return d, ok
+}
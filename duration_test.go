@@ -0,0 +1,72 @@
+package harpy_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+// durationRecordingLogger is an ExchangeLogger that records the request
+// duration associated with the context passed to LogCall().
+type durationRecordingLogger struct {
+	ExchangeLogger
+	Duration time.Duration
+}
+
+func (l *durationRecordingLogger) LogCall(ctx context.Context, req Request, res Response) {
+	l.Duration, _ = RequestDurationFromContext(ctx)
+	l.ExchangeLogger.LogCall(ctx, req, res)
+}
+
+var _ = Describe("func RequestDurationFromContext()", func() {
+	It("returns the duration associated with the context", func() {
+		ctx := WithRequestDuration(context.Background(), 3*time.Second)
+
+		d, ok := RequestDurationFromContext(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(d).To(Equal(3 * time.Second))
+	})
+
+	It("returns false if the context is not associated with a duration", func() {
+		_, ok := RequestDurationFromContext(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("is provided to the logger by Exchange()", func() {
+		logger := &durationRecordingLogger{
+			ExchangeLogger: NewZapExchangeLogger(zap.NewNop()),
+		}
+
+		err := Exchange(
+			context.Background(),
+			&ExchangerStub{
+				CallFunc: func(_ context.Context, req Request) Response {
+					time.Sleep(10 * time.Millisecond)
+					return NewSuccessResponse(req.ID, nil)
+				},
+			},
+			&RequestSetReaderStub{
+				ReadFunc: func(context.Context) (RequestSet, error) {
+					return RequestSet{
+						Requests: []Request{
+							{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>"},
+						},
+					}, nil
+				},
+			},
+			&ResponseWriterStub{
+				WriteUnbatchedFunc: func(Response) error { return nil },
+				CloseFunc:          func() error { return nil },
+			},
+			logger,
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(logger.Duration).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+})
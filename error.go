@@ -3,6 +3,7 @@ package harpy
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/dogmatiq/harpy/internal/jsonx"
@@ -30,13 +31,35 @@ type Error struct {
 	cause error
 }
 
-// newError returns a new Error with the given code.
+// Side identifies the party to a JSON-RPC exchange on whose behalf an error is
+// produced.
+type Side int
+
+const (
+	// ServerSide is the side of a JSON-RPC server. Errors produced on the
+	// server side are intended to be delivered to the caller.
+	ServerSide Side = iota
+
+	// ClientSide is the side of a JSON-RPC client. Errors produced on the
+	// client side describe problems that a server would report if it received
+	// the offending request.
+	ClientSide
+)
+
+// newError returns a new server-side Error with the given code.
 //
 // The options are applied in order.
 func newError(code ErrorCode, options []ErrorOption) Error {
+	return ServerSide.newError(code, options...)
+}
+
+// newError returns a new Error with the given code, produced on side s.
+//
+// The options are applied in order.
+func (s Side) newError(code ErrorCode, options ...ErrorOption) Error {
 	e := Error{
 		code:         code,
-		isServerSide: true,
+		isServerSide: s == ServerSide,
 	}
 
 	for _, opt := range options {
@@ -79,6 +102,25 @@ func NewErrorWithReservedCode(code ErrorCode, options ...ErrorOption) Error {
 	return newError(code, options)
 }
 
+// WrapError returns a new JSON-RPC error with an application-defined error code
+// that is caused by err.
+//
+// err is wrapped by the resulting JSON-RPC error, such as it can be used with
+// errors.Is() and errors.As(). Unless the options provide a user-defined
+// message, err.Error() is used as the message. It is equivalent to calling
+// NewError() with the WithCause(err) option applied after all other options.
+//
+// As per NewError(), use of a reserved error code causes a panic. It also
+// panics if err is nil.
+func WrapError(code ErrorCode, err error, options ...ErrorOption) Error {
+	if err == nil {
+		panic("can not wrap a nil error")
+	}
+
+	options = append(slices.Clip(options), WithCause(err))
+	return NewError(code, options...)
+}
+
 // NewClientSideError returns a new client-side error that represents a JSON-RPC
 // error returned as part of an ErrorResponse.
 func NewClientSideError(
@@ -125,6 +167,16 @@ func (e Error) Message() string {
 	return e.code.String()
 }
 
+// IsServerSide returns true if e was produced on the server side, such as by
+// NewError() within a handler, in which case it is intended to be delivered to
+// the caller.
+//
+// It returns false if e was produced on the client side, such as by
+// NewClientSideError() to represent an error received from a remote server.
+func (e Error) IsServerSide() bool {
+	return e.isServerSide
+}
+
 // MarshalData returns the JSON representation user-defined data value
 // associated with the error.
 //
@@ -160,6 +212,16 @@ func (e Error) Unwrap() error {
 	return e.cause
 }
 
+// Is returns true if target is a JSON-RPC error with the same error code as e.
+//
+// It allows errors.Is() to compare JSON-RPC errors by their code, such as
+// errors.Is(err, harpy.MethodNotFound()). The message and user-defined data of
+// the errors are not compared.
+func (e Error) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && t.code == e.code
+}
+
 // ErrorOption is an option that provides further information about an error.
 type ErrorOption func(*Error)
 
@@ -197,13 +259,31 @@ func WithMessage(format string, values ...any) ErrorOption {
 // WithData is an ErrorOption that associates additional data with an error.
 //
 // The data is provided to the RPC caller via the "data" field of the error
-// object in the JSON-RPC response.
+// object in the JSON-RPC response. It is marshaled using json.Marshal(), so
+// data may implement json.Marshaler to control its JSON representation. See
+// also WithRawData().
 func WithData(data any) ErrorOption {
 	return func(e *Error) {
 		e.data = &inMemoryErrorData{value: data}
 	}
 }
 
+// WithRawData is an ErrorOption that associates pre-marshaled JSON data with an
+// error.
+//
+// Unlike WithData(), the data is used verbatim, without being re-marshaled. This
+// is useful when forwarding the data from an upstream JSON-RPC error. It panics
+// if data is not valid JSON.
+func WithRawData(data json.RawMessage) ErrorOption {
+	if !json.Valid(data) {
+		panic("error data must be valid JSON")
+	}
+
+	return func(e *Error) {
+		e.data = jsonErrorData(data)
+	}
+}
+
 // errorData is an interface for user-defined error data values.
 type errorData interface {
 	Marshal() (json.RawMessage, error)
@@ -3,6 +3,7 @@ package harpy_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	. "github.com/dogmatiq/harpy"
 	. "github.com/onsi/ginkgo"
@@ -26,6 +27,52 @@ var _ = Describe("type Error", func() {
 		})
 	})
 
+	Describe("func WrapError()", func() {
+		It("returns an error that wraps the cause", func() {
+			cause := errors.New("<cause>")
+			e := WrapError(123, cause)
+
+			Expect(e.Code()).To(BeEquivalentTo(123))
+			Expect(e.Message()).To(Equal("<cause>"))
+			Expect(e).To(MatchError(cause))
+		})
+
+		It("prefers a user-defined message over the cause's message", func() {
+			e := WrapError(
+				123,
+				errors.New("<cause>"),
+				WithMessage("<message>"),
+			)
+
+			Expect(e.Message()).To(Equal("<message>"))
+		})
+
+		It("applies other options", func() {
+			e := WrapError(
+				123,
+				errors.New("<cause>"),
+				WithData([]int{1, 2, 3}),
+			)
+
+			data, ok, err := e.MarshalData()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(data).To(MatchJSON(`[1, 2, 3]`))
+		})
+
+		It("panics if the error code is reserved", func() {
+			Expect(func() {
+				WrapError(InternalErrorCode, errors.New("<cause>"))
+			}).To(PanicWith("the error code -32603 is reserved by the JSON-RPC specification (internal server error)"))
+		})
+
+		It("panics if the cause is nil", func() {
+			Expect(func() {
+				WrapError(123, nil)
+			}).To(PanicWith("can not wrap a nil error"))
+		})
+	})
+
 	Describe("func NewClientSideError()", func() {
 		It("does not panic if the error code is reserved", func() {
 			err := NewClientSideError(
@@ -84,6 +131,37 @@ var _ = Describe("type Error", func() {
 		})
 	})
 
+	Describe("func IsServerSide()", func() {
+		It("returns true for errors produced by NewError()", func() {
+			e := NewError(100)
+			Expect(e.IsServerSide()).To(BeTrue())
+		})
+
+		It("returns true for errors with reserved codes", func() {
+			Expect(MethodNotFound().IsServerSide()).To(BeTrue())
+			Expect(InvalidParameters().IsServerSide()).To(BeTrue())
+			Expect(NewErrorWithReservedCode(InternalErrorCode).IsServerSide()).To(BeTrue())
+		})
+
+		It("returns true for errors produced by WrapError()", func() {
+			e := WrapError(100, errors.New("<cause>"))
+			Expect(e.IsServerSide()).To(BeTrue())
+		})
+
+		It("returns false for errors produced by NewClientSideError()", func() {
+			e := NewClientSideError(100, "<message>", nil)
+			Expect(e.IsServerSide()).To(BeFalse())
+		})
+
+		It("returns false for errors produced by client-side validation", func() {
+			req := Request{Version: "2.0", Method: "<method>", Parameters: []byte(`123`)}
+
+			e, ok := req.ValidateClientSide()
+			Expect(ok).To(BeFalse())
+			Expect(e.IsServerSide()).To(BeFalse())
+		})
+	})
+
 	Describe("func MarshalData()", func() {
 		It("returns the JSON representation of the user-defined data (server side)", func() {
 			e := NewError(100, WithData("<data>"))
@@ -105,6 +183,14 @@ var _ = Describe("type Error", func() {
 			Expect(data).To(Equal(json.RawMessage(`"<data>"`)))
 		})
 
+		It("returns the raw user-defined data without re-marshaling it", func() {
+			e := NewError(100, WithRawData(json.RawMessage(`{"b": 2, "a": "<data>"}`)))
+			data, ok, err := e.MarshalData()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(data).To(Equal(json.RawMessage(`{"b": 2, "a": "<data>"}`)))
+		})
+
 		It("returns false if there is no user-defined data", func() {
 			e := NewError(100)
 			_, ok, err := e.MarshalData()
@@ -119,6 +205,14 @@ var _ = Describe("type Error", func() {
 		})
 	})
 
+	Describe("func WithRawData()", func() {
+		It("panics if the data is not valid JSON", func() {
+			Expect(func() {
+				WithRawData(json.RawMessage(`{`))
+			}).To(PanicWith("error data must be valid JSON"))
+		})
+	})
+
 	Describe("func UnmarshalData()", func() {
 		It("unmarshals the user-defined data", func() {
 			e := NewError(100, WithData("<data>"))
@@ -205,4 +299,37 @@ var _ = Describe("type Error", func() {
 			Expect(e.Unwrap()).To(BeIdenticalTo(cause))
 		})
 	})
+
+	Describe("func Is()", func() {
+		It("returns true if the target has the same error code", func() {
+			e := MethodNotFound(WithMessage("<message>"), WithData("<data>"))
+			Expect(errors.Is(e, MethodNotFound())).To(BeTrue())
+		})
+
+		It("returns true if the target is a client-side error with the same error code", func() {
+			e := NewClientSideError(MethodNotFoundCode, "<message>", nil)
+			Expect(errors.Is(e, MethodNotFound())).To(BeTrue())
+		})
+
+		It("returns false if the target has a different error code", func() {
+			e := MethodNotFound()
+			Expect(errors.Is(e, InvalidParameters())).To(BeFalse())
+		})
+
+		It("returns false if the target is not a JSON-RPC error", func() {
+			e := MethodNotFound()
+			Expect(errors.Is(e, errors.New("<error>"))).To(BeFalse())
+		})
+
+		It("matches JSON-RPC errors that are wrapped by other errors", func() {
+			err := fmt.Errorf("<context>: %w", NewError(100))
+			Expect(errors.Is(err, NewError(100))).To(BeTrue())
+		})
+
+		It("matches the causal error", func() {
+			cause := errors.New("<cause>")
+			e := NewError(100, WithCause(cause))
+			Expect(errors.Is(e, cause)).To(BeTrue())
+		})
+	})
 })
@@ -31,6 +31,24 @@ const (
 	InternalErrorCode ErrorCode = -32603
 )
 
+// The following error codes are used to indicate that the server stopped
+// processing a request because its context was canceled or its deadline was
+// exceeded.
+//
+// They are not reserved by the JSON-RPC specification, but are used by
+// NewErrorResponse() to represent context errors so that clients can
+// distinguish them from other internal errors. Applications should avoid
+// using these codes for other purposes.
+const (
+	// CanceledCode indicates that the request was canceled before the server
+	// finished processing it.
+	CanceledCode ErrorCode = -31000
+
+	// DeadlineExceededCode indicates that the request's deadline was exceeded
+	// before the server finished processing it.
+	DeadlineExceededCode ErrorCode = -31001
+)
+
 // IsReserved returns true if c falls within the range of error codes reserved
 // for pre-defined errors.
 func (c ErrorCode) IsReserved() bool {
@@ -65,6 +83,10 @@ func (c ErrorCode) String() string {
 		return "invalid parameters"
 	case InternalErrorCode:
 		return "internal server error"
+	case CanceledCode:
+		return "request canceled"
+	case DeadlineExceededCode:
+		return "deadline exceeded"
 	}
 
 	if c.IsReserved() {
@@ -19,6 +19,8 @@ var _ = Describe("type ErrorCode", func() {
 			Entry("method not found", MethodNotFoundCode, "method not found"),
 			Entry("invalid parameters", InvalidParametersCode, "invalid parameters"),
 			Entry("internal server error", InternalErrorCode, "internal server error"),
+			Entry("canceled", CanceledCode, "request canceled"),
+			Entry("deadline exceeded", DeadlineExceededCode, "deadline exceeded"),
 			Entry("undefined reserved code", ErrorCode(-32000), "undefined reserved error"),
 			Entry("user-defined error", ErrorCode(100), "unknown error"),
 		)
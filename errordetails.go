@@ -0,0 +1,138 @@
+package harpy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrorDetails is a conventional structure for the user-defined data
+// associated with a JSON-RPC error.
+//
+// It is represented in JSON as an object with exactly two properties:
+//
+//   - "details", an object containing application-defined information about
+//     the error, which is empty if there is no such information
+//   - "retryAfter", the number of seconds that the caller should wait before
+//     retrying the request, or null if the caller should not retry
+//
+// Use WithErrorDetails() to associate ErrorDetails with an error on the server
+// side, and Error.UnmarshalDetails() to read them on the client side.
+type ErrorDetails struct {
+	// Details is application-defined information about the error.
+	Details map[string]any
+
+	// RetryAfter is the amount of time that the caller should wait before
+	// retrying the request. It is rounded up to a whole number of seconds. A
+	// zero value indicates that the caller should not retry.
+	RetryAfter time.Duration
+}
+
+// maxRetryAfterSeconds is the largest "retryAfter" value, in seconds, that can
+// be represented as a time.Duration.
+const maxRetryAfterSeconds = float64(math.MaxInt64 / int64(time.Second))
+
+// errorDetailsJSON is the JSON representation of ErrorDetails.
+type errorDetailsJSON struct {
+	Details    map[string]any `json:"details"`
+	RetryAfter *float64       `json:"retryAfter"`
+}
+
+// MarshalJSON returns the JSON representation of d.
+func (d ErrorDetails) MarshalJSON() ([]byte, error) {
+	if d.RetryAfter < 0 {
+		return nil, errors.New("retry-after duration must not be negative")
+	}
+
+	enc := errorDetailsJSON{
+		Details: d.Details,
+	}
+
+	if enc.Details == nil {
+		enc.Details = map[string]any{}
+	}
+
+	if d.RetryAfter > 0 {
+		seconds := math.Ceil(d.RetryAfter.Seconds())
+		enc.RetryAfter = &seconds
+	}
+
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON populates d from its JSON representation.
+//
+// Properties other than "details" and "retryAfter" are ignored. A missing
+// property is treated the same as a null value.
+func (d *ErrorDetails) UnmarshalJSON(data []byte) error {
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil || properties == nil {
+		return errors.New("error details must be a JSON object")
+	}
+
+	var dec errorDetailsJSON
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return fmt.Errorf("unable to unmarshal error details: %w", err)
+	}
+
+	*d = ErrorDetails{
+		Details: dec.Details,
+	}
+
+	if dec.RetryAfter != nil {
+		if *dec.RetryAfter < 0 {
+			return errors.New("error details must not contain a negative retry-after duration")
+		}
+
+		if *dec.RetryAfter > maxRetryAfterSeconds {
+			return fmt.Errorf(
+				"error details must not contain a retry-after duration greater than %d seconds",
+				int64(maxRetryAfterSeconds),
+			)
+		}
+
+		d.RetryAfter = time.Duration(*dec.RetryAfter * float64(time.Second))
+	}
+
+	return nil
+}
+
+// WithErrorDetails is an ErrorOption that associates ErrorDetails with an
+// error.
+//
+// It is an alternative to WithData() that ensures the "data" field of the
+// JSON-RPC error object always has the same shape. It panics if
+// d.RetryAfter is negative.
+func WithErrorDetails(d ErrorDetails) ErrorOption {
+	if d.RetryAfter < 0 {
+		panic("retry-after duration must not be negative")
+	}
+
+	return WithData(d)
+}
+
+// UnmarshalDetails unmarshals the user-defined data into ErrorDetails.
+//
+// ok is false if there is no user-defined data associated with the error, in
+// which case the error is always nil. Otherwise, ok is true, and an error is
+// returned if the data can not be marshaled, or is not a JSON object of the
+// shape described by ErrorDetails.
+func (e Error) UnmarshalDetails() (_ ErrorDetails, ok bool, _ error) {
+	data, ok, err := e.MarshalData()
+	if !ok {
+		return ErrorDetails{}, false, nil
+	}
+
+	if err != nil {
+		return ErrorDetails{}, true, err
+	}
+
+	var d ErrorDetails
+	if err := json.Unmarshal(data, &d); err != nil {
+		return ErrorDetails{}, true, err
+	}
+
+	return d, true, nil
+}
@@ -0,0 +1,133 @@
+package harpy_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type ErrorDetails", func() {
+	Describe("func WithErrorDetails()", func() {
+		It("associates the details with the error", func() {
+			e := NewError(
+				100,
+				WithErrorDetails(ErrorDetails{
+					Details:    map[string]any{"field": "<value>"},
+					RetryAfter: 1500 * time.Millisecond,
+				}),
+			)
+
+			data, ok, err := e.MarshalData()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(data).To(MatchJSON(`{"details": {"field": "<value>"}, "retryAfter": 2}`))
+		})
+
+		It("always includes both properties", func() {
+			e := NewError(100, WithErrorDetails(ErrorDetails{}))
+
+			data, ok, err := e.MarshalData()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(data).To(MatchJSON(`{"details": {}, "retryAfter": null}`))
+		})
+
+		It("panics if the retry-after duration is negative", func() {
+			Expect(func() {
+				WithErrorDetails(ErrorDetails{RetryAfter: -time.Second})
+			}).To(PanicWith("retry-after duration must not be negative"))
+		})
+	})
+
+	Describe("func UnmarshalDetails()", func() {
+		It("unmarshals the details (client side)", func() {
+			e := NewClientSideError(
+				100,
+				"<message>",
+				json.RawMessage(`{"details": {"field": "<value>"}, "retryAfter": 3, "other": true}`),
+			)
+
+			d, ok, err := e.UnmarshalDetails()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(d).To(Equal(ErrorDetails{
+				Details:    map[string]any{"field": "<value>"},
+				RetryAfter: 3 * time.Second,
+			}))
+		})
+
+		It("round-trips details produced by WithErrorDetails()", func() {
+			e := NewError(
+				100,
+				WithErrorDetails(ErrorDetails{
+					Details:    map[string]any{"field": "<value>"},
+					RetryAfter: 5 * time.Second,
+				}),
+			)
+
+			d, ok, err := e.UnmarshalDetails()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(d).To(Equal(ErrorDetails{
+				Details:    map[string]any{"field": "<value>"},
+				RetryAfter: 5 * time.Second,
+			}))
+		})
+
+		It("treats missing properties as empty", func() {
+			e := NewClientSideError(100, "<message>", json.RawMessage(`{}`))
+
+			d, ok, err := e.UnmarshalDetails()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(d).To(Equal(ErrorDetails{}))
+		})
+
+		It("returns false if there is no user-defined data", func() {
+			e := NewError(100)
+
+			_, ok, err := e.UnmarshalDetails()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		DescribeTable(
+			"it returns an error if the data does not have the expected shape",
+			func(data, expect string) {
+				e := NewClientSideError(100, "<message>", json.RawMessage(data))
+
+				d, ok, err := e.UnmarshalDetails()
+				Expect(err).To(MatchError(ContainSubstring(expect)))
+				Expect(ok).To(BeTrue())
+				Expect(d).To(Equal(ErrorDetails{}))
+			},
+			Entry("not an object", `"<data>"`, "error details must be a JSON object"),
+			Entry("null", `null`, "error details must be a JSON object"),
+			Entry("details is not an object", `{"details": 123}`, "unable to unmarshal error details"),
+			Entry("retryAfter is not a number", `{"retryAfter": "soon"}`, "unable to unmarshal error details"),
+			Entry("retryAfter is negative", `{"retryAfter": -1}`, "error details must not contain a negative retry-after duration"),
+			Entry("retryAfter is too large", `{"retryAfter": 9223372037}`, "error details must not contain a retry-after duration greater than 9223372036 seconds"),
+		)
+
+		It("accepts the largest representable retryAfter", func() {
+			e := NewClientSideError(100, "<message>", json.RawMessage(`{"retryAfter": 9223372036}`))
+
+			d, ok, err := e.UnmarshalDetails()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(d.RetryAfter).To(BeNumerically(">", 0))
+		})
+
+		It("returns true and an error if the data can not be marshaled", func() {
+			e := NewError(100, WithData(make(chan int)))
+
+			_, ok, err := e.UnmarshalDetails()
+			Expect(err).To(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+	})
+})
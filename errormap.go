@@ -0,0 +1,50 @@
+package harpy
+
+import "context"
+
+// ErrorMapper is an implementation of Exchanger that rewrites the errors within
+// the error responses produced by the next exchanger.
+//
+// It allows a gateway that aggregates several services to renumber or relabel
+// their errors consistently, such as when the application-defined error codes
+// used by those services collide.
+type ErrorMapper struct {
+	// Next is the next exchanger in the middleware stack.
+	Next Exchanger
+
+	// Map returns the error to send to the client in place of in, which is the
+	// error produced by the next exchanger in response to a call to the given
+	// method. If it is nil, errors are not changed.
+	Map func(method string, in ErrorInfo) ErrorInfo
+}
+
+var _ Exchanger = (*ErrorMapper)(nil)
+
+// Call handles a call request and returns the response.
+//
+// If the next exchanger produces an ErrorResponse, its error is replaced with
+// the error returned by m.Map. The response's ServerError field is preserved,
+// so that the original error is still available for logging. Success responses
+// are returned unchanged.
+func (m *ErrorMapper) Call(ctx context.Context, req Request) Response {
+	res := m.Next.Call(ctx, req)
+
+	if m.Map == nil {
+		return res
+	}
+
+	if res, ok := res.(ErrorResponse); ok {
+		res.Error = m.Map(req.Method, res.Error)
+		return res
+	}
+
+	return res
+}
+
+// Notify handles a notification request.
+//
+// It forwards the request to the next exchanger unchanged. Notifications do
+// not produce a response, so there is no error to rewrite.
+func (m *ErrorMapper) Notify(ctx context.Context, req Request) error {
+	return m.Next.Notify(ctx, req)
+}
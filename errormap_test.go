@@ -0,0 +1,103 @@
+package harpy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type ErrorMapper", func() {
+	var (
+		next    *ExchangerStub
+		request Request
+		mapper  *ErrorMapper
+	)
+
+	BeforeEach(func() {
+		next = &ExchangerStub{}
+
+		request = Request{
+			Version: "2.0",
+			ID:      json.RawMessage(`123`),
+			Method:  "<method>",
+		}
+
+		mapper = &ErrorMapper{
+			Next: next,
+			Map: func(method string, in ErrorInfo) ErrorInfo {
+				Expect(method).To(Equal("<method>"))
+
+				in.Code += 1000
+				in.Message = "<mapped>: " + in.Message
+				return in
+			},
+		}
+	})
+
+	Describe("func Call()", func() {
+		It("rewrites the error in error responses", func() {
+			serverErr := errors.New("<server error>")
+
+			next.CallFunc = func(_ context.Context, req Request) Response {
+				return ErrorResponse{
+					Version:   "2.0",
+					RequestID: req.ID,
+					Error: ErrorInfo{
+						Code:    123,
+						Message: "<message>",
+						Data:    json.RawMessage(`"<data>"`),
+					},
+					ServerError: serverErr,
+				}
+			}
+
+			res := mapper.Call(context.Background(), request)
+			Expect(res).To(Equal(ErrorResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`123`),
+				Error: ErrorInfo{
+					Code:    1123,
+					Message: "<mapped>: <message>",
+					Data:    json.RawMessage(`"<data>"`),
+				},
+				ServerError: serverErr,
+			}))
+		})
+
+		It("does not modify success responses", func() {
+			next.CallFunc = func(_ context.Context, req Request) Response {
+				return NewSuccessResponse(req.ID, 456)
+			}
+
+			res := mapper.Call(context.Background(), request)
+			Expect(res).To(Equal(NewSuccessResponse(json.RawMessage(`123`), 456)))
+		})
+
+		It("does not modify error responses if there is no mapping function", func() {
+			mapper.Map = nil
+
+			next.CallFunc = func(_ context.Context, req Request) Response {
+				return NewErrorResponse(req.ID, NewError(123))
+			}
+
+			res := mapper.Call(context.Background(), request)
+			Expect(res).To(Equal(NewErrorResponse(json.RawMessage(`123`), NewError(123))))
+		})
+	})
+
+	Describe("func Notify()", func() {
+		It("returns the error from the next exchanger", func() {
+			next.NotifyFunc = func(context.Context, Request) error {
+				return errors.New("<error>")
+			}
+
+			err := mapper.Notify(context.Background(), request)
+			Expect(err).To(MatchError("<error>"))
+		})
+	})
+})
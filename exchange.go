@@ -3,6 +3,7 @@ package harpy
 import (
 	"context"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -12,8 +13,10 @@ import (
 // for its response.
 //
 // The Exchanger is responsible for resolving any error conditions. In the case
-// of a JSON-RPC call it must also provide the response. It therefore has no
-// facility to return an error.
+// of a JSON-RPC call it must also provide the response, and therefore has no
+// facility to return an error. Errors that occur while handling notifications
+// are returned so that they can be logged, but they are never sent to the
+// caller.
 type Exchanger interface {
 	// Call handles call request and returns its response.
 	Call(context.Context, Request) Response
@@ -56,6 +59,36 @@ type ResponseWriter interface {
 	Close() error
 }
 
+// A NotificationAcknowledger is a ResponseWriter that acknowledges each
+// notification once it has been handled.
+//
+// Acknowledgements are a transport extension; they are NOT part of the JSON-RPC
+// specification, which requires that the server does not reply to
+// notifications. Transports should only acknowledge notifications when
+// explicitly configured to do so, such as when the application requires
+// confirmation that a notification has been handled before sending the next
+// message.
+//
+// Exchange() acknowledges notifications if the ResponseWriter it is given
+// implements this interface. Transports that do not support acknowledgements,
+// such as the HTTP transport, simply do not implement it.
+type NotificationAcknowledger interface {
+	ResponseWriter
+
+	// AcknowledgeUnbatched acknowledges a notification that was not part of
+	// a batch.
+	//
+	// It is called after the exchanger has handled the notification,
+	// regardless of whether it returned an error.
+	AcknowledgeUnbatched(Request) error
+
+	// AcknowledgeBatched acknowledges a notification that was part of a batch.
+	//
+	// It is called after the exchanger has handled the notification,
+	// regardless of whether it returned an error.
+	AcknowledgeBatched(Request) error
+}
+
 // Exchange performs a JSON-RPC exchange, whether for a single request or a
 // batch of requests.
 //
@@ -74,6 +107,9 @@ type ResponseWriter interface {
 // If w produces an error, the context passed to e is canceled and Exchange()
 // returns the ResponseWriter's error. Execution blocks until all goroutines are
 // completed, but no more responses are written.
+//
+// If w implements NotificationAcknowledger, each notification is acknowledged
+// once it has been handled.
 func Exchange(
 	ctx context.Context,
 	e Exchanger,
@@ -198,16 +234,31 @@ func exchangeOne(
 	e Exchanger,
 	req Request,
 	w func(Response) error,
+	ack func(Request) error,
 	l ExchangeLogger,
 ) error {
+	if sl, ok := l.(RequestStartLogger); ok {
+		sl.LogRequestStart(ctx, req)
+	}
+
+	start := time.Now()
+
 	if req.IsNotification() {
 		err := e.Notify(ctx, req)
-		l.LogNotification(ctx, req, err)
+		l.LogNotification(WithRequestDuration(ctx, time.Since(start)), req, err)
+
+		if ack != nil {
+			if err := ack(req); err != nil {
+				l.LogWriterError(ctx, err)
+				return err
+			}
+		}
+
 		return nil
 	}
 
 	res := e.Call(ctx, req)
-	l.LogCall(ctx, req, res)
+	l.LogCall(WithRequestDuration(ctx, time.Since(start)), req, res)
 
 	if err := w(res); err != nil {
 		l.LogWriterError(ctx, err)
@@ -226,11 +277,17 @@ func exchangeSingle(
 	w ResponseWriter,
 	l ExchangeLogger,
 ) error {
+	var ack func(Request) error
+	if a, ok := w.(NotificationAcknowledger); ok {
+		ack = a.AcknowledgeUnbatched
+	}
+
 	return exchangeOne(
 		ctx,
 		e,
 		req,
 		w.WriteUnbatched,
+		ack,
 		l,
 	)
 }
@@ -249,6 +306,11 @@ func exchangeBatch(
 		return exchangeMany(ctx, e, requests, w, l)
 	}
 
+	var ack func(Request) error
+	if a, ok := w.(NotificationAcknowledger); ok {
+		ack = a.AcknowledgeBatched
+	}
+
 	// Otherwise we have a batch that happens to contain a single request. We
 	// avoid the overhead and latency of starting the extra goroutines and
 	// awaiting their completion.
@@ -257,6 +319,7 @@ func exchangeBatch(
 		e,
 		requests[0],
 		w.WriteBatched,
+		ack,
 		l,
 	)
 }
@@ -279,6 +342,34 @@ func exchangeMany(
 	// error occurs when writing responses.
 	g, ctx := errgroup.WithContext(ctx)
 
+	// Associate the batch with the context so that exchangers can share state
+	// between the requests within it.
+	ctx = context.WithValue(ctx, batchKey{}, &batch{})
+
+	// write calls fn with m locked, unless there has already been an error
+	// writing responses.
+	write := func(fn func() error) error {
+		m.Lock()
+		defer m.Unlock()
+
+		if ok {
+			err := fn()
+			ok = err == nil
+			return err
+		}
+
+		return nil
+	}
+
+	var ack func(Request) error
+	if a, ok := w.(NotificationAcknowledger); ok {
+		ack = func(req Request) error {
+			return write(func() error {
+				return a.AcknowledgeBatched(req)
+			})
+		}
+	}
+
 	// Start a goroutine for each request.
 	for _, req := range requests {
 		req := req // capture loop variable
@@ -289,19 +380,11 @@ func exchangeMany(
 				e,
 				req,
 				func(res Response) error {
-					m.Lock()
-					defer m.Unlock()
-
-					// Only write the response if there has not already been
-					// an error writing responses.
-					if ok {
-						err := w.WriteBatched(res)
-						ok = err == nil
-						return err
-					}
-
-					return nil
+					return write(func() error {
+						return w.WriteBatched(res)
+					})
 				},
+				ack,
 				l,
 			)
 		})
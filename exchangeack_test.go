@@ -0,0 +1,129 @@
+package harpy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("func Exchange() (notification acknowledgements)", func() {
+	var (
+		exchanger    *ExchangerStub
+		requestSet   RequestSet
+		reader       *RequestSetReaderStub
+		writer       *AcknowledgingResponseWriterStub
+		logger       ExchangeLogger
+		m            sync.Mutex
+		unbatched    []Request
+		batched      []Request
+		notification Request
+	)
+
+	BeforeEach(func() {
+		exchanger = &ExchangerStub{}
+
+		notification = Request{
+			Version: "2.0",
+			Method:  "<notification>",
+		}
+
+		reader = &RequestSetReaderStub{
+			ReadFunc: func(context.Context) (RequestSet, error) {
+				return requestSet, nil
+			},
+		}
+
+		unbatched = nil
+		batched = nil
+
+		writer = &AcknowledgingResponseWriterStub{
+			AcknowledgeUnbatchedFunc: func(req Request) error {
+				unbatched = append(unbatched, req)
+				return nil
+			},
+			AcknowledgeBatchedFunc: func(req Request) error {
+				m.Lock()
+				defer m.Unlock()
+				batched = append(batched, req)
+				return nil
+			},
+		}
+
+		logger = NewZapExchangeLogger(zap.NewNop())
+	})
+
+	It("acknowledges an unbatched notification", func() {
+		requestSet = RequestSet{
+			Requests: []Request{notification},
+		}
+
+		err := Exchange(context.Background(), exchanger, reader, writer, logger)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(unbatched).To(Equal([]Request{notification}))
+		Expect(batched).To(BeEmpty())
+	})
+
+	It("acknowledges the notification in a batch containing a single request", func() {
+		requestSet = RequestSet{
+			Requests: []Request{notification},
+			IsBatch:  true,
+		}
+
+		err := Exchange(context.Background(), exchanger, reader, writer, logger)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(unbatched).To(BeEmpty())
+		Expect(batched).To(Equal([]Request{notification}))
+	})
+
+	It("acknowledges each notification in a batch, but not calls", func() {
+		call := Request{
+			Version: "2.0",
+			ID:      json.RawMessage(`1`),
+			Method:  "<call>",
+		}
+
+		requestSet = RequestSet{
+			Requests: []Request{notification, call, notification},
+			IsBatch:  true,
+		}
+
+		err := Exchange(context.Background(), exchanger, reader, writer, logger)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(unbatched).To(BeEmpty())
+		Expect(batched).To(Equal([]Request{notification, notification}))
+	})
+
+	It("acknowledges notifications even if the exchanger returns an error", func() {
+		exchanger.NotifyFunc = func(context.Context, Request) error {
+			return errors.New("<error>")
+		}
+
+		requestSet = RequestSet{
+			Requests: []Request{notification},
+		}
+
+		err := Exchange(context.Background(), exchanger, reader, writer, logger)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(unbatched).To(HaveLen(1))
+	})
+
+	It("returns an error if the acknowledgement can not be written", func() {
+		writer.AcknowledgeUnbatchedFunc = func(Request) error {
+			return errors.New("<error>")
+		}
+
+		requestSet = RequestSet{
+			Requests: []Request{notification},
+		}
+
+		err := Exchange(context.Background(), exchanger, reader, writer, logger)
+		Expect(err).To(MatchError("<error>"))
+	})
+})
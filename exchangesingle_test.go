@@ -114,6 +114,18 @@ var _ = Describe("func Exchange() (single request)", func() {
 			)
 
 			Expect(err).ShouldNot(HaveOccurred())
+			Expect(logs.AllUntimed()).To(ContainElement(
+				observer.LoggedEntry{
+					Entry: zapcore.Entry{
+						Level:   zapcore.DebugLevel,
+						Message: `received`,
+					},
+					Context: []zapcore.Field{
+						zap.String("method", "<method>"),
+						zap.Int("param_size", 2),
+					},
+				},
+			))
 			Expect(logs.AllUntimed()).To(ContainElement(
 				observer.LoggedEntry{
 					Entry: zapcore.Entry{
@@ -195,5 +207,37 @@ var _ = Describe("func Exchange() (single request)", func() {
 				},
 			))
 		})
+
+		It("logs errors returned by the exchanger", func() {
+			exchanger.NotifyFunc = func(
+				context.Context,
+				Request,
+			) error {
+				return errors.New("<error>")
+			}
+
+			err := Exchange(
+				context.Background(),
+				exchanger,
+				reader,
+				writer,
+				logger,
+			)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(logs.AllUntimed()).To(ContainElement(
+				observer.LoggedEntry{
+					Entry: zapcore.Entry{
+						Level:   zapcore.ErrorLevel,
+						Message: `notify`,
+					},
+					Context: []zapcore.Field{
+						zap.String("method", "<method>"),
+						zap.Int("param_size", 2),
+						zap.String("error", "<error>"),
+					},
+				},
+			))
+		})
 	})
 })
@@ -0,0 +1,244 @@
+package harpy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// FanOutStrategy determines how a FanOut exchanger chooses the response to a
+// call from the responses produced by its backends.
+type FanOutStrategy int
+
+const (
+	// FirstSuccess is a FanOutStrategy that returns the first successful
+	// response produced by any backend.
+	FirstSuccess FanOutStrategy = iota
+
+	// Quorum is a FanOutStrategy that returns a successful response once a
+	// quorum of backends have produced successful responses with equivalent
+	// results.
+	Quorum
+)
+
+// FanOut is an implementation of Exchanger that sends each request to multiple
+// backend exchangers concurrently.
+//
+// Calls are passed to every backend using a shared context. Once a response
+// has been chosen, as per the strategy, the context is canceled so that the
+// remaining backends may stop work. If no suitable response is produced, the
+// errors from all backends are aggregated into a single ErrorResponse.
+//
+// Notifications are broadcast to every backend.
+type FanOut struct {
+	// Backends is the set of exchangers that each request is sent to. It must
+	// not be empty; Call() and Notify() panic if there are no backends.
+	Backends []Exchanger
+
+	// Strategy determines how the response to a call is chosen. The default
+	// is FirstSuccess.
+	Strategy FanOutStrategy
+
+	// QuorumSize is the number of backends that must produce equivalent
+	// successful responses when using the Quorum strategy. If it is zero, a
+	// majority of the backends is required.
+	//
+	// It must not exceed the number of backends, as such a quorum can never be
+	// reached; Call() panics if it does.
+	QuorumSize int
+}
+
+var _ Exchanger = (*FanOut)(nil)
+
+// Call handles a call request and returns the response.
+func (f *FanOut) Call(ctx context.Context, req Request) Response {
+	f.mustHaveBackends()
+
+	var quorum int
+	if f.Strategy == Quorum {
+		quorum = f.quorumSize()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		res   Response
+	}
+
+	// The channel is buffered so that backends that complete after a response
+	// has been chosen do not block forever.
+	results := make(chan result, len(f.Backends))
+
+	for i, x := range f.Backends {
+		go func() {
+			results <- result{i, x.Call(ctx, req)}
+		}()
+	}
+
+	votes := map[string]int{}
+	responses := make([]Response, len(f.Backends))
+
+	for range f.Backends {
+		r := <-results
+		responses[r.index] = r.res
+
+		res, ok := r.res.(SuccessResponse)
+		if !ok {
+			continue
+		}
+
+		if f.Strategy != Quorum {
+			return res
+		}
+
+		// Streamed results must be read so that they can be compared with
+		// the results from other backends.
+		res, err := res.BufferResult()
+		if err != nil {
+			responses[r.index] = NewErrorResponse(req.ID, err)
+			continue
+		}
+
+		key := compactJSON(res.Result)
+		votes[key]++
+
+		if votes[key] >= quorum {
+			return res
+		}
+	}
+
+	return f.aggregateErrors(req, responses)
+}
+
+// Notify handles a notification request.
+//
+// The notification is sent to each backend concurrently. It returns the errors
+// produced by all backends, if any.
+func (f *FanOut) Notify(ctx context.Context, req Request) error {
+	f.mustHaveBackends()
+
+	var (
+		g    sync.WaitGroup
+		errs = make([]error, len(f.Backends))
+	)
+
+	for i, x := range f.Backends {
+		g.Add(1)
+		go func() {
+			defer g.Done()
+			errs[i] = x.Notify(ctx, req)
+		}()
+	}
+
+	g.Wait()
+
+	return errors.Join(errs...)
+}
+
+// mustHaveBackends panics if f has no backends.
+func (f *FanOut) mustHaveBackends() {
+	if len(f.Backends) == 0 {
+		panic("fan-out exchanger has no backends")
+	}
+}
+
+// quorumSize returns the number of equivalent successful responses required
+// when using the Quorum strategy.
+//
+// It panics if f.QuorumSize exceeds the number of backends.
+func (f *FanOut) quorumSize() int {
+	if f.QuorumSize > len(f.Backends) {
+		panic("fan-out quorum size exceeds the number of backends")
+	}
+
+	if f.QuorumSize > 0 {
+		return f.QuorumSize
+	}
+
+	return len(f.Backends)/2 + 1
+}
+
+// aggregateErrors returns an ErrorResponse that describes the failure of the
+// backends to produce a suitable response.
+//
+// If every backend produced the same error code and message, that error is
+// returned as-is. Otherwise, the response indicates an internal error and its
+// data contains the errors produced by each backend that failed.
+func (f *FanOut) aggregateErrors(req Request, responses []Response) ErrorResponse {
+	var (
+		infos     []ErrorInfo
+		causes    []error
+		successes int
+	)
+
+	for _, res := range responses {
+		switch res := res.(type) {
+		case ErrorResponse:
+			infos = append(infos, res.Error)
+			causes = append(causes, res.ServerError)
+		case SuccessResponse:
+			successes++
+		}
+	}
+
+	if successes == 0 && len(infos) != 0 && allSameError(infos) {
+		return ErrorResponse{
+			Version:     jsonRPCVersion,
+			RequestID:   req.ID,
+			Error:       infos[0],
+			ServerError: errors.Join(causes...),
+		}
+	}
+
+	message := "all backends failed"
+	if successes != 0 {
+		message = "backends did not reach a quorum"
+	}
+
+	res := ErrorResponse{
+		Version:   jsonRPCVersion,
+		RequestID: req.ID,
+		Error: ErrorInfo{
+			Code:    InternalErrorCode,
+			Message: message,
+		},
+		ServerError: errors.Join(causes...),
+	}
+
+	if len(infos) != 0 {
+		// CODE COVERAGE: ErrorInfo always marshals successfully, as its data
+		// is already JSON.
+		if data, err := json.Marshal(infos); err == nil {
+			res.Error.Data = data
+		}
+	}
+
+	return res
+}
+
+// allSameError returns true if all of the given errors have the same code and
+// message.
+func allSameError(infos []ErrorInfo) bool {
+	for _, info := range infos[1:] {
+		if info.Code != infos[0].Code || info.Message != infos[0].Message {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compactJSON returns the compact representation of data, or data unchanged if
+// it is not valid JSON.
+func compactJSON(data json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return string(data)
+	}
+
+	return buf.String()
+}
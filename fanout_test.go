@@ -0,0 +1,264 @@
+package harpy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type FanOut", func() {
+	var request Request
+
+	BeforeEach(func() {
+		request = Request{
+			Version: "2.0",
+			ID:      json.RawMessage(`123`),
+			Method:  "<method>",
+		}
+	})
+
+	// succeed returns a backend that responds successfully with the given
+	// result.
+	succeed := func(result string) *ExchangerStub {
+		return &ExchangerStub{
+			CallFunc: func(_ context.Context, req Request) Response {
+				return SuccessResponse{
+					Version:   "2.0",
+					RequestID: req.ID,
+					Result:    json.RawMessage(result),
+				}
+			},
+		}
+	}
+
+	// fail returns a backend that responds with the given error.
+	fail := func(err error) *ExchangerStub {
+		return &ExchangerStub{
+			CallFunc: func(_ context.Context, req Request) Response {
+				return NewErrorResponse(req.ID, err)
+			},
+		}
+	}
+
+	// block returns a backend that does not respond until its context is
+	// canceled.
+	block := func(canceled *atomic.Bool) *ExchangerStub {
+		return &ExchangerStub{
+			CallFunc: func(ctx context.Context, req Request) Response {
+				<-ctx.Done()
+				canceled.Store(true)
+				return NewErrorResponse(req.ID, ctx.Err())
+			},
+		}
+	}
+
+	Describe("func Call()", func() {
+		When("using the FirstSuccess strategy", func() {
+			It("returns the first successful response", func() {
+				var canceled atomic.Bool
+
+				exchanger := &FanOut{
+					Backends: []Exchanger{
+						fail(errors.New("<error>")),
+						block(&canceled),
+						succeed(`[1, 2, 3]`),
+					},
+				}
+
+				res := exchanger.Call(context.Background(), request)
+				Expect(res).To(Equal(SuccessResponse{
+					Version:   "2.0",
+					RequestID: json.RawMessage(`123`),
+					Result:    json.RawMessage(`[1, 2, 3]`),
+				}))
+
+				Eventually(canceled.Load).Should(BeTrue())
+			})
+
+			It("returns the error if all backends fail with the same error", func() {
+				exchanger := &FanOut{
+					Backends: []Exchanger{
+						fail(NewError(456, WithMessage("<message>"))),
+						fail(NewError(456, WithMessage("<message>"))),
+					},
+				}
+
+				res := exchanger.Call(context.Background(), request)
+				Expect(res).To(Equal(ErrorResponse{
+					Version:   "2.0",
+					RequestID: json.RawMessage(`123`),
+					Error: ErrorInfo{
+						Code:    456,
+						Message: "<message>",
+					},
+				}))
+			})
+
+			It("aggregates the errors if all backends fail with different errors", func() {
+				cause := errors.New("<cause>")
+
+				exchanger := &FanOut{
+					Backends: []Exchanger{
+						fail(NewError(456, WithMessage("<message>"))),
+						fail(cause),
+					},
+				}
+
+				res := exchanger.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+
+				errRes := res.(ErrorResponse)
+				Expect(errRes.RequestID).To(Equal(json.RawMessage(`123`)))
+				Expect(errRes.Error.Code).To(Equal(InternalErrorCode))
+				Expect(errRes.Error.Message).To(Equal("all backends failed"))
+				Expect(errRes.Error.Data).To(MatchJSON(`[
+					{"code": 456, "message": "<message>"},
+					{"code": -32603, "message": "internal server error"}
+				]`))
+				Expect(errRes.ServerError).To(MatchError(cause))
+			})
+		})
+
+		When("using the Quorum strategy", func() {
+			It("returns a response once a majority of backends agree", func() {
+				var canceled atomic.Bool
+
+				exchanger := &FanOut{
+					Backends: []Exchanger{
+						succeed(`[1, 2, 3]`),
+						succeed(`[1,2,3]`),
+						block(&canceled),
+					},
+					Strategy: Quorum,
+				}
+
+				res := exchanger.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+				Expect(res.(SuccessResponse).Result).To(MatchJSON(`[1, 2, 3]`))
+
+				Eventually(canceled.Load).Should(BeTrue())
+			})
+
+			It("honors the quorum size", func() {
+				exchanger := &FanOut{
+					Backends: []Exchanger{
+						succeed(`1`),
+						succeed(`1`),
+						succeed(`2`),
+					},
+					Strategy:   Quorum,
+					QuorumSize: 3,
+				}
+
+				res := exchanger.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				Expect(res.(ErrorResponse).Error.Message).To(Equal("backends did not reach a quorum"))
+			})
+
+			It("panics if the quorum size exceeds the number of backends", func() {
+				exchanger := &FanOut{
+					Backends: []Exchanger{
+						succeed(`1`),
+						succeed(`1`),
+					},
+					Strategy:   Quorum,
+					QuorumSize: 3,
+				}
+
+				Expect(func() {
+					exchanger.Call(context.Background(), request)
+				}).To(PanicWith("fan-out quorum size exceeds the number of backends"))
+			})
+
+			It("returns an error if the backends do not agree", func() {
+				exchanger := &FanOut{
+					Backends: []Exchanger{
+						succeed(`1`),
+						succeed(`2`),
+						fail(NewError(456, WithMessage("<message>"))),
+					},
+					Strategy: Quorum,
+				}
+
+				res := exchanger.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+
+				errRes := res.(ErrorResponse)
+				Expect(errRes.Error.Code).To(Equal(InternalErrorCode))
+				Expect(errRes.Error.Message).To(Equal("backends did not reach a quorum"))
+				Expect(errRes.Error.Data).To(MatchJSON(`[
+					{"code": 456, "message": "<message>"}
+				]`))
+			})
+		})
+
+		It("panics if there are no backends", func() {
+			exchanger := &FanOut{}
+
+			Expect(func() {
+				exchanger.Call(context.Background(), request)
+			}).To(PanicWith("fan-out exchanger has no backends"))
+		})
+	})
+
+	Describe("func Notify()", func() {
+		BeforeEach(func() {
+			request.ID = nil
+		})
+
+		It("sends the notification to all backends", func() {
+			var count atomic.Int32
+
+			backend := &ExchangerStub{
+				NotifyFunc: func(_ context.Context, req Request) error {
+					Expect(req).To(Equal(request))
+					count.Add(1)
+					return nil
+				},
+			}
+
+			exchanger := &FanOut{
+				Backends: []Exchanger{backend, backend, backend},
+			}
+
+			err := exchanger.Notify(context.Background(), request)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count.Load()).To(BeEquivalentTo(3))
+		})
+
+		It("returns the errors from all backends", func() {
+			exchanger := &FanOut{
+				Backends: []Exchanger{
+					&ExchangerStub{
+						NotifyFunc: func(context.Context, Request) error {
+							return errors.New("<error 1>")
+						},
+					},
+					&ExchangerStub{},
+					&ExchangerStub{
+						NotifyFunc: func(context.Context, Request) error {
+							return errors.New("<error 2>")
+						},
+					},
+				},
+			}
+
+			err := exchanger.Notify(context.Background(), request)
+			Expect(err).To(MatchError("<error 1>\n<error 2>"))
+		})
+
+		It("panics if there are no backends", func() {
+			exchanger := &FanOut{}
+
+			Expect(func() {
+				exchanger.Notify(context.Background(), request)
+			}).To(PanicWith("fan-out exchanger has no backends"))
+		})
+	})
+})
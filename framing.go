@@ -0,0 +1,209 @@
+package harpy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// maxFrameHeaderSize is the maximum size of the header of a Content-Length
+// framed message, in bytes.
+const maxFrameHeaderSize = 8 * 1024
+
+// UnmarshalRequestSetFramed unmarshals a JSON-RPC request or request batch
+// from r, where the request is preceded by a header that specifies its length,
+// as used by the Language Server Protocol. For example:
+//
+//	Content-Length: 63\r\n
+//	\r\n
+//	{"jsonrpc": "2.0", "id": 1, "method": "<method>", "params": []}
+//
+// Exactly the number of bytes specified by the Content-Length header are read
+// from r after the header, allowing multiple framed requests to be read from
+// the same stream. Other header fields are ignored.
+//
+// If there is a problem parsing the header or the request, or the request is
+// malformed, an Error is returned. If r is exhausted before any data is read
+// io.EOF is returned. Any other non-nil error should be considered an IO
+// error.
+func UnmarshalRequestSetFramed(r io.Reader, options ...RequestSetOption) (RequestSet, error) {
+	opts := newRequestSetOptions(options)
+
+	data, err := readFrame(r, opts.MaxFrameSize)
+	if err != nil {
+		var headerErr frameHeaderError
+		if errors.As(err, &headerErr) {
+			return RequestSet{}, NewErrorWithReservedCode(
+				ParseErrorCode,
+				WithCause(fmt.Errorf("unable to parse request: %w", err)),
+			)
+		}
+
+		return RequestSet{}, err
+	}
+
+	return UnmarshalRequestSetBytes(data, options...)
+}
+
+// MaxFrameSize is a RequestSetOption that sets the maximum size of the content
+// of a framed request set read by UnmarshalRequestSetFramed(), in bytes.
+//
+// The content of a frame with a larger Content-Length is discarded without
+// being buffered, such that subsequent frames can still be read from the same
+// stream, and a JSON-RPC "parse error" is returned.
+//
+// It has no effect on UnmarshalRequestSet() or UnmarshalRequestSetBytes().
+// Frames are not limited by default. It panics if n is not positive.
+func MaxFrameSize(n int64) RequestSetOption {
+	if n <= 0 {
+		panic("the maximum frame size must be positive")
+	}
+
+	return func(opts *requestSetOptions) {
+		opts.MaxFrameSize = n
+	}
+}
+
+// UnmarshalResponseSetFramed unmarshals a JSON-RPC response or response batch
+// from r, where the response is preceded by a header that specifies its
+// length.
+//
+// It is the response counterpart to UnmarshalRequestSetFramed().
+func UnmarshalResponseSetFramed(r io.Reader, options ...ResponseSetOption) (ResponseSet, error) {
+	data, err := readFrame(r, 0)
+	if err != nil {
+		var headerErr frameHeaderError
+		if errors.As(err, &headerErr) {
+			return ResponseSet{}, fmt.Errorf("unable to parse response: %w", err)
+		}
+
+		return ResponseSet{}, err
+	}
+
+	return UnmarshalResponseSet(bytes.NewReader(data), options...)
+}
+
+// frameHeaderError indicates that the header of a framed message is malformed.
+type frameHeaderError string
+
+func (e frameHeaderError) Error() string {
+	return string(e)
+}
+
+// readFrame reads the content of a single Content-Length framed message from
+// r.
+//
+// It reads only as many bytes from r as are necessary. If maxSize is positive
+// and the content is larger than maxSize bytes, the content is discarded and a
+// frameHeaderError is returned.
+func readFrame(r io.Reader, maxSize int64) ([]byte, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &unbufferedByteReader{r: r}
+	}
+
+	length := int64(-1)
+	size := 0
+
+	for {
+		line, err := readFrameHeaderLine(br, &size)
+		if err != nil {
+			return nil, err
+		}
+
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, frameHeaderError(fmt.Sprintf("malformed frame header field: %q", line))
+		}
+
+		if textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)) != "Content-Length" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || n <= 0 {
+			return nil, frameHeaderError(fmt.Sprintf("invalid Content-Length in frame header: %q", strings.TrimSpace(value)))
+		}
+
+		length = n
+	}
+
+	if length == -1 {
+		return nil, frameHeaderError("frame header does not contain a Content-Length field")
+	}
+
+	if maxSize > 0 && length > maxSize {
+		if _, err := io.CopyN(io.Discard, r, length); err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		return nil, frameHeaderError(fmt.Sprintf("frame content exceeds the maximum size of %d bytes", maxSize))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, length))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) != length {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return data, nil
+}
+
+// readFrameHeaderLine reads a single line of a frame header from r, without
+// the trailing line terminator.
+//
+// size is the number of header bytes read so far; it is updated to include
+// the bytes read by this call.
+func readFrameHeaderLine(r io.ByteReader, size *int) (string, error) {
+	var line []byte
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && (*size > 0 || len(line) > 0) {
+				return "", io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+
+		*size++
+		if *size > maxFrameHeaderSize {
+			return "", frameHeaderError("frame header is too large")
+		}
+
+		if b == '\n' {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+
+		line = append(line, b)
+	}
+}
+
+// unbufferedByteReader is an io.ByteReader that reads from an io.Reader one
+// byte at a time, so as to never read beyond the end of a frame header.
+type unbufferedByteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+func (r *unbufferedByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.r, r.buf[:]); err != nil {
+		return 0, err
+	}
+
+	return r.buf[0], nil
+}
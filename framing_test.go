@@ -0,0 +1,214 @@
+package harpy_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing/iotest"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// frame returns content preceded by a Content-Length frame header.
+func frame(content string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+var _ = Describe("func UnmarshalRequestSetFramed()", func() {
+	It("parses a framed request", func() {
+		r := strings.NewReader(frame(`{"jsonrpc":"2.0","id":123,"method":"<method>","params":[1,2,3]}`))
+
+		rs, err := UnmarshalRequestSetFramed(r)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs).To(Equal(RequestSet{
+			Requests: []Request{
+				{
+					Version:    "2.0",
+					ID:         json.RawMessage(`123`),
+					Method:     "<method>",
+					Parameters: json.RawMessage(`[1,2,3]`),
+				},
+			},
+			IsBatch: false,
+		}))
+	})
+
+	It("parses a framed batch request", func() {
+		r := strings.NewReader(frame(`[{"jsonrpc":"2.0","id":123,"method":"<method>"}]`))
+
+		rs, err := UnmarshalRequestSetFramed(r)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs.IsBatch).To(BeTrue())
+		Expect(rs.Requests).To(HaveLen(1))
+	})
+
+	It("reads consecutive frames from the same stream", func() {
+		r := iotest.OneByteReader(
+			strings.NewReader(
+				frame(`{"jsonrpc":"2.0","id":1,"method":"<method>"}`) +
+					frame(`{"jsonrpc":"2.0","id":2,"method":"<method>"}`),
+			),
+		)
+
+		rs, err := UnmarshalRequestSetFramed(r)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs.Requests[0].ID).To(Equal(json.RawMessage(`1`)))
+
+		rs, err = UnmarshalRequestSetFramed(r)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs.Requests[0].ID).To(Equal(json.RawMessage(`2`)))
+
+		_, err = UnmarshalRequestSetFramed(r)
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("ignores other header fields", func() {
+		content := `{"jsonrpc":"2.0","id":123,"method":"<method>"}`
+		r := strings.NewReader(
+			fmt.Sprintf(
+				"content-type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: %d\r\n\r\n%s",
+				len(content),
+				content,
+			),
+		)
+
+		rs, err := UnmarshalRequestSetFramed(r)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs.Requests).To(HaveLen(1))
+	})
+
+	It("passes options to the underlying parser", func() {
+		r := strings.NewReader(frame(`{"jsonrpc":"1.0","id":123,"method":"<method>"}`))
+
+		rs, err := UnmarshalRequestSetFramed(r, AcceptVersions("1.0"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs.Requests).To(HaveLen(1))
+	})
+
+	It("discards frames that exceed the maximum size and continues from the next frame", func() {
+		r := iotest.OneByteReader(
+			strings.NewReader(
+				frame(`{"jsonrpc":"2.0","id":1,"method":"<method>","params":[1,2,3]}`) +
+					frame(`{"jsonrpc":"2.0","id":2}`),
+			),
+		)
+
+		_, err := UnmarshalRequestSetFramed(r, MaxFrameSize(32))
+
+		var rpcErr Error
+		Expect(errors.As(err, &rpcErr)).To(BeTrue())
+		Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+		Expect(rpcErr.Unwrap()).To(MatchError("unable to parse request: frame content exceeds the maximum size of 32 bytes"))
+
+		rs, err := UnmarshalRequestSetFramed(r, MaxFrameSize(32))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs.Requests[0].ID).To(Equal(json.RawMessage(`2`)))
+	})
+
+	It("returns an error if the content of a frame that exceeds the maximum size is truncated", func() {
+		r := strings.NewReader("Content-Length: 100\r\n\r\n{}")
+
+		_, err := UnmarshalRequestSetFramed(r, MaxFrameSize(10))
+		Expect(err).To(Equal(io.ErrUnexpectedEOF))
+	})
+
+	It("returns an error if the frame content is truncated", func() {
+		r := strings.NewReader("Content-Length: 100\r\n\r\n{}")
+
+		_, err := UnmarshalRequestSetFramed(r)
+		Expect(err).To(Equal(io.ErrUnexpectedEOF))
+	})
+
+	It("returns an error if the frame header is truncated", func() {
+		r := strings.NewReader("Content-Length: 100\r\n")
+
+		_, err := UnmarshalRequestSetFramed(r)
+		Expect(err).To(Equal(io.ErrUnexpectedEOF))
+	})
+
+	DescribeTable(
+		"it returns a parse error if the frame header is invalid",
+		func(header, expect string) {
+			r := strings.NewReader(header + "{}")
+
+			_, err := UnmarshalRequestSetFramed(r)
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+			Expect(rpcErr.Unwrap()).To(MatchError("unable to parse request: " + expect))
+		},
+		Entry(
+			"missing Content-Length",
+			"Content-Type: application/json\r\n\r\n",
+			"frame header does not contain a Content-Length field",
+		),
+		Entry(
+			"non-numeric Content-Length",
+			"Content-Length: abc\r\n\r\n",
+			`invalid Content-Length in frame header: "abc"`,
+		),
+		Entry(
+			"zero Content-Length",
+			"Content-Length: 0\r\n\r\n",
+			`invalid Content-Length in frame header: "0"`,
+		),
+		Entry(
+			"malformed field",
+			"Content-Length\r\n\r\n",
+			`malformed frame header field: "Content-Length"`,
+		),
+		Entry(
+			"oversized header",
+			"X-Padding: "+strings.Repeat("x", 8*1024)+"\r\n\r\n",
+			"frame header is too large",
+		),
+	)
+})
+
+var _ = Describe("func MaxFrameSize()", func() {
+	It("panics if n is not positive", func() {
+		Expect(func() {
+			MaxFrameSize(0)
+		}).To(PanicWith("the maximum frame size must be positive"))
+	})
+})
+
+var _ = Describe("func UnmarshalResponseSetFramed()", func() {
+	It("parses a framed response", func() {
+		r := strings.NewReader(frame(`{"jsonrpc":"2.0","id":123,"result":[1,2,3]}`))
+
+		rs, err := UnmarshalResponseSetFramed(r)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs).To(Equal(ResponseSet{
+			Responses: []Response{
+				SuccessResponse{
+					Version:   "2.0",
+					RequestID: json.RawMessage(`123`),
+					Result:    json.RawMessage(`[1,2,3]`),
+				},
+			},
+			IsBatch: false,
+		}))
+	})
+
+	It("passes options to the underlying parser", func() {
+		r := strings.NewReader(frame(`{"jsonrpc":"2.0","id":123}`))
+
+		_, err := UnmarshalResponseSetFramed(r, StrictResponses(true))
+		Expect(err).To(MatchError("unable to parse response: response must contain either a result or an error"))
+	})
+
+	It("returns an error if the frame header is invalid", func() {
+		r := strings.NewReader("Content-Type: application/json\r\n\r\n{}")
+
+		_, err := UnmarshalResponseSetFramed(r)
+		Expect(err).To(MatchError("unable to parse response: frame header does not contain a Content-Length field"))
+	})
+})
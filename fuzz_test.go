@@ -0,0 +1,58 @@
+package harpy_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/dogmatiq/harpy"
+)
+
+func FuzzParseRequestSet(f *testing.F) {
+	seeds := []string{
+		``,
+		` `,
+		`{}`,
+		`[]`,
+		`null`,
+		`""`,
+		`}`,
+		`[{}]`,
+		`{"jsonrpc":"2.0","id":1,"method":"<method>","params":[1,2,3]}`,
+		`{"jsonrpc":"2.0","method":"<method>","params":{"a":1}}`,
+		`[{"jsonrpc":"2.0","id":1,"method":"<method>"},{"jsonrpc":"2.0","method":"<method>"}]`,
+		`{"jsonrpc":"2.0","id":null,"method":"<method>"}`,
+		`{"jsonrpc":"2.0","id":1,"method":"<method>","unknown":1}`,
+		"\xff\xfe",
+		" [",
+		strings.Repeat("[", 100000),
+		`{"params":` + strings.Repeat("[", 100000),
+	}
+
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rs, err := ParseRequestSetBytes(data)
+		if err != nil {
+			var rpcErr Error
+			if !errors.As(err, &rpcErr) {
+				t.Fatalf("expected a JSON-RPC error, got %T: %s", err, err)
+			}
+
+			if rpcErr.Code() != ParseErrorCode {
+				t.Fatalf("expected a parse error, got %s", rpcErr)
+			}
+
+			return
+		}
+
+		if !rs.IsBatch && len(rs.Requests) != 1 {
+			t.Fatalf("expected exactly one request in a non-batch request set, got %d", len(rs.Requests))
+		}
+
+		// Validation must not panic, regardless of the content of the request.
+		rs.ValidateServerSide()
+	})
+}
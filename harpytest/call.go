@@ -0,0 +1,44 @@
+package harpytest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// CallExchanger calls a JSON-RPC method via e and returns the response.
+//
+// params is marshaled to JSON to form the request's parameters. The request
+// always has an ID of 1. If the response is a harpy.SuccessResponse with a
+// streamed result, the stream is read in full, such that the result is always
+// available in the response's Result field.
+//
+// It panics if the request can not be built, or if the result stream can not
+// be read.
+func CallExchanger(
+	e harpy.Exchanger,
+	method string,
+	params any,
+) harpy.Response {
+	req, err := harpy.NewCallRequest(1, method, params)
+	if err != nil {
+		panic(fmt.Sprintf("unable to call JSON-RPC method (%s): %s", method, err))
+	}
+
+	if err, ok := req.ValidateClientSide(); !ok {
+		panic(fmt.Sprintf("unable to call JSON-RPC method (%s): %s", method, err.Message()))
+	}
+
+	res := e.Call(context.Background(), req)
+
+	if r, ok := res.(harpy.SuccessResponse); ok {
+		r, err := r.BufferResult()
+		if err != nil {
+			panic(fmt.Sprintf("unable to call JSON-RPC method (%s): %s", method, err))
+		}
+		return r
+	}
+
+	return res
+}
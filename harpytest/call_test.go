@@ -0,0 +1,59 @@
+package harpytest_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dogmatiq/harpy"
+	"github.com/dogmatiq/harpy/harpytest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func harpytest.CallExchanger()", func() {
+	var router *harpy.Router
+
+	BeforeEach(func() {
+		router = harpy.NewRouter(
+			harpy.WithRoute(
+				"echo",
+				func(_ context.Context, params []int) ([]int, error) {
+					return params, nil
+				},
+			),
+			harpy.WithRoute(
+				"stream",
+				func(context.Context, any) (harpy.StreamResult, error) {
+					return harpy.StreamResult{
+						Reader: strings.NewReader(`"<streamed>"`),
+					}, nil
+				},
+			),
+		)
+	})
+
+	It("returns the response produced by the exchanger", func() {
+		res := harpytest.CallExchanger(router, "echo", []int{1, 2, 3})
+		Expect(res).To(harpytest.MatchSuccessResult([]int{1, 2, 3}))
+	})
+
+	It("returns error responses", func() {
+		res := harpytest.CallExchanger(router, "<unknown>", nil)
+		Expect(res).To(harpytest.MatchError(harpy.MethodNotFoundCode, "method not found"))
+	})
+
+	It("buffers streamed results", func() {
+		res := harpytest.CallExchanger(router, "stream", nil)
+
+		sr, ok := res.(harpy.SuccessResponse)
+		Expect(ok).To(BeTrue())
+		Expect(sr.ResultStream).To(BeNil())
+		Expect(res).To(harpytest.MatchSuccessResult("<streamed>"))
+	})
+
+	It("panics if the request can not be built", func() {
+		Expect(func() {
+			harpytest.CallExchanger(router, "echo", 123)
+		}).To(PanicWith("unable to call JSON-RPC method (echo): parameters must be an array, an object, or null"))
+	})
+})
@@ -0,0 +1,8 @@
+// Package harpytest provides utilities for testing JSON-RPC services built with
+// harpy.
+//
+// The matchers within this package implement the same interface as Gomega's
+// matchers, and can therefore be used with Expect(), but they may also be used
+// with the standard library's testing package by calling their Match() method
+// directly.
+package harpytest
@@ -0,0 +1,185 @@
+package harpytest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// Matcher is an interface for a matcher that tests whether a value meets
+// some expectation.
+//
+// It is identical to Gomega's types.GomegaMatcher interface.
+type Matcher interface {
+	// Match returns true if actual meets the expectation.
+	//
+	// It returns an error if actual is not a type that the matcher supports.
+	Match(actual any) (success bool, err error)
+
+	// FailureMessage returns a message describing why actual does not meet
+	// the expectation.
+	FailureMessage(actual any) string
+
+	// NegatedFailureMessage returns a message describing why actual meets the
+	// expectation when it was not expected to.
+	NegatedFailureMessage(actual any) string
+}
+
+// MatchSuccessResult returns a matcher that matches a harpy.SuccessResponse
+// with a result that is equivalent to expected.
+//
+// The result and expected are compared by their JSON representations, after
+// both have been normalized, such that differences in whitespace, key order
+// and character escaping are ignored. expected may be a json.RawMessage, in
+// which case it is used as-is.
+//
+// The matcher returns an error if the actual value is not a harpy.Response,
+// or if it is a SuccessResponse with an unbuffered result stream.
+func MatchSuccessResult(expected any) Matcher {
+	return &successResultMatcher{expected}
+}
+
+// successResultMatcher is the Matcher returned by MatchSuccessResult().
+type successResultMatcher struct {
+	expected any
+}
+
+func (m *successResultMatcher) Match(actual any) (bool, error) {
+	res, ok := actual.(harpy.Response)
+	if !ok {
+		return false, fmt.Errorf("expected a harpy.Response, got %T", actual)
+	}
+
+	sr, ok := res.(harpy.SuccessResponse)
+	if !ok {
+		return false, nil
+	}
+
+	if sr.ResultStream != nil {
+		return false, errors.New("the response has a result stream, call BufferResult() before matching")
+	}
+
+	expected, err := json.Marshal(m.expected)
+	if err != nil {
+		return false, fmt.Errorf("unable to marshal the expected result: %w", err)
+	}
+
+	return jsonEqual(sr.Result, expected)
+}
+
+func (m *successResultMatcher) FailureMessage(actual any) string {
+	return fmt.Sprintf(
+		"Expected\n\t%s\nto be a success response with a result equivalent to\n\t%s",
+		describeResponse(actual),
+		describeExpectedResult(m.expected),
+	)
+}
+
+func (m *successResultMatcher) NegatedFailureMessage(actual any) string {
+	return fmt.Sprintf(
+		"Expected\n\t%s\nnot to be a success response with a result equivalent to\n\t%s",
+		describeResponse(actual),
+		describeExpectedResult(m.expected),
+	)
+}
+
+// MatchError returns a matcher that matches a JSON-RPC error with the given
+// code and message.
+//
+// The actual value may be a harpy.Response, in which case it must be a
+// harpy.ErrorResponse, or an error, in which case it must be, or wrap, a
+// harpy.Error. The message is compared exactly.
+func MatchError(code harpy.ErrorCode, message string) Matcher {
+	return &errorMatcher{code, message}
+}
+
+// errorMatcher is the Matcher returned by MatchError().
+type errorMatcher struct {
+	code    harpy.ErrorCode
+	message string
+}
+
+func (m *errorMatcher) Match(actual any) (bool, error) {
+	switch actual := actual.(type) {
+	case harpy.ErrorResponse:
+		return actual.Error.Code == m.code && actual.Error.Message == m.message, nil
+	case harpy.Response:
+		return false, nil
+	case error:
+		var err harpy.Error
+		if !errors.As(actual, &err) {
+			return false, nil
+		}
+		return err.Code() == m.code && err.Message() == m.message, nil
+	default:
+		return false, fmt.Errorf("expected a harpy.Response or an error, got %T", actual)
+	}
+}
+
+func (m *errorMatcher) FailureMessage(actual any) string {
+	return fmt.Sprintf(
+		"Expected\n\t%s\nto be a JSON-RPC error with code %d and message %q",
+		describeError(actual),
+		m.code,
+		m.message,
+	)
+}
+
+func (m *errorMatcher) NegatedFailureMessage(actual any) string {
+	return fmt.Sprintf(
+		"Expected\n\t%s\nnot to be a JSON-RPC error with code %d and message %q",
+		describeError(actual),
+		m.code,
+		m.message,
+	)
+}
+
+// jsonEqual returns true if a and b are equivalent JSON values.
+func jsonEqual(a, b []byte) (bool, error) {
+	var va, vb any
+
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false, fmt.Errorf("unable to unmarshal the actual result: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false, fmt.Errorf("unable to unmarshal the expected result: %w", err)
+	}
+
+	return reflect.DeepEqual(va, vb), nil
+}
+
+// describeExpectedResult returns a human-readable representation of an
+// expected result for use in failure messages.
+func describeExpectedResult(v any) string {
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+
+	return fmt.Sprintf("%#v", v)
+}
+
+// describeError returns a human-readable representation of v for use in
+// failure messages.
+func describeError(v any) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+
+	return describeResponse(v)
+}
+
+// describeResponse returns a human-readable representation of v for use in
+// failure messages.
+func describeResponse(v any) string {
+	if res, ok := v.(harpy.Response); ok {
+		if data, err := json.Marshal(res); err == nil {
+			return string(data)
+		}
+	}
+
+	return fmt.Sprintf("%#v", v)
+}
@@ -0,0 +1,94 @@
+package harpytest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dogmatiq/harpy"
+	"github.com/dogmatiq/harpy/harpytest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func harpytest.MatchSuccessResult()", func() {
+	It("matches a success response with an equivalent result", func() {
+		res := harpy.SuccessResponse{
+			Version:   "2.0",
+			RequestID: json.RawMessage(`1`),
+			Result:    json.RawMessage(`{ "b": 2, "a": "<value>" }`),
+		}
+
+		Expect(res).To(harpytest.MatchSuccessResult(map[string]any{
+			"a": "<value>",
+			"b": 2,
+		}))
+		Expect(res).To(harpytest.MatchSuccessResult(json.RawMessage(`{"a":"<value>","b":2}`)))
+	})
+
+	It("does not match a success response with a different result", func() {
+		res := harpy.NewSuccessResponse(json.RawMessage(`1`), []int{1, 2, 3})
+		Expect(res).NotTo(harpytest.MatchSuccessResult([]int{3, 2, 1}))
+	})
+
+	It("does not match an error response", func() {
+		res := harpy.NewErrorResponse(json.RawMessage(`1`), harpy.NewError(123))
+		Expect(res).NotTo(harpytest.MatchSuccessResult(nil))
+	})
+
+	It("returns an error if the actual value is not a response", func() {
+		_, err := harpytest.MatchSuccessResult(nil).Match("<value>")
+		Expect(err).To(MatchError("expected a harpy.Response, got string"))
+	})
+
+	It("returns an error if the response has a result stream", func() {
+		res := harpy.SuccessResponse{
+			Version:      "2.0",
+			RequestID:    json.RawMessage(`1`),
+			ResultStream: strings.NewReader(`123`),
+		}
+
+		_, err := harpytest.MatchSuccessResult(nil).Match(res)
+		Expect(err).To(MatchError("the response has a result stream, call BufferResult() before matching"))
+	})
+
+	It("describes the mismatch", func() {
+		res := harpy.NewSuccessResponse(json.RawMessage(`1`), 123)
+		Expect(harpytest.MatchSuccessResult(456).FailureMessage(res)).To(Equal(
+			"Expected\n\t{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":123}\nto be a success response with a result equivalent to\n\t456",
+		))
+	})
+})
+
+var _ = Describe("func MatchError()", func() {
+	It("matches an error response with the same code and message", func() {
+		res := harpy.NewErrorResponse(
+			json.RawMessage(`1`),
+			harpy.NewError(123, harpy.WithMessage("<message>")),
+		)
+
+		Expect(res).To(harpytest.MatchError(123, "<message>"))
+		Expect(res).NotTo(harpytest.MatchError(456, "<message>"))
+		Expect(res).NotTo(harpytest.MatchError(123, "<other>"))
+	})
+
+	It("matches a harpy.Error, including when it is wrapped", func() {
+		err := fmt.Errorf("<context>: %w", harpy.NewError(123, harpy.WithMessage("<message>")))
+		Expect(err).To(harpytest.MatchError(123, "<message>"))
+	})
+
+	It("does not match an error that is not a harpy.Error", func() {
+		Expect(errors.New("<error>")).NotTo(harpytest.MatchError(123, "<error>"))
+	})
+
+	It("does not match a success response", func() {
+		res := harpy.NewSuccessResponse(json.RawMessage(`1`), 123)
+		Expect(res).NotTo(harpytest.MatchError(123, "<message>"))
+	})
+
+	It("returns an error if the actual value is neither a response nor an error", func() {
+		_, err := harpytest.MatchError(123, "<message>").Match("<value>")
+		Expect(err).To(HaveOccurred())
+	})
+})
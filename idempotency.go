@@ -0,0 +1,292 @@
+package harpy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyTTL is the default amount of time for which the response
+// to an idempotent call is retained by an IdempotentExchanger.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotentExchanger is an implementation of Exchanger that allows calls to be
+// safely retried by invoking the next exchanger at most once for each
+// idempotency key.
+//
+// The response to the first call with a given key is stored, and is returned in
+// response to any subsequent call with the same key, method and parameters
+// instead of invoking the next exchanger again. Calls that reuse a key with a
+// different method or different parameters are treated as distinct calls.
+// Concurrent identical calls share the response of a single invocation of the
+// next exchanger. The request ID within each response is always that of the
+// request it answers.
+//
+// Only successful responses are stored, such that a call that fails may be
+// retried. If the next exchanger panics, the panic is propagated to the caller
+// and any concurrent identical calls receive an "internal error" response.
+// Calls without an idempotency key and notifications are always passed to the
+// next exchanger.
+type IdempotentExchanger struct {
+	// Next is the next exchanger in the middleware stack.
+	Next Exchanger
+
+	// Store is the store in which responses are kept. If it is nil, responses
+	// are kept in memory.
+	Store IdempotencyStore
+
+	// TTL is the amount of time for which responses are kept. If it is zero,
+	// DefaultIdempotencyTTL is used.
+	TTL time.Duration
+
+	// UseRequestID controls whether the request ID is used as the idempotency
+	// key of calls that do not have a key associated with their context.
+	//
+	// It should only be enabled if request IDs are unique across all clients,
+	// such as when they are randomly generated UUIDs.
+	UseRequestID bool
+
+	once     sync.Once
+	store    IdempotencyStore
+	m        sync.Mutex
+	inFlight map[string]*sharedCall
+}
+
+var _ Exchanger = (*IdempotentExchanger)(nil)
+
+// Call handles a call request and returns the response.
+//
+// If the store can not be read, it returns an ErrorResponse without invoking
+// the next exchanger, as it can not be determined whether the call has already
+// been made.
+func (x *IdempotentExchanger) Call(ctx context.Context, req Request) Response {
+	key, ok := x.key(ctx, req)
+	if !ok {
+		return x.Next.Call(ctx, req)
+	}
+
+	c, isDuplicate := x.call(key)
+
+	if isDuplicate {
+		select {
+		case <-ctx.Done():
+			return NewErrorResponse(req.ID, ctx.Err())
+		case <-c.done:
+			return withRequestID(c.res, req.ID)
+		}
+	}
+
+	defer x.done(key, c, req)
+
+	res, ok, err := x.store.Load(ctx, key)
+	if err != nil {
+		c.res = NewErrorResponse(req.ID, err)
+		return c.res
+	}
+
+	if ok {
+		c.res = withRequestID(res, req.ID)
+		return c.res
+	}
+
+	c.res = x.Next.Call(ctx, req)
+
+	if res, ok := c.res.(SuccessResponse); ok {
+		// A streamed result can only be read once, so it is buffered so that
+		// it may be stored and shared with any concurrent calls.
+		buffered, err := res.BufferResult()
+		if err != nil {
+			c.res = NewErrorResponse(req.ID, err)
+			return c.res
+		}
+
+		c.res = buffered
+
+		// The call has already been made, so its response is returned even if
+		// it can not be stored.
+		x.store.Store(ctx, key, buffered, x.ttl()) // nolint:errcheck
+	}
+
+	return c.res
+}
+
+// Notify handles a notification request.
+func (x *IdempotentExchanger) Notify(ctx context.Context, req Request) error {
+	return x.Next.Notify(ctx, req)
+}
+
+// key returns the key under which the response to req is stored.
+//
+// It combines the idempotency key of req with a hash of its method and
+// parameters, such that a key that is reused for a different call does not
+// return the response to the original call.
+func (x *IdempotentExchanger) key(ctx context.Context, req Request) (string, bool) {
+	key, ok := IdempotencyKeyFromContext(ctx)
+
+	if !ok && x.UseRequestID && len(req.ID) != 0 {
+		key, ok = string(req.ID), true
+	}
+
+	if !ok {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s:%x", key, callKey(req)), true
+}
+
+// call returns the sharedCall for the in-flight call with the given key.
+//
+// If isDuplicate is false, there is no other call in flight with the same key
+// and the caller is responsible for populating the response and calling
+// x.done().
+func (x *IdempotentExchanger) call(key string) (c *sharedCall, isDuplicate bool) {
+	x.once.Do(func() {
+		x.store = x.Store
+		if x.store == nil {
+			x.store = &MemoryIdempotencyStore{}
+		}
+	})
+
+	x.m.Lock()
+	defer x.m.Unlock()
+
+	if c, ok := x.inFlight[key]; ok {
+		return c, true
+	}
+
+	if x.inFlight == nil {
+		x.inFlight = map[string]*sharedCall{}
+	}
+
+	c = &sharedCall{
+		done: make(chan struct{}),
+	}
+	x.inFlight[key] = c
+
+	return c, false
+}
+
+// done marks the in-flight call with the given key as complete.
+func (x *IdempotentExchanger) done(key string, c *sharedCall, req Request) {
+	x.m.Lock()
+	delete(x.inFlight, key)
+	x.m.Unlock()
+
+	c.complete(req)
+}
+
+// ttl returns the amount of time for which responses are kept.
+func (x *IdempotentExchanger) ttl() time.Duration {
+	if x.TTL != 0 {
+		return x.TTL
+	}
+	return DefaultIdempotencyTTL
+}
+
+// idempotencyKey is the context key used to associate an idempotency key with
+// a context.
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a copy of ctx that is associated with the given
+// idempotency key.
+//
+// It is intended to be used by transports to propagate a key supplied by the
+// client, such that it may be used by an IdempotentExchanger.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key associated with ctx,
+// if any.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	return key, ok
+}
+
+// IdempotencyStore is an interface for storing the responses to calls made via
+// an IdempotentExchanger.
+//
+// The keys passed to the store are derived from the idempotency key and the
+// method and parameters of the call; they are not the idempotency key itself.
+type IdempotencyStore interface {
+	// Load returns the response stored under the given key.
+	//
+	// ok is false if there is no such response, or if it has expired.
+	Load(ctx context.Context, key string) (res Response, ok bool, err error)
+
+	// Store stores res under the given key, such that it expires after ttl.
+	//
+	// res never has a streamed result.
+	Store(ctx context.Context, key string, res Response, ttl time.Duration) error
+}
+
+// MemoryIdempotencyStore is an implementation of IdempotencyStore that keeps
+// responses in memory.
+type MemoryIdempotencyStore struct {
+	m       sync.Mutex
+	entries map[string]idempotencyEntry
+	sweepAt int
+}
+
+var _ IdempotencyStore = (*MemoryIdempotencyStore)(nil)
+
+// idempotencyEntry is a response kept by a MemoryIdempotencyStore.
+type idempotencyEntry struct {
+	res     Response
+	expires time.Time
+}
+
+// minIdempotencySweepSize is the minimum number of entries that must be
+// present in a MemoryIdempotencyStore before expired entries are removed.
+const minIdempotencySweepSize = 64
+
+// Load returns the response stored under the given key.
+func (s *MemoryIdempotencyStore) Load(_ context.Context, key string) (Response, bool, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if !time.Now().Before(e.expires) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+
+	return e.res, true, nil
+}
+
+// Store stores res under the given key, such that it expires after ttl.
+//
+// Expired responses are removed periodically, as the number of stored
+// responses grows.
+func (s *MemoryIdempotencyStore) Store(_ context.Context, key string, res Response, ttl time.Duration) error {
+	now := time.Now()
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.entries == nil {
+		s.entries = map[string]idempotencyEntry{}
+	}
+
+	if len(s.entries) >= s.sweepAt {
+		for k, e := range s.entries {
+			if !now.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+
+		s.sweepAt = max(2*len(s.entries), minIdempotencySweepSize)
+	}
+
+	s.entries[key] = idempotencyEntry{
+		res:     res,
+		expires: now.Add(ttl),
+	}
+
+	return nil
+}
@@ -0,0 +1,345 @@
+package harpy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type IdempotentExchanger", func() {
+	var (
+		ctx       context.Context
+		next      *ExchangerStub
+		calls     int32
+		exchanger *IdempotentExchanger
+	)
+
+	BeforeEach(func() {
+		ctx = WithIdempotencyKey(context.Background(), "<key>")
+		calls = 0
+
+		next = &ExchangerStub{
+			CallFunc: func(
+				_ context.Context,
+				req Request,
+			) Response {
+				n := atomic.AddInt32(&calls, 1)
+
+				if req.Method == "<error>" {
+					return NewErrorResponse(req.ID, errors.New("<error>"))
+				}
+
+				if req.Method == "<stream>" {
+					return NewSuccessResponse(
+						req.ID,
+						StreamResult{Reader: bytes.NewReader(req.Parameters)},
+					)
+				}
+
+				return NewSuccessResponse(req.ID, n)
+			},
+		}
+
+		exchanger = &IdempotentExchanger{Next: next}
+	})
+
+	Describe("func Call()", func() {
+		It("returns the stored response to repeated calls with the same key", func() {
+			res := exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+			Expect(res).To(Equal(SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`1`),
+			}))
+
+			res = exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`2`)})
+			Expect(res).To(Equal(SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`2`),
+				Result:    json.RawMessage(`1`),
+			}))
+
+			Expect(calls).To(BeNumerically("==", 1))
+		})
+
+		It("invokes the next exchanger for calls with different keys", func() {
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+			exchanger.Call(
+				WithIdempotencyKey(context.Background(), "<other>"),
+				Request{Version: "2.0", ID: json.RawMessage(`2`)},
+			)
+
+			Expect(calls).To(BeNumerically("==", 2))
+		})
+
+		It("invokes the next exchanger for calls with the same key but a different method or parameters", func() {
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>", Parameters: json.RawMessage(`[1]`)})
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`2`), Method: "<other>", Parameters: json.RawMessage(`[1]`)})
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`3`), Method: "<method>", Parameters: json.RawMessage(`[2]`)})
+
+			Expect(calls).To(BeNumerically("==", 3))
+		})
+
+		It("always invokes the next exchanger for calls without a key", func() {
+			ctx := context.Background()
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+
+			Expect(calls).To(BeNumerically("==", 2))
+		})
+
+		It("uses the request ID as the key if UseRequestID is true", func() {
+			exchanger.UseRequestID = true
+
+			ctx := context.Background()
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`"<id>"`)})
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`"<id>"`)})
+
+			Expect(calls).To(BeNumerically("==", 1))
+		})
+
+		It("does not store error responses", func() {
+			res := exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<error>"})
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`2`), Method: "<error>"})
+			Expect(calls).To(BeNumerically("==", 2))
+		})
+
+		It("buffers streamed results so that they may be stored", func() {
+			req := Request{
+				Version:    "2.0",
+				ID:         json.RawMessage(`1`),
+				Method:     "<stream>",
+				Parameters: json.RawMessage(`[1, 2, 3]`),
+			}
+
+			expect := SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`[1, 2, 3]`),
+			}
+
+			Expect(exchanger.Call(ctx, req)).To(Equal(expect))
+			Expect(exchanger.Call(ctx, req)).To(Equal(expect))
+			Expect(calls).To(BeNumerically("==", 1))
+		})
+
+		It("invokes the next exchanger again once the response has expired", func() {
+			exchanger.TTL = time.Millisecond
+
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+			time.Sleep(5 * time.Millisecond)
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`2`)})
+
+			Expect(calls).To(BeNumerically("==", 2))
+		})
+
+		It("shares the response to concurrent calls with the same key", func() {
+			barrier := make(chan struct{})
+			fn := next.CallFunc
+			next.CallFunc = func(ctx context.Context, req Request) Response {
+				<-barrier
+				return fn(ctx, req)
+			}
+
+			responses := make(chan Response)
+			for i := 1; i <= 3; i++ {
+				id := json.RawMessage([]byte{byte('0' + i)})
+				go func() {
+					responses <- exchanger.Call(ctx, Request{Version: "2.0", ID: id})
+				}()
+			}
+
+			// Wait for the first call to reach the next exchanger, then give
+			// the others a chance to join it.
+			Consistently(responses, 20*time.Millisecond).ShouldNot(Receive())
+			close(barrier)
+
+			for i := 0; i < 3; i++ {
+				var res Response
+				Eventually(responses).Should(Receive(&res))
+				Expect(res.(SuccessResponse).Result).To(Equal(json.RawMessage(`1`)))
+			}
+
+			Expect(calls).To(BeNumerically("==", 1))
+		})
+
+		It("returns an error response if the context is canceled while waiting for a concurrent call", func() {
+			started := make(chan struct{})
+			barrier := make(chan struct{})
+			defer close(barrier)
+
+			next.CallFunc = func(context.Context, Request) Response {
+				close(started)
+				<-barrier
+				return nil
+			}
+
+			go exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+			<-started
+
+			waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+
+			res := exchanger.Call(waitCtx, Request{Version: "2.0", ID: json.RawMessage(`2`)})
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).Error.Code).To(Equal(DeadlineExceededCode))
+		})
+
+		It("propagates a panic and returns an error response to concurrent calls with the same key", func() {
+			started := make(chan struct{})
+			barrier := make(chan struct{})
+
+			next.CallFunc = func(context.Context, Request) Response {
+				close(started)
+				<-barrier
+				panic("<panic>")
+			}
+
+			panicked := make(chan any)
+			go func() {
+				defer func() {
+					panicked <- recover()
+				}()
+				exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+			}()
+			<-started
+
+			responses := make(chan Response)
+			go func() {
+				responses <- exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`2`)})
+			}()
+
+			// Give the second call a chance to join the first.
+			Consistently(responses, 20*time.Millisecond).ShouldNot(Receive())
+			close(barrier)
+
+			Eventually(panicked).Should(Receive(Equal("<panic>")))
+
+			var res Response
+			Eventually(responses).Should(Receive(&res))
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).RequestID).To(Equal(json.RawMessage(`2`)))
+			Expect(res.(ErrorResponse).Error.Code).To(Equal(InternalErrorCode))
+		})
+
+		It("invokes the next exchanger again after a panic", func() {
+			next.CallFunc = func(context.Context, Request) Response {
+				atomic.AddInt32(&calls, 1)
+				panic("<panic>")
+			}
+
+			req := Request{Version: "2.0", ID: json.RawMessage(`1`)}
+			Expect(func() { exchanger.Call(ctx, req) }).To(PanicWith("<panic>"))
+			Expect(func() { exchanger.Call(ctx, req) }).To(PanicWith("<panic>"))
+			Expect(calls).To(BeNumerically("==", 2))
+		})
+
+		It("uses the given store", func() {
+			store := &idempotencyStoreStub{}
+			exchanger.Store = store
+			exchanger.TTL = time.Minute
+
+			exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+
+			Expect(store.key).To(HavePrefix("<key>:"))
+			Expect(store.ttl).To(Equal(time.Minute))
+			Expect(store.res).To(Equal(SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`1`),
+			}))
+		})
+
+		It("returns an error response without invoking the next exchanger if the store can not be read", func() {
+			exchanger.Store = &idempotencyStoreStub{
+				err: errors.New("<error>"),
+			}
+
+			res := exchanger.Call(ctx, Request{Version: "2.0", ID: json.RawMessage(`1`)})
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).ServerError).To(MatchError("<error>"))
+			Expect(calls).To(BeNumerically("==", 0))
+		})
+	})
+
+	Describe("func Notify()", func() {
+		It("always invokes the next exchanger", func() {
+			var notifications int32
+			next.NotifyFunc = func(context.Context, Request) error {
+				atomic.AddInt32(&notifications, 1)
+				return nil
+			}
+
+			Expect(exchanger.Notify(ctx, Request{Version: "2.0"})).To(Succeed())
+			Expect(exchanger.Notify(ctx, Request{Version: "2.0"})).To(Succeed())
+			Expect(notifications).To(BeNumerically("==", 2))
+		})
+	})
+})
+
+var _ = Describe("type MemoryIdempotencyStore", func() {
+	var store *MemoryIdempotencyStore
+
+	BeforeEach(func() {
+		store = &MemoryIdempotencyStore{}
+	})
+
+	It("returns false if there is no stored response", func() {
+		_, ok, err := store.Load(context.Background(), "<key>")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the stored response", func() {
+		res := NewSuccessResponse(json.RawMessage(`1`), 123)
+
+		err := store.Store(context.Background(), "<key>", res, time.Minute)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		loaded, ok, err := store.Load(context.Background(), "<key>")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(loaded).To(Equal(res))
+	})
+
+	It("returns false if the stored response has expired", func() {
+		res := NewSuccessResponse(json.RawMessage(`1`), 123)
+
+		err := store.Store(context.Background(), "<key>", res, -time.Second)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, ok, err := store.Load(context.Background(), "<key>")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+})
+
+// idempotencyStoreStub is an IdempotencyStore that records the response passed
+// to Store().
+type idempotencyStoreStub struct {
+	key string
+	res Response
+	ttl time.Duration
+	err error
+}
+
+func (s *idempotencyStoreStub) Load(context.Context, string) (Response, bool, error) {
+	return nil, false, s.err
+}
+
+func (s *idempotencyStoreStub) Store(_ context.Context, key string, res Response, ttl time.Duration) error {
+	s.key = key
+	s.res = res
+	s.ttl = ttl
+	return nil
+}
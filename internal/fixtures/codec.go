@@ -0,0 +1,77 @@
+package fixtures
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// HexCodec is a harpy.Codec that encodes values as hexadecimal-encoded JSON.
+//
+// It is used to test support for wire formats other than JSON.
+type HexCodec struct{}
+
+// Marshal returns the encoding of v.
+func (HexCodec) Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(hex.EncodeToString(data)), nil
+}
+
+// Unmarshal decodes data into v.
+func (HexCodec) Unmarshal(data []byte, v any) error {
+	data, err := hex.DecodeString(string(data))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// NewEncoder returns an encoder that writes to w.
+func (c HexCodec) NewEncoder(w io.Writer) harpy.Encoder {
+	return hexEncoder{c, w}
+}
+
+// NewDecoder returns a decoder that reads from r.
+func (c HexCodec) NewDecoder(r io.Reader) harpy.Decoder {
+	return hexDecoder{c, r}
+}
+
+type hexEncoder struct {
+	codec HexCodec
+	w     io.Writer
+}
+
+func (e hexEncoder) Encode(v any) error {
+	data, err := e.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(data)
+	return err
+}
+
+type hexDecoder struct {
+	codec HexCodec
+	r     io.Reader
+}
+
+func (d hexDecoder) Decode(v any) error {
+	data, err := io.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return io.EOF
+	}
+
+	return d.codec.Unmarshal(data, v)
+}
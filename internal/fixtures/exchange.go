@@ -83,3 +83,28 @@ func (s *ResponseWriterStub) Close() error {
 
 	return nil
 }
+
+// AcknowledgingResponseWriterStub is a test implementation of the
+// NotificationAcknowledger interface.
+type AcknowledgingResponseWriterStub struct {
+	ResponseWriterStub
+
+	AcknowledgeUnbatchedFunc func(harpy.Request) error
+	AcknowledgeBatchedFunc   func(harpy.Request) error
+}
+
+func (s *AcknowledgingResponseWriterStub) AcknowledgeUnbatched(req harpy.Request) error {
+	if s.AcknowledgeUnbatchedFunc != nil {
+		return s.AcknowledgeUnbatchedFunc(req)
+	}
+
+	return nil
+}
+
+func (s *AcknowledgingResponseWriterStub) AcknowledgeBatched(req harpy.Request) error {
+	if s.AcknowledgeBatchedFunc != nil {
+		return s.AcknowledgeBatchedFunc(req)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package jsonx
+
+import "io"
+
+// Codec encodes and decodes values using a specific wire format.
+type Codec interface {
+	// Marshal returns the encoding of v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v any) error
+
+	// NewEncoder returns an encoder that writes to w.
+	NewEncoder(w io.Writer) Encoder
+
+	// NewDecoder returns a decoder that reads from r.
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Encoder writes encoded values to a stream.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads encoded values from a stream.
+type Decoder interface {
+	Decode(v any) error
+}
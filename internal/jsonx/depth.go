@@ -0,0 +1,77 @@
+package jsonx
+
+import (
+	"fmt"
+	"io"
+)
+
+// DefaultMaxDepth is the maximum nesting depth of JSON arrays and objects
+// that is permitted within content received from JSON-RPC clients, unless
+// another limit is specified.
+const DefaultMaxDepth = 64
+
+// DepthError indicates that JSON content contains arrays or objects that are
+// nested more deeply than permitted.
+type DepthError struct {
+	MaxDepth int
+}
+
+func (e DepthError) Error() string {
+	return fmt.Sprintf("json: exceeded maximum nesting depth of %d", e.MaxDepth)
+}
+
+// depthLimitedReader is an io.Reader that fails with a DepthError if the JSON
+// content read from the underlying reader is nested too deeply.
+//
+// It tracks the nesting depth of the content as it is read, without building
+// any intermediate representation, so deeply nested content is rejected before
+// it is ever decoded.
+type depthLimitedReader struct {
+	r        io.Reader
+	maxDepth int
+	depth    int
+	inString bool
+	escaped  bool
+	err      error
+}
+
+func (r *depthLimitedReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := r.r.Read(p)
+
+	for i, b := range p[:n] {
+		if r.inString {
+			switch {
+			case r.escaped:
+				r.escaped = false
+			case b == '\\':
+				r.escaped = true
+			case b == '"':
+				r.inString = false
+			}
+
+			continue
+		}
+
+		switch b {
+		case '"':
+			r.inString = true
+		case '[', '{':
+			r.depth++
+			if r.depth > r.maxDepth {
+				// The error is retained so that it is returned by any
+				// subsequent reads; the content that follows is never seen
+				// by the decoder.
+				r.err = DepthError{r.maxDepth}
+				return i, r.err
+			}
+		case ']', '}':
+			r.depth--
+		}
+	}
+
+	return n, err
+}
@@ -14,6 +14,10 @@ func IsParseError(err error) bool {
 		return true
 	case *json.UnmarshalTypeError:
 		return true
+	case DepthError:
+		return true
+	case TrailingDataError:
+		return true
 	default:
 		// Unfortunately, some JSON errors do not have distinct types. For
 		// example, when parsing using a decoder with DisallowUnknownFields()
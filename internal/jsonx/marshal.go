@@ -0,0 +1,33 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Marshal returns the JSON encoding of v.
+func Marshal(v any, options ...MarshalOption) ([]byte, error) {
+	var opts MarshalOptions
+	for _, fn := range options {
+		fn(&opts)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!opts.DisableHTMLEscaping)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	// Remove the trailing newline that is always added by the encoder.
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// MarshalOption is an option that changes the behavior of JSON marshaling.
+type MarshalOption func(*MarshalOptions)
+
+// MarshalOptions is a set of options that control how JSON is marshaled.
+type MarshalOptions struct {
+	DisableHTMLEscaping bool
+}
@@ -0,0 +1,126 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// PositionalFieldsError indicates that a JSON array contained more elements
+// than there are fields in the struct into which it is unmarshaled.
+type PositionalFieldsError struct {
+	Elements int
+	Fields   int
+}
+
+func (e PositionalFieldsError) Error() string {
+	return fmt.Sprintf(
+		"too many positional fields: expected at most %d, got %d",
+		e.Fields,
+		e.Elements,
+	)
+}
+
+// positionalToObject converts a JSON array in data into a JSON object with
+// properties named after the fields of v, such that the first element is
+// assigned to the first field, and so on.
+//
+// Fields are ordered as they are declared, including those promoted from
+// embedded structs. Fields that are ignored by encoding/json are skipped. If
+// v is not a struct, or data is not a JSON array, data is returned unchanged.
+// Any content following the array is preserved.
+func positionalToObject(data []byte, v any) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return data, nil
+	}
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+
+	var elements []json.RawMessage
+	if err := dec.Decode(&elements); err != nil {
+		// Let the decoder report the problem with the original content.
+		return data, nil
+	}
+
+	names := positionalFieldNames(t)
+	if len(elements) > len(names) {
+		return nil, PositionalFieldsError{
+			Elements: len(elements),
+			Fields:   len(names),
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, elem := range elements {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(names[i])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(elem)
+	}
+
+	buf.WriteByte('}')
+	buf.Write(trimmed[dec.InputOffset():])
+
+	return buf.Bytes(), nil
+}
+
+// positionalFieldNames returns the JSON names of the fields of t in the order
+// that they are declared.
+func positionalFieldNames(t reflect.Type) []string {
+	var names []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				names = append(names, positionalFieldNames(ft)...)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
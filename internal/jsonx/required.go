@@ -0,0 +1,113 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// RequiredTag is the struct tag used to mark a field as required when
+// UnmarshalOptions.EnforceRequiredFields is enabled.
+//
+// A field is marked as required using the `jsonrpc:"required"` tag.
+const RequiredTag = "jsonrpc"
+
+// MissingFieldsError indicates that JSON content did not contain one or more
+// of the fields marked as required.
+type MissingFieldsError struct {
+	Fields []string
+}
+
+func (e MissingFieldsError) Error() string {
+	return "missing required fields: " + strings.Join(e.Fields, ", ")
+}
+
+// checkRequiredFields returns an error if the JSON object in data does not
+// contain each of the fields of v that are marked as required.
+//
+// A field that is present but explicitly null is considered missing. Only the
+// top-level fields of v (including those promoted from embedded structs) are
+// checked. If v is not a struct, or data is not a JSON object, no checks are
+// performed.
+func checkRequiredFields(data []byte, v any) error {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+
+	var missing []string
+	for _, name := range requiredFieldNames(t) {
+		if !hasField(object, name) {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) != 0 {
+		return MissingFieldsError{missing}
+	}
+
+	return nil
+}
+
+// requiredFieldNames returns the JSON names of the fields of t that are marked
+// as required.
+func requiredFieldNames(t reflect.Type) []string {
+	var names []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				names = append(names, requiredFieldNames(ft)...)
+				continue
+			}
+		}
+
+		if !f.IsExported() || f.Tag.Get(RequiredTag) != "required" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// hasField returns true if object contains a non-null value for the field with
+// the given name.
+//
+// Like encoding/json, field names are matched case-insensitively.
+func hasField(object map[string]json.RawMessage, name string) bool {
+	for k, v := range object {
+		if strings.EqualFold(k, name) && !bytes.Equal(bytes.TrimSpace(v), []byte("null")) {
+			return true
+		}
+	}
+
+	return false
+}
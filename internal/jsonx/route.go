@@ -0,0 +1,17 @@
+package jsonx
+
+// RouteConfig is the configuration of a route to which an UnmarshalOption can
+// be applied.
+type RouteConfig interface {
+	// AddUnmarshalOption adds an option that is used when unmarshaling the
+	// route's parameters.
+	AddUnmarshalOption(UnmarshalOption)
+}
+
+// ApplyToRoute adds fn to the options used to unmarshal the parameters of the
+// route configured by c.
+//
+// It allows an UnmarshalOption to be used as a harpy.RouteOption.
+func (fn UnmarshalOption) ApplyToRoute(c RouteConfig) {
+	c.AddUnmarshalOption(fn)
+}
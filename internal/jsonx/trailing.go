@@ -0,0 +1,37 @@
+package jsonx
+
+import (
+	"bufio"
+	"io"
+)
+
+// TrailingDataError indicates that JSON content is followed by data other than
+// whitespace.
+type TrailingDataError struct{}
+
+func (e TrailingDataError) Error() string {
+	return "json: invalid data after top-level value"
+}
+
+// checkTrailingData returns a TrailingDataError if r contains anything other
+// than JSON whitespace.
+func checkTrailingData(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		default:
+			return TrailingDataError{}
+		}
+	}
+}
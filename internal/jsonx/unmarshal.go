@@ -13,12 +13,59 @@ func Decode(r io.Reader, v any, options ...UnmarshalOption) error {
 		fn(&opts)
 	}
 
-	dec := json.NewDecoder(r)
+	if opts.EnforceRequiredFields || opts.AllowPositionalFields {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		if opts.AllowPositionalFields {
+			data, err = positionalToObject(data, v)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := decode(bytes.NewReader(data), v, opts); err != nil {
+			return err
+		}
+
+		if opts.EnforceRequiredFields {
+			return checkRequiredFields(data, v)
+		}
+
+		return nil
+	}
+
+	return decode(r, v, opts)
+}
+
+// decode unmarshals JSON content from r into v.
+func decode(r io.Reader, v any, opts UnmarshalOptions) error {
+	var dr io.Reader = r
+	if opts.MaxDepth != 0 {
+		dr = &depthLimitedReader{
+			r:        r,
+			maxDepth: opts.MaxDepth,
+		}
+	}
+
+	dec := json.NewDecoder(dr)
 	if !opts.AllowUnknownFields {
 		dec.DisallowUnknownFields()
 	}
 
-	return dec.Decode(&v)
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	if opts.DisallowTrailingData {
+		// Any content that has already been buffered by the decoder is checked
+		// before the remainder of r.
+		return checkTrailingData(io.MultiReader(dec.Buffered(), r))
+	}
+
+	return nil
 }
 
 // Unmarshal unmarshals JSON content from data into v.
@@ -35,5 +82,19 @@ type UnmarshalOption func(*UnmarshalOptions)
 
 // UnmarshalOptions is a set of options that control how JSON is unmarshaled.
 type UnmarshalOptions struct {
-	AllowUnknownFields bool
+	AllowUnknownFields    bool
+	EnforceRequiredFields bool
+
+	// AllowPositionalFields causes a JSON array to be unmarshaled into a
+	// struct by assigning each element to the field at the same position.
+	AllowPositionalFields bool
+
+	// DisallowTrailingData causes Decode() and Unmarshal() to fail with a
+	// TrailingDataError if the JSON value is followed by anything other than
+	// whitespace.
+	DisallowTrailingData bool
+
+	// MaxDepth is the maximum nesting depth of arrays and objects. If it is
+	// zero, the nesting depth is not limited.
+	MaxDepth int
 }
@@ -7,6 +7,19 @@ import (
 // UnmarshalOption is an option that changes the behavior of JSON unmarshaling.
 type UnmarshalOption = jsonx.UnmarshalOption
 
+// EncoderOption is an option that changes the behavior of JSON marshaling.
+type EncoderOption = jsonx.MarshalOption
+
+// EscapeHTML is an EncoderOption that controls whether the characters <, > and
+// & are escaped within JSON strings.
+//
+// HTML characters are escaped by default, as per json.Marshal().
+func EscapeHTML(escape bool) EncoderOption {
+	return func(opts *jsonx.MarshalOptions) {
+		opts.DisableHTMLEscaping = !escape
+	}
+}
+
 // AllowUnknownFields is an UnmarshalOption that controls whether parameters,
 // results and error data may contain unknown fields.
 //
@@ -16,3 +29,84 @@ func AllowUnknownFields(allow bool) UnmarshalOption {
 		opts.AllowUnknownFields = allow
 	}
 }
+
+// AllowPositionalFields is an UnmarshalOption that controls whether a JSON
+// array may be unmarshaled into a struct, such that parameters can be passed
+// either by position or by name.
+//
+// When enabled, each element of the array is assigned to the struct field at
+// the same position, in the order that the fields are declared. Fields that
+// are ignored by encoding/json, such as unexported fields, do not have a
+// position. Fields of embedded structs are positioned as though they were
+// declared in place of the embedded struct. An array with more elements than
+// there are fields is rejected. A JSON object is unmarshaled as usual.
+//
+// This allows a single route added via WithRoute() to accept both calling
+// conventions permitted by the JSON-RPC specification. For example, given:
+//
+//	type Params struct {
+//		Name  string `json:"name"`
+//		Count int    `json:"count"`
+//	}
+//
+// the parameters ["foo", 3] and {"name": "foo", "count": 3} are equivalent.
+// When used with Request.UnmarshalParameters() (and hence WithRoute()), a
+// mismatch results in a JSON-RPC "invalid parameters" error.
+//
+// Positional fields are disallowed by default.
+func AllowPositionalFields(allow bool) UnmarshalOption {
+	return func(opts *jsonx.UnmarshalOptions) {
+		opts.AllowPositionalFields = allow
+	}
+}
+
+// EnforceRequiredFields is an UnmarshalOption that controls whether struct
+// fields tagged with `jsonrpc:"required"` must be present in the JSON content.
+//
+// When enabled, a field marked as required that is missing or null causes
+// unmarshaling to fail. When used with Request.UnmarshalParameters() (and
+// hence WithRoute()), the resulting JSON-RPC "invalid parameters" error lists
+// the missing fields.
+//
+// Only the top-level fields of the target struct are checked. Required fields
+// are not enforced by default.
+func EnforceRequiredFields(enforce bool) UnmarshalOption {
+	return func(opts *jsonx.UnmarshalOptions) {
+		opts.EnforceRequiredFields = enforce
+	}
+}
+
+// MaxNestingDepth is an UnmarshalOption that sets the maximum depth to which
+// JSON arrays and objects may be nested.
+//
+// Content that is nested more deeply is rejected before it is decoded,
+// preventing maliciously crafted input from exhausting resources. When used
+// with Request.UnmarshalParameters() (and hence WithRoute()), exceeding the
+// limit results in a JSON-RPC "invalid parameters" error. Use
+// MaxRequestSetNestingDepth() to limit the nesting depth of request sets.
+//
+// The default limit is 64 when unmarshaling request parameters. The nesting
+// depth of other content, such as the results received by a client, is not
+// limited by default. It panics if n is not positive.
+func MaxNestingDepth(n int) UnmarshalOption {
+	if n <= 0 {
+		panic("the maximum nesting depth must be positive")
+	}
+
+	return func(opts *jsonx.UnmarshalOptions) {
+		opts.MaxDepth = n
+	}
+}
+
+// withDefaultMaxNestingDepth returns options with the default maximum nesting
+// depth applied, such that it is used unless options contains
+// MaxNestingDepth().
+//
+// It is used when unmarshaling content received from a client, which may be
+// maliciously crafted.
+func withDefaultMaxNestingDepth(options []UnmarshalOption) []UnmarshalOption {
+	return append(
+		[]UnmarshalOption{MaxNestingDepth(jsonx.DefaultMaxDepth)},
+		options...,
+	)
+}
@@ -0,0 +1,121 @@
+package harpy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrServerBusy indicates that a request was rejected because the server was
+// too busy to handle it.
+//
+// It is available via the ServerError field of the ErrorResponse produced by a
+// LoadShedder, allowing transports to report the condition appropriately. For
+// example, the HTTP transport responds with an HTTP 503 (Service Unavailable)
+// status.
+var ErrServerBusy = errors.New("server busy")
+
+// LoadShedder is an implementation of Exchanger that rejects requests when the
+// next exchanger is already handling its maximum number of requests, rather
+// than waiting for capacity to become available.
+//
+// Rejected requests are never passed to the next exchanger.
+type LoadShedder struct {
+	// Next is the next exchanger in the middleware stack.
+	Next Exchanger
+
+	// Capacity is the maximum number of requests that the next exchanger may
+	// handle concurrently. It must be positive.
+	Capacity int
+
+	// Error is the error used to reject requests. If it is nil, requests are
+	// rejected with a JSON-RPC "internal error".
+	Error error
+
+	once     sync.Once
+	slots    chan struct{}
+	accepted atomic.Uint64
+	shed     atomic.Uint64
+}
+
+var _ Exchanger = (*LoadShedder)(nil)
+
+// Call handles a call request and returns the response.
+//
+// If the next exchanger is at capacity, it returns an ErrorResponse without
+// invoking the next exchanger. The response's ServerError field matches
+// ErrServerBusy, as per errors.Is().
+func (l *LoadShedder) Call(ctx context.Context, req Request) Response {
+	if !l.acquire() {
+		res := NewErrorResponse(req.ID, l.err())
+		res.ServerError = errors.Join(ErrServerBusy, res.ServerError)
+		return res
+	}
+	defer l.release()
+
+	return l.Next.Call(ctx, req)
+}
+
+// Notify handles a notification request.
+//
+// If the next exchanger is at capacity, it returns an error that matches
+// ErrServerBusy, as per errors.Is(), without invoking the next exchanger.
+func (l *LoadShedder) Notify(ctx context.Context, req Request) error {
+	if !l.acquire() {
+		return errors.Join(ErrServerBusy, l.Error)
+	}
+	defer l.release()
+
+	return l.Next.Notify(ctx, req)
+}
+
+// Accepted returns the number of requests that have been passed to the next
+// exchanger.
+func (l *LoadShedder) Accepted() uint64 {
+	return l.accepted.Load()
+}
+
+// Shed returns the number of requests that have been rejected because the next
+// exchanger was at capacity.
+func (l *LoadShedder) Shed() uint64 {
+	return l.shed.Load()
+}
+
+// acquire attempts to acquire a slot without blocking. It returns false if all
+// slots are in use.
+func (l *LoadShedder) acquire() bool {
+	l.once.Do(func() {
+		if l.Capacity <= 0 {
+			panic("load shedder capacity must be positive")
+		}
+
+		l.slots = make(chan struct{}, l.Capacity)
+	})
+
+	select {
+	case l.slots <- struct{}{}:
+		l.accepted.Add(1)
+		return true
+	default:
+		l.shed.Add(1)
+		return false
+	}
+}
+
+// release releases a slot acquired by acquire().
+func (l *LoadShedder) release() {
+	<-l.slots
+}
+
+// err returns the error used to reject calls.
+func (l *LoadShedder) err() error {
+	if l.Error != nil {
+		return l.Error
+	}
+
+	return NewErrorWithReservedCode(
+		InternalErrorCode,
+		WithMessage("the server is too busy to handle the request"),
+	)
+}
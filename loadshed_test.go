@@ -0,0 +1,155 @@
+package harpy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type LoadShedder", func() {
+	var (
+		next      *ExchangerStub
+		release   chan struct{}
+		started   chan struct{}
+		exchanger *LoadShedder
+		request   Request
+	)
+
+	BeforeEach(func() {
+		// The stub refers to its own copies of the channels, as it may still be
+		// running when the next test begins.
+		rel := make(chan struct{})
+		st := make(chan struct{}, 10)
+		release, started = rel, st
+
+		next = &ExchangerStub{
+			CallFunc: func(_ context.Context, req Request) Response {
+				st <- struct{}{}
+				<-rel
+				return NewSuccessResponse(req.ID, nil)
+			},
+			NotifyFunc: func(context.Context, Request) error {
+				st <- struct{}{}
+				<-rel
+				return nil
+			},
+		}
+
+		exchanger = &LoadShedder{
+			Next:     next,
+			Capacity: 1,
+		}
+
+		request = Request{
+			Version: "2.0",
+			ID:      json.RawMessage(`123`),
+			Method:  "<method>",
+		}
+	})
+
+	AfterEach(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	// occupy starts a call that holds the exchanger's only slot until release
+	// is closed.
+	occupy := func() {
+		x, req := exchanger, request
+		go x.Call(context.Background(), req)
+		Eventually(started).Should(Receive())
+	}
+
+	Describe("func Call()", func() {
+		It("passes the call to the next exchanger when there is capacity", func() {
+			close(release)
+
+			res := exchanger.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+			Expect(exchanger.Accepted()).To(BeEquivalentTo(1))
+			Expect(exchanger.Shed()).To(BeEquivalentTo(0))
+		})
+
+		It("rejects the call without blocking when at capacity", func() {
+			occupy()
+
+			res := exchanger.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+
+			errRes := res.(ErrorResponse)
+			Expect(errRes.RequestID).To(Equal(json.RawMessage(`123`)))
+			Expect(errRes.Error).To(Equal(ErrorInfo{
+				Code:    InternalErrorCode,
+				Message: "the server is too busy to handle the request",
+			}))
+			Expect(errRes.ServerError).To(MatchError(ErrServerBusy))
+
+			Expect(exchanger.Accepted()).To(BeEquivalentTo(1))
+			Expect(exchanger.Shed()).To(BeEquivalentTo(1))
+		})
+
+		It("uses the configured error", func() {
+			exchanger.Error = NewError(456, WithMessage("<message>"))
+			occupy()
+
+			res := exchanger.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+
+			errRes := res.(ErrorResponse)
+			Expect(errRes.Error).To(Equal(ErrorInfo{
+				Code:    456,
+				Message: "<message>",
+			}))
+			Expect(errRes.ServerError).To(MatchError(ErrServerBusy))
+		})
+
+		It("accepts calls again once capacity is available", func() {
+			occupy()
+			close(release)
+
+			Eventually(func() Response {
+				return exchanger.Call(context.Background(), request)
+			}).Should(BeAssignableToTypeOf(SuccessResponse{}))
+		})
+
+		It("panics if the capacity is not positive", func() {
+			exchanger.Capacity = 0
+
+			Expect(func() {
+				exchanger.Call(context.Background(), request)
+			}).To(PanicWith("load shedder capacity must be positive"))
+		})
+	})
+
+	Describe("func Notify()", func() {
+		BeforeEach(func() {
+			request.ID = nil
+		})
+
+		It("passes the notification to the next exchanger when there is capacity", func() {
+			close(release)
+
+			err := exchanger.Notify(context.Background(), request)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(exchanger.Accepted()).To(BeEquivalentTo(1))
+		})
+
+		It("rejects the notification without blocking when at capacity", func() {
+			exchanger.Error = errors.New("<error>")
+			occupy()
+
+			err := exchanger.Notify(context.Background(), request)
+			Expect(err).To(MatchError(ErrServerBusy))
+			Expect(err).To(MatchError(exchanger.Error))
+			Expect(exchanger.Shed()).To(BeEquivalentTo(1))
+		})
+	})
+})
@@ -1,7 +1,13 @@
 package harpy
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -10,6 +16,8 @@ import (
 
 // ExchangeLogger is an interface for logging JSON-RPC requests, responses and
 // errors.
+//
+// An ExchangeLogger may also implement RequestStartLogger.
 type ExchangeLogger interface {
 	// LogError logs about an error that is a result of some problem with the
 	// request set as a whole.
@@ -20,25 +28,117 @@ type ExchangeLogger interface {
 	LogWriterError(ctx context.Context, err error)
 
 	// LogNotification logs about a notification request.
+	//
+	// The time taken to handle the request is available via
+	// RequestDurationFromContext(ctx).
 	LogNotification(ctx context.Context, req Request, err error)
 
 	// LogCall logs about a call request/response pair.
+	//
+	// The time taken to handle the request is available via
+	// RequestDurationFromContext(ctx).
 	LogCall(ctx context.Context, req Request, res Response)
 }
 
+// RequestStartLogger is an interface for logging JSON-RPC requests before they
+// are handled.
+//
+// It is an optional interface that may be implemented by an ExchangeLogger.
+type RequestStartLogger interface {
+	// LogRequestStart logs about a request that is about to be passed to the
+	// exchanger.
+	LogRequestStart(ctx context.Context, req Request)
+}
+
+// ExchangeLoggerOption is an option that changes the behavior of the
+// ExchangeLogger returned by NewZapExchangeLogger() or NewSLogExchangeLogger().
+type ExchangeLoggerOption func(*exchangeLoggerOptions)
+
+// exchangeLoggerOptions is the set of options applied by ExchangeLoggerOption.
+type exchangeLoggerOptions struct {
+	NamespaceSeparator   string
+	ParameterFields      []string
+	SlowRequestThreshold time.Duration
+	SlowRequestLevel     slog.Level
+}
+
+// WithMethodNamespace is an ExchangeLoggerOption that adds a "namespace"
+// attribute to log messages about requests.
+//
+// The namespace is the portion of the method name before the last occurrence
+// of sep. For example, if sep is ".", the namespace of the "user.profile.get"
+// method is "user.profile". The attribute is omitted for methods that do not
+// contain sep.
+//
+// If sep is empty, the attribute is never added, which is the default.
+func WithMethodNamespace(sep string) ExchangeLoggerOption {
+	return func(opts *exchangeLoggerOptions) {
+		opts.NamespaceSeparator = sep
+	}
+}
+
+// maxParameterFieldLength is the maximum length, in bytes, of a parameter value
+// logged via WithParameterFields(). Longer values are truncated at a UTF-8
+// character boundary.
+const maxParameterFieldLength = 256
+
+// WithParameterFields is an ExchangeLoggerOption that adds the values of
+// specific request parameters to log messages about requests.
+//
+// Each path identifies a value within the request's parameters as a sequence
+// of object keys or array indices separated by dots. For example, "tenant_id"
+// identifies the "tenant_id" property of the parameters object, "user.id"
+// identifies the "id" property of the "user" object, and "0" identifies the
+// first positional parameter. Each value is logged as a "params.<path>"
+// attribute.
+//
+// Only strings, numbers and booleans are logged; paths that do not exist or
+// that refer to any other type of value are omitted. Values longer than 256
+// bytes are truncated, so that arbitrarily large values are never logged.
+//
+// By default, no parameter values are logged.
+func WithParameterFields(paths ...string) ExchangeLoggerOption {
+	return func(opts *exchangeLoggerOptions) {
+		opts.ParameterFields = append(opts.ParameterFields, paths...)
+	}
+}
+
+// WithSlowRequestThreshold is an ExchangeLoggerOption that logs requests that
+// take at least threshold to handle at the given level, along with a
+// "duration_ms" attribute.
+//
+// It applies to log messages about calls and notifications, including those
+// that succeed. If the request would otherwise be logged at a more severe
+// level, such as when it fails, the more severe level is used. The duration is
+// obtained via RequestDurationFromContext().
+//
+// If threshold is zero, which is the default, slow requests are logged in the
+// same way as any other. It panics if threshold is negative.
+func WithSlowRequestThreshold(threshold time.Duration, level slog.Level) ExchangeLoggerOption {
+	if threshold < 0 {
+		panic("the slow request threshold must not be negative")
+	}
+
+	return func(opts *exchangeLoggerOptions) {
+		opts.SlowRequestThreshold = threshold
+		opts.SlowRequestLevel = level
+	}
+}
+
 // NewZapExchangeLogger returns an ExchangeLogger that targets the given
 // [zap.Logger].
-func NewZapExchangeLogger(t *zap.Logger) ExchangeLogger {
+func NewZapExchangeLogger(t *zap.Logger, options ...ExchangeLoggerOption) ExchangeLogger {
 	return &structuredExchangeLogger[zap.Field]{
-		Target: t,
-		Int:    zap.Int,
-		String: zap.String,
+		Target:  t,
+		Int:     zap.Int,
+		String:  zap.String,
+		Options: exchangeLoggerOptionsFrom(options),
 	}
 }
 
 // NewSLogExchangeLogger returns an ExchangeLogger that targets the given
 // [slog.Logger].
-func NewSLogExchangeLogger(t *slog.Logger) ExchangeLogger {
+func NewSLogExchangeLogger(t *slog.Logger, options ...ExchangeLoggerOption) ExchangeLogger {
 	return &structuredExchangeLogger[any]{
 		Target: t,
 		Int: func(n string, v int) any {
@@ -47,19 +147,45 @@ func NewSLogExchangeLogger(t *slog.Logger) ExchangeLogger {
 		String: func(n string, v string) any {
 			return slog.String(n, v)
 		},
+		Options: exchangeLoggerOptionsFrom(options),
 	}
 }
 
+// exchangeLoggerOptionsFrom returns the result of applying the given options.
+func exchangeLoggerOptionsFrom(options []ExchangeLoggerOption) exchangeLoggerOptions {
+	var opts exchangeLoggerOptions
+	for _, fn := range options {
+		fn(&opts)
+	}
+	return opts
+}
+
 type structuredExchangeLogger[Attr any] struct {
 	Target interface {
+		Debug(message string, attrs ...Attr)
 		Info(message string, attrs ...Attr)
+		Warn(message string, attrs ...Attr)
 		Error(message string, attrs ...Attr)
 	}
-	Int    func(string, int) Attr
-	String func(string, string) Attr
+	Int     func(string, int) Attr
+	String  func(string, string) Attr
+	Options exchangeLoggerOptions
 }
 
-var _ ExchangeLogger = (*structuredExchangeLogger[any])(nil)
+var (
+	_ ExchangeLogger     = (*structuredExchangeLogger[any])(nil)
+	_ RequestStartLogger = (*structuredExchangeLogger[any])(nil)
+)
+
+// LogRequestStart logs information about a request that is about to be passed
+// to the exchanger.
+func (l structuredExchangeLogger[Attr]) LogRequestStart(ctx context.Context, req Request) {
+	attrs := l.requestAttrs(req)
+
+	attrs = l.appendContextAttrs(ctx, attrs)
+
+	l.Target.Debug("received", attrs...)
+}
 
 // LogError writes an information about an error response that is a result of
 // some problem with the request set as a whole.
@@ -69,9 +195,7 @@ func (l structuredExchangeLogger[Attr]) LogError(ctx context.Context, res ErrorR
 		l.String("error", res.Error.Code.String()),
 	}
 
-	if span := trace.SpanFromContext(ctx); span.IsRecording() {
-		attrs = append(attrs, l.String("trace_id", span.SpanContext().TraceID().String()))
-	}
+	attrs = l.appendContextAttrs(ctx, attrs)
 
 	if res.ServerError != nil {
 		attrs = append(attrs, l.String("caused_by", res.ServerError.Error()))
@@ -94,9 +218,7 @@ func (l structuredExchangeLogger[Attr]) LogWriterError(ctx context.Context, err
 		l.String("error", err.Error()),
 	}
 
-	if span := trace.SpanFromContext(ctx); span.IsRecording() {
-		attrs = append(attrs, l.String("trace_id", span.SpanContext().TraceID().String()))
-	}
+	attrs = l.appendContextAttrs(ctx, attrs)
 
 	l.Target.Error(
 		"unable to write JSON-RPC response",
@@ -106,18 +228,13 @@ func (l structuredExchangeLogger[Attr]) LogWriterError(ctx context.Context, err
 
 // LogNotification logs information about a notification request.
 func (l structuredExchangeLogger[Attr]) LogNotification(ctx context.Context, req Request, err error) {
-	attrs := []Attr{
-		l.String("method", req.Method),
-		l.Int("param_size", len(req.Parameters)),
-	}
+	attrs := l.requestAttrs(req)
 
-	if span := trace.SpanFromContext(ctx); span.IsRecording() {
-		attrs = append(attrs, l.String("trace_id", span.SpanContext().TraceID().String()))
-	}
+	attrs = l.appendContextAttrs(ctx, attrs)
 
 	switch err := err.(type) {
 	case nil:
-		l.Target.Info("notify", attrs...)
+		l.logRequest(ctx, slog.LevelInfo, "notify", attrs)
 	case Error:
 		attrs = append(
 			attrs,
@@ -129,31 +246,23 @@ func (l structuredExchangeLogger[Attr]) LogNotification(ctx context.Context, req
 			attrs = append(attrs, l.String("caused_by", cause.Error()))
 		}
 
-		l.Target.Error("notify", attrs...)
+		l.logRequest(ctx, slog.LevelError, "notify", attrs)
 	default:
 		attrs = append(attrs, l.String("error", err.Error()))
-		l.Target.Error("notify", attrs...)
+		l.logRequest(ctx, slog.LevelError, "notify", attrs)
 	}
 }
 
 // LogCall logs information about a call request and its response.
 func (l structuredExchangeLogger[Attr]) LogCall(ctx context.Context, req Request, res Response) {
-	attrs := []Attr{
-		l.String("method", req.Method),
-		l.Int("param_size", len(req.Parameters)),
-	}
+	attrs := l.requestAttrs(req)
 
-	if span := trace.SpanFromContext(ctx); span.IsRecording() {
-		attrs = append(attrs, l.String("trace_id", span.SpanContext().TraceID().String()))
-	}
+	attrs = l.appendContextAttrs(ctx, attrs)
 
 	switch res := res.(type) {
 	case SuccessResponse:
 		attrs = append(attrs, l.Int("result_size", len(res.Result)))
-		l.Target.Info(
-			"call",
-			attrs...,
-		)
+		l.logRequest(ctx, slog.LevelInfo, "call", attrs)
 	case ErrorResponse:
 		attrs = append(
 			attrs,
@@ -169,9 +278,140 @@ func (l structuredExchangeLogger[Attr]) LogCall(ctx context.Context, req Request
 			attrs = append(attrs, l.String("responded_with", res.Error.Message))
 		}
 
-		l.Target.Error(
-			"call",
-			attrs...,
-		)
+		l.logRequest(ctx, slog.LevelError, "call", attrs)
+	}
+}
+
+// logRequest writes a log message about a call or notification at the given
+// level.
+//
+// If the request took at least as long as the slow request threshold, the
+// duration is added to attrs and the message is written at the slow request
+// level instead, if it is more severe.
+func (l structuredExchangeLogger[Attr]) logRequest(
+	ctx context.Context,
+	level slog.Level,
+	message string,
+	attrs []Attr,
+) {
+	if t := l.Options.SlowRequestThreshold; t > 0 {
+		if d, ok := RequestDurationFromContext(ctx); ok && d >= t {
+			attrs = append(attrs, l.Int("duration_ms", int(d.Milliseconds())))
+			level = max(level, l.Options.SlowRequestLevel)
+		}
+	}
+
+	switch {
+	case level >= slog.LevelError:
+		l.Target.Error(message, attrs...)
+	case level >= slog.LevelWarn:
+		l.Target.Warn(message, attrs...)
+	case level >= slog.LevelInfo:
+		l.Target.Info(message, attrs...)
+	default:
+		l.Target.Debug(message, attrs...)
+	}
+}
+
+// appendContextAttrs appends the attributes that describe ctx to attrs, such
+// as the transport over which the request was received and the trace ID.
+func (l structuredExchangeLogger[Attr]) appendContextAttrs(ctx context.Context, attrs []Attr) []Attr {
+	if name, ok := TransportFromContext(ctx); ok {
+		attrs = append(attrs, l.String("transport", name))
+	}
+
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		attrs = append(attrs, l.String("trace_id", span.SpanContext().TraceID().String()))
+	}
+
+	return attrs
+}
+
+// requestAttrs returns the attributes that describe req.
+func (l structuredExchangeLogger[Attr]) requestAttrs(req Request) []Attr {
+	attrs := []Attr{
+		l.String("method", req.Method),
+	}
+
+	if sep := l.Options.NamespaceSeparator; sep != "" {
+		if i := strings.LastIndex(req.Method, sep); i != -1 {
+			attrs = append(attrs, l.String("namespace", req.Method[:i]))
+		}
 	}
+
+	attrs = append(attrs, l.Int("param_size", len(req.Parameters)))
+
+	for _, path := range l.Options.ParameterFields {
+		if v, ok := lookupParameter(req.Parameters, path); ok {
+			attrs = append(attrs, l.String("params."+path, v))
+		}
+	}
+
+	return attrs
+}
+
+// lookupParameter returns a string representation of the scalar value at the
+// given dot-separated path within params.
+//
+// It returns false if the path does not exist or does not refer to a string,
+// number or boolean.
+func lookupParameter(params json.RawMessage, path string) (string, bool) {
+	value := bytes.TrimSpace(params)
+
+	for _, key := range strings.Split(path, ".") {
+		if len(value) == 0 {
+			return "", false
+		}
+
+		switch value[0] {
+		case '{':
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal(value, &obj); err != nil {
+				return "", false
+			}
+			value = obj[key]
+		case '[':
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 {
+				return "", false
+			}
+
+			var arr []json.RawMessage
+			if err := json.Unmarshal(value, &arr); err != nil || index >= len(arr) {
+				return "", false
+			}
+			value = arr[index]
+		default:
+			return "", false
+		}
+
+		value = bytes.TrimSpace(value)
+	}
+
+	if len(value) == 0 {
+		return "", false
+	}
+
+	var v string
+
+	switch value[0] {
+	case '{', '[', 'n':
+		return "", false
+	case '"':
+		if err := json.Unmarshal(value, &v); err != nil {
+			return "", false
+		}
+	default:
+		v = string(value)
+	}
+
+	if len(v) > maxParameterFieldLength {
+		n := maxParameterFieldLength
+		for n > 0 && !utf8.RuneStart(v[n]) {
+			n--
+		}
+		v = v[:n] + "..."
+	}
+
+	return v, true
 }
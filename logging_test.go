@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/dogmatiq/harpy"
 	. "github.com/dogmatiq/harpy"
@@ -16,6 +19,7 @@ import (
 	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/exp/slog"
 )
 
 type stubIDGenerator struct {
@@ -100,6 +104,23 @@ var _ = Describe("type structuredExchangeLogger", func() {
 		)
 	})
 
+	Describe("func LogRequestStart()", func() {
+		It("logs the request information at the debug level", func() {
+			ctx, span := tracer.Start(ctx, "<span>")
+			defer span.End()
+
+			logger.(RequestStartLogger).LogRequestStart(ctx, request)
+
+			substr := fmt.Sprintf(
+				`DEBUG	received	{"method": "<method>", "param_size": 9, "trace_id": "%s"}`,
+				"01020304050607080102040810203040",
+			)
+			Expect(buffer.String()).To(
+				ContainSubstring(substr),
+			)
+		})
+	})
+
 	Describe("func LogError()", func() {
 		It("logs details of a native error response", func() {
 			ctx, span := tracer.Start(ctx, "<span>")
@@ -280,4 +301,207 @@ var _ = Describe("type structuredExchangeLogger", func() {
 			)
 		})
 	})
+
+	When("the method namespace is enabled", func() {
+		BeforeEach(func() {
+			logger = NewZapExchangeLogger(
+				zap.New(
+					zapcore.NewCore(
+						zapcore.NewConsoleEncoder(
+							zap.NewDevelopmentEncoderConfig(),
+						),
+						zapcore.AddSync(&buffer),
+						zapcore.DebugLevel,
+					),
+				),
+				WithMethodNamespace("."),
+			)
+		})
+
+		It("logs the portion of the method name before the last separator", func() {
+			request.Method = "<namespace>.<sub>.<method>"
+			logger.LogCall(ctx, request, success)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`INFO	call	{"method": "<namespace>.<sub>.<method>", "namespace": "<namespace>.<sub>", "param_size": 9, "result_size": 3}`),
+			)
+		})
+
+		It("logs the namespace of notifications", func() {
+			request.ID = nil
+			request.Method = "<namespace>.<method>"
+			logger.LogNotification(ctx, request, nil)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`INFO	notify	{"method": "<namespace>.<method>", "namespace": "<namespace>", "param_size": 9}`),
+			)
+		})
+
+		It("does not log a namespace if the method does not contain the separator", func() {
+			logger.(RequestStartLogger).LogRequestStart(ctx, request)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`DEBUG	received	{"method": "<method>", "param_size": 9}`),
+			)
+		})
+	})
+
+	When("parameter fields are enabled", func() {
+		BeforeEach(func() {
+			logger = NewZapExchangeLogger(
+				zap.New(
+					zapcore.NewCore(
+						zapcore.NewConsoleEncoder(
+							zap.NewDevelopmentEncoderConfig(),
+						),
+						zapcore.AddSync(&buffer),
+						zapcore.DebugLevel,
+					),
+				),
+				WithParameterFields("tenant_id", "user.id", "user.admin", "missing", "nested"),
+			)
+
+			request.Parameters = json.RawMessage(`{"tenant_id": "<tenant>", "user": {"id": 456, "admin": true}, "nested": {"a": 1}}`)
+		})
+
+		It("logs the values at the given paths", func() {
+			logger.LogCall(ctx, request, success)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`INFO	call	{"method": "<method>", "param_size": 81, "params.tenant_id": "<tenant>", "params.user.id": "456", "params.user.admin": "true", "result_size": 3}`),
+			)
+		})
+
+		It("logs the values of notification parameters", func() {
+			request.ID = nil
+			logger.LogNotification(ctx, request, nil)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`INFO	notify	{"method": "<method>", "param_size": 81, "params.tenant_id": "<tenant>", "params.user.id": "456", "params.user.admin": "true"}`),
+			)
+		})
+
+		It("supports positional parameters", func() {
+			logger = NewZapExchangeLogger(
+				zap.New(
+					zapcore.NewCore(
+						zapcore.NewConsoleEncoder(
+							zap.NewDevelopmentEncoderConfig(),
+						),
+						zapcore.AddSync(&buffer),
+						zapcore.DebugLevel,
+					),
+				),
+				WithParameterFields("1", "5"),
+			)
+
+			request.Parameters = json.RawMessage(`[1, 2, 3]`)
+			logger.(RequestStartLogger).LogRequestStart(ctx, request)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`DEBUG	received	{"method": "<method>", "param_size": 9, "params.1": "2"}`),
+			)
+		})
+
+		It("truncates long values", func() {
+			long := strings.Repeat("x", 300)
+			request.Parameters = json.RawMessage(`{"tenant_id": "` + long + `"}`)
+			logger.(RequestStartLogger).LogRequestStart(ctx, request)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`"params.tenant_id": "` + long[:256] + `..."}`),
+			)
+		})
+
+		It("does not truncate long values within a multi-byte character", func() {
+			// Each "é" is two bytes, so byte 256 is in the middle of a
+			// character.
+			long := "x" + strings.Repeat("é", 150)
+			request.Parameters = json.RawMessage(`{"tenant_id": "` + long + `"}`)
+			logger.(RequestStartLogger).LogRequestStart(ctx, request)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`"params.tenant_id": "` + long[:255] + `..."}`),
+			)
+			Expect(utf8.ValidString(buffer.String())).To(BeTrue())
+		})
+	})
+
+	When("the slow request threshold is enabled", func() {
+		BeforeEach(func() {
+			logger = NewZapExchangeLogger(
+				zap.New(
+					zapcore.NewCore(
+						zapcore.NewConsoleEncoder(
+							zap.NewDevelopmentEncoderConfig(),
+						),
+						zapcore.AddSync(&buffer),
+						zapcore.DebugLevel,
+					),
+				),
+				WithSlowRequestThreshold(100*time.Millisecond, slog.LevelWarn),
+			)
+		})
+
+		It("logs slow calls at the given level with their duration", func() {
+			ctx = WithRequestDuration(ctx, 150*time.Millisecond)
+			logger.LogCall(ctx, request, success)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`WARN	call	{"method": "<method>", "param_size": 9, "result_size": 3, "duration_ms": 150}`),
+			)
+		})
+
+		It("logs slow notifications at the given level with their duration", func() {
+			request.ID = nil
+			ctx = WithRequestDuration(ctx, 100*time.Millisecond)
+			logger.LogNotification(ctx, request, nil)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`WARN	notify	{"method": "<method>", "param_size": 9, "duration_ms": 100}`),
+			)
+		})
+
+		It("does not lower the level of failed requests", func() {
+			ctx = WithRequestDuration(ctx, 150*time.Millisecond)
+			logger.LogCall(ctx, request, nativeError)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`ERROR	call	{"method": "<method>", "param_size": 9, "error_code": -32601, "error": "method not found", "duration_ms": 150}`),
+			)
+		})
+
+		It("does not change how fast requests are logged", func() {
+			ctx = WithRequestDuration(ctx, 50*time.Millisecond)
+			logger.LogCall(ctx, request, success)
+
+			Expect(buffer.String()).To(
+				ContainSubstring(`INFO	call	{"method": "<method>", "param_size": 9, "result_size": 3}`),
+			)
+		})
+
+		It("panics if the threshold is negative", func() {
+			Expect(func() {
+				WithSlowRequestThreshold(-1, slog.LevelWarn)
+			}).To(PanicWith("the slow request threshold must not be negative"))
+		})
+	})
+
+	It("logs the transport associated with the context", func() {
+		ctx = WithTransport(ctx, "<transport>")
+		logger.LogCall(ctx, request, success)
+
+		Expect(buffer.String()).To(
+			ContainSubstring(`INFO	call	{"method": "<method>", "param_size": 9, "transport": "<transport>", "result_size": 3}`),
+		)
+	})
+
+	It("does not log a namespace by default", func() {
+		request.Method = "<namespace>.<method>"
+		logger.LogCall(ctx, request, success)
+
+		Expect(buffer.String()).To(
+			ContainSubstring(`INFO	call	{"method": "<namespace>.<method>", "param_size": 9, "result_size": 3}`),
+		)
+	})
 })
@@ -35,7 +35,7 @@ func requestAttributes(req harpy.Request) []attribute.KeyValue {
 }
 
 // errorResponseAttributes returns the OpenTelemetry attributes that are to be
-// recorded for given error response on every span and meter.
+// recorded for given error response on every span.
 func errorResponseAttributes(res harpy.ErrorResponse) []attribute.KeyValue {
 	return []attribute.KeyValue{
 		semconv.RPCJsonrpcErrorCodeKey.Int(int(res.Error.Code)),
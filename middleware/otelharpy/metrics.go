@@ -9,6 +9,7 @@ import (
 	"github.com/dogmatiq/harpy/internal/version"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 )
 
 // Metrics is an implementation of harpy.Exchanger that provides OpenTelemetry
@@ -29,6 +30,30 @@ type Metrics struct {
 	// It may be empty, in which case it is omitted from the span.
 	ServiceName string
 
+	// DurationBuckets is the set of explicit bucket boundaries used by the
+	// "rpc.server.duration" histogram, in milliseconds.
+	//
+	// If it is empty, the default boundaries provided by the MeterProvider are
+	// used.
+	DurationBuckets []float64
+
+	// RecordErrorMessages enables the "rpc.jsonrpc.error_message" attribute on
+	// the "rpc.server.errors" counter.
+	//
+	// It is disabled by default, as error messages often contain
+	// request-specific details that result in an unbounded number of
+	// distinct attribute values.
+	RecordErrorMessages bool
+
+	// RecordApplicationErrorCodes enables the "rpc.jsonrpc.error_code"
+	// attribute on the "rpc.server.errors" counter for application-defined
+	// error codes.
+	//
+	// It is disabled by default, in which case the attribute is only recorded
+	// for error codes within the range reserved by the JSON-RPC
+	// specification, and all application-defined errors are counted together.
+	RecordApplicationErrorCodes bool
+
 	once          sync.Once
 	calls         metric.Int64Counter
 	notifications metric.Int64Counter
@@ -56,8 +81,8 @@ func (m *Metrics) Call(ctx context.Context, req harpy.Request) harpy.Response {
 	m.duration.Record(ctx, durationToMillis(elapsed), attrOption)
 
 	if res, ok := res.(harpy.ErrorResponse); ok {
-		attrs = append(attrs, errorResponseAttributes(res)...)
-		m.errors.Add(ctx, 1, attrOption)
+		attrs = append(attrs, m.errorAttributes(res)...)
+		m.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
 	}
 
 	return res
@@ -126,10 +151,21 @@ func (m *Metrics) init() {
 			panic(err)
 		}
 
-		m.duration, err = meter.Int64Histogram(
-			"rpc.server.duration",
+		durationOptions := []metric.Int64HistogramOption{
 			metric.WithDescription("The amount of time it takes user-provided handlers to process JSON-RPC requests."),
 			metric.WithUnit("ms"),
+		}
+
+		if len(m.DurationBuckets) != 0 {
+			durationOptions = append(
+				durationOptions,
+				metric.WithExplicitBucketBoundaries(m.DurationBuckets...),
+			)
+		}
+
+		m.duration, err = meter.Int64Histogram(
+			"rpc.server.duration",
+			durationOptions...,
 		)
 		if err != nil {
 			panic(err)
@@ -139,6 +175,28 @@ func (m *Metrics) init() {
 	})
 }
 
+// errorAttributes returns the OpenTelemetry attributes that are recorded on
+// the errors counter for the given error response.
+func (m *Metrics) errorAttributes(res harpy.ErrorResponse) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+
+	if res.Error.Code.IsReserved() || m.RecordApplicationErrorCodes {
+		attrs = append(
+			attrs,
+			semconv.RPCJsonrpcErrorCodeKey.Int(int(res.Error.Code)),
+		)
+	}
+
+	if m.RecordErrorMessages {
+		attrs = append(
+			attrs,
+			semconv.RPCJsonrpcErrorMessageKey.String(res.Error.Message),
+		)
+	}
+
+	return attrs
+}
+
 // durationToMillis converts a duration to milliseconds.
 func durationToMillis(d time.Duration) int64 {
 	return int64(d / time.Millisecond)
@@ -0,0 +1,172 @@
+package otelharpy_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/middleware/otelharpy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+var _ = Describe("type Metrics", func() {
+	var (
+		request   harpy.Request
+		response  harpy.Response
+		exchanger *ExchangerStub
+		reader    *sdkmetric.ManualReader
+		metrics   *Metrics
+	)
+
+	BeforeEach(func() {
+		request = harpy.Request{
+			Version:    "2.0",
+			ID:         json.RawMessage(`123`),
+			Method:     "<method>",
+			Parameters: json.RawMessage(`[1, 2, 3]`),
+		}
+
+		response = harpy.NewSuccessResponse(request.ID, nil)
+
+		exchanger = &ExchangerStub{
+			CallFunc: func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return response
+			},
+		}
+
+		reader = sdkmetric.NewManualReader()
+
+		metrics = &Metrics{
+			Next: exchanger,
+			MeterProvider: sdkmetric.NewMeterProvider(
+				sdkmetric.WithReader(reader),
+			),
+			ServiceName: "<service>",
+		}
+	})
+
+	// collect returns the metric with the given name.
+	collect := func(name string) metricdata.Metrics {
+		var rm metricdata.ResourceMetrics
+		err := reader.Collect(context.Background(), &rm)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Name == name {
+					return m
+				}
+			}
+		}
+
+		Fail("metric not found: " + name)
+		return metricdata.Metrics{}
+	}
+
+	// errorAttributes returns the attributes recorded on the errors counter.
+	errorAttributes := func() attribute.Set {
+		data := collect("rpc.server.errors").Data.(metricdata.Sum[int64])
+		Expect(data.DataPoints).To(HaveLen(1))
+		return data.DataPoints[0].Attributes
+	}
+
+	// requestAttributes are the attributes recorded for the request on every
+	// metric.
+	requestAttributes := []attribute.KeyValue{
+		semconv.RPCSystemKey.String("dogmatiq/harpy"),
+		semconv.RPCServiceKey.String("<service>"),
+		semconv.RPCMethodKey.String("<method>"),
+		semconv.RPCJsonrpcVersionKey.String("2.0"),
+	}
+
+	Describe("func Call()", func() {
+		It("uses the default duration histogram boundaries", func() {
+			metrics.Call(context.Background(), request)
+
+			data := collect("rpc.server.duration").Data.(metricdata.Histogram[int64])
+			Expect(data.DataPoints).To(HaveLen(1))
+			Expect(data.DataPoints[0].Bounds).To(Equal(
+				sdkmetric.DefaultAggregationSelector(sdkmetric.InstrumentKindHistogram).(sdkmetric.AggregationExplicitBucketHistogram).Boundaries,
+			))
+		})
+
+		It("uses the boundaries in DurationBuckets", func() {
+			metrics.DurationBuckets = []float64{1, 10, 100}
+
+			metrics.Call(context.Background(), request)
+
+			data := collect("rpc.server.duration").Data.(metricdata.Histogram[int64])
+			Expect(data.DataPoints).To(HaveLen(1))
+			Expect(data.DataPoints[0].Bounds).To(Equal([]float64{1, 10, 100}))
+			Expect(data.DataPoints[0].Attributes).To(Equal(
+				attribute.NewSet(requestAttributes...),
+			))
+		})
+
+		It("records the error code of reserved errors without the message by default", func() {
+			response = harpy.NewErrorResponse(request.ID, harpy.MethodNotFound())
+
+			metrics.Call(context.Background(), request)
+
+			Expect(errorAttributes()).To(Equal(
+				attribute.NewSet(
+					append(
+						requestAttributes,
+						semconv.RPCJsonrpcErrorCodeKey.Int(int(harpy.MethodNotFoundCode)),
+					)...,
+				),
+			))
+		})
+
+		It("does not record the error code of application-defined errors by default", func() {
+			response = harpy.NewErrorResponse(request.ID, harpy.NewError(456, harpy.WithMessage("<message>")))
+
+			metrics.Call(context.Background(), request)
+
+			Expect(errorAttributes()).To(Equal(
+				attribute.NewSet(requestAttributes...),
+			))
+		})
+
+		It("records the error code of application-defined errors if RecordApplicationErrorCodes is true", func() {
+			metrics.RecordApplicationErrorCodes = true
+			response = harpy.NewErrorResponse(request.ID, harpy.NewError(456, harpy.WithMessage("<message>")))
+
+			metrics.Call(context.Background(), request)
+
+			Expect(errorAttributes()).To(Equal(
+				attribute.NewSet(
+					append(
+						requestAttributes,
+						semconv.RPCJsonrpcErrorCodeKey.Int(456),
+					)...,
+				),
+			))
+		})
+
+		It("records the error message if RecordErrorMessages is true", func() {
+			metrics.RecordErrorMessages = true
+			response = harpy.NewErrorResponse(request.ID, harpy.NewError(456, harpy.WithMessage("<message>")))
+
+			metrics.Call(context.Background(), request)
+
+			Expect(errorAttributes()).To(Equal(
+				attribute.NewSet(
+					append(
+						requestAttributes,
+						semconv.RPCJsonrpcErrorMessageKey.String("<message>"),
+					)...,
+				),
+			))
+		})
+	})
+})
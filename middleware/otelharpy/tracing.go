@@ -42,6 +42,18 @@ type Tracing struct {
 	// creating the span, and no new span will be created.
 	CreateNewSpan bool
 
+	// ShouldSample is an optional function that determines whether a span is
+	// created for a specific request.
+	//
+	// If it returns false, no span is created and the next exchanger is
+	// invoked with a non-recording span that retains the parent's span
+	// context, so that the trace is still propagated.
+	//
+	// It is only used when CreateNewSpan is true. If it is nil, a span is
+	// created for every request. Sampling decisions made by the
+	// TracerProvider still apply to requests for which it returns true.
+	ShouldSample func(req harpy.Request) bool
+
 	once           sync.Once
 	tracer         trace.Tracer
 	spanNamePrefix string
@@ -114,6 +126,15 @@ func (t *Tracing) withSpan(
 	var span trace.Span
 
 	if t.CreateNewSpan {
+		if t.ShouldSample != nil && !t.ShouldSample(req) {
+			// Replace the parent span with a non-recording span that has the
+			// same span context. This prevents fn from modifying the parent
+			// span without breaking propagation.
+			ctx = trace.ContextWithSpanContext(ctx, trace.SpanContextFromContext(ctx))
+			fn(ctx, trace.SpanFromContext(ctx))
+			return
+		}
+
 		ctx, span = t.tracer.Start(
 			ctx,
 			name,
@@ -320,6 +320,46 @@ var _ = Describe("type Tracing", func() {
 		})
 	})
 
+	When("a sampling function is provided", func() {
+		It("does not record a span if the function returns false", func() {
+			tracer := tracing.TracerProvider.Tracer("test")
+			ctx, outerSpan := tracer.Start(context.Background(), "<span>")
+			defer outerSpan.End()
+
+			tracing.ShouldSample = func(req harpy.Request) bool {
+				Expect(req).To(Equal(request))
+				return false
+			}
+
+			exchanger.CallFunc = func(
+				ctx context.Context,
+				_ harpy.Request,
+			) harpy.Response {
+				span := trace.SpanFromContext(ctx)
+				Expect(span.IsRecording()).To(BeFalse())
+				Expect(span.SpanContext()).To(Equal(outerSpan.SpanContext()))
+				return response
+			}
+
+			res := tracing.Call(ctx, request)
+			Expect(res).To(Equal(response))
+			Expect(recorder.Ended()).To(BeEmpty())
+
+			span := outerSpan.(tracesdk.ReadOnlySpan)
+			Expect(span.Name()).To(Equal("<span>"))
+			Expect(span.Attributes()).To(BeEmpty())
+		})
+
+		It("records a span if the function returns true", func() {
+			tracing.ShouldSample = func(harpy.Request) bool {
+				return true
+			}
+
+			tracing.Call(context.Background(), request)
+			Expect(recorder.Ended()).To(HaveLen(1))
+		})
+	})
+
 	When("configured to modify an existing span", func() {
 		var tracer trace.Tracer
 
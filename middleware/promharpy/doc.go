@@ -0,0 +1,6 @@
+// Package promharpy provides middleware that instruments JSON-RPC servers with
+// Prometheus metrics.
+//
+// It is an alternative to the metrics provided by the otelharpy package for
+// applications that do not use the OpenTelemetry metrics SDK.
+package promharpy
@@ -0,0 +1,207 @@
+package promharpy
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dogmatiq/harpy"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is an implementation of harpy.Exchanger that provides Prometheus
+// metrics for each JSON-RPC request.
+//
+// The metrics are equivalent to those provided by otelharpy.Metrics, named
+// according to Prometheus conventions:
+//
+//   - rpc_server_calls_total
+//   - rpc_server_notifications_total
+//   - rpc_server_errors_total
+//   - rpc_server_duration_seconds
+//
+// Unlike the OpenTelemetry equivalent, the duration is measured in seconds,
+// which is the base unit of time used by Prometheus.
+type Metrics struct {
+	// Next is the next exchanger in the middleware stack.
+	Next harpy.Exchanger
+
+	// Registerer is the Prometheus registerer with which the metrics are
+	// registered. If it is nil, prometheus.DefaultRegisterer is used.
+	//
+	// Multiple Metrics instances may share the same registerer, in which case
+	// they also share the same metrics.
+	Registerer prometheus.Registerer
+
+	// ServiceName is an application specific service name to use in the
+	// metric labels.
+	//
+	// It may be prefixed with a dot-separated "package name", for example
+	// "myapp.test.EchoService".
+	//
+	// It may be empty, in which case the "rpc_service" label is empty.
+	ServiceName string
+
+	// RecordApplicationErrorCodes enables the "rpc_jsonrpc_error_code" label
+	// on the "rpc_server_errors_total" counter for application-defined error
+	// codes.
+	//
+	// It is disabled by default, in which case the label is only populated
+	// for error codes within the range reserved by the JSON-RPC
+	// specification, and all application-defined errors are counted together
+	// with an empty label. This matches the behavior of otelharpy.Metrics.
+	RecordApplicationErrorCodes bool
+
+	once          sync.Once
+	calls         *prometheus.CounterVec
+	notifications *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+}
+
+var _ harpy.Exchanger = (*Metrics)(nil)
+
+// Call handles a call request and returns the response.
+func (m *Metrics) Call(ctx context.Context, req harpy.Request) harpy.Response {
+	m.init()
+
+	labels := m.requestLabels(req)
+	m.calls.With(labels).Inc()
+
+	start := time.Now()
+	res := m.Next.Call(ctx, req)
+	elapsed := time.Since(start)
+
+	m.duration.With(labels).Observe(elapsed.Seconds())
+
+	if res, ok := res.(harpy.ErrorResponse); ok {
+		labels[errorCodeLabel] = m.errorCodeLabel(res.Error.Code)
+		m.errors.With(labels).Inc()
+	}
+
+	return res
+}
+
+// Notify handles a notification request.
+func (m *Metrics) Notify(ctx context.Context, req harpy.Request) error {
+	m.init()
+
+	labels := m.requestLabels(req)
+	m.notifications.With(labels).Inc()
+
+	start := time.Now()
+	err := m.Next.Notify(ctx, req)
+	elapsed := time.Since(start)
+
+	m.duration.With(labels).Observe(elapsed.Seconds())
+
+	if err != nil {
+		labels[errorCodeLabel] = ""
+		m.errors.With(labels).Inc()
+	}
+
+	return err
+}
+
+// The names of the labels applied to each metric. They are equivalent to the
+// attributes used by otelharpy.
+const (
+	systemLabel    = "rpc_system"
+	serviceLabel   = "rpc_service"
+	methodLabel    = "rpc_method"
+	versionLabel   = "rpc_jsonrpc_version"
+	errorCodeLabel = "rpc_jsonrpc_error_code"
+)
+
+// requestLabels returns the labels that are recorded for the given request on
+// every metric.
+func (m *Metrics) requestLabels(req harpy.Request) prometheus.Labels {
+	return prometheus.Labels{
+		serviceLabel: m.ServiceName,
+		methodLabel:  req.Method,
+		versionLabel: req.Version,
+	}
+}
+
+// errorCodeLabel returns the value of the error code label for the given
+// error code.
+func (m *Metrics) errorCodeLabel(c harpy.ErrorCode) string {
+	if c.IsReserved() || m.RecordApplicationErrorCodes {
+		return strconv.Itoa(int(c))
+	}
+
+	return ""
+}
+
+// init initializes the metrics if they have not already been initialized.
+func (m *Metrics) init() {
+	m.once.Do(func() {
+		reg := m.Registerer
+		if reg == nil {
+			reg = prometheus.DefaultRegisterer
+		}
+
+		constLabels := prometheus.Labels{
+			systemLabel: "dogmatiq/harpy",
+		}
+		labels := []string{serviceLabel, methodLabel, versionLabel}
+
+		m.calls = register(reg, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "rpc_server_calls_total",
+				Help:        "The number of JSON-RPC requests that are 'calls'.",
+				ConstLabels: constLabels,
+			},
+			labels,
+		))
+
+		m.notifications = register(reg, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "rpc_server_notifications_total",
+				Help:        "The number of JSON-RPC requests that are 'notifications'.",
+				ConstLabels: constLabels,
+			},
+			labels,
+		))
+
+		m.errors = register(reg, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "rpc_server_errors_total",
+				Help:        "The number of JSON-RPC requests that result in an error.",
+				ConstLabels: constLabels,
+			},
+			append(labels, errorCodeLabel),
+		))
+
+		m.duration = register(reg, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "rpc_server_duration_seconds",
+				Help:        "The amount of time it takes user-provided handlers to process JSON-RPC requests.",
+				ConstLabels: constLabels,
+			},
+			labels,
+		))
+	})
+}
+
+// register registers c with reg.
+//
+// If an equivalent collector is already registered, the existing collector is
+// returned instead.
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) T {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		if existing, ok := already.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+
+	panic(err)
+}
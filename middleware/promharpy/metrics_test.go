@@ -0,0 +1,207 @@
+package promharpy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/middleware/promharpy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("type Metrics", func() {
+	var (
+		request   harpy.Request
+		exchanger *ExchangerStub
+		registry  *prometheus.Registry
+		metrics   *Metrics
+	)
+
+	BeforeEach(func() {
+		request = harpy.Request{
+			Version:    "2.0",
+			ID:         json.RawMessage(`123`),
+			Method:     "<method>",
+			Parameters: json.RawMessage(`[1, 2, 3]`),
+		}
+
+		exchanger = &ExchangerStub{}
+		registry = prometheus.NewRegistry()
+
+		metrics = &Metrics{
+			Next:        exchanger,
+			Registerer:  registry,
+			ServiceName: "<service>",
+		}
+	})
+
+	Describe("func Call()", func() {
+		It("forwards to the next exchanger", func() {
+			expect := harpy.NewSuccessResponse(request.ID, 123)
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				Expect(req).To(Equal(request))
+				return expect
+			}
+
+			res := metrics.Call(context.Background(), request)
+			Expect(res).To(Equal(expect))
+		})
+
+		It("records the call and its duration", func() {
+			metrics.Call(context.Background(), request)
+
+			err := testutil.GatherAndCompare(
+				registry,
+				strings.NewReader(`
+# HELP rpc_server_calls_total The number of JSON-RPC requests that are 'calls'.
+# TYPE rpc_server_calls_total counter
+rpc_server_calls_total{rpc_jsonrpc_version="2.0",rpc_method="<method>",rpc_service="<service>",rpc_system="dogmatiq/harpy"} 1
+`),
+				"rpc_server_calls_total",
+				"rpc_server_errors_total",
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			count, err := testutil.GatherAndCount(registry, "rpc_server_duration_seconds")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+
+		It("records errors with the JSON-RPC error code", func() {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewErrorResponse(req.ID, harpy.MethodNotFound())
+			}
+
+			metrics.Call(context.Background(), request)
+
+			err := testutil.GatherAndCompare(
+				registry,
+				strings.NewReader(`
+# HELP rpc_server_errors_total The number of JSON-RPC requests that result in an error.
+# TYPE rpc_server_errors_total counter
+rpc_server_errors_total{rpc_jsonrpc_error_code="-32601",rpc_jsonrpc_version="2.0",rpc_method="<method>",rpc_service="<service>",rpc_system="dogmatiq/harpy"} 1
+`),
+				"rpc_server_errors_total",
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("does not record application-defined error codes by default", func() {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewErrorResponse(req.ID, harpy.NewError(456))
+			}
+
+			metrics.Call(context.Background(), request)
+
+			err := testutil.GatherAndCompare(
+				registry,
+				strings.NewReader(`
+# HELP rpc_server_errors_total The number of JSON-RPC requests that result in an error.
+# TYPE rpc_server_errors_total counter
+rpc_server_errors_total{rpc_jsonrpc_error_code="",rpc_jsonrpc_version="2.0",rpc_method="<method>",rpc_service="<service>",rpc_system="dogmatiq/harpy"} 1
+`),
+				"rpc_server_errors_total",
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("records application-defined error codes if RecordApplicationErrorCodes is true", func() {
+			metrics.RecordApplicationErrorCodes = true
+
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewErrorResponse(req.ID, harpy.NewError(456))
+			}
+
+			metrics.Call(context.Background(), request)
+
+			err := testutil.GatherAndCompare(
+				registry,
+				strings.NewReader(`
+# HELP rpc_server_errors_total The number of JSON-RPC requests that result in an error.
+# TYPE rpc_server_errors_total counter
+rpc_server_errors_total{rpc_jsonrpc_error_code="456",rpc_jsonrpc_version="2.0",rpc_method="<method>",rpc_service="<service>",rpc_system="dogmatiq/harpy"} 1
+`),
+				"rpc_server_errors_total",
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Describe("func Notify()", func() {
+		BeforeEach(func() {
+			request.ID = nil
+		})
+
+		It("forwards to the next exchanger", func() {
+			exchanger.NotifyFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) error {
+				Expect(req).To(Equal(request))
+				return errors.New("<error>")
+			}
+
+			err := metrics.Notify(context.Background(), request)
+			Expect(err).To(MatchError("<error>"))
+		})
+
+		It("records the notification, its duration and any error", func() {
+			exchanger.NotifyFunc = func(context.Context, harpy.Request) error {
+				return errors.New("<error>")
+			}
+
+			metrics.Notify(context.Background(), request)
+
+			err := testutil.GatherAndCompare(
+				registry,
+				strings.NewReader(`
+# HELP rpc_server_notifications_total The number of JSON-RPC requests that are 'notifications'.
+# TYPE rpc_server_notifications_total counter
+rpc_server_notifications_total{rpc_jsonrpc_version="2.0",rpc_method="<method>",rpc_service="<service>",rpc_system="dogmatiq/harpy"} 1
+# HELP rpc_server_errors_total The number of JSON-RPC requests that result in an error.
+# TYPE rpc_server_errors_total counter
+rpc_server_errors_total{rpc_jsonrpc_error_code="",rpc_jsonrpc_version="2.0",rpc_method="<method>",rpc_service="<service>",rpc_system="dogmatiq/harpy"} 1
+`),
+				"rpc_server_notifications_total",
+				"rpc_server_errors_total",
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			count, err := testutil.GatherAndCount(registry, "rpc_server_duration_seconds")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+	})
+
+	It("shares metrics between instances that use the same registerer", func() {
+		other := &Metrics{
+			Next:       exchanger,
+			Registerer: registry,
+		}
+
+		metrics.Call(context.Background(), request)
+		other.Call(context.Background(), request)
+
+		count, err := testutil.GatherAndCount(registry, "rpc_server_calls_total")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(count).To(Equal(2))
+	})
+})
@@ -0,0 +1,88 @@
+package harpy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// parameterMetadataKey is the context key used to store the metadata extracted
+// from a request's parameters.
+type parameterMetadataKey struct{}
+
+// WithParameterMetadata is a RouterOption that extracts metadata from the
+// parameters of each request before it is passed to a handler.
+//
+// If a request's parameters are a JSON object that contains the given key, the
+// key is removed from the parameters and its value is added to the context
+// passed to the handler, where it can be obtained via
+// ParameterMetadataFromContext(). This allows clients to pass cross-cutting
+// flags, such as a "dry run" flag, without adding them to the parameter type
+// of every method. For example, given the key "$meta", the handler for the
+// parameters {"name": "foo", "$meta": {"dryRun": true}} sees only
+// {"name": "foo"}.
+//
+// This is an extension to the JSON-RPC specification. Parameters that are not
+// an object, or that do not contain the key, are passed to the handler
+// unchanged. Parameters are not extracted by default. It panics if key is
+// empty.
+func WithParameterMetadata(key string) RouterOption {
+	if key == "" {
+		panic("the parameter metadata key must not be empty")
+	}
+
+	return func(r *Router) {
+		r.metadataKey = key
+	}
+}
+
+// ParameterMetadataFromContext returns the metadata that was extracted from the
+// request parameters by a Router configured with WithParameterMetadata().
+//
+// ok is false if ctx does not contain any parameter metadata.
+func ParameterMetadataFromContext(ctx context.Context) (_ json.RawMessage, ok bool) {
+	meta, ok := ctx.Value(parameterMetadataKey{}).(json.RawMessage)
+	return meta, ok
+}
+
+// extractParameterMetadata removes the metadata from the parameters of req, if
+// enabled, and adds it to ctx.
+//
+// It returns a JSON-RPC "invalid parameters" error if the parameters can not
+// be parsed.
+func (r *Router) extractParameterMetadata(
+	ctx context.Context,
+	req Request,
+) (context.Context, Request, error) {
+	if r.metadataKey == "" {
+		return ctx, req, nil
+	}
+
+	if p := bytes.TrimSpace(req.Parameters); len(p) == 0 || p[0] != '{' {
+		return ctx, req, nil
+	}
+
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(req.Parameters, &params); err != nil {
+		return nil, Request{}, InvalidParameters(WithCause(err))
+	}
+
+	meta, ok := params[r.metadataKey]
+	if !ok {
+		return ctx, req, nil
+	}
+
+	delete(params, r.metadataKey)
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		// CODE COVERAGE: This branch can not be reached, as the remaining
+		// parameters have already been parsed as valid JSON.
+		return nil, Request{}, InvalidParameters(WithCause(err))
+	}
+
+	req.Parameters = data
+	ctx = context.WithValue(ctx, parameterMetadataKey{}, meta)
+
+	return ctx, req, nil
+}
@@ -0,0 +1,277 @@
+package harpy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Recording is a request and its response, as recorded by a Recorder.
+//
+// Recordings are written as newline-delimited JSON, with one recording per
+// line. For example:
+//
+//	{"request": {"jsonrpc": "2.0", "id": 1, "method": "<method>"}, "response": {"jsonrpc": "2.0", "id": 1, "result": null}}
+type Recording struct {
+	// Request is the recorded request.
+	Request Request
+
+	// Response is the response to the request. It is nil if the request is a
+	// notification. It never has a streamed result.
+	Response Response
+}
+
+// recordingJSON is the JSON representation of a Recording.
+type recordingJSON struct {
+	Request  Request         `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// MarshalJSON returns the JSON representation of the recording.
+func (r Recording) MarshalJSON() ([]byte, error) {
+	rec := recordingJSON{
+		Request: r.Request,
+	}
+
+	if r.Response != nil {
+		if res, ok := r.Response.(SuccessResponse); ok && res.ResultStream != nil {
+			return nil, errors.New("can not marshal a recording of a response with a streamed result")
+		}
+
+		data, err := json.Marshal(r.Response)
+		if err != nil {
+			return nil, err
+		}
+
+		rec.Response = data
+	}
+
+	return json.Marshal(rec)
+}
+
+// UnmarshalJSON populates the recording from its JSON representation.
+func (r *Recording) UnmarshalJSON(data []byte) error {
+	var rec recordingJSON
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+
+	r.Request = rec.Request
+	r.Response = nil
+
+	if len(rec.Response) != 0 {
+		rs, err := UnmarshalResponseSet(bytes.NewReader(rec.Response))
+		if err != nil {
+			return err
+		}
+
+		if rs.IsBatch || len(rs.Responses) != 1 {
+			return errors.New("recorded response must be a single response")
+		}
+
+		r.Response = rs.Responses[0]
+	}
+
+	return nil
+}
+
+// Recorder is an implementation of Exchanger that records each request, and its
+// response, to a writer.
+//
+// The recording can be reproduced using a ReplayReader or Replay(), allowing
+// real traffic to be used for golden-file and regression testing of handlers.
+//
+// Requests are recorded in the order that they complete, which is not
+// necessarily the order in which they were received.
+type Recorder struct {
+	// Next is the next exchanger in the middleware stack.
+	Next Exchanger
+
+	// Target is the writer to which each Recording is written, as a single
+	// line of JSON.
+	Target io.Writer
+
+	m   sync.Mutex
+	err error
+}
+
+var _ Exchanger = (*Recorder)(nil)
+
+// Call handles a call request and returns the response.
+//
+// A streamed result is read into memory so that it can be recorded.
+func (r *Recorder) Call(ctx context.Context, req Request) Response {
+	res := r.Next.Call(ctx, req)
+
+	if s, ok := res.(SuccessResponse); ok {
+		var err error
+		if res, err = s.BufferResult(); err != nil {
+			res = NewErrorResponse(req.ID, err)
+		}
+	}
+
+	r.record(Recording{
+		Request:  req,
+		Response: res,
+	})
+
+	return res
+}
+
+// Notify handles a notification request.
+func (r *Recorder) Notify(ctx context.Context, req Request) error {
+	err := r.Next.Notify(ctx, req)
+
+	r.record(Recording{
+		Request: req,
+	})
+
+	return err
+}
+
+// Err returns the first error that occurred while writing to r.Target, if
+// any.
+//
+// The responses are returned to the caller regardless of whether they can be
+// recorded.
+func (r *Recorder) Err() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.err
+}
+
+// record writes rec to r.Target.
+func (r *Recorder) record(rec Recording) {
+	data, err := json.Marshal(rec)
+	data = append(data, '\n')
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if err == nil {
+		_, err = r.Target.Write(data)
+	}
+
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// ReplayReader is an implementation of RequestSetReader that reproduces the
+// requests in a recording produced by a Recorder.
+//
+// Each call to Read() returns the next recorded request as a non-batch
+// request set.
+type ReplayReader struct {
+	// Source is the reader from which the recording is read.
+	Source io.Reader
+
+	buf     *bufio.Reader
+	current Recording
+}
+
+var _ RequestSetReader = (*ReplayReader)(nil)
+
+// Read reads the next RequestSet that is to be processed.
+//
+// It returns io.EOF when there are no more recorded requests.
+func (r *ReplayReader) Read(ctx context.Context) (RequestSet, error) {
+	if err := ctx.Err(); err != nil {
+		return RequestSet{}, err
+	}
+
+	rec, err := r.next()
+	if err != nil {
+		return RequestSet{}, err
+	}
+
+	r.current = rec
+
+	return RequestSet{
+		Requests: []Request{rec.Request},
+	}, nil
+}
+
+// Recording returns the recording of the request that was most recently
+// returned by Read(), including its recorded response.
+func (r *ReplayReader) Recording() Recording {
+	return r.current
+}
+
+// next reads the next recording from r.Source, skipping any blank lines.
+func (r *ReplayReader) next() (Recording, error) {
+	if r.buf == nil {
+		r.buf = bufio.NewReader(r.Source)
+	}
+
+	for {
+		line, err := r.buf.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return Recording{}, err
+		}
+
+		if len(bytes.TrimSpace(line)) != 0 {
+			var rec Recording
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return Recording{}, fmt.Errorf("unable to parse recording: %w", err)
+			}
+			return rec, nil
+		}
+
+		if err == io.EOF {
+			return Recording{}, io.EOF
+		}
+	}
+}
+
+// Replay sends each request in a recording produced by a Recorder to e.
+//
+// fn is called for each request with its recording and the response produced
+// by e, which is nil if the request is a notification. A streamed result is
+// read into memory before fn is called. If fn returns an error, replaying stops
+// and the error is returned.
+//
+// It returns nil once every request has been replayed.
+func Replay(
+	ctx context.Context,
+	e Exchanger,
+	source io.Reader,
+	fn func(rec Recording, res Response) error,
+) error {
+	r := &ReplayReader{Source: source}
+
+	for {
+		if _, err := r.Read(ctx); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		rec := r.Recording()
+		var res Response
+
+		if rec.Request.IsNotification() {
+			e.Notify(ctx, rec.Request) // nolint:errcheck // notifications have no response to compare
+		} else {
+			res = e.Call(ctx, rec.Request)
+
+			if s, ok := res.(SuccessResponse); ok {
+				var err error
+				if res, err = s.BufferResult(); err != nil {
+					res = NewErrorResponse(rec.Request.ID, err)
+				}
+			}
+		}
+
+		if err := fn(rec, res); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package harpy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type Recorder", func() {
+	var (
+		next     *ExchangerStub
+		buf      *bytes.Buffer
+		recorder *Recorder
+	)
+
+	BeforeEach(func() {
+		next = &ExchangerStub{
+			CallFunc: func(_ context.Context, req Request) Response {
+				if req.Method == "<error>" {
+					return NewErrorResponse(req.ID, NewError(123, WithMessage("<message>")))
+				}
+
+				if req.Method == "<stream>" {
+					return NewSuccessResponse(
+						req.ID,
+						StreamResult{Reader: bytes.NewReader(req.Parameters)},
+					)
+				}
+
+				return SuccessResponse{
+					Version:   "2.0",
+					RequestID: req.ID,
+					Result:    req.Parameters,
+				}
+			},
+		}
+
+		buf = &bytes.Buffer{}
+		recorder = &Recorder{
+			Next:   next,
+			Target: buf,
+		}
+	})
+
+	Describe("func Call()", func() {
+		It("records the request and its response", func() {
+			req := Request{
+				Version:    "2.0",
+				ID:         json.RawMessage(`1`),
+				Method:     "<method>",
+				Parameters: json.RawMessage(`[1,2,3]`),
+			}
+
+			res := recorder.Call(context.Background(), req)
+			Expect(res).To(Equal(SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`[1,2,3]`),
+			}))
+
+			Expect(buf.String()).To(HaveSuffix("\n"))
+			Expect(buf.String()).To(MatchJSON(`{
+				"request": {"jsonrpc": "2.0", "id": 1, "method": "<method>", "params": [1, 2, 3]},
+				"response": {"jsonrpc": "2.0", "id": 1, "result": [1, 2, 3]}
+			}`))
+		})
+
+		It("records error responses", func() {
+			recorder.Call(context.Background(), Request{
+				Version: "2.0",
+				ID:      json.RawMessage(`1`),
+				Method:  "<error>",
+			})
+
+			Expect(buf.String()).To(MatchJSON(`{
+				"request": {"jsonrpc": "2.0", "id": 1, "method": "<error>"},
+				"response": {"jsonrpc": "2.0", "id": 1, "error": {"code": 123, "message": "<message>"}}
+			}`))
+		})
+
+		It("buffers streamed results so that they can be recorded", func() {
+			res := recorder.Call(context.Background(), Request{
+				Version:    "2.0",
+				ID:         json.RawMessage(`1`),
+				Method:     "<stream>",
+				Parameters: json.RawMessage(`[1,2,3]`),
+			})
+
+			Expect(res).To(Equal(SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`[1,2,3]`),
+			}))
+
+			Expect(buf.String()).To(MatchJSON(`{
+				"request": {"jsonrpc": "2.0", "id": 1, "method": "<stream>", "params": [1, 2, 3]},
+				"response": {"jsonrpc": "2.0", "id": 1, "result": [1, 2, 3]}
+			}`))
+		})
+
+		It("returns the response even if it can not be recorded", func() {
+			recorder.Target = &failingWriter{}
+
+			res := recorder.Call(context.Background(), Request{
+				Version:    "2.0",
+				ID:         json.RawMessage(`1`),
+				Method:     "<method>",
+				Parameters: json.RawMessage(`[]`),
+			})
+			Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+			Expect(recorder.Err()).To(MatchError("<write error>"))
+		})
+	})
+
+	Describe("func Notify()", func() {
+		It("records the request without a response", func() {
+			next.NotifyFunc = func(context.Context, Request) error {
+				return errors.New("<error>")
+			}
+
+			err := recorder.Notify(context.Background(), Request{
+				Version: "2.0",
+				Method:  "<method>",
+			})
+			Expect(err).To(MatchError("<error>"))
+
+			Expect(buf.String()).To(MatchJSON(`{
+				"request": {"jsonrpc": "2.0", "method": "<method>"}
+			}`))
+		})
+	})
+
+	Describe("func Err()", func() {
+		It("returns nil if all recordings have been written", func() {
+			recorder.Notify(context.Background(), Request{Version: "2.0"})
+			Expect(recorder.Err()).ShouldNot(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("type Recording", func() {
+	DescribeTable(
+		"it can be marshaled and unmarshaled",
+		func(rec Recording) {
+			data, err := json.Marshal(rec)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			var unmarshaled Recording
+			err = json.Unmarshal(data, &unmarshaled)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(unmarshaled).To(Equal(rec))
+		},
+		Entry("success response", Recording{
+			Request: Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>"},
+			Response: SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`[1,2,3]`),
+			},
+		}),
+		Entry("error response", Recording{
+			Request: Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>"},
+			Response: ErrorResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Error: ErrorInfo{
+					Code:    123,
+					Message: "<message>",
+					Data:    json.RawMessage(`{"key":"value"}`),
+				},
+			},
+		}),
+		Entry("notification", Recording{
+			Request: Request{Version: "2.0", Method: "<method>", Parameters: json.RawMessage(`{}`)},
+		}),
+	)
+
+	It("can not be marshaled if the response has a streamed result", func() {
+		_, err := json.Marshal(Recording{
+			Request:  Request{Version: "2.0", ID: json.RawMessage(`1`)},
+			Response: NewSuccessResponse(json.RawMessage(`1`), StreamResult{Reader: strings.NewReader(`{}`)}),
+		})
+		Expect(err).To(MatchError(ContainSubstring("can not marshal a recording of a response with a streamed result")))
+	})
+
+	It("can not be unmarshaled if the response is a batch", func() {
+		var rec Recording
+		err := json.Unmarshal(
+			[]byte(`{"request": {"jsonrpc": "2.0", "id": 1}, "response": [{"jsonrpc": "2.0", "id": 1, "result": null}]}`),
+			&rec,
+		)
+		Expect(err).To(MatchError("recorded response must be a single response"))
+	})
+})
+
+var _ = Describe("type ReplayReader", func() {
+	It("returns each recorded request as a non-batch request set", func() {
+		reader := &ReplayReader{
+			Source: strings.NewReader(
+				`{"request": {"jsonrpc": "2.0", "id": 1, "method": "<first>"}, "response": {"jsonrpc": "2.0", "id": 1, "result": 1}}` + "\n" +
+					"\n" +
+					`{"request": {"jsonrpc": "2.0", "method": "<second>"}}`,
+			),
+		}
+
+		rs, err := reader.Read(context.Background())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs).To(Equal(RequestSet{
+			Requests: []Request{
+				{Version: "2.0", ID: json.RawMessage(`1`), Method: "<first>"},
+			},
+		}))
+		Expect(reader.Recording().Response).To(Equal(SuccessResponse{
+			Version:   "2.0",
+			RequestID: json.RawMessage(`1`),
+			Result:    json.RawMessage(`1`),
+		}))
+
+		rs, err = reader.Read(context.Background())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rs.Requests).To(Equal([]Request{
+			{Version: "2.0", Method: "<second>"},
+		}))
+		Expect(reader.Recording().Response).To(BeNil())
+
+		_, err = reader.Read(context.Background())
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("returns an error if a recording can not be parsed", func() {
+		reader := &ReplayReader{
+			Source: strings.NewReader("{\n"),
+		}
+
+		_, err := reader.Read(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("unable to parse recording")))
+	})
+
+	It("returns the context error if the context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		reader := &ReplayReader{
+			Source: strings.NewReader(""),
+		}
+
+		_, err := reader.Read(ctx)
+		Expect(err).To(Equal(context.Canceled))
+	})
+})
+
+var _ = Describe("func Replay()", func() {
+	var exchanger *ExchangerStub
+
+	BeforeEach(func() {
+		exchanger = &ExchangerStub{
+			CallFunc: func(_ context.Context, req Request) Response {
+				return NewSuccessResponse(
+					req.ID,
+					StreamResult{Reader: bytes.NewReader(req.Parameters)},
+				)
+			},
+		}
+	})
+
+	It("reproduces requests recorded by a Recorder", func() {
+		var recording bytes.Buffer
+		recorder := &Recorder{
+			Next:   exchanger,
+			Target: &recording,
+		}
+
+		recorder.Call(context.Background(), Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<call>", Parameters: json.RawMessage(`[1]`)})
+		recorder.Notify(context.Background(), Request{Version: "2.0", Method: "<notify>"})
+		Expect(recorder.Err()).ShouldNot(HaveOccurred())
+
+		var notified []string
+		exchanger.NotifyFunc = func(_ context.Context, req Request) error {
+			notified = append(notified, req.Method)
+			return nil
+		}
+
+		var replayed []Recording
+		err := Replay(
+			context.Background(),
+			exchanger,
+			&recording,
+			func(rec Recording, res Response) error {
+				if rec.Response == nil {
+					Expect(res).To(BeNil())
+				} else {
+					Expect(res).To(Equal(rec.Response))
+				}
+				replayed = append(replayed, rec)
+				return nil
+			},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(replayed).To(HaveLen(2))
+		Expect(notified).To(Equal([]string{"<notify>"}))
+	})
+
+	It("stops replaying if the function returns an error", func() {
+		recording := strings.NewReader(
+			`{"request": {"jsonrpc": "2.0", "id": 1, "params": [1]}}` + "\n" +
+				`{"request": {"jsonrpc": "2.0", "id": 2, "params": [2]}}` + "\n",
+		)
+
+		count := 0
+		err := Replay(
+			context.Background(),
+			exchanger,
+			recording,
+			func(Recording, Response) error {
+				count++
+				return errors.New("<error>")
+			},
+		)
+		Expect(err).To(MatchError("<error>"))
+		Expect(count).To(Equal(1))
+	})
+})
+
+// failingWriter is an io.Writer that always fails.
+type failingWriter struct{}
+
+func (*failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("<write error>")
+}
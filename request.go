@@ -3,9 +3,13 @@ package harpy
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
+	"slices"
+	"strings"
 	"unicode"
 
 	"github.com/dogmatiq/harpy/internal/jsonx"
@@ -64,19 +68,22 @@ type Request struct {
 
 // NewCallRequest returns a new JSON-RPC call request.
 //
+// The options control how the request ID and parameters are marshaled.
+//
 // The returned request is not necessarily valid; it should be validated by
 // calling Request.ValidateClientSide() before sending to a server.
 func NewCallRequest(
 	id any,
 	method string,
 	params any,
+	options ...EncoderOption,
 ) (Request, error) {
-	data, err := json.Marshal(id)
+	data, err := jsonx.Marshal(id, options...)
 	if err != nil {
 		return Request{}, fmt.Errorf("unable to marshal request ID: %w", err)
 	}
 
-	req, err := newRequest(data, method, params)
+	req, err := newRequest(data, method, params, options)
 	if err != nil {
 		return Request{}, err
 	}
@@ -88,13 +95,16 @@ func NewCallRequest(
 
 // NewNotifyRequest returns a new JSON-RPC notify request.
 //
+// The options control how the parameters are marshaled.
+//
 // The returned request is not necessarily valid; it should be validated by
 // calling Request.ValidateClientSide() before sending to a server.
 func NewNotifyRequest(
 	method string,
 	params any,
+	options ...EncoderOption,
 ) (Request, error) {
-	return newRequest(nil, method, params)
+	return newRequest(nil, method, params, options)
 }
 
 // newRequest returns a new JSON-RPC request.
@@ -105,8 +115,9 @@ func newRequest(
 	id json.RawMessage,
 	method string,
 	params any,
+	options []EncoderOption,
 ) (Request, error) {
-	data, err := json.Marshal(params)
+	data, err := jsonx.Marshal(params, options...)
 	if err != nil {
 		return Request{}, fmt.Errorf("unable to marshal request parameters: %w", err)
 	}
@@ -119,27 +130,48 @@ func newRequest(
 	}, nil
 }
 
+// Clone returns a deep copy of r.
+//
+// The ID and Parameters fields of a request may share their underlying memory
+// with other requests, such as those within the same batch. Middleware that
+// modifies the content of these fields in place must do so on a clone.
+func (r Request) Clone() Request {
+	r.ID = bytes.Clone(r.ID)
+	r.Parameters = bytes.Clone(r.Parameters)
+	return r
+}
+
+// IsReserved returns true if r is a request for a method that is reserved for
+// system extensions, that is, its method name begins with "rpc.".
+func (r Request) IsReserved() bool {
+	return strings.HasPrefix(r.Method, reservedMethodPrefix)
+}
+
 // IsNotification returns true if r is a notification, as opposed to an RPC call
 // that expects a response.
+//
+// A request with an explicit null ID is a call, not a notification. Its response
+// contains a null request ID.
 func (r Request) IsNotification() bool {
 	return r.ID == nil
 }
 
-// ValidateServerSide checks that the request conforms to the JSON-RPC
-// specification.
+// Validate checks that the request conforms to the JSON-RPC specification.
 //
-// If the request is invalid ok is false and err is a JSON-RPC error intended to
-// be sent to the caller in an ErrorResponse.
-func (r Request) ValidateServerSide() (err Error, ok bool) {
+// If the request is invalid ok is false and err describes the problem. side
+// determines whether err is a server-side error intended to be sent to the
+// caller in an ErrorResponse, or a client-side error that is the error a
+// server would return upon receiving the invalid request.
+func (r Request) Validate(side Side) (err Error, ok bool) {
 	if r.Version != jsonRPCVersion {
-		return NewErrorWithReservedCode(
+		return side.newError(
 			InvalidRequestCode,
 			WithMessage(`request version must be "2.0"`),
 		), false
 	}
 
 	if len(r.ID) != 0 {
-		if err, ok := validateRequestID(r.ID); !ok {
+		if err, ok := validateRequestID(r.ID, side); !ok {
 			return err, false
 		}
 	}
@@ -156,7 +188,7 @@ func (r Request) ValidateServerSide() (err Error, ok bool) {
 	}
 
 	if len(r.Parameters) < 2 || (r.Parameters[0] != '{' && r.Parameters[0] != '[') {
-		return NewErrorWithReservedCode(
+		return side.newError(
 			InvalidParametersCode,
 			WithMessage(`parameters must be an array, an object, or null`),
 		), false
@@ -165,19 +197,27 @@ func (r Request) ValidateServerSide() (err Error, ok bool) {
 	return Error{}, true
 }
 
+// ValidateServerSide checks that the request conforms to the JSON-RPC
+// specification.
+//
+// If the request is invalid ok is false and err is a JSON-RPC error intended to
+// be sent to the caller in an ErrorResponse.
+//
+// It is equivalent to r.Validate(ServerSide).
+func (r Request) ValidateServerSide() (err Error, ok bool) {
+	return r.Validate(ServerSide)
+}
+
 // ValidateClientSide checks that the request conforms to the JSON-RPC
 // specification.
 //
 // It is intended to be called before sending the request to a server; if the
 // request is invalid ok is false and err is the error that a server would
 // return upon receiving the invalid request.
+//
+// It is equivalent to r.Validate(ClientSide).
 func (r Request) ValidateClientSide() (err Error, ok bool) {
-	if err, ok := r.ValidateServerSide(); !ok {
-		err.isServerSide = false
-		return err, false
-	}
-
-	return Error{}, true
+	return r.Validate(ClientSide)
 }
 
 // UnmarshalParameters is a convenience method for unmarshaling request
@@ -189,8 +229,14 @@ func (r Request) ValidateClientSide() (err Error, ok bool) {
 // If v implements the Validatable interface, it calls v.Validate() after
 // unmarshaling successfully. If validation fails it wraps the validation error
 // in the appropriate native JSON-RPC error.
+//
+// If the request has no parameters, a struct is unmarshaled as though the
+// parameters were an empty JSON object, such that any required fields are
+// still enforced, and any other type is set to its zero value. For example, a
+// slice is set to nil. Use the RequireParameters() route option to reject
+// requests without parameters instead.
 func (r Request) UnmarshalParameters(v any, options ...UnmarshalOption) error {
-	if err := jsonx.Unmarshal(r.Parameters, v, options...); err != nil {
+	if err := r.unmarshalParameters(v, options); err != nil {
 		return InvalidParameters(
 			WithCause(err),
 		)
@@ -207,14 +253,34 @@ func (r Request) UnmarshalParameters(v any, options ...UnmarshalOption) error {
 	return nil
 }
 
+// unmarshalParameters unmarshals the request parameters into v.
+func (r Request) unmarshalParameters(v any, options []UnmarshalOption) error {
+	options = withDefaultMaxNestingDepth(options)
+
+	if len(r.Parameters) != 0 {
+		return jsonx.Unmarshal(r.Parameters, v, options...)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct {
+		rv.Elem().SetZero()
+		return nil
+	}
+
+	// Structs are unmarshaled from an empty object so that required fields
+	// are enforced. Invalid targets are reported by the JSON decoder.
+	return jsonx.Unmarshal([]byte(`{}`), v, options...)
+}
+
 // validateRequestID checks that id is a valid request ID according to the
 // JSON-RPC specification.
 //
-// It returns true if the response is valid.
-func validateRequestID(id json.RawMessage) (Error, bool) {
+// It returns true if the response is valid. Otherwise, it returns an error
+// produced on the given side.
+func validateRequestID(id json.RawMessage, side Side) (Error, bool) {
 	var value any
 	if err := json.Unmarshal(id, &value); err != nil {
-		return NewErrorWithReservedCode(
+		return side.newError(
 			ParseErrorCode,
 			WithCause(err),
 		), false
@@ -224,7 +290,7 @@ func validateRequestID(id json.RawMessage) (Error, bool) {
 		return Error{}, true
 	}
 
-	return NewErrorWithReservedCode(
+	return side.newError(
 		InvalidRequestCode,
 		WithMessage(`request ID must be a JSON string, number or null`),
 	), false
@@ -257,6 +323,24 @@ type RequestSet struct {
 	IsBatch bool
 }
 
+// NewRequestSet returns a request set containing the given requests.
+//
+// If exactly one request is given the set is not a batch, otherwise it is a
+// batch. It returns an error if the request set is invalid, as per
+// RequestSet.ValidateServerSide().
+func NewRequestSet(requests ...Request) (RequestSet, error) {
+	rs := RequestSet{
+		Requests: requests,
+		IsBatch:  len(requests) != 1,
+	}
+
+	if err, ok := rs.ValidateServerSide(); !ok {
+		return RequestSet{}, err
+	}
+
+	return rs, nil
+}
+
 // UnmarshalRequestSet unmarshals a JSON-RPC request or request batch from r.
 //
 // If there is a problem parsing the request or the request is malformed, an
@@ -264,7 +348,26 @@ type RequestSet struct {
 //
 // On success it returns a request set containing well-formed (but not
 // necessarily valid) requests.
-func UnmarshalRequestSet(r io.Reader) (RequestSet, error) {
+//
+// The options control how the content is parsed. For example,
+// MaxRequestSetNestingDepth() may be used to change the maximum nesting depth
+// of the request set, including the parameters of each request. Content that
+// is nested too deeply results in an Error with the "parse error" code.
+//
+// The request set is decoded using the codec specified by DecodeWith(), or as
+// JSON by default.
+func UnmarshalRequestSet(r io.Reader, options ...RequestSetOption) (RequestSet, error) {
+	opts := newRequestSetOptions(options)
+
+	if !isJSONCodec(opts.Codec) {
+		data, err := transcodeToJSON(r, opts.Codec)
+		if err != nil {
+			return RequestSet{}, requestCodecError(err)
+		}
+
+		return unmarshalRequestSetJSON(data, opts)
+	}
+
 	br := bufio.NewReader(r)
 
 	for {
@@ -282,35 +385,193 @@ func UnmarshalRequestSet(r io.Reader) (RequestSet, error) {
 		}
 
 		if ch == '[' {
-			return unmarshalBatchRequest(br)
+			return unmarshalBatchRequest(br, opts)
 		}
 
-		return unmarshalSingleRequest(br)
+		return unmarshalSingleRequest(br, opts)
 	}
 }
 
-// ValidateServerSide checks that the request set is valid and that the requests
-// within conform to the JSON-RPC specification.
+// UnmarshalRequestSetBytes unmarshals a JSON-RPC request or request batch from
+// data.
 //
-// If the request set is invalid ok is false and err is a JSON-RPC error
-// intended to be sent to the caller in an ErrorResponse.
-func (rs RequestSet) ValidateServerSide() (err Error, ok bool) {
+// It is equivalent to UnmarshalRequestSet(), but is more efficient when the
+// request set is already held in memory, such as after an HTTP request body
+// has been read in full. The leading whitespace is skipped without decoding
+// each rune and there is no need to allocate a buffered reader.
+//
+// If data is empty, or contains only whitespace when using the default JSON
+// codec, io.EOF is returned.
+func UnmarshalRequestSetBytes(data []byte, options ...RequestSetOption) (RequestSet, error) {
+	opts := newRequestSetOptions(options)
+
+	if !isJSONCodec(opts.Codec) {
+		raw, err := transcodeBytesToJSON(data, opts.Codec)
+		if err != nil {
+			return RequestSet{}, requestCodecError(err)
+		}
+
+		data = raw
+	}
+
+	return unmarshalRequestSetJSON(data, opts)
+}
+
+// RequestSetOption is an option that changes the behavior of
+// UnmarshalRequestSet() and UnmarshalRequestSetBytes().
+type RequestSetOption func(*requestSetOptions)
+
+// requestSetOptions is a set of options that control how request sets are
+// unmarshaled.
+type requestSetOptions struct {
+	Codec                Codec
+	DisallowTrailingData bool
+	AcceptedVersions     []string
+	MaxNestingDepth      int
+	MaxFrameSize         int64
+}
+
+// newRequestSetOptions returns the result of applying the given options.
+func newRequestSetOptions(options []RequestSetOption) requestSetOptions {
+	var opts requestSetOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return opts
+}
+
+// MaxRequestSetNestingDepth is a RequestSetOption that sets the maximum depth
+// to which JSON arrays and objects may be nested within a request set,
+// including the parameters of each request.
+//
+// Content that is nested more deeply is rejected before it is decoded,
+// preventing maliciously crafted input from exhausting resources. Exceeding
+// the limit results in a JSON-RPC "parse error".
+//
+// The default limit is 64. It panics if n is not positive.
+func MaxRequestSetNestingDepth(n int) RequestSetOption {
+	if n <= 0 {
+		panic("the maximum nesting depth must be positive")
+	}
+
+	return func(opts *requestSetOptions) {
+		opts.MaxNestingDepth = n
+	}
+}
+
+// DisallowTrailingData is a RequestSetOption that controls whether request
+// sets that are followed by data other than whitespace are rejected.
+//
+// When enabled, content such as two consecutive JSON objects results in a
+// JSON-RPC "parse error", rather than the trailing data being ignored. This
+// prevents ambiguity about which content was actually processed.
+//
+// Trailing data is permitted by default.
+func DisallowTrailingData(disallow bool) RequestSetOption {
+	return func(opts *requestSetOptions) {
+		opts.DisallowTrailingData = disallow
+	}
+}
+
+// AcceptVersions is a RequestSetOption that causes requests that specify any
+// of the given values in their "jsonrpc" field to be accepted, in addition to
+// "2.0".
+//
+// The version of each such request is replaced with "2.0", such that it passes
+// validation and is handled as any other request. Responses always specify
+// "2.0". An empty string accepts requests that omit the "jsonrpc" field
+// altogether.
+//
+// WARNING: The JSON-RPC 2.0 specification requires the version to be exactly
+// "2.0". Accepting other versions deviates from the specification. It is
+// intended only to support non-conformant clients, such as during a migration.
+//
+// By default only "2.0" is accepted.
+func AcceptVersions(versions ...string) RequestSetOption {
+	return func(opts *requestSetOptions) {
+		opts.AcceptedVersions = versions
+	}
+}
+
+// unmarshalRequestSetJSON unmarshals a JSON-RPC request or request batch from
+// JSON content in data.
+func unmarshalRequestSetJSON(data []byte, opts requestSetOptions) (RequestSet, error) {
+	data = bytes.TrimLeftFunc(data, unicode.IsSpace)
+
+	if len(data) == 0 {
+		return RequestSet{}, io.EOF
+	}
+
+	if data[0] == '[' {
+		return unmarshalBatchRequest(bytes.NewReader(data), opts)
+	}
+
+	return unmarshalSingleRequest(bytes.NewReader(data), opts)
+}
+
+// ParseRequestSetBytes unmarshals a JSON-RPC request or request batch from
+// data.
+//
+// Unlike UnmarshalRequestSetBytes(), any non-nil error is an Error with the
+// "parse error" code, including when data is empty. It never panics, even if
+// data is arbitrary input from an untrusted source.
+//
+// On success it returns a request set containing well-formed (but not
+// necessarily valid) requests.
+func ParseRequestSetBytes(data []byte) (rs RequestSet, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			rs = RequestSet{}
+			err = NewErrorWithReservedCode(
+				ParseErrorCode,
+				WithCause(fmt.Errorf("unable to parse request: %v", v)),
+			)
+		}
+	}()
+
+	rs, err = UnmarshalRequestSetBytes(data)
+	if err == nil {
+		return rs, nil
+	}
+
+	if _, ok := err.(Error); ok {
+		return RequestSet{}, err
+	}
+
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return RequestSet{}, NewErrorWithReservedCode(
+		ParseErrorCode,
+		WithCause(fmt.Errorf("unable to parse request: %w", err)),
+	)
+}
+
+// Validate checks that the request set is valid and that the requests within
+// conform to the JSON-RPC specification.
+//
+// If the request set is invalid ok is false and err describes the problem.
+// side determines whether err is a server-side error intended to be sent to
+// the caller in an ErrorResponse, or a client-side error that is the error a
+// server would return upon receiving the invalid request set.
+func (rs RequestSet) Validate(side Side) (err Error, ok bool) {
 	if rs.IsBatch {
 		if len(rs.Requests) == 0 {
-			return NewErrorWithReservedCode(
+			return side.newError(
 				InvalidRequestCode,
 				WithMessage("batches must contain at least one request"),
 			), false
 		}
 	} else if len(rs.Requests) != 1 {
-		return NewErrorWithReservedCode(
+		return side.newError(
 			InvalidRequestCode,
 			WithMessage("non-batch request sets must contain exactly one request"),
 		), false
 	}
 
 	for _, req := range rs.Requests {
-		if err, ok := req.ValidateServerSide(); !ok {
+		if err, ok := req.Validate(side); !ok {
 			return err, false
 		}
 	}
@@ -318,53 +579,101 @@ func (rs RequestSet) ValidateServerSide() (err Error, ok bool) {
 	return Error{}, true
 }
 
+// ValidateServerSide checks that the request set is valid and that the requests
+// within conform to the JSON-RPC specification.
+//
+// If the request set is invalid ok is false and err is a JSON-RPC error
+// intended to be sent to the caller in an ErrorResponse.
+//
+// It is equivalent to rs.Validate(ServerSide).
+func (rs RequestSet) ValidateServerSide() (err Error, ok bool) {
+	return rs.Validate(ServerSide)
+}
+
 // ValidateClientSide checks that the request set is valid and that the requests
 // within conform to the JSON-RPC specification.
 //
 // It is intended to be called before sending the request set to a server; if
 // the request is invalid ok is false and err is the error that a server would
 // return upon receiving the invalid request set.
+//
+// It is equivalent to rs.Validate(ClientSide).
 func (rs RequestSet) ValidateClientSide() (err Error, ok bool) {
-	if err, ok := rs.ValidateServerSide(); !ok {
-		err.isServerSide = false
-		return err, false
-	}
-
-	return Error{}, true
+	return rs.Validate(ClientSide)
 }
 
 // unmarshalSingleRequest unmarshals a non-batch JSON-RPC request set.
-func unmarshalSingleRequest(r *bufio.Reader) (RequestSet, error) {
+func unmarshalSingleRequest(r io.Reader, opts requestSetOptions) (RequestSet, error) {
 	var req Request
 
-	if err := unmarshalJSONForRequest(r, &req); err != nil {
+	if err := unmarshalJSONForRequest(r, &req, opts); err != nil {
 		return RequestSet{}, err
 	}
 
-	return RequestSet{
+	rs := RequestSet{
 		Requests: []Request{req},
 		IsBatch:  false,
-	}, nil
+	}
+	normalizeVersions(rs, opts.AcceptedVersions)
+
+	return rs, nil
 }
 
 // unmarshalBatchRequest unmarshals a batched JSON-RPC request set.
-func unmarshalBatchRequest(r *bufio.Reader) (RequestSet, error) {
+func unmarshalBatchRequest(r io.Reader, opts requestSetOptions) (RequestSet, error) {
 	var batch []Request
 
-	if err := unmarshalJSONForRequest(r, &batch); err != nil {
+	if err := unmarshalJSONForRequest(r, &batch, opts); err != nil {
 		return RequestSet{}, err
 	}
 
-	return RequestSet{
+	rs := RequestSet{
 		Requests: batch,
 		IsBatch:  true,
-	}, nil
+	}
+	normalizeVersions(rs, opts.AcceptedVersions)
+
+	return rs, nil
+}
+
+// normalizeVersions replaces the version of each request in rs with "2.0" if
+// it is one of the versions accepted by the AcceptVersions() option.
+func normalizeVersions(rs RequestSet, accepted []string) {
+	if len(accepted) == 0 {
+		return
+	}
+
+	for i, req := range rs.Requests {
+		if slices.Contains(accepted, req.Version) {
+			rs.Requests[i].Version = jsonRPCVersion
+		}
+	}
+}
+
+// requestCodecError returns the error to return when a request set can not be
+// decoded by a non-JSON codec.
+func requestCodecError(err error) error {
+	if _, ok := err.(codecError); ok {
+		return NewErrorWithReservedCode(
+			ParseErrorCode,
+			WithCause(fmt.Errorf("unable to parse request: %w", err)),
+		)
+	}
+
+	return err
 }
 
 // unmarshalJSONForRequest unmarshals JSON content from r into v. If the JSON
 // cannot be parsed it returns a JSON-RPC error with the "parse error" code.
-func unmarshalJSONForRequest(r io.Reader, v any) error {
-	err := jsonx.Decode(r, v)
+func unmarshalJSONForRequest(r io.Reader, v any, opts requestSetOptions) error {
+	err := jsonx.Decode(r, v, func(o *jsonx.UnmarshalOptions) {
+		o.DisallowTrailingData = opts.DisallowTrailingData
+		o.MaxDepth = opts.MaxNestingDepth
+
+		if o.MaxDepth == 0 {
+			o.MaxDepth = jsonx.DefaultMaxDepth
+		}
+	})
 
 	if jsonx.IsParseError(err) {
 		return NewErrorWithReservedCode(
@@ -423,11 +732,35 @@ func (m *BatchRequestMarshaler) MarshalRequest(req Request) error {
 	return m.encoder.Encode(req)
 }
 
+// MarshalRequestContext marshals the next JSON-RPC request in the batch to
+// m.Writer, unless ctx is already done.
+//
+// If ctx is done it returns ctx.Err() without writing anything. This allows
+// a caller that produces a large batch to stop promptly when ctx is canceled.
+//
+// It panics if the marshaler is already closed.
+func (m *BatchRequestMarshaler) MarshalRequestContext(ctx context.Context, req Request) error {
+	if m.closed {
+		panic("marshaler has been closed")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return m.MarshalRequest(req)
+}
+
 // Close finishes writing the batch to m.Writer.
 //
 // If no requests have been marshaled, Close() is a no-op. This means that no
-// data will have been written to m.Target at all.
+// data will have been written to m.Target at all. It is also a no-op if the
+// marshaler has already been closed or aborted.
 func (m *BatchRequestMarshaler) Close() error {
+	if m.closed {
+		return nil
+	}
+
 	m.closed = true
 
 	if m.encoder == nil {
@@ -440,3 +773,16 @@ func (m *BatchRequestMarshaler) Close() error {
 
 	return nil
 }
+
+// Abort stops marshaling the batch without finishing it, such as when an error
+// occurs part-way through the batch.
+//
+// Unlike Close(), it does not write the closing bracket of the batch. If any
+// requests have been marshaled, m.Target contains an incomplete JSON array; the
+// caller is responsible for discarding it.
+//
+// Once aborted, the marshaler is considered closed. Any subsequent call to
+// Close() is a no-op.
+func (m *BatchRequestMarshaler) Abort() {
+	m.closed = true
+}
@@ -0,0 +1,31 @@
+package harpy_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/dogmatiq/harpy"
+)
+
+// benchmarkRequest is a typical single (non-batch) JSON-RPC request.
+var benchmarkRequest = []byte(`{"jsonrpc":"2.0","id":123,"method":"<method>","params":{"name":"<name>","values":[1,2,3]}}`)
+
+func BenchmarkUnmarshalRequestSet(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := UnmarshalRequestSet(bytes.NewReader(benchmarkRequest)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalRequestSetBytes(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := UnmarshalRequestSetBytes(benchmarkRequest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
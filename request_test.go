@@ -2,6 +2,7 @@ package harpy_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -48,6 +49,33 @@ var _ = Describe("type Request", func() {
 			Entry("number", 123, json.RawMessage(`123`)),
 		)
 
+		It("escapes HTML characters in the ID and parameters by default", func() {
+			req, err := NewCallRequest(
+				"<id>",
+				"<method>",
+				[]string{"<b>&</b>"},
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(req.ID).To(Equal(json.RawMessage(`"\u003cid\u003e"`)))
+			Expect(req.Parameters).To(Equal(json.RawMessage(`["\u003cb\u003e\u0026\u003c/b\u003e"]`)))
+		})
+
+		It("does not escape HTML characters when HTML escaping is disabled", func() {
+			req, err := NewCallRequest(
+				"<id>",
+				"<method>",
+				[]string{"<b>&</b>"},
+				EscapeHTML(false),
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(req).To(Equal(Request{
+				Version:    "2.0",
+				ID:         json.RawMessage(`"<id>"`),
+				Method:     "<method>",
+				Parameters: json.RawMessage(`["<b>&</b>"]`),
+			}))
+		})
+
 		It("returns an error if the ID cannot be marshaled", func() {
 			_, err := NewCallRequest(
 				make(chan struct{}),
@@ -81,6 +109,16 @@ var _ = Describe("type Request", func() {
 			}))
 		})
 
+		It("does not escape HTML characters when HTML escaping is disabled", func() {
+			req, err := NewNotifyRequest(
+				"<method>",
+				[]string{"<b>&</b>"},
+				EscapeHTML(false),
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(req.Parameters).To(Equal(json.RawMessage(`["<b>&</b>"]`)))
+		})
+
 		It("returns an error if the parameters cannot be marshaled", func() {
 			_, err := NewNotifyRequest(
 				"<method>",
@@ -90,6 +128,52 @@ var _ = Describe("type Request", func() {
 		})
 	})
 
+	Describe("func Clone()", func() {
+		It("returns a request that does not share memory with the original", func() {
+			req := Request{
+				Version:    "2.0",
+				ID:         json.RawMessage(`123`),
+				Method:     "<method>",
+				Parameters: json.RawMessage(`[1, 2, 3]`),
+			}
+
+			clone := req.Clone()
+			Expect(clone).To(Equal(req))
+
+			clone.ID[0] = '4'
+			clone.Parameters[1] = '9'
+
+			Expect(req.ID).To(Equal(json.RawMessage(`123`)))
+			Expect(req.Parameters).To(Equal(json.RawMessage(`[1, 2, 3]`)))
+		})
+
+		It("preserves the distinction between a nil and an empty ID", func() {
+			req := Request{Version: "2.0"}
+			Expect(req.Clone().IsNotification()).To(BeTrue())
+
+			req.ID = json.RawMessage{}
+			Expect(req.Clone().IsNotification()).To(BeFalse())
+		})
+	})
+
+	Describe("func IsReserved()", func() {
+		It("returns true when the method name begins with 'rpc.'", func() {
+			req := Request{Version: "2.0", Method: "rpc.discover"}
+			Expect(req.IsReserved()).To(BeTrue())
+		})
+
+		It("returns false when the method name does not begin with 'rpc.'", func() {
+			req := Request{Version: "2.0", Method: "<method>"}
+			Expect(req.IsReserved()).To(BeFalse())
+
+			req.Method = "rpcdiscover"
+			Expect(req.IsReserved()).To(BeFalse())
+
+			req.Method = "RPC.discover"
+			Expect(req.IsReserved()).To(BeFalse())
+		})
+	})
+
 	Describe("func IsNotification()", func() {
 		It("returns false when a request ID is present", func() {
 			req := Request{
@@ -406,6 +490,121 @@ var _ = Describe("type Request", func() {
 			Expect(rpcErr.Code()).To(Equal(InvalidParametersCode))
 		})
 
+		It("returns an error if the parameters are nested too deeply", func() {
+			req := Request{
+				Version:    "2.0",
+				Parameters: []byte(strings.Repeat("[", 65) + strings.Repeat("]", 65)),
+			}
+
+			var params any
+			err := req.UnmarshalParameters(&params)
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(InvalidParametersCode))
+			Expect(rpcErr.Unwrap()).To(MatchError("json: exceeded maximum nesting depth of 64"))
+		})
+
+		It("supports the MaxNestingDepth() option", func() {
+			req := Request{
+				Version:    "2.0",
+				Parameters: []byte(`[[[1]]]`),
+			}
+
+			var params any
+			err := req.UnmarshalParameters(&params, MaxNestingDepth(3))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = req.UnmarshalParameters(&params, MaxNestingDepth(2))
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(InvalidParametersCode))
+			Expect(rpcErr.Unwrap()).To(MatchError("json: exceeded maximum nesting depth of 2"))
+		})
+
+		It("does not count brackets within strings towards the nesting depth", func() {
+			req := Request{
+				Version:    "2.0",
+				Parameters: []byte(`["[[[\\\"{{{"]`),
+			}
+
+			var params []string
+			err := req.UnmarshalParameters(&params, MaxNestingDepth(1))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(params).To(Equal([]string{`[[[\"{{{`}))
+		})
+
+		When("the request has no parameters", func() {
+			It("unmarshals a struct as though the parameters were an empty object", func() {
+				req := Request{
+					Version: "2.0",
+				}
+
+				params := struct {
+					Value int
+				}{123}
+				err := req.UnmarshalParameters(&params)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(params.Value).To(Equal(123))
+			})
+
+			It("enforces required fields when unmarshaling a struct", func() {
+				req := Request{
+					Version: "2.0",
+				}
+
+				var params struct {
+					Value int `json:"value" jsonrpc:"required"`
+				}
+				err := req.UnmarshalParameters(&params, EnforceRequiredFields(true))
+
+				var rpcErr Error
+				ok := errors.As(err, &rpcErr)
+				Expect(ok).To(BeTrue())
+				Expect(rpcErr.Code()).To(Equal(InvalidParametersCode))
+				Expect(rpcErr.Message()).To(Equal("missing required fields: value"))
+			})
+
+			DescribeTable(
+				"it sets other types to their zero value",
+				func(v, expect any) {
+					req := Request{
+						Version:    "2.0",
+						Parameters: json.RawMessage{},
+					}
+
+					err := req.UnmarshalParameters(v)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(v).To(Equal(expect))
+				},
+				Entry("slice", &[]int{1, 2, 3}, new([]int)),
+				Entry("map", &map[string]int{"a": 1}, new(map[string]int)),
+				Entry("interface", func() *any { var v any = 123; return &v }(), new(any)),
+			)
+
+			It("calls Validate() on the target", func() {
+				req := Request{
+					Version: "2.0",
+				}
+
+				params := validatableStub{
+					ValidateFunc: func() error {
+						return errors.New("<error>")
+					},
+				}
+				err := req.UnmarshalParameters(&params)
+
+				var rpcErr Error
+				ok := errors.As(err, &rpcErr)
+				Expect(ok).To(BeTrue())
+				Expect(rpcErr.Code()).To(Equal(InvalidParametersCode))
+				Expect(rpcErr.Unwrap()).To(MatchError("<error>"))
+			})
+		})
+
 		When("the target type implements the Validatable interface", func() {
 			It("returns nil if validation succeeds", func() {
 				req := Request{
@@ -470,6 +669,163 @@ func (p validatableStub) Validate() error {
 }
 
 var _ = Describe("type RequestSet", func() {
+	Describe("func NewRequestSet()", func() {
+		It("returns a non-batch request set if there is a single request", func() {
+			req := Request{
+				Version: "2.0",
+				ID:      json.RawMessage(`123`),
+				Method:  "<method>",
+			}
+
+			rs, err := NewRequestSet(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs).To(Equal(RequestSet{
+				Requests: []Request{req},
+				IsBatch:  false,
+			}))
+		})
+
+		It("returns a batch request set if there are multiple requests", func() {
+			req1 := Request{
+				Version: "2.0",
+				ID:      json.RawMessage(`123`),
+				Method:  "<method>",
+			}
+
+			req2 := Request{
+				Version: "2.0",
+				Method:  "<method>",
+			}
+
+			rs, err := NewRequestSet(req1, req2)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs).To(Equal(RequestSet{
+				Requests: []Request{req1, req2},
+				IsBatch:  true,
+			}))
+		})
+
+		It("returns an error if any of the requests is invalid", func() {
+			_, err := NewRequestSet(
+				Request{
+					Version: "1.0",
+					Method:  "<method>",
+				},
+			)
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(InvalidRequestCode))
+		})
+	})
+
+	Describe("func ParseRequestSetBytes()", func() {
+		It("parses a request", func() {
+			rs, err := ParseRequestSetBytes([]byte(`{"jsonrpc": "2.0", "id": 123, "method": "<method>"}`))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs).To(Equal(RequestSet{
+				Requests: []Request{
+					{
+						Version: "2.0",
+						ID:      json.RawMessage(`123`),
+						Method:  "<method>",
+					},
+				},
+				IsBatch: false,
+			}))
+		})
+
+		DescribeTable(
+			"it returns a parse error if the data can not be parsed",
+			func(data string) {
+				_, err := ParseRequestSetBytes([]byte(data))
+
+				var rpcErr Error
+				ok := errors.As(err, &rpcErr)
+				Expect(ok).To(BeTrue())
+				Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+			},
+			Entry("empty", ``),
+			Entry("whitespace", `   `),
+			Entry("invalid syntax", `}`),
+			Entry("truncated", `[{"jsonrpc": "2.0"`),
+			Entry("deeply nested", strings.Repeat(`[`, 100000)),
+		)
+	})
+
+	Describe("func UnmarshalRequestSetBytes()", func() {
+		It("parses a single request", func() {
+			rs, err := UnmarshalRequestSetBytes([]byte(` {"jsonrpc": "2.0", "id": 123, "method": "<method>", "params": [1, 2, 3]}`))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs).To(Equal(RequestSet{
+				Requests: []Request{
+					{
+						Version:    "2.0",
+						ID:         json.RawMessage(`123`),
+						Method:     "<method>",
+						Parameters: json.RawMessage(`[1, 2, 3]`),
+					},
+				},
+				IsBatch: false,
+			}))
+		})
+
+		It("parses a batch request", func() {
+			rs, err := UnmarshalRequestSetBytes([]byte("\n\t[{\"jsonrpc\": \"2.0\", \"id\": 123, \"method\": \"<method>\"}]"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs).To(Equal(RequestSet{
+				Requests: []Request{
+					{
+						Version: "2.0",
+						ID:      json.RawMessage(`123`),
+						Method:  "<method>",
+					},
+				},
+				IsBatch: true,
+			}))
+		})
+
+		It("returns io.EOF if the data contains only whitespace", func() {
+			_, err := UnmarshalRequestSetBytes([]byte(" \r\n"))
+			Expect(err).To(Equal(io.EOF))
+		})
+
+		It("returns an error if the request is malformed", func() {
+			_, err := UnmarshalRequestSetBytes([]byte(`{"jsonrpc": "2.0", "unknown": 1}`))
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+			Expect(rpcErr.Unwrap()).To(MatchError(`unable to parse request: json: unknown field "unknown"`))
+		})
+
+		It("supports the DisallowTrailingData() option", func() {
+			_, err := UnmarshalRequestSetBytes(
+				[]byte(`[{"jsonrpc":"2.0","id":1,"method":"<method>"}] []`),
+				DisallowTrailingData(true),
+			)
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+		})
+
+		It("supports the MaxRequestSetNestingDepth() option", func() {
+			_, err := UnmarshalRequestSetBytes(
+				[]byte(`{"jsonrpc": "2.0", "params": [[1]]}`),
+				MaxRequestSetNestingDepth(2),
+			)
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+		})
+	})
+
 	Describe("func UnmarshalRequestSet()", func() {
 		It("parses a single request", func() {
 			r := strings.NewReader(`{
@@ -600,6 +956,90 @@ var _ = Describe("type RequestSet", func() {
 			Expect(rpcErr.Unwrap()).To(MatchError("unable to parse request: json: cannot unmarshal string into Go value of type harpy.Request"))
 		})
 
+		It("returns an error if the request is nested too deeply", func() {
+			r := strings.NewReader(
+				`{"jsonrpc":"2.0","id":1,"method":"<method>","params":` +
+					strings.Repeat("[", 64) + strings.Repeat("]", 64) +
+					`}`,
+			)
+
+			_, err := UnmarshalRequestSet(r)
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+			Expect(rpcErr.Unwrap()).To(MatchError("unable to parse request: json: exceeded maximum nesting depth of 64"))
+		})
+
+		It("supports the MaxRequestSetNestingDepth() option", func() {
+			r := strings.NewReader(`[{"jsonrpc":"2.0","id":1,"method":"<method>","params":[[1]]}]`)
+
+			_, err := UnmarshalRequestSet(r, MaxRequestSetNestingDepth(3))
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+			Expect(rpcErr.Unwrap()).To(MatchError("unable to parse request: json: exceeded maximum nesting depth of 3"))
+		})
+
+		It("supports the DisallowTrailingData() option", func() {
+			r := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"<method>"}{"jsonrpc":"2.0","id":2,"method":"<method>"}`)
+
+			_, err := UnmarshalRequestSet(r, DisallowTrailingData(true))
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(ParseErrorCode))
+			Expect(rpcErr.Unwrap()).To(MatchError("unable to parse request: json: invalid data after top-level value"))
+		})
+
+		It("allows trailing whitespace when trailing data is disallowed", func() {
+			r := strings.NewReader("[{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"<method>\"}] \n")
+
+			rs, err := UnmarshalRequestSet(r, DisallowTrailingData(true))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs.Requests).To(HaveLen(1))
+		})
+
+		It("ignores trailing data by default", func() {
+			r := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"<method>"} <garbage>`)
+
+			rs, err := UnmarshalRequestSet(r)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs.Requests).To(HaveLen(1))
+		})
+
+		It("supports the AcceptVersions() option", func() {
+			r := strings.NewReader(`[
+				{"jsonrpc":"2","id":1,"method":"<method>"},
+				{"id":2,"method":"<method>"},
+				{"jsonrpc":"2.0","id":3,"method":"<method>"},
+				{"jsonrpc":"1.0","id":4,"method":"<method>"}
+			]`)
+
+			rs, err := UnmarshalRequestSet(r, AcceptVersions("2", ""))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs.Requests).To(HaveLen(4))
+			Expect(rs.Requests[0].Version).To(Equal("2.0"))
+			Expect(rs.Requests[1].Version).To(Equal("2.0"))
+			Expect(rs.Requests[2].Version).To(Equal("2.0"))
+			Expect(rs.Requests[3].Version).To(Equal("1.0"))
+		})
+
+		It("does not accept other versions by default", func() {
+			r := strings.NewReader(`{"jsonrpc":"2","id":1,"method":"<method>"}`)
+
+			rs, err := UnmarshalRequestSet(r)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs.Requests[0].Version).To(Equal("2"))
+
+			_, ok := rs.ValidateServerSide()
+			Expect(ok).To(BeFalse())
+		})
+
 		It("returns an error if a request within a batch is malformed", func() {
 			r := strings.NewReader(`[""]`) // not an array or object
 
@@ -788,6 +1228,83 @@ var _ = Describe("type RequestSet", func() {
 			Expect(ok).To(BeFalse())
 		})
 	})
+
+	Describe("func Validate()", func() {
+		DescribeTable(
+			"it returns a server-side error if a request within a batch is invalid",
+			func(req Request, code ErrorCode, message string) {
+				rs := RequestSet{
+					Requests: []Request{
+						{Version: "2.0"},
+						req,
+					},
+					IsBatch: true,
+				}
+
+				err, ok := rs.Validate(ServerSide)
+				Expect(err).To(Equal(
+					NewErrorWithReservedCode(
+						code,
+						WithMessage(message),
+					),
+				))
+				Expect(ok).To(BeFalse())
+
+				// Server-side errors are sent to the caller verbatim.
+				res := NewErrorResponse(nil, err)
+				Expect(res.Error.Code).To(Equal(code))
+			},
+			validateBatchEntries...,
+		)
+
+		DescribeTable(
+			"it returns a client-side error if a request within a batch is invalid",
+			func(req Request, code ErrorCode, message string) {
+				rs := RequestSet{
+					Requests: []Request{
+						{Version: "2.0"},
+						req,
+					},
+					IsBatch: true,
+				}
+
+				err, ok := rs.Validate(ClientSide)
+				Expect(err).To(Equal(
+					NewClientSideError(
+						code,
+						message,
+						nil,
+					),
+				))
+				Expect(ok).To(BeFalse())
+
+				// Client-side errors are not exposed to the caller.
+				res := NewErrorResponse(nil, err)
+				Expect(res.Error.Code).To(Equal(InternalErrorCode))
+			},
+			validateBatchEntries...,
+		)
+
+		It("returns an error on the given side if a request ID within a batch is malformed", func() {
+			rs := RequestSet{
+				Requests: []Request{
+					{Version: "2.0"},
+					{Version: "2.0", ID: json.RawMessage(`{`)},
+				},
+				IsBatch: true,
+			}
+
+			err, ok := rs.Validate(ServerSide)
+			Expect(ok).To(BeFalse())
+			Expect(err.Code()).To(Equal(ParseErrorCode))
+			Expect(NewErrorResponse(nil, err).Error.Code).To(Equal(ParseErrorCode))
+
+			err, ok = rs.Validate(ClientSide)
+			Expect(ok).To(BeFalse())
+			Expect(err.Code()).To(Equal(ParseErrorCode))
+			Expect(NewErrorResponse(nil, err).Error.Code).To(Equal(InternalErrorCode))
+		})
+	})
 })
 
 var _ = Describe("type BatchRequestMarshaler", func() {
@@ -852,6 +1369,58 @@ var _ = Describe("type BatchRequestMarshaler", func() {
 		})
 	})
 
+	Describe("func MarshalRequestContext()", func() {
+		It("marshals requests into a batch", func() {
+			ctx := context.Background()
+
+			err := marshaler.MarshalRequestContext(ctx, req1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = marshaler.MarshalRequestContext(ctx, req2)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = marshaler.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			rs, err := UnmarshalRequestSet(buf)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(rs.IsBatch).To(BeTrue())
+			Expect(rs.Requests).To(ContainElements(req1, req2))
+		})
+
+		It("returns the context error without writing if the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := marshaler.MarshalRequestContext(ctx, req1)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(buf.Bytes()).To(BeEmpty())
+		})
+
+		It("does not write a subsequent request once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			err := marshaler.MarshalRequestContext(ctx, req1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			n := buf.Len()
+			cancel()
+
+			err = marshaler.MarshalRequestContext(ctx, req2)
+			Expect(err).To(Equal(context.Canceled))
+			Expect(buf.Len()).To(Equal(n))
+		})
+
+		It("panics if the marshaler has been closed", func() {
+			marshaler.Close()
+
+			Expect(func() {
+				marshaler.MarshalRequestContext(context.Background(), req1)
+			}).To(PanicWith("marshaler has been closed"))
+		})
+	})
+
 	Describe("func Close()", func() {
 		It("does not write anything if no requests have been written", func() {
 			err := marshaler.Close()
@@ -867,5 +1436,69 @@ var _ = Describe("type BatchRequestMarshaler", func() {
 			err = marshaler.Close()
 			Expect(err).To(MatchError(`<induced write error>`))
 		})
+
+		It("does not write anything if the marshaler has already been closed", func() {
+			err := marshaler.MarshalRequest(req1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = marshaler.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			n := buf.Len()
+
+			err = marshaler.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(buf.Len()).To(Equal(n))
+		})
+	})
+
+	Describe("func Abort()", func() {
+		It("does not write the closing bracket", func() {
+			err := marshaler.MarshalRequest(req1)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			marshaler.Abort()
+
+			err = marshaler.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(buf.String()).To(HavePrefix("["))
+			Expect(buf.String()).NotTo(HaveSuffix("]"))
+		})
+
+		It("does not write anything if no requests have been written", func() {
+			marshaler.Abort()
+			Expect(buf.Bytes()).To(BeEmpty())
+		})
+
+		It("causes subsequent requests to panic", func() {
+			marshaler.Abort()
+
+			Expect(func() {
+				marshaler.MarshalRequest(req1)
+			}).To(PanicWith("marshaler has been closed"))
+		})
 	})
 })
+
+// validateBatchEntries are the table entries used to test that
+// RequestSet.Validate() produces errors on the correct side.
+var validateBatchEntries = []TableEntry{
+	Entry(
+		"invalid version",
+		Request{},
+		InvalidRequestCode,
+		`request version must be "2.0"`,
+	),
+	Entry(
+		"invalid request ID",
+		Request{Version: "2.0", ID: json.RawMessage(`{}`)},
+		InvalidRequestCode,
+		`request ID must be a JSON string, number or null`,
+	),
+	Entry(
+		"invalid parameters",
+		Request{Version: "2.0", Parameters: json.RawMessage(`123`)},
+		InvalidParametersCode,
+		`parameters must be an array, an object, or null`,
+	),
+}
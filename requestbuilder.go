@@ -0,0 +1,67 @@
+package harpy
+
+// RequestSetBuilder builds a RequestSet from Go values.
+//
+// It is intended for use in tests and proxies that need to assemble request
+// sets without constructing each Request by hand. The zero value is ready to
+// use.
+type RequestSetBuilder struct {
+	requests []Request
+	err      error
+}
+
+// Call adds a "call" request to the set.
+//
+// The ID and parameters are marshaled to JSON, as per NewCallRequest(). If
+// marshaling fails, the error is returned by Build().
+func (b *RequestSetBuilder) Call(
+	id any,
+	method string,
+	params any,
+	options ...EncoderOption,
+) *RequestSetBuilder {
+	req, err := NewCallRequest(id, method, params, options...)
+	return b.add(req, err)
+}
+
+// Notify adds a "notification" request to the set.
+//
+// The parameters are marshaled to JSON, as per NewNotifyRequest(). If
+// marshaling fails, the error is returned by Build().
+func (b *RequestSetBuilder) Notify(
+	method string,
+	params any,
+	options ...EncoderOption,
+) *RequestSetBuilder {
+	req, err := NewNotifyRequest(method, params, options...)
+	return b.add(req, err)
+}
+
+// Build returns the request set.
+//
+// If exactly one request has been added the set is not a batch, otherwise it
+// is a batch. It returns an error if any of the requests could not be
+// marshaled, or if the request set is invalid, as per
+// RequestSet.ValidateServerSide().
+func (b *RequestSetBuilder) Build() (RequestSet, error) {
+	if b.err != nil {
+		return RequestSet{}, b.err
+	}
+
+	return NewRequestSet(append([]Request(nil), b.requests...)...)
+}
+
+// add adds req to the set, or records err if it is non-nil.
+func (b *RequestSetBuilder) add(req Request, err error) *RequestSetBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	b.requests = append(b.requests, req)
+	return b
+}
@@ -0,0 +1,91 @@
+package harpy_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type RequestSetBuilder", func() {
+	var builder *RequestSetBuilder
+
+	BeforeEach(func() {
+		builder = &RequestSetBuilder{}
+	})
+
+	Describe("func Build()", func() {
+		It("returns a non-batch request set if there is a single request", func() {
+			rs, err := builder.
+				Call(123, "<method>", []int{1, 2, 3}).
+				Build()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs).To(Equal(RequestSet{
+				Requests: []Request{
+					{
+						Version:    "2.0",
+						ID:         json.RawMessage(`123`),
+						Method:     "<method>",
+						Parameters: json.RawMessage(`[1,2,3]`),
+					},
+				},
+				IsBatch: false,
+			}))
+		})
+
+		It("returns a batch request set if there are multiple requests", func() {
+			rs, err := builder.
+				Call(123, "<method>", []int{1, 2, 3}).
+				Notify("<method>", map[string]int{"value": 456}).
+				Build()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs).To(Equal(RequestSet{
+				Requests: []Request{
+					{
+						Version:    "2.0",
+						ID:         json.RawMessage(`123`),
+						Method:     "<method>",
+						Parameters: json.RawMessage(`[1,2,3]`),
+					},
+					{
+						Version:    "2.0",
+						Method:     "<method>",
+						Parameters: json.RawMessage(`{"value":456}`),
+					},
+				},
+				IsBatch: true,
+			}))
+		})
+
+		It("returns an error if the parameters can not be marshaled", func() {
+			_, err := builder.
+				Call(123, "<method>", 10i+1).
+				Notify("<method>", []int{1, 2, 3}).
+				Build()
+			Expect(err).To(MatchError("unable to marshal request parameters: json: unsupported type: complex128"))
+		})
+
+		It("returns an error if the request set is invalid", func() {
+			_, err := builder.
+				Call(123, "<method>", 123).
+				Build()
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(InvalidParametersCode))
+		})
+
+		It("returns an error if there are no requests", func() {
+			_, err := builder.Build()
+
+			var rpcErr Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(Equal(InvalidRequestCode))
+			Expect(rpcErr.Message()).To(Equal("batches must contain at least one request"))
+		})
+	})
+})
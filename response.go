@@ -2,6 +2,7 @@ package harpy
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -38,10 +39,22 @@ type SuccessResponse struct {
 	// Result is the user-defined result value produce in response to the
 	// request.
 	Result json.RawMessage `json:"result"`
+
+	// ResultStream, if non-nil, produces the result value. It is used instead
+	// of Result when the result is a StreamResult.
+	//
+	// It is never marshaled directly. Transports that do not support streaming
+	// must call BufferResult() to populate Result before marshaling the
+	// response.
+	ResultStream io.Reader `json:"-"`
 }
 
 // NewSuccessResponse returns a new SuccessResponse containing the given result.
 //
+// If the result is nil, the response's result is the JSON null value. If the
+// result is a StreamResult, its reader is used as the response's ResultStream
+// without being read.
+//
 // If the result can not be marshaled an ErrorResponse is returned instead.
 func NewSuccessResponse(requestID json.RawMessage, result any) Response {
 	res := SuccessResponse{
@@ -49,26 +62,41 @@ func NewSuccessResponse(requestID json.RawMessage, result any) Response {
 		RequestID: requestID,
 	}
 
-	if result != nil {
-		if result, ok := result.(Validatable); ok {
-			if err := result.Validate(); err != nil {
-				return NewErrorResponse(
-					requestID,
-					fmt.Errorf("result is invalid: %w", err),
-				)
-			}
+	if s, ok := result.(StreamResult); ok {
+		if s.Reader == nil {
+			return NewErrorResponse(
+				requestID,
+				errors.New("stream result has a nil reader"),
+			)
 		}
 
-		var err error
-		res.Result, err = json.Marshal(result)
-		if err != nil {
+		res.ResultStream = s.Reader
+		return res
+	}
+
+	if result == nil {
+		res.Result = json.RawMessage(`null`)
+		return res
+	}
+
+	if result, ok := result.(Validatable); ok {
+		if err := result.Validate(); err != nil {
 			return NewErrorResponse(
 				requestID,
-				fmt.Errorf("could not marshal success result value: %w", err),
+				fmt.Errorf("result is invalid: %w", err),
 			)
 		}
 	}
 
+	var err error
+	res.Result, err = json.Marshal(result)
+	if err != nil {
+		return NewErrorResponse(
+			requestID,
+			fmt.Errorf("could not marshal success result value: %w", err),
+		)
+	}
+
 	return res
 }
 
@@ -84,7 +112,7 @@ func (r SuccessResponse) Validate() error {
 		return err
 	}
 
-	if len(r.Result) == 0 {
+	if len(r.Result) == 0 && r.ResultStream == nil {
 		return errors.New("success response must contain a result")
 	}
 
@@ -117,6 +145,15 @@ type ErrorResponse struct {
 }
 
 // NewErrorResponse returns a new ErrorResponse for the given error.
+//
+// If err is a server-side native JSON-RPC Error, the response contains its
+// code, message and data. If err is caused by the cancelation of a context or
+// the expiry of its deadline, the response uses CanceledCode or
+// DeadlineExceededCode, respectively. Otherwise, the response indicates an
+// internal server error without exposing the error message to the client.
+//
+// In all but the first case, err is available in the response's ServerError
+// field.
 func NewErrorResponse(requestID json.RawMessage, err error) ErrorResponse {
 	if err, ok := err.(Error); ok && err.isServerSide {
 		// Only include error information if this is a "server-side" error,
@@ -126,13 +163,16 @@ func NewErrorResponse(requestID json.RawMessage, err error) ErrorResponse {
 		return newNativeErrorResponse(requestID, err)
 	}
 
-	if isInternalError(err) {
+	if code, ok := contextErrorCode(err); ok {
+		// Context errors are reported using well-known codes, so that clients
+		// can distinguish them from other internal errors without relying on
+		// the Go error message.
 		return ErrorResponse{
 			Version:   jsonRPCVersion,
 			RequestID: requestID,
 			Error: ErrorInfo{
-				Code:    InternalErrorCode,
-				Message: InternalErrorCode.String(),
+				Code:    code,
+				Message: code.String(),
 			},
 			ServerError: err,
 		}
@@ -143,8 +183,9 @@ func NewErrorResponse(requestID json.RawMessage, err error) ErrorResponse {
 		RequestID: requestID,
 		Error: ErrorInfo{
 			Code:    InternalErrorCode,
-			Message: err.Error(), // Note, we use the actual error message in the response.
+			Message: InternalErrorCode.String(),
 		},
+		ServerError: err,
 	}
 }
 
@@ -240,11 +281,17 @@ func (e ErrorInfo) String() string {
 	return describeError(e.Code, e.Message)
 }
 
-// isInternalError returns true if err is considered "internal" to the server,
-// and hence should not be shown to the client.
-func isInternalError(err error) bool {
-	return !errors.Is(err, context.Canceled) &&
-		!errors.Is(err, context.DeadlineExceeded)
+// contextErrorCode returns the error code used to represent err if it is
+// caused by the cancelation of a context or the expiry of its deadline.
+func contextErrorCode(err error) (ErrorCode, bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return DeadlineExceededCode, true
+	case errors.Is(err, context.Canceled):
+		return CanceledCode, true
+	default:
+		return 0, false
+	}
 }
 
 // ResponseSet encapsulates one or more JSON-RPC responses that were parsed from
@@ -261,7 +308,33 @@ type ResponseSet struct {
 }
 
 // UnmarshalResponseSet parses a set of JSON-RPC response set.
-func UnmarshalResponseSet(r io.Reader) (ResponseSet, error) {
+//
+// By default, responses that contain both a "result" and an "error" field are
+// accepted and treated as an ErrorResponse; the result is ignored. Responses
+// that contain neither field are treated as a SuccessResponse with an empty
+// result. Use the StrictResponses() option to reject such responses instead.
+//
+// The response set is decoded using the codec specified by
+// DecodeResponsesWith(), or as JSON by default.
+func UnmarshalResponseSet(r io.Reader, options ...ResponseSetOption) (ResponseSet, error) {
+	var opts responseSetOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	if !isJSONCodec(opts.Codec) {
+		data, err := transcodeToJSON(r, opts.Codec)
+		if err != nil {
+			if _, ok := err.(codecError); ok {
+				return ResponseSet{}, fmt.Errorf("unable to parse response: %w", err)
+			}
+
+			return ResponseSet{}, err
+		}
+
+		r = bytes.NewReader(data)
+	}
+
 	br := bufio.NewReader(r)
 
 	for {
@@ -279,10 +352,50 @@ func UnmarshalResponseSet(r io.Reader) (ResponseSet, error) {
 		}
 
 		if ch == '[' {
-			return unmarshalBatchResponse(br)
+			return unmarshalBatchResponse(br, opts)
 		}
 
-		return unmarshalSingleResponse(br)
+		return unmarshalSingleResponse(br, opts)
+	}
+}
+
+// ResponseSetOption is an option that changes the behavior of
+// UnmarshalResponseSet().
+type ResponseSetOption func(*responseSetOptions)
+
+// responseSetOptions is a set of options that control how response sets are
+// unmarshaled.
+type responseSetOptions struct {
+	Strict               bool
+	DisallowTrailingData bool
+	Codec                Codec
+}
+
+// StrictResponses is a ResponseSetOption that controls whether responses that
+// are ambiguous according to the JSON-RPC specification are rejected.
+//
+// When strict parsing is enabled UnmarshalResponseSet() returns an error if any
+// response contains both a "result" and an "error" field, or if it contains
+// neither.
+//
+// Strict parsing is disabled by default.
+func StrictResponses(strict bool) ResponseSetOption {
+	return func(opts *responseSetOptions) {
+		opts.Strict = strict
+	}
+}
+
+// DisallowTrailingResponseData is a ResponseSetOption that controls whether
+// UnmarshalResponseSet() rejects response sets that are followed by data other
+// than whitespace.
+//
+// When enabled, content such as two consecutive JSON objects results in an
+// error, rather than the trailing data being ignored.
+//
+// Trailing data is permitted by default.
+func DisallowTrailingResponseData(disallow bool) ResponseSetOption {
+	return func(opts *responseSetOptions) {
+		opts.DisallowTrailingData = disallow
 	}
 }
 
@@ -326,27 +439,88 @@ func (rs ResponseSet) Validate() error {
 	return nil
 }
 
-// unmarshalSingleRequest unmarshals a non-batch JSON-RPC request set.
-func unmarshalSingleResponse(r *bufio.Reader) (ResponseSet, error) {
+// MatchRequests checks that the response set contains exactly one response to
+// each of the calls within reqs.
+//
+// A response matches a request if the JSON representation of their request IDs
+// are identical. Notifications do not require a response. It returns an error
+// describing the first problem found if any call does not have a response, if
+// any call has more than one response, or if any response does not match a
+// call. It also returns an error if reqs contains more than one call with the
+// same ID, as the responses to those calls can not be distinguished.
+//
+// It is useful for verifying that a response set has not been corrupted, such
+// as by a proxy or by middleware that reassembles fanned-out requests.
+func (rs ResponseSet) MatchRequests(reqs RequestSet) error {
+	calls := map[string]bool{}
+
+	for _, req := range reqs.Requests {
+		if req.IsNotification() {
+			continue
+		}
+
+		id := string(req.ID)
+		if _, ok := calls[id]; ok {
+			return fmt.Errorf("request set contains more than one request with ID %s", id)
+		}
+
+		calls[id] = false
+	}
+
+	for _, res := range rs.Responses {
+		var raw json.RawMessage
+		res.UnmarshalRequestID(&raw) // nolint:errcheck // always succeeds for json.RawMessage
+
+		id := string(raw)
+		if id == "" {
+			id = "null"
+		}
+
+		responded, ok := calls[id]
+		if !ok {
+			return fmt.Errorf("response with ID %s does not match any request", id)
+		}
+
+		if responded {
+			return fmt.Errorf("response set contains more than one response to the request with ID %s", id)
+		}
+
+		calls[id] = true
+	}
+
+	for _, req := range reqs.Requests {
+		if !req.IsNotification() && !calls[string(req.ID)] {
+			return fmt.Errorf("response set does not contain a response to the request with ID %s", req.ID)
+		}
+	}
+
+	return nil
+}
+
+// unmarshalSingleResponse unmarshals a non-batch JSON-RPC response set.
+func unmarshalSingleResponse(r *bufio.Reader, opts responseSetOptions) (ResponseSet, error) {
 	var res successOrErrorResponse
 
-	if err := unmarshalJSONForResponse(r, &res); err != nil {
+	if err := unmarshalJSONForResponse(r, &res, opts); err != nil {
+		return ResponseSet{}, err
+	}
+
+	normalized, err := normalizeResponse(res, opts)
+	if err != nil {
 		return ResponseSet{}, err
 	}
 
 	return ResponseSet{
-		Responses: []Response{
-			normalizeResponse(res),
-		},
-		IsBatch: false,
+		Responses: []Response{normalized},
+		IsBatch:   false,
 	}, nil
 }
 
-// unmarshalBatchResponse unmarshals a batched JSON-RPC request set.
-func unmarshalBatchResponse(r *bufio.Reader) (ResponseSet, error) {
+// unmarshalBatchResponse unmarshals a batched JSON-RPC response set.
+func unmarshalBatchResponse(r *bufio.Reader, opts responseSetOptions) (ResponseSet, error) {
 	var batch []successOrErrorResponse
 
-	if err := unmarshalJSONForResponse(r, &batch); err != nil {
+	if err := unmarshalJSONForResponse(r, &batch, opts); err != nil {
 		return ResponseSet{}, err
 	}
 
@@ -356,15 +530,22 @@ func unmarshalBatchResponse(r *bufio.Reader) (ResponseSet, error) {
 	}
 
 	for i, res := range batch {
-		set.Responses[i] = normalizeResponse(res)
+		normalized, err := normalizeResponse(res, opts)
+		if err != nil {
+			return ResponseSet{}, err
+		}
+
+		set.Responses[i] = normalized
 	}
 
 	return set, nil
 }
 
 // unmarshalJSONForResponse unmarshals JSON content from r into v.
-func unmarshalJSONForResponse(r io.Reader, v any) error {
-	err := jsonx.Decode(r, v)
+func unmarshalJSONForResponse(r io.Reader, v any, opts responseSetOptions) error {
+	err := jsonx.Decode(r, v, func(o *jsonx.UnmarshalOptions) {
+		o.DisallowTrailingData = opts.DisallowTrailingData
+	})
 
 	if jsonx.IsParseError(err) {
 		return fmt.Errorf("unable to parse response: %w", err)
@@ -375,18 +556,30 @@ func unmarshalJSONForResponse(r io.Reader, v any) error {
 
 // normalizeResponse returns a response of a specific type based on the content
 // of res.
-func normalizeResponse(res successOrErrorResponse) Response {
+//
+// If res contains both a result and an error, the error takes precedence. If it
+// contains neither, it is treated as a success response. If opts.Strict is true
+// an error is returned in both of these cases instead.
+func normalizeResponse(res successOrErrorResponse, opts responseSetOptions) (Response, error) {
 	if res.Error != nil {
+		if opts.Strict && len(res.Result) != 0 {
+			return nil, errors.New("unable to parse response: response must not contain both a result and an error")
+		}
+
 		return ErrorResponse{
 			Version:   res.Version,
 			RequestID: res.RequestID,
 			Error:     *res.Error,
-		}
+		}, nil
+	}
+
+	if opts.Strict && len(res.Result) == 0 {
+		return nil, errors.New("unable to parse response: response must contain either a result or an error")
 	}
 
 	return SuccessResponse{
 		Version:   res.Version,
 		RequestID: res.RequestID,
 		Result:    res.Result,
-	}
+	}, nil
 }
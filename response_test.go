@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 
@@ -29,7 +30,7 @@ var _ = Describe("type SuccessResponse", func() {
 			}))
 		})
 
-		It("returns a SuccessResponse with an empty result when the result is nil", func() {
+		It("returns a SuccessResponse with a null result when the result is nil", func() {
 			res := NewSuccessResponse(
 				json.RawMessage(`123`),
 				nil,
@@ -38,7 +39,35 @@ var _ = Describe("type SuccessResponse", func() {
 			Expect(res).To(Equal(SuccessResponse{
 				Version:   `2.0`,
 				RequestID: json.RawMessage(`123`),
+				Result:    json.RawMessage(`null`),
 			}))
+			Expect(res.Validate()).To(Succeed())
+		})
+
+		It("returns a SuccessResponse with a result stream when the result is a StreamResult", func() {
+			r := strings.NewReader(`456`)
+
+			res := NewSuccessResponse(
+				json.RawMessage(`123`),
+				StreamResult{Reader: r},
+			)
+
+			Expect(res).To(Equal(SuccessResponse{
+				Version:      `2.0`,
+				RequestID:    json.RawMessage(`123`),
+				ResultStream: r,
+			}))
+			Expect(r.Len()).To(Equal(3), "stream must not be read")
+		})
+
+		It("returns an ErrorResponse if the StreamResult has a nil reader", func() {
+			res := NewSuccessResponse(
+				json.RawMessage(`123`),
+				StreamResult{},
+			)
+
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).ServerError).To(MatchError("stream result has a nil reader"))
 		})
 
 		It("returns an ErrorResponse if the result can not be marshaled", func() {
@@ -105,6 +134,17 @@ var _ = Describe("type SuccessResponse", func() {
 			Entry("object ID", json.RawMessage(`{}`)),
 		)
 
+		It("returns nil when the result is streamed", func() {
+			res := SuccessResponse{
+				Version:      "2.0",
+				RequestID:    json.RawMessage(`123`),
+				ResultStream: strings.NewReader(`456`),
+			}
+
+			err := res.Validate()
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
 		It("returns an error if the request ID is not valid JSON", func() {
 			res := SuccessResponse{
 				Version:   "2.0",
@@ -144,6 +184,30 @@ var _ = Describe("type SuccessResponse", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(id).To(BeNumerically("==", 123))
 		})
+
+		It("unmarshals fractional request IDs", func() {
+			res := SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1.5`),
+			}
+
+			var id float64
+			err := res.UnmarshalRequestID(&id)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(id).To(Equal(1.5))
+		})
+
+		It("preserves the exact representation of the request ID when unmarshaling into a json.RawMessage", func() {
+			res := SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1.50`),
+			}
+
+			var id json.RawMessage
+			err := res.UnmarshalRequestID(&id)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(id).To(Equal(json.RawMessage(`1.50`)))
+		})
 	})
 })
 
@@ -236,10 +300,10 @@ var _ = Describe("type ErrorResponse", func() {
 			})
 		})
 
-		When("the error is not a native JSON-RPC error, and it is not an internal error", func() {
+		When("the error is caused by a context error", func() {
 			DescribeTable(
-				"it returns an ErrorResponse that includes the error message",
-				func(err error) {
+				"it returns an ErrorResponse with a well-known error code",
+				func(err error, code ErrorCode, message string) {
 					res := NewErrorResponse(
 						json.RawMessage(`123`),
 						err,
@@ -249,13 +313,16 @@ var _ = Describe("type ErrorResponse", func() {
 						Version:   `2.0`,
 						RequestID: json.RawMessage(`123`),
 						Error: ErrorInfo{
-							Code:    InternalErrorCode,
-							Message: err.Error(),
+							Code:    code,
+							Message: message,
 						},
+						ServerError: err,
 					}))
 				},
-				Entry("context deadline exceeded", context.DeadlineExceeded),
-				Entry("context canceled", context.Canceled),
+				Entry("context deadline exceeded", context.DeadlineExceeded, DeadlineExceededCode, "deadline exceeded"),
+				Entry("context canceled", context.Canceled, CanceledCode, "request canceled"),
+				Entry("wrapped context deadline exceeded", fmt.Errorf("<error>: %w", context.DeadlineExceeded), DeadlineExceededCode, "deadline exceeded"),
+				Entry("wrapped context canceled", fmt.Errorf("<error>: %w", context.Canceled), CanceledCode, "request canceled"),
 			)
 		})
 
@@ -411,6 +478,21 @@ var _ = Describe("type ResponseSet", func() {
 			))
 		})
 
+		It("does not limit the nesting depth of the response", func() {
+			result := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+			r := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "result": ` + result + `}`)
+
+			rs, err := UnmarshalResponseSet(r)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs.Responses).To(ConsistOf(
+				SuccessResponse{
+					Version:   "2.0",
+					RequestID: json.RawMessage(`123`),
+					Result:    json.RawMessage(result),
+				},
+			))
+		})
+
 		It("parses a single error response", func() {
 			r := strings.NewReader(`{
 				"jsonrpc": "2.0",
@@ -540,6 +622,297 @@ var _ = Describe("type ResponseSet", func() {
 			_, err := UnmarshalResponseSet(r)
 			Expect(err).To(MatchError("unable to parse response: json: cannot unmarshal string into Go value of type harpy.successOrErrorResponse"))
 		})
+
+		It("parses a response that contains neither a result nor an error as a success response", func() {
+			r := strings.NewReader(`{
+				"jsonrpc": "2.0",
+				"id": 123
+			}`)
+
+			rs, err := UnmarshalResponseSet(r)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs.Responses).To(ConsistOf(
+				SuccessResponse{
+					Version:   "2.0",
+					RequestID: json.RawMessage(`123`),
+				},
+			))
+		})
+
+		It("prefers the error if the response contains both a result and an error", func() {
+			r := strings.NewReader(`{
+				"jsonrpc": "2.0",
+				"id": 123,
+				"result": [1, 2, 3],
+				"error": {
+					"code": 456,
+					"message": "<error message>"
+				}
+			}`)
+
+			rs, err := UnmarshalResponseSet(r)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs.Responses).To(ConsistOf(
+				ErrorResponse{
+					Version:   "2.0",
+					RequestID: json.RawMessage(`123`),
+					Error: ErrorInfo{
+						Code:    456,
+						Message: "<error message>",
+					},
+				},
+			))
+		})
+
+		When("strict parsing is enabled", func() {
+			It("returns an error if a single response contains both a result and an error", func() {
+				r := strings.NewReader(`{
+					"jsonrpc": "2.0",
+					"id": 123,
+					"result": [1, 2, 3],
+					"error": {
+						"code": 456,
+						"message": "<error message>"
+					}
+				}`)
+
+				_, err := UnmarshalResponseSet(r, StrictResponses(true))
+				Expect(err).To(MatchError("unable to parse response: response must not contain both a result and an error"))
+			})
+
+			It("returns an error if a response within a batch contains both a result and an error", func() {
+				r := strings.NewReader(`[{
+					"jsonrpc": "2.0",
+					"id": 123,
+					"result": [1, 2, 3]
+				},{
+					"jsonrpc": "2.0",
+					"id": 456,
+					"result": null,
+					"error": {
+						"code": 789,
+						"message": "<error message>"
+					}
+				}]`)
+
+				_, err := UnmarshalResponseSet(r, StrictResponses(true))
+				Expect(err).To(MatchError("unable to parse response: response must not contain both a result and an error"))
+			})
+
+			It("parses a response that contains only a result", func() {
+				r := strings.NewReader(`{
+					"jsonrpc": "2.0",
+					"id": 123,
+					"result": [1, 2, 3]
+				}`)
+
+				rs, err := UnmarshalResponseSet(r, StrictResponses(true))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(rs.Responses).To(ConsistOf(
+					SuccessResponse{
+						Version:   "2.0",
+						RequestID: json.RawMessage(`123`),
+						Result:    json.RawMessage(`[1, 2, 3]`),
+					},
+				))
+			})
+
+			It("parses a response that contains only an error", func() {
+				r := strings.NewReader(`{
+					"jsonrpc": "2.0",
+					"id": 123,
+					"error": {
+						"code": 456,
+						"message": "<error message>"
+					}
+				}`)
+
+				rs, err := UnmarshalResponseSet(r, StrictResponses(true))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(rs.Responses).To(ConsistOf(
+					ErrorResponse{
+						Version:   "2.0",
+						RequestID: json.RawMessage(`123`),
+						Error: ErrorInfo{
+							Code:    456,
+							Message: "<error message>",
+						},
+					},
+				))
+			})
+
+			It("returns an error if a single response contains neither a result nor an error", func() {
+				r := strings.NewReader(`{
+					"jsonrpc": "2.0",
+					"id": 123
+				}`)
+
+				_, err := UnmarshalResponseSet(r, StrictResponses(true))
+				Expect(err).To(MatchError("unable to parse response: response must contain either a result or an error"))
+			})
+
+			It("returns an error if a response within a batch contains neither a result nor an error", func() {
+				r := strings.NewReader(`[{
+					"jsonrpc": "2.0",
+					"id": 123,
+					"result": [1, 2, 3]
+				},{
+					"jsonrpc": "2.0",
+					"id": 456,
+					"error": null
+				}]`)
+
+				_, err := UnmarshalResponseSet(r, StrictResponses(true))
+				Expect(err).To(MatchError("unable to parse response: response must contain either a result or an error"))
+			})
+
+			It("parses a response with a null result", func() {
+				r := strings.NewReader(`{
+					"jsonrpc": "2.0",
+					"id": 123,
+					"result": null
+				}`)
+
+				rs, err := UnmarshalResponseSet(r, StrictResponses(true))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(rs.Responses).To(ConsistOf(
+					SuccessResponse{
+						Version:   "2.0",
+						RequestID: json.RawMessage(`123`),
+						Result:    json.RawMessage(`null`),
+					},
+				))
+			})
+		})
+
+		When("trailing data is disallowed", func() {
+			It("returns an error if a single response is followed by another value", func() {
+				r := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "result": 1}{"jsonrpc": "2.0", "id": 456, "result": 2}`)
+
+				_, err := UnmarshalResponseSet(r, DisallowTrailingResponseData(true))
+				Expect(err).To(MatchError("unable to parse response: json: invalid data after top-level value"))
+			})
+
+			It("returns an error if a batch is followed by another value", func() {
+				r := strings.NewReader(`[{"jsonrpc": "2.0", "id": 123, "result": 1}] x`)
+
+				_, err := UnmarshalResponseSet(r, DisallowTrailingResponseData(true))
+				Expect(err).To(MatchError("unable to parse response: json: invalid data after top-level value"))
+			})
+
+			It("allows trailing whitespace", func() {
+				r := strings.NewReader("{\"jsonrpc\": \"2.0\", \"id\": 123, \"result\": 1}\n\t \r\n")
+
+				_, err := UnmarshalResponseSet(r, DisallowTrailingResponseData(true))
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		It("ignores trailing data by default", func() {
+			r := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "result": 1}{"jsonrpc": "2.0", "id": 456, "result": 2}`)
+
+			rs, err := UnmarshalResponseSet(r)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rs.Responses).To(HaveLen(1))
+		})
+	})
+
+	Describe("func MatchRequests()", func() {
+		var requests RequestSet
+
+		BeforeEach(func() {
+			requests = RequestSet{
+				Requests: []Request{
+					{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>"},
+					{Version: "2.0", Method: "<notification>"},
+					{Version: "2.0", ID: json.RawMessage(`"two"`), Method: "<method>"},
+				},
+				IsBatch: true,
+			}
+		})
+
+		It("returns nil if there is exactly one response to each call", func() {
+			rs := ResponseSet{
+				Responses: []Response{
+					NewSuccessResponse(json.RawMessage(`"two"`), nil),
+					NewErrorResponse(json.RawMessage(`1`), NewError(123)),
+				},
+				IsBatch: true,
+			}
+
+			err := rs.MatchRequests(requests)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("returns nil if there are no calls and no responses", func() {
+			requests.Requests = []Request{
+				{Version: "2.0", Method: "<notification>"},
+			}
+
+			err := ResponseSet{IsBatch: true}.MatchRequests(requests)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("returns an error if a call does not have a response", func() {
+			rs := ResponseSet{
+				Responses: []Response{
+					NewSuccessResponse(json.RawMessage(`1`), nil),
+				},
+				IsBatch: true,
+			}
+
+			err := rs.MatchRequests(requests)
+			Expect(err).To(MatchError(`response set does not contain a response to the request with ID "two"`))
+		})
+
+		It("returns an error if a call has more than one response", func() {
+			rs := ResponseSet{
+				Responses: []Response{
+					NewSuccessResponse(json.RawMessage(`1`), nil),
+					NewSuccessResponse(json.RawMessage(`"two"`), nil),
+					NewSuccessResponse(json.RawMessage(`1`), nil),
+				},
+				IsBatch: true,
+			}
+
+			err := rs.MatchRequests(requests)
+			Expect(err).To(MatchError(`response set contains more than one response to the request with ID 1`))
+		})
+
+		It("returns an error if a response does not match any call", func() {
+			rs := ResponseSet{
+				Responses: []Response{
+					NewSuccessResponse(json.RawMessage(`1`), nil),
+					NewSuccessResponse(json.RawMessage(`"two"`), nil),
+					NewSuccessResponse(json.RawMessage(`3`), nil),
+				},
+				IsBatch: true,
+			}
+
+			err := rs.MatchRequests(requests)
+			Expect(err).To(MatchError(`response with ID 3 does not match any request`))
+		})
+
+		It("returns an error if a response has a null ID", func() {
+			rs := ResponseSet{
+				Responses: []Response{
+					NewErrorResponse(nil, NewError(123)),
+				},
+			}
+
+			err := rs.MatchRequests(requests)
+			Expect(err).To(MatchError(`response with ID null does not match any request`))
+		})
+
+		It("returns an error if the request set contains calls with the same ID", func() {
+			requests.Requests = append(
+				requests.Requests,
+				Request{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>"},
+			)
+
+			err := ResponseSet{IsBatch: true}.MatchRequests(requests)
+			Expect(err).To(MatchError(`request set contains more than one request with ID 1`))
+		})
 	})
 
 	Describe("func Validate()", func() {
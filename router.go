@@ -2,16 +2,68 @@ package harpy
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dogmatiq/harpy/internal/jsonx"
 )
 
 // Router is a Exchanger that dispatches to different handlers based on the
 // JSON-RPC method name.
 type Router struct {
 	routes map[string]UntypedHandler
+
+	// routeConfigs contains the configuration of each route added via
+	// WithRoute(), keyed by method name.
+	routeConfigs map[string]*routeConfig
+
+	// allowReserved indicates whether routes may be added for methods that are
+	// reserved for system extensions.
+	allowReserved bool
+
+	// disallowBlankMethods indicates whether requests for methods with names
+	// that are empty or consist only of whitespace are rejected.
+	disallowBlankMethods bool
+
+	// metadataKey is the key of the parameter that is extracted from each
+	// request's parameters and added to the context. If it is empty,
+	// parameter metadata is not extracted.
+	metadataKey string
+
+	// onUnknownNotification is called with each notification for which there
+	// is no route. If it is nil, such notifications are silently dropped.
+	onUnknownNotification func(ctx context.Context, req Request)
+
+	// interceptors is a list of functions that are invoked, in order, with the
+	// response to each call.
+	interceptors []ResponseInterceptor
+
+	// defaultRouteOptions is a list of options that are applied to every route
+	// added via WithRoute(), before the route's own options.
+	defaultRouteOptions []RouteOption
+
+	// strict indicates whether the result types of routes added via
+	// WithRoute() are checked when the router is constructed.
+	strict bool
+
+	// resultTypes contains the result type of each route added via
+	// WithRoute(), keyed by method name.
+	resultTypes map[string]reflect.Type
 }
 
+// reservedMethodPrefix is the prefix of method names that are reserved for
+// system extensions by the JSON-RPC specification.
+const reservedMethodPrefix = "rpc."
+
 // NewRouter returns a new router containing the given routes.
+//
+// It panics if any of the routes are for methods with names that begin with
+// "rpc.", as such methods are reserved for system extensions. The
+// WithReservedMethods() option permits the use of these method names.
 func NewRouter(options ...RouterOption) *Router {
 	router := &Router{}
 
@@ -19,6 +71,33 @@ func NewRouter(options ...RouterOption) *Router {
 		opt(router)
 	}
 
+	for _, cfg := range router.routeConfigs {
+		cfg.resolve(router.defaultRouteOptions)
+	}
+
+	if router.strict {
+		for m, t := range router.resultTypes {
+			if err := checkResultType(t); err != nil {
+				panic(fmt.Sprintf(
+					"route for '%s' method has a result type that can not be marshaled: %s",
+					m,
+					err,
+				))
+			}
+		}
+	}
+
+	if !router.allowReserved {
+		for m := range router.routes {
+			if strings.HasPrefix(m, reservedMethodPrefix) {
+				panic(fmt.Sprintf(
+					"route for '%s' method uses a name reserved for system extensions, use WithReservedMethods() to permit it",
+					m,
+				))
+			}
+		}
+	}
+
 	return router
 }
 
@@ -27,7 +106,31 @@ func NewRouter(options ...RouterOption) *Router {
 // It invokes the handler associated with the method specified by the request.
 // If no such method has been registered it returns a JSON-RPC "method not
 // found" error response.
+//
+// If ctx is already canceled or its deadline has passed the handler is not
+// invoked, and an error response built from ctx.Err() is returned instead, as
+// per NewErrorResponse().
 func (r *Router) Call(ctx context.Context, req Request) Response {
+	res := r.call(ctx, req)
+
+	for _, fn := range r.interceptors {
+		res = fn(ctx, req, res)
+	}
+
+	return res
+}
+
+// call invokes the handler associated with the method specified by req and
+// returns the response, before it is passed to any interceptors.
+func (r *Router) call(ctx context.Context, req Request) Response {
+	if err := ctx.Err(); err != nil {
+		return NewErrorResponse(req.ID, err)
+	}
+
+	if err, ok := r.validateMethod(req.Method); !ok {
+		return NewErrorResponse(req.ID, err)
+	}
+
 	h, ok := r.routes[req.Method]
 	if !ok {
 		return NewErrorResponse(
@@ -36,6 +139,14 @@ func (r *Router) Call(ctx context.Context, req Request) Response {
 		)
 	}
 
+	// The original request is retained if the metadata can not be extracted,
+	// so that the error response contains its ID.
+	mctx, mreq, err := r.extractParameterMetadata(ctx, req)
+	if err != nil {
+		return NewErrorResponse(req.ID, err)
+	}
+	ctx, req = mctx, mreq
+
 	result, err := h(ctx, req)
 	if err != nil {
 		return NewErrorResponse(req.ID, err)
@@ -46,50 +157,402 @@ func (r *Router) Call(ctx context.Context, req Request) Response {
 
 // Notify handles a notification request.
 //
-// It invokes the handler associated with the method specified by the request.
-// If no such method has been registered it does nothing.
+// It invokes the handler associated with the method specified by the request
+// and returns the handler's error, if any. If no such method has been
+// registered it returns a JSON-RPC "method not found" error, after invoking
+// the hook configured by WithUnknownNotificationHook(), if any. As
+// notifications do not produce a response, these errors are only used for
+// logging.
+//
+// If ctx is already canceled or its deadline has passed the handler is not
+// invoked, and ctx.Err() is returned instead.
 func (r *Router) Notify(ctx context.Context, req Request) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err, ok := r.validateMethod(req.Method); !ok {
+		return err
+	}
+
 	h, ok := r.routes[req.Method]
 	if !ok {
+		if r.onUnknownNotification != nil {
+			r.onUnknownNotification(ctx, req)
+		}
 		return MethodNotFound()
 	}
 
-	_, err := h(ctx, req)
+	mctx, mreq, err := r.extractParameterMetadata(ctx, req)
+	if err != nil {
+		return err
+	}
+	ctx, req = mctx, mreq
+
+	_, err = h(ctx, req)
 	return err
 }
 
+// validateMethod returns an error if requests for the method m are rejected
+// before they are routed.
+func (r *Router) validateMethod(m string) (Error, bool) {
+	if r.disallowBlankMethods && strings.TrimSpace(m) == "" {
+		return NewErrorWithReservedCode(
+			InvalidRequestCode,
+			WithMessage("method name must not be empty"),
+		), false
+	}
+
+	return Error{}, true
+}
+
 // HasRoute returns true if the router has a route for the given method.
 func (r *Router) HasRoute(method string) bool {
 	_, ok := r.routes[method]
 	return ok
 }
 
+// MethodInfo describes a method that is handled by a Router.
+type MethodInfo struct {
+	// Name is the name of the method.
+	Name string
+
+	// Summary is a short description of the method. It may be empty.
+	Summary string
+
+	// ParamsSchema is a JSON schema that describes the method's parameters.
+	// It may be nil.
+	ParamsSchema json.RawMessage
+
+	// ResultSchema is a JSON schema that describes the method's result. It may
+	// be nil.
+	ResultSchema json.RawMessage
+}
+
+// Describe returns information about each of the methods handled by the
+// router, sorted by name.
+//
+// Metadata can be attached to routes added via WithRoute() using the
+// WithSummary() and WithSchemas() options. Routes without metadata, including
+// those added via WithUntypedRoute(), are described by name only.
+func (r *Router) Describe() []MethodInfo {
+	methods := make([]MethodInfo, 0, len(r.routes))
+
+	for m := range r.routes {
+		var info MethodInfo
+		if cfg, ok := r.routeConfigs[m]; ok {
+			info = cfg.info
+		}
+		info.Name = m
+		methods = append(methods, info)
+	}
+
+	slices.SortFunc(methods, func(a, b MethodInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return methods
+}
+
 // RouterOption represents a single route within a router.
 type RouterOption func(*Router)
 
+// WithReservedMethods is a RouterOption that permits routes for methods with
+// names that begin with "rpc.".
+//
+// The JSON-RPC specification reserves these method names for system
+// extensions. This option should only be used when intentionally implementing
+// such an extension.
+func WithReservedMethods() RouterOption {
+	return func(r *Router) {
+		r.allowReserved = true
+	}
+}
+
+// WithBlankMethods is a RouterOption that controls whether the router accepts
+// requests for methods with names that are empty or consist only of
+// whitespace.
+//
+// Such method names are permitted by the JSON-RPC specification, but are
+// almost always the result of a bug in the client. By default, they are routed
+// like any other method, typically resulting in a JSON-RPC "method not found"
+// error. If allow is false, these requests are rejected with a JSON-RPC
+// "invalid request" error instead, even if there is a route for the method.
+func WithBlankMethods(allow bool) RouterOption {
+	return func(r *Router) {
+		r.disallowBlankMethods = !allow
+	}
+}
+
+// WithStrictRoutes is a RouterOption that checks that the result type of each
+// route added via WithRoute() can be marshaled to JSON.
+//
+// The check is performed when the router is constructed by attempting to
+// marshal the zero value of the result type, or of the type it points to if it
+// is a pointer. NewRouter() panics if the value can not be marshaled, such as
+// when the result type is a channel or a function. This catches such
+// misconfigurations at startup, rather than when the route is first called.
+//
+// Routes added via WithUntypedRoute() are not checked.
+func WithStrictRoutes() RouterOption {
+	return func(r *Router) {
+		r.strict = true
+	}
+}
+
+// checkResultType returns an error if the zero value of t can not be marshaled
+// to JSON.
+func checkResultType(t reflect.Type) error {
+	v := reflect.Zero(t)
+	if t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem())
+	}
+
+	_, err := json.Marshal(v.Interface())
+	return err
+}
+
+// WithUnknownNotificationHook is a RouterOption that sets a function that is
+// called with each notification for which the router has no route.
+//
+// Such notifications usually indicate a bug in the client, but as per the
+// JSON-RPC specification they never produce a response, so the client is not
+// informed of the problem. The hook allows the server to make the problem
+// visible, such as by logging the request or recording a metric.
+//
+// By default, these notifications are dropped silently.
+func WithUnknownNotificationHook(fn func(ctx context.Context, req Request)) RouterOption {
+	return func(r *Router) {
+		r.onUnknownNotification = fn
+	}
+}
+
+// ResponseInterceptor is a function that inspects the response to a call, and
+// returns the response to send to the caller.
+//
+// res is either a SuccessResponse or an ErrorResponse. The interceptor may
+// return res unchanged, or return a replacement.
+type ResponseInterceptor func(ctx context.Context, req Request, res Response) Response
+
+// WithResponseInterceptor is a RouterOption that adds a function that is
+// invoked with the response to every call handled by the router, including
+// "method not found" errors.
+//
+// Interceptors are not invoked for notifications, as they do not produce a
+// response. If there are multiple interceptors they are invoked in the order
+// they are added, each receiving the response returned by the previous one.
+func WithResponseInterceptor(fn ResponseInterceptor) RouterOption {
+	return func(r *Router) {
+		r.interceptors = append(r.interceptors, fn)
+	}
+}
+
+// WithDefaultRouteOptions is a RouterOption that adds options that are applied
+// to every route added via WithRoute().
+//
+// The default options are applied before the options passed to WithRoute(),
+// so a route's own options take precedence when they configure the same
+// behavior. If this option is used multiple times, the options are applied in
+// the order they are added. They apply to all routes regardless of whether
+// the routes are added before or after this option.
+//
+// The options are not used by routes added via WithUntypedRoute(), as such
+// routes unmarshal their own parameters.
+func WithDefaultRouteOptions(options ...RouteOption) RouterOption {
+	return func(r *Router) {
+		r.defaultRouteOptions = append(r.defaultRouteOptions, options...)
+	}
+}
+
 // WithRoute it a router option that adds a route from the method m to the
 // "typed" handler function h.
 //
 // P is the type into which the JSON-RPC request parameters are unmarshaled. R
 // is the type of the result included in a successful JSON-RPC response.
+//
+// The options configure the route. Any UnmarshalOption may be used to control
+// how the parameters are unmarshaled.
 func WithRoute[P, R any](
 	m string,
 	h func(context.Context, P) (R, error),
-	options ...UnmarshalOption,
+	options ...RouteOption,
 ) RouterOption {
-	return WithUntypedRoute(
-		m,
-		func(ctx context.Context, req Request) (any, error) {
-			var params P
-			if err := req.UnmarshalParameters(&params, options...); err != nil {
-				return nil, err
-			}
+	return func(r *Router) {
+		cfg := &routeConfig{options: options}
+
+		WithUntypedRoute(
+			m,
+			func(ctx context.Context, req Request) (any, error) {
+				if cfg.requireParameters && len(req.Parameters) == 0 {
+					return nil, InvalidParameters(
+						WithCause(errParametersRequired),
+					)
+				}
+
+				var params P
+				if err := req.UnmarshalParameters(&params, cfg.unmarshalOptions...); err != nil {
+					return nil, err
+				}
+
+				for _, v := range cfg.contextValues {
+					ctx = context.WithValue(ctx, v.key, v.value)
+				}
+
+				return h(ctx, params)
+			},
+		)(r)
+
+		if r.resultTypes == nil {
+			r.resultTypes = map[string]reflect.Type{}
+		}
+		r.resultTypes[m] = reflect.TypeFor[R]()
+
+		if r.routeConfigs == nil {
+			r.routeConfigs = map[string]*routeConfig{}
+		}
+		r.routeConfigs[m] = cfg
+	}
+}
+
+// WithRouteAliases is a router option that adds a route from each of the given
+// methods to the "typed" handler function h.
+//
+// It is equivalent to calling WithRoute() with the same handler and options for
+// each method. It panics if any of the methods already has a route, including
+// if the same method appears in the list more than once.
+func WithRouteAliases[P, R any](
+	methods []string,
+	h func(context.Context, P) (R, error),
+	options ...RouteOption,
+) RouterOption {
+	return func(r *Router) {
+		for _, m := range methods {
+			WithRoute(m, h, options...)(r)
+		}
+	}
+}
 
-			return h(ctx, params)
-		},
-	)
+// RouteOption is an option that configures a route added via WithRoute().
+//
+// Any UnmarshalOption may be used as a RouteOption, in which case it controls
+// how the route's parameters are unmarshaled.
+type RouteOption interface {
+	ApplyToRoute(jsonx.RouteConfig)
 }
 
+// routeOption is an implementation of RouteOption for options that only apply
+// to routes.
+type routeOption func(*routeConfig)
+
+func (fn routeOption) ApplyToRoute(c jsonx.RouteConfig) {
+	fn(c.(*routeConfig))
+}
+
+// routeConfig is the configuration of a route added via WithRoute().
+type routeConfig struct {
+	// options is the list of the route's own options.
+	options []RouteOption
+
+	unmarshalOptions  []UnmarshalOption
+	requireParameters bool
+	contextValues     []contextValue
+	info              MethodInfo
+}
+
+// contextValue is a key/value pair that is added to the context passed to a
+// route's handler.
+type contextValue struct {
+	key, value any
+}
+
+// resolve populates the configuration by applying the default options
+// followed by the route's own options.
+func (c *routeConfig) resolve(defaults []RouteOption) {
+	for _, opt := range defaults {
+		opt.ApplyToRoute(c)
+	}
+
+	for _, opt := range c.options {
+		opt.ApplyToRoute(c)
+	}
+}
+
+func (c *routeConfig) AddUnmarshalOption(opt UnmarshalOption) {
+	c.unmarshalOptions = append(c.unmarshalOptions, opt)
+}
+
+// WithSummary is a RouteOption that attaches a short description of the
+// method to the route. The description is available via Router.Describe().
+//
+// It does not affect how requests are handled.
+func WithSummary(summary string) RouteOption {
+	return routeOption(func(c *routeConfig) {
+		c.info.Summary = summary
+	})
+}
+
+// WithSchemas is a RouteOption that attaches JSON schemas that describe the
+// method's parameters and result to the route. The schemas are available via
+// Router.Describe().
+//
+// Either schema may be nil. The schemas are not used to validate requests or
+// responses. It panics if either schema is not valid JSON.
+func WithSchemas(params, result json.RawMessage) RouteOption {
+	for _, s := range []json.RawMessage{params, result} {
+		if s != nil && !json.Valid(s) {
+			panic("schema must be valid JSON")
+		}
+	}
+
+	return routeOption(func(c *routeConfig) {
+		c.info.ParamsSchema = params
+		c.info.ResultSchema = result
+	})
+}
+
+// WithContextValue is a RouteOption that adds a value to the context passed to
+// the route's handler.
+//
+// It allows dependencies, such as a database handle, to be provided to
+// handlers without the need for closures. When passed to
+// WithDefaultRouteOptions() the value is added to the context of every route
+// added via WithRoute().
+//
+// key must be comparable and should not be of a built-in type, as per
+// context.WithValue(). It panics if key is nil or not comparable.
+func WithContextValue(key, value any) RouteOption {
+	if key == nil {
+		panic("context value key must not be nil")
+	}
+
+	if !reflect.TypeOf(key).Comparable() {
+		panic("context value key must be comparable")
+	}
+
+	return routeOption(func(c *routeConfig) {
+		c.contextValues = append(
+			c.contextValues,
+			contextValue{key, value},
+		)
+	})
+}
+
+// RequireParameters is a RouteOption that controls whether the route rejects
+// requests that do not have any parameters.
+//
+// When enabled, a request without parameters results in a JSON-RPC "invalid
+// parameters" error. By default, a request without parameters is treated as
+// though it had empty parameters, as per Request.UnmarshalParameters().
+func RequireParameters(require bool) RouteOption {
+	return routeOption(func(c *routeConfig) {
+		c.requireParameters = require
+	})
+}
+
+// errParametersRequired is the cause of the error returned by a route when the
+// RequireParameters() option is used and the request has no parameters.
+var errParametersRequired = errors.New("parameters are required")
+
 // NoResult adapts a "typed" handler function that does not return a JSON-RPC
 // result value so that it can be used with the WithRoute() function.
 func NoResult[P any](
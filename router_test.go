@@ -3,6 +3,7 @@ package harpy_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 
 	. "github.com/dogmatiq/harpy"
 	. "github.com/onsi/ginkgo"
@@ -67,6 +68,371 @@ var _ = Describe("type Router", func() {
 			Expect(called).To(BeTrue())
 		})
 
+		It("applies default route options to all routes (via WithDefaultRouteOptions())", func() {
+			called := false
+			request.Parameters = json.RawMessage(`{"Value": 123, "Unknown": 456}`)
+
+			type Params struct {
+				Value int
+			}
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						called = true
+						Expect(params).To(Equal(Params{Value: 123}))
+						return nil, nil
+					},
+				),
+				WithDefaultRouteOptions(AllowUnknownFields(true)),
+			)
+
+			router.Call(context.Background(), request)
+			Expect(called).To(BeTrue())
+		})
+
+		It("gives precedence to the route's own unmarshal options over the defaults", func() {
+			request.Parameters = json.RawMessage(`{"Value": 123, "Unknown": 456}`)
+
+			type Params struct {
+				Value int
+			}
+
+			router = NewRouter(
+				WithDefaultRouteOptions(AllowUnknownFields(true)),
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						panic("unexpected call")
+					},
+					AllowUnknownFields(false),
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).Error.Code).To(Equal(InvalidParametersCode))
+		})
+
+		It("adds values to the handler's context (via WithContextValue())", func() {
+			type key string
+			called := false
+
+			router = NewRouter(
+				WithDefaultRouteOptions(
+					WithContextValue(key("<default-key>"), "<default-value>"),
+				),
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params []int) (any, error) {
+						called = true
+						Expect(ctx.Value(key("<default-key>"))).To(Equal("<default-value>"))
+						Expect(ctx.Value(key("<key>"))).To(Equal("<value>"))
+						return nil, nil
+					},
+					WithContextValue(key("<key>"), "<value>"),
+				),
+			)
+
+			router.Call(context.Background(), request)
+			Expect(called).To(BeTrue())
+		})
+
+		It("applies default route-only options to all routes (via WithDefaultRouteOptions())", func() {
+			request.Parameters = nil
+
+			router = NewRouter(
+				WithDefaultRouteOptions(
+					RequireParameters(true),
+					WithSummary("<summary>"),
+				),
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params []int) (any, error) {
+						panic("unexpected call")
+					},
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).Error.Code).To(Equal(InvalidParametersCode))
+			Expect(router.Describe()).To(Equal([]MethodInfo{
+				{Name: "<method>", Summary: "<summary>"},
+			}))
+		})
+
+		It("panics if a context value key is nil", func() {
+			Expect(func() {
+				WithContextValue(nil, "<value>")
+			}).To(PanicWith("context value key must not be nil"))
+		})
+
+		It("panics if a context value key is not comparable", func() {
+			Expect(func() {
+				WithContextValue([]int{}, "<value>")
+			}).To(PanicWith("context value key must be comparable"))
+		})
+
+		It("enforces required parameter fields when requested (via WithRoute())", func() {
+			request.Parameters = json.RawMessage(`{"name": "<name>", "note": null}`)
+
+			type Embedded struct {
+				Tag string `json:"tag" jsonrpc:"required"`
+			}
+
+			type Params struct {
+				Embedded
+				Name   string  `json:"name" jsonrpc:"required"`
+				Amount int     `json:"amount,omitempty" jsonrpc:"required"`
+				Note   *string `json:"note" jsonrpc:"required"`
+				Other  string  `json:"other"`
+			}
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						panic("unexpected call")
+					},
+					EnforceRequiredFields(true),
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+
+			var errorRes ErrorResponse
+			Expect(res).To(BeAssignableToTypeOf(errorRes))
+
+			errorRes = res.(ErrorResponse)
+			errorRes.ServerError = nil // remove for comparison
+
+			Expect(errorRes).To(Equal(ErrorResponse{
+				Version:   `2.0`,
+				RequestID: json.RawMessage(`123`),
+				Error: ErrorInfo{
+					Code:    InvalidParametersCode,
+					Message: "missing required fields: tag, amount, note",
+				},
+			}))
+		})
+
+		It("accepts both positional and named parameters when requested (via WithRoute())", func() {
+			type Embedded struct {
+				Tag string `json:"tag"`
+			}
+
+			type Params struct {
+				Name string `json:"name"`
+				Embedded
+				hidden bool
+				Skip   int `json:"-"`
+				Amount int
+			}
+
+			var params []Params
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, p Params) (any, error) {
+						params = append(params, p)
+						return nil, nil
+					},
+					AllowPositionalFields(true),
+				),
+			)
+
+			request.Parameters = json.RawMessage(`["<name>", "<tag>", 10]`)
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+
+			request.Parameters = json.RawMessage(`["<name>"]`)
+			res = router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+
+			request.Parameters = json.RawMessage(`{"name": "<name>", "tag": "<tag>", "Amount": 10}`)
+			res = router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+
+			expect := Params{Name: "<name>", Embedded: Embedded{Tag: "<tag>"}, Amount: 10}
+			Expect(params).To(Equal([]Params{
+				expect,
+				{Name: "<name>"},
+				expect,
+			}))
+		})
+
+		It("enforces required parameter fields that are passed by position", func() {
+			request.Parameters = json.RawMessage(`["<name>"]`)
+
+			type Params struct {
+				Name   string `json:"name" jsonrpc:"required"`
+				Amount int    `json:"amount" jsonrpc:"required"`
+			}
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						panic("unexpected call")
+					},
+					AllowPositionalFields(true),
+					EnforceRequiredFields(true),
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).Error.Code).To(Equal(InvalidParametersCode))
+			Expect(res.(ErrorResponse).Error.Message).To(Equal("missing required fields: amount"))
+		})
+
+		It("returns an error if there are more positional parameters than fields", func() {
+			request.Parameters = json.RawMessage(`["<name>", 10]`)
+
+			type Params struct {
+				Name string `json:"name"`
+			}
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						panic("unexpected call")
+					},
+					AllowPositionalFields(true),
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).Error.Code).To(Equal(InvalidParametersCode))
+		})
+
+		It("does not accept positional parameters for struct types by default", func() {
+			request.Parameters = json.RawMessage(`["<name>"]`)
+
+			type Params struct {
+				Name string `json:"name"`
+			}
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						panic("unexpected call")
+					},
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			Expect(res.(ErrorResponse).Error.Code).To(Equal(InvalidParametersCode))
+		})
+
+		It("calls the handler if all required parameter fields are present", func() {
+			called := false
+			request.Parameters = json.RawMessage(`{"Name": "<name>"}`)
+
+			type Params struct {
+				Name string `json:"name" jsonrpc:"required"`
+			}
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						called = true
+						Expect(params).To(Equal(Params{Name: "<name>"}))
+						return nil, nil
+					},
+					EnforceRequiredFields(true),
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+			Expect(called).To(BeTrue())
+		})
+
+		It("does not enforce required parameter fields by default", func() {
+			called := false
+			request.Parameters = json.RawMessage(`{}`)
+
+			type Params struct {
+				Name string `json:"name" jsonrpc:"required"`
+			}
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						called = true
+						return nil, nil
+					},
+				),
+			)
+
+			router.Call(context.Background(), request)
+			Expect(called).To(BeTrue())
+		})
+
+		It("calls the handler with empty parameters if the request has no parameters", func() {
+			called := false
+			request.Parameters = nil
+
+			type Params struct {
+				Name string `json:"name"`
+			}
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params Params) (any, error) {
+						called = true
+						Expect(params).To(Equal(Params{}))
+						return nil, nil
+					},
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+			Expect(called).To(BeTrue())
+		})
+
+		It("returns an error response if parameters are required but the request has none", func() {
+			request.Parameters = nil
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					func(ctx context.Context, params []int) (any, error) {
+						panic("unexpected call")
+					},
+					RequireParameters(true),
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+
+			var errorRes ErrorResponse
+			Expect(res).To(BeAssignableToTypeOf(errorRes))
+
+			errorRes = res.(ErrorResponse)
+			errorRes.ServerError = nil // remove for comparison
+
+			Expect(errorRes).To(Equal(ErrorResponse{
+				Version:   `2.0`,
+				RequestID: json.RawMessage(`123`),
+				Error: ErrorInfo{
+					Code:    InvalidParametersCode,
+					Message: "parameters are required",
+				},
+			}))
+		})
+
 		It("allows calls to handlers that don't return a result (via NoResult())", func() {
 			called := false
 
@@ -85,6 +451,25 @@ var _ = Describe("type Router", func() {
 			Expect(called).To(BeTrue())
 		})
 
+		It("returns a valid response with a null result for handlers that don't return a result (via NoResult())", func() {
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					NoResult(func(ctx context.Context, params []int) error {
+						return nil
+					}),
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(Equal(SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`123`),
+				Result:    json.RawMessage(`null`),
+			}))
+			Expect(res.Validate()).To(Succeed())
+		})
+
 		It("returns an error response if the parameters can not be unpacked", func() {
 			router = NewRouter(
 				WithRoute(
@@ -113,6 +498,38 @@ var _ = Describe("type Router", func() {
 			}))
 		})
 
+		It("panics if a route refers to a reserved method name", func() {
+			Expect(func() {
+				NewRouter(
+					WithRoute(
+						"rpc.<method>",
+						func(context.Context, []int) (any, error) {
+							panic("unexpected call")
+						},
+					),
+				)
+			}).To(PanicWith("route for 'rpc.<method>' method uses a name reserved for system extensions, use WithReservedMethods() to permit it"))
+		})
+
+		It("allows routes that refer to reserved method names (via WithReservedMethods())", func() {
+			called := false
+			request.Method = "rpc.<method>"
+
+			router = NewRouter(
+				WithRoute(
+					"rpc.<method>",
+					func(ctx context.Context, params []int) (any, error) {
+						called = true
+						return nil, nil
+					},
+				),
+				WithReservedMethods(),
+			)
+
+			router.Call(context.Background(), request)
+			Expect(called).To(BeTrue())
+		})
+
 		It("panics if two routes refer to the same method", func() {
 			Expect(func() {
 				NewRouter(
@@ -122,8 +539,135 @@ var _ = Describe("type Router", func() {
 							panic("not implemented")
 						},
 					),
-					WithRoute(
-						"<method>",
+					WithRoute(
+						"<method>",
+						func(context.Context, []int) (any, error) {
+							panic("not implemented")
+						},
+					),
+				)
+			}).To(PanicWith("duplicate route for '<method>' method"))
+		})
+
+		It("panics if a route's result type can not be marshaled (via WithStrictRoutes())", func() {
+			Expect(func() {
+				NewRouter(
+					WithRoute(
+						"<method>",
+						func(context.Context, []int) (chan int, error) {
+							panic("not implemented")
+						},
+					),
+					WithStrictRoutes(),
+				)
+			}).To(PanicWith("route for '<method>' method has a result type that can not be marshaled: json: unsupported type: chan int"))
+		})
+
+		It("panics if a route's result type is a pointer to a type that can not be marshaled (via WithStrictRoutes())", func() {
+			type Result struct {
+				Func func()
+			}
+
+			Expect(func() {
+				NewRouter(
+					WithStrictRoutes(),
+					WithRoute(
+						"<method>",
+						func(context.Context, []int) (*Result, error) {
+							panic("not implemented")
+						},
+					),
+				)
+			}).To(PanicWith("route for '<method>' method has a result type that can not be marshaled: json: unsupported type: func()"))
+		})
+
+		It("allows routes with result types that can be marshaled (via WithStrictRoutes())", func() {
+			Expect(func() {
+				NewRouter(
+					WithStrictRoutes(),
+					WithRoute(
+						"<method>",
+						func(context.Context, []int) (*struct{ Value int }, error) {
+							panic("not implemented")
+						},
+					),
+					WithRoute(
+						"<no-result>",
+						NoResult(func(context.Context, []int) error {
+							panic("not implemented")
+						}),
+					),
+				)
+			}).NotTo(Panic())
+		})
+
+		It("does not check result types by default", func() {
+			Expect(func() {
+				NewRouter(
+					WithRoute(
+						"<method>",
+						func(context.Context, []int) (chan int, error) {
+							panic("not implemented")
+						},
+					),
+				)
+			}).NotTo(Panic())
+		})
+
+		It("adds a route for each alias (via WithRouteAliases())", func() {
+			var called []string
+
+			router = NewRouter(
+				WithRouteAliases(
+					[]string{"<method>", "<alias>"},
+					func(ctx context.Context, params struct{ Value int }) (any, error) {
+						Expect(params.Value).To(Equal(123))
+						return nil, nil
+					},
+					AllowUnknownFields(true),
+				),
+				WithResponseInterceptor(
+					func(_ context.Context, req Request, res Response) Response {
+						Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+						called = append(called, req.Method)
+						return res
+					},
+				),
+			)
+
+			request.Parameters = json.RawMessage(`{"Value": 123, "Unknown": 456}`)
+			router.Call(context.Background(), request)
+
+			request.Method = "<alias>"
+			router.Call(context.Background(), request)
+
+			Expect(called).To(Equal([]string{"<method>", "<alias>"}))
+		})
+
+		It("panics if an alias refers to a method that already has a route", func() {
+			Expect(func() {
+				NewRouter(
+					WithRoute(
+						"<alias>",
+						func(context.Context, []int) (any, error) {
+							panic("not implemented")
+						},
+					),
+					WithRouteAliases(
+						[]string{"<method>", "<alias>"},
+						func(context.Context, []int) (any, error) {
+							panic("not implemented")
+						},
+					),
+				)
+			}).To(PanicWith("duplicate route for '<alias>' method"))
+		})
+
+		It("panics if the same alias is listed more than once", func() {
+			Expect(func() {
+				NewRouter(
+					WithRouteAliases(
+						[]string{"<method>", "<method>"},
 						func(context.Context, []int) (any, error) {
 							panic("not implemented")
 						},
@@ -156,6 +700,34 @@ var _ = Describe("type Router", func() {
 				Expect(called).To(BeTrue())
 			})
 
+			It("does not call the handler if the context is already canceled", func() {
+				router = NewRouter(
+					WithUntypedRoute(
+						"<method>",
+						func(context.Context, Request) (any, error) {
+							Fail("unexpected call")
+							return nil, nil
+						},
+					),
+				)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				res := router.Call(ctx, request)
+				Expect(res).To(Equal(
+					ErrorResponse{
+						Version:   "2.0",
+						RequestID: json.RawMessage(`123`),
+						Error: ErrorInfo{
+							Code:    CanceledCode,
+							Message: "request canceled",
+						},
+						ServerError: context.Canceled,
+					},
+				))
+			})
+
 			When("the handler succeeds", func() {
 				var result any
 
@@ -182,6 +754,25 @@ var _ = Describe("type Router", func() {
 						Result:    json.RawMessage(`456`),
 					}))
 				})
+
+				It("echoes a null request ID in the response", func() {
+					request.ID = json.RawMessage(`null`)
+
+					res := router.Call(context.Background(), request)
+					Expect(res).To(Equal(SuccessResponse{
+						Version:   `2.0`,
+						RequestID: json.RawMessage(`null`),
+						Result:    json.RawMessage(`456`),
+					}))
+
+					data, err := json.Marshal(res)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(data).To(MatchJSON(`{
+						"jsonrpc": "2.0",
+						"id": null,
+						"result": 456
+					}`))
+				})
 			})
 
 			When("the handler returns an error", func() {
@@ -214,6 +805,23 @@ var _ = Describe("type Router", func() {
 						},
 					}))
 				})
+
+				It("echoes a null request ID in the response", func() {
+					request.ID = json.RawMessage(`null`)
+
+					res := router.Call(context.Background(), request)
+
+					data, err := json.Marshal(res)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(data).To(MatchJSON(`{
+						"jsonrpc": "2.0",
+						"id": null,
+						"error": {
+							"code": 789,
+							"message": "<error>"
+						}
+					}`))
+				})
 			})
 		})
 
@@ -234,6 +842,252 @@ var _ = Describe("type Router", func() {
 				}))
 			})
 		})
+
+		When("there are response interceptors", func() {
+			BeforeEach(func() {
+				router = NewRouter(
+					WithRoute(
+						"<method>",
+						func(_ context.Context, params []int) ([]int, error) {
+							return params, nil
+						},
+					),
+					WithRoute(
+						"<error>",
+						func(_ context.Context, params []int) ([]int, error) {
+							return nil, NewError(789, WithMessage("<message>"))
+						},
+					),
+					WithResponseInterceptor(
+						func(_ context.Context, req Request, res Response) Response {
+							switch res := res.(type) {
+							case SuccessResponse:
+								res.Result = json.RawMessage(`{"result":` + string(res.Result) + `}`)
+								return res
+							case ErrorResponse:
+								res.Error.Message += " (intercepted)"
+								return res
+							default:
+								panic("unexpected response type")
+							}
+						},
+					),
+					WithResponseInterceptor(
+						func(_ context.Context, req Request, res Response) Response {
+							if res, ok := res.(SuccessResponse); ok {
+								res.Result = json.RawMessage(`{"wrapped":` + string(res.Result) + `}`)
+								return res
+							}
+							return res
+						},
+					),
+				)
+			})
+
+			It("passes success responses to each interceptor in order", func() {
+				res := router.Call(context.Background(), request)
+				Expect(res).To(Equal(SuccessResponse{
+					Version:   `2.0`,
+					RequestID: json.RawMessage(`123`),
+					Result:    json.RawMessage(`{"wrapped":{"result":[1,2,3]}}`),
+				}))
+			})
+
+			It("passes error responses to the interceptors", func() {
+				request.Method = "<error>"
+
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				Expect(res.(ErrorResponse).Error).To(Equal(ErrorInfo{
+					Code:    789,
+					Message: "<message> (intercepted)",
+				}))
+			})
+
+			It("passes method-not-found responses to the interceptors", func() {
+				request.Method = "<unknown>"
+
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				Expect(res.(ErrorResponse).Error.Message).To(Equal("method not found (intercepted)"))
+			})
+
+			It("does not invoke the interceptors for notifications", func() {
+				router = NewRouter(
+					WithRoute(
+						"<method>",
+						func(_ context.Context, params []int) ([]int, error) {
+							return params, nil
+						},
+					),
+					WithResponseInterceptor(
+						func(context.Context, Request, Response) Response {
+							panic("unexpected call")
+						},
+					),
+				)
+
+				request.ID = nil
+				err := router.Notify(context.Background(), request)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+		When("blank method names are disallowed", func() {
+			BeforeEach(func() {
+				router = NewRouter(
+					WithUntypedRoute(
+						" ",
+						func(context.Context, Request) (any, error) {
+							panic("unexpected call")
+						},
+					),
+					WithBlankMethods(false),
+				)
+			})
+
+			It("returns an invalid request error if the method name is empty", func() {
+				request.Method = ""
+				res := router.Call(context.Background(), request)
+				Expect(res).To(Equal(ErrorResponse{
+					Version:   `2.0`,
+					RequestID: json.RawMessage(`123`),
+					Error: ErrorInfo{
+						Code:    InvalidRequestCode,
+						Message: "method name must not be empty",
+					},
+				}))
+			})
+
+			It("returns an invalid request error if the method name consists only of whitespace", func() {
+				request.Method = " "
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				Expect(res.(ErrorResponse).Error.Code).To(Equal(InvalidRequestCode))
+			})
+
+			It("routes other methods as usual", func() {
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				Expect(res.(ErrorResponse).Error.Code).To(Equal(MethodNotFoundCode))
+			})
+		})
+
+		When("parameter metadata is enabled", func() {
+			type Params struct {
+				Name string `json:"name"`
+			}
+
+			var (
+				params Params
+				meta   json.RawMessage
+				ok     bool
+			)
+
+			BeforeEach(func() {
+				params, meta, ok = Params{}, nil, false
+
+				router = NewRouter(
+					WithRoute(
+						"<method>",
+						func(ctx context.Context, p Params) (any, error) {
+							params = p
+							meta, ok = ParameterMetadataFromContext(ctx)
+							return nil, nil
+						},
+					),
+					WithParameterMetadata("$meta"),
+				)
+			})
+
+			It("removes the metadata from the parameters and adds it to the context", func() {
+				request.Parameters = json.RawMessage(`{"name": "<name>", "$meta": {"dryRun": true}}`)
+
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+				Expect(params).To(Equal(Params{Name: "<name>"}))
+				Expect(ok).To(BeTrue())
+				Expect(meta).To(MatchJSON(`{"dryRun": true}`))
+			})
+
+			It("does not add metadata to the context if the parameters do not contain the key", func() {
+				request.Parameters = json.RawMessage(`{"name": "<name>"}`)
+
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+				Expect(params).To(Equal(Params{Name: "<name>"}))
+				Expect(ok).To(BeFalse())
+			})
+
+			It("does not modify parameters that are not an object", func() {
+				router = NewRouter(
+					WithRoute(
+						"<method>",
+						func(ctx context.Context, p []int) (any, error) {
+							_, ok = ParameterMetadataFromContext(ctx)
+							return p, nil
+						},
+					),
+					WithParameterMetadata("$meta"),
+				)
+
+				res := router.Call(context.Background(), request)
+				Expect(res).To(Equal(SuccessResponse{
+					Version:   "2.0",
+					RequestID: json.RawMessage(`123`),
+					Result:    json.RawMessage(`[1,2,3]`),
+				}))
+				Expect(ok).To(BeFalse())
+			})
+
+			It("returns an invalid parameters error if the parameters can not be parsed", func() {
+				request.Parameters = json.RawMessage(`{"name": }`)
+
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				Expect(res.(ErrorResponse).Error.Code).To(Equal(InvalidParametersCode))
+			})
+
+			It("includes the request ID in the error response if the parameters can not be parsed", func() {
+				request.Parameters = json.RawMessage(`{"name": }`)
+
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+				Expect(res.(ErrorResponse).RequestID).To(Equal(json.RawMessage(`123`)))
+			})
+
+			It("extracts the metadata from notifications", func() {
+				request.ID = nil
+				request.Parameters = json.RawMessage(`{"name": "<name>", "$meta": 123}`)
+
+				err := router.Notify(context.Background(), request)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(params).To(Equal(Params{Name: "<name>"}))
+				Expect(meta).To(Equal(json.RawMessage(`123`)))
+			})
+
+			It("panics if the key is empty", func() {
+				Expect(func() {
+					WithParameterMetadata("")
+				}).To(PanicWith("the parameter metadata key must not be empty"))
+			})
+		})
+
+		When("blank method names are allowed", func() {
+			It("routes them as usual", func() {
+				router = NewRouter(
+					WithUntypedRoute(
+						"",
+						func(context.Context, Request) (any, error) {
+							return 123, nil
+						},
+					),
+				)
+
+				request.Method = ""
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+			})
+		})
 	})
 
 	Describe("func Notify()", func() {
@@ -262,6 +1116,61 @@ var _ = Describe("type Router", func() {
 				router.Notify(context.Background(), request)
 				Expect(called).To(BeTrue())
 			})
+
+			It("does not call the handler if the context is already canceled", func() {
+				router = NewRouter(
+					WithUntypedRoute(
+						"<method>",
+						func(context.Context, Request) (any, error) {
+							Fail("unexpected call")
+							return nil, nil
+						},
+					),
+				)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				err := router.Notify(ctx, request)
+				Expect(err).To(Equal(context.Canceled))
+			})
+
+			It("returns the error produced by the handler", func() {
+				router = NewRouter(
+					WithRoute(
+						"<method>",
+						NoResult(func(context.Context, []int) error {
+							return errors.New("<error>")
+						}),
+					),
+				)
+
+				err := router.Notify(context.Background(), request)
+				Expect(err).To(MatchError("<error>"))
+			})
+		})
+
+		When("blank method names are disallowed", func() {
+			It("returns an error without calling the handler", func() {
+				router = NewRouter(
+					WithUntypedRoute(
+						"",
+						func(context.Context, Request) (any, error) {
+							panic("unexpected call")
+						},
+					),
+					WithBlankMethods(false),
+				)
+
+				request.Method = ""
+				err := router.Notify(context.Background(), request)
+				Expect(err).To(Equal(
+					NewErrorWithReservedCode(
+						InvalidRequestCode,
+						WithMessage("method name must not be empty"),
+					),
+				))
+			})
 		})
 
 		When("there is no route for the method", func() {
@@ -274,6 +1183,125 @@ var _ = Describe("type Router", func() {
 					router.Notify(context.Background(), request)
 				}).NotTo(Panic())
 			})
+
+			It("calls the unknown notification hook (via WithUnknownNotificationHook())", func() {
+				type contextKey struct{}
+				ctx := context.WithValue(context.Background(), contextKey{}, "<value>")
+
+				var (
+					hookCtx context.Context
+					hookReq Request
+				)
+
+				router = NewRouter(
+					WithUnknownNotificationHook(
+						func(ctx context.Context, req Request) {
+							hookCtx = ctx
+							hookReq = req
+						},
+					),
+				)
+
+				err := router.Notify(ctx, request)
+				Expect(err).To(Equal(MethodNotFound()))
+				Expect(hookReq).To(Equal(request))
+				Expect(hookCtx.Value(contextKey{})).To(Equal("<value>"))
+			})
+
+			It("does not call the unknown notification hook for calls", func() {
+				router = NewRouter(
+					WithUnknownNotificationHook(
+						func(context.Context, Request) {
+							panic("unexpected call")
+						},
+					),
+				)
+
+				request.ID = json.RawMessage(`123`)
+				res := router.Call(context.Background(), request)
+				Expect(res).To(BeAssignableToTypeOf(ErrorResponse{}))
+			})
+		})
+
+		When("the method is rejected before it is routed", func() {
+			It("does not call the unknown notification hook", func() {
+				router = NewRouter(
+					WithBlankMethods(false),
+					WithUnknownNotificationHook(
+						func(context.Context, Request) {
+							panic("unexpected call")
+						},
+					),
+				)
+
+				request.Method = ""
+				err := router.Notify(context.Background(), request)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("func Describe()", func() {
+		It("returns information about each method, sorted by name", func() {
+			router = NewRouter(
+				WithRoute(
+					"b",
+					NoResult(func(context.Context, []int) error { return nil }),
+					WithSummary("<summary>"),
+					WithSchemas(
+						json.RawMessage(`{"type": "array"}`),
+						json.RawMessage(`{"type": "null"}`),
+					),
+				),
+				WithUntypedRoute(
+					"c",
+					func(context.Context, Request) (any, error) { return nil, nil },
+				),
+				WithRoute(
+					"a",
+					NoResult(func(context.Context, []int) error { return nil }),
+				),
+			)
+
+			Expect(router.Describe()).To(Equal([]MethodInfo{
+				{
+					Name: "a",
+				},
+				{
+					Name:         "b",
+					Summary:      "<summary>",
+					ParamsSchema: json.RawMessage(`{"type": "array"}`),
+					ResultSchema: json.RawMessage(`{"type": "null"}`),
+				},
+				{
+					Name: "c",
+				},
+			}))
+		})
+
+		It("does not affect dispatch", func() {
+			called := false
+
+			router = NewRouter(
+				WithRoute(
+					"<method>",
+					NoResult(func(context.Context, []int) error {
+						called = true
+						return nil
+					}),
+					WithSummary("<summary>"),
+				),
+			)
+
+			res := router.Call(context.Background(), request)
+			Expect(res).To(BeAssignableToTypeOf(SuccessResponse{}))
+			Expect(called).To(BeTrue())
+		})
+
+		It("panics if a schema is not valid JSON", func() {
+			Expect(func() {
+				WithSchemas(json.RawMessage(`{`), nil)
+			}).To(PanicWith("schema must be valid JSON"))
 		})
 	})
 })
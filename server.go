@@ -0,0 +1,60 @@
+package harpy
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// Server performs JSON-RPC exchanges using a fixed exchanger, logger and set
+// of limits.
+//
+// It captures the configuration that is common to every exchange so that
+// transports need not supply it each time they handle a request set. Unlike
+// Exchange(), it never constructs a logger on a per-exchange basis, making it
+// suitable for use in hot loops, benchmarks and tests.
+//
+// A Server may be copied, such as to use a different logger for a specific
+// exchange. It is safe for concurrent use provided that its fields are not
+// modified while exchanges are in progress.
+type Server struct {
+	// Exchanger is the exchanger used to obtain a response to each request.
+	Exchanger Exchanger
+
+	// Logger is the target for log messages about JSON-RPC requests and
+	// responses. If it is nil, no logging is performed.
+	Logger ExchangeLogger
+
+	// MaxRequestsPerMethod is the maximum number of requests within a batch
+	// that may target the same method. If it is zero, there is no limit. See
+	// BatchMethodLimiter.
+	MaxRequestsPerMethod int
+}
+
+// nopExchangeLogger is the logger used by a Server that has no logger.
+var nopExchangeLogger ExchangeLogger = NewZapExchangeLogger(zap.NewNop())
+
+// Handle performs a single JSON-RPC exchange, reading a request set from r and
+// writing the responses to w.
+//
+// It is equivalent to calling Exchange() with the server's exchanger and
+// logger. See Exchange() for details.
+func (s *Server) Handle(
+	ctx context.Context,
+	r RequestSetReader,
+	w ResponseWriter,
+) error {
+	if s.MaxRequestsPerMethod != 0 {
+		r = &BatchMethodLimiter{
+			Next:                 r,
+			MaxRequestsPerMethod: s.MaxRequestsPerMethod,
+		}
+	}
+
+	logger := s.Logger
+	if logger == nil {
+		logger = nopExchangeLogger
+	}
+
+	return Exchange(ctx, s.Exchanger, r, w, logger)
+}
@@ -0,0 +1,115 @@
+package harpy_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var _ = Describe("type Server", func() {
+	var (
+		requestSet RequestSet
+		reader     *RequestSetReaderStub
+		writer     *ResponseWriterStub
+		responses  []Response
+		logs       *observer.ObservedLogs
+		server     *Server
+	)
+
+	BeforeEach(func() {
+		requestSet = RequestSet{
+			Requests: []Request{
+				{
+					Version:    "2.0",
+					ID:         json.RawMessage(`123`),
+					Method:     "<method>",
+					Parameters: json.RawMessage(`[1, 2, 3]`),
+				},
+			},
+		}
+
+		reader = &RequestSetReaderStub{
+			ReadFunc: func(context.Context) (RequestSet, error) {
+				return requestSet, nil
+			},
+		}
+
+		responses = nil
+		record := func(res Response) error {
+			responses = append(responses, res)
+			return nil
+		}
+
+		writer = &ResponseWriterStub{
+			WriteErrorFunc: func(res ErrorResponse) error {
+				return record(res)
+			},
+			WriteUnbatchedFunc: record,
+			WriteBatchedFunc:   record,
+			CloseFunc: func() error {
+				return nil
+			},
+		}
+
+		var core zapcore.Core
+		core, logs = observer.New(zapcore.DebugLevel)
+
+		server = &Server{
+			Exchanger: &ExchangerStub{
+				CallFunc: func(_ context.Context, req Request) Response {
+					return NewSuccessResponse(req.ID, req.Parameters)
+				},
+			},
+			Logger: NewZapExchangeLogger(zap.New(core)),
+		}
+	})
+
+	Describe("func Handle()", func() {
+		It("performs an exchange using the server's exchanger and logger", func() {
+			err := server.Handle(context.Background(), reader, writer)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(responses).To(Equal([]Response{
+				NewSuccessResponse(json.RawMessage(`123`), json.RawMessage(`[1, 2, 3]`)),
+			}))
+			Expect(logs.Len()).To(BeNumerically(">", 0))
+		})
+
+		It("does not perform any logging if the logger is nil", func() {
+			server.Logger = nil
+
+			err := server.Handle(context.Background(), reader, writer)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(responses).To(HaveLen(1))
+		})
+
+		It("rejects batches that exceed the per-method limit", func() {
+			server.MaxRequestsPerMethod = 1
+			requestSet = RequestSet{
+				Requests: []Request{
+					{Version: "2.0", ID: json.RawMessage(`1`), Method: "<method>"},
+					{Version: "2.0", ID: json.RawMessage(`2`), Method: "<method>"},
+				},
+				IsBatch: true,
+			}
+
+			err := server.Handle(context.Background(), reader, writer)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(responses).To(Equal([]Response{
+				NewErrorResponse(
+					nil,
+					NewErrorWithReservedCode(
+						InvalidRequestCode,
+						WithMessage(`batch contains more than 1 requests to the "<method>" method`),
+					),
+				),
+			}))
+		})
+	})
+})
@@ -0,0 +1,61 @@
+package harpy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamResult is a result value that is streamed to the client, rather than
+// being marshaled into memory before the response is sent.
+//
+// A handler may return a StreamResult to send a large result, such as the
+// content of a file, without buffering it. The reader is copied verbatim into
+// the "result" property of the JSON-RPC response, so it must produce exactly
+// one complete, valid JSON value. The content is not validated before it is
+// sent.
+//
+// If the reader implements io.Closer it is closed once the result has been
+// sent.
+//
+// Transports that can not stream the result, and middleware that needs to
+// inspect it, read the entire result into memory using
+// SuccessResponse.BufferResult().
+type StreamResult struct {
+	io.Reader
+}
+
+// BufferResult returns a copy of r with the content of r.ResultStream read into
+// r.Result.
+//
+// It returns r unchanged if r.ResultStream is nil. Otherwise, the stream is
+// closed if it implements io.Closer, and the returned response's
+// ResultStream field is nil.
+//
+// It returns an error if the stream can not be read, or if it does not
+// contain valid JSON.
+func (r SuccessResponse) BufferResult() (SuccessResponse, error) {
+	if r.ResultStream == nil {
+		return r, nil
+	}
+
+	stream := r.ResultStream
+	r.ResultStream = nil
+
+	data, err := io.ReadAll(stream)
+	if c, ok := stream.(io.Closer); ok {
+		err = errors.Join(err, c.Close())
+	}
+	if err != nil {
+		return r, fmt.Errorf("unable to read result stream: %w", err)
+	}
+
+	if !json.Valid(data) {
+		return r, errors.New("result stream does not contain valid JSON")
+	}
+
+	r.Result = data
+
+	return r, nil
+}
@@ -0,0 +1,113 @@
+package harpy_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	. "github.com/dogmatiq/harpy"
+	"github.com/dogmatiq/iago/iotest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type SuccessResponse", func() {
+	Describe("func BufferResult()", func() {
+		It("reads the result stream into the result", func() {
+			res := SuccessResponse{
+				Version:      "2.0",
+				RequestID:    json.RawMessage(`123`),
+				ResultStream: strings.NewReader(`[1, 2, 3]`),
+			}
+
+			res, err := res.BufferResult()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res).To(Equal(SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`123`),
+				Result:    json.RawMessage(`[1, 2, 3]`),
+			}))
+		})
+
+		It("closes the result stream", func() {
+			closed := false
+
+			res := SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`123`),
+				ResultStream: readCloserFunc{
+					Reader: strings.NewReader(`123`),
+					CloseFunc: func() error {
+						closed = true
+						return nil
+					},
+				},
+			}
+
+			_, err := res.BufferResult()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(closed).To(BeTrue())
+		})
+
+		It("returns the response unchanged if there is no result stream", func() {
+			res := SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`123`),
+				Result:    json.RawMessage(`456`),
+			}
+
+			buffered, err := res.BufferResult()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(buffered).To(Equal(res))
+		})
+
+		It("returns an error if the result stream can not be read", func() {
+			res := SuccessResponse{
+				Version:      "2.0",
+				RequestID:    json.RawMessage(`123`),
+				ResultStream: iotest.NewFailer(errors.New("<error>"), nil),
+			}
+
+			_, err := res.BufferResult()
+			Expect(err).To(MatchError("unable to read result stream: <error>"))
+		})
+
+		It("returns an error if the result stream can not be closed", func() {
+			res := SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`123`),
+				ResultStream: readCloserFunc{
+					Reader: strings.NewReader(`123`),
+					CloseFunc: func() error {
+						return errors.New("<error>")
+					},
+				},
+			}
+
+			_, err := res.BufferResult()
+			Expect(err).To(MatchError("unable to read result stream: <error>"))
+		})
+
+		It("returns an error if the result stream does not contain valid JSON", func() {
+			res := SuccessResponse{
+				Version:      "2.0",
+				RequestID:    json.RawMessage(`123`),
+				ResultStream: strings.NewReader(`{`),
+			}
+
+			_, err := res.BufferResult()
+			Expect(err).To(MatchError("result stream does not contain valid JSON"))
+		})
+	})
+})
+
+// readCloserFunc is an io.ReadCloser that calls a function when it is closed.
+type readCloserFunc struct {
+	io.Reader
+	CloseFunc func() error
+}
+
+func (r readCloserFunc) Close() error {
+	return r.CloseFunc()
+}
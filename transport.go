@@ -0,0 +1,25 @@
+package harpy
+
+import "context"
+
+// transportKey is the context key used to associate the name of a transport
+// with a context.
+type transportKey struct{}
+
+// WithTransport returns a copy of ctx that is associated with the name of the
+// transport over which a request was received, such as "http".
+//
+// It is intended to be used by transports, such that handlers and loggers can
+// determine how a request arrived when the same exchanger is served over
+// several transports. The transports within this module use the names "http",
+// "tcp" and "local".
+func WithTransport(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, transportKey{}, name)
+}
+
+// TransportFromContext returns the name of the transport associated with ctx,
+// if any.
+func TransportFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(transportKey{}).(string)
+	return name, ok
+}
@@ -0,0 +1,143 @@
+package httptransport
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// unacceptableMediaTypeFormat is the format of the error message to use when a
+// request is received that does not accept any of the MIME media-types that the
+// handler can produce.
+//
+// This constant is used by the ResponseWriter implementation to send a
+// more-specific HTTP status code when this error occurs.
+const unacceptableMediaTypeFormat = "JSON-RPC responses are only available in the %s content type"
+
+// NegotiateResponseMediaType selects the MIME media-type of the response to r
+// from the offered media-types, as determined by its "Accept" header.
+//
+// The offered media-types are listed in order of preference. If r has no
+// "Accept" header, the first offered media-type is selected. Quality values are
+// honored, such that a media-type with a q-value of zero is considered
+// unacceptable. The quality of each offered media-type is determined by the
+// most specific media range that matches it, including the "type/*" and "*/*"
+// wildcards.
+//
+// ok is false if none of the offered media-types are acceptable, in which case
+// the request should be rejected.
+func NegotiateResponseMediaType(r *http.Request, offered ...string) (mediaType string, ok bool) {
+	values := r.Header.Values("Accept")
+	if len(values) == 0 {
+		if len(offered) == 0 {
+			return "", false
+		}
+		return offered[0], true
+	}
+
+	var bestQ float64
+
+	for _, mt := range offered {
+		q := acceptQuality(values, strings.ToLower(mt))
+		if q > bestQ {
+			mediaType, bestQ = mt, q
+		}
+	}
+
+	return mediaType, bestQ > 0
+}
+
+// acceptQuality returns the quality of the media-type mt according to the
+// values of an "Accept" header.
+//
+// It returns zero if mt is not matched by any media range.
+func acceptQuality(values []string, mt string) float64 {
+	typ, _, _ := strings.Cut(mt, "/")
+
+	var (
+		quality     float64
+		specificity = -1
+	)
+
+	for _, v := range values {
+		for _, entry := range strings.Split(v, ",") {
+			mr, q := parseQualityValue(entry)
+
+			s := -1
+			switch mr {
+			case mt:
+				s = 2
+			case typ + "/*":
+				s = 1
+			case "*/*":
+				s = 0
+			}
+
+			if s > specificity {
+				quality, specificity = q, s
+			}
+		}
+	}
+
+	return quality
+}
+
+// responseCodec is a codec that may be used to encode responses, along with the
+// MIME media-type that identifies its wire format.
+type responseCodec struct {
+	Codec     harpy.Codec
+	MediaType string
+}
+
+// responseCodecs returns the codecs that the handler may use to encode
+// responses, in order of preference.
+func (h *Handler) responseCodecs() []responseCodec {
+	return []responseCodec{
+		{h.codec, mediaTypeOrDefault(h.mediaType)},
+	}
+}
+
+// negotiateResponseCodec selects the codec used to encode the response to r,
+// based on its "Accept" header.
+//
+// If none of the handler's codecs are acceptable, it returns the preferred
+// codec and an error that describes the media-types that are available.
+func (h *Handler) negotiateResponseCodec(r *http.Request) (responseCodec, error) {
+	codecs := h.responseCodecs()
+
+	mediaTypes := make([]string, len(codecs))
+	for i, c := range codecs {
+		mediaTypes[i] = c.MediaType
+	}
+
+	mt, ok := NegotiateResponseMediaType(r, mediaTypes...)
+	if !ok {
+		return codecs[0], harpy.NewErrorWithReservedCode(
+			harpy.InvalidRequestCode,
+			harpy.WithMessage(
+				fmt.Sprintf(
+					unacceptableMediaTypeFormat,
+					strings.Join(mediaTypes, ", "),
+				),
+			),
+		)
+	}
+
+	for _, c := range codecs {
+		if c.MediaType == mt {
+			return c, nil
+		}
+	}
+
+	// CODE COVERAGE: This branch can not be reached, as the negotiated
+	// media-type is always one of those offered.
+	return codecs[0], nil
+}
+
+// isUnacceptableMediaTypeMessage returns true if m is an error message produced
+// using unacceptableMediaTypeFormat.
+func isUnacceptableMediaTypeMessage(m string) bool {
+	return matchesMessageFormat(m, unacceptableMediaTypeFormat)
+}
@@ -0,0 +1,39 @@
+package httptransport_test
+
+import (
+	"net/http"
+
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func NegotiateResponseMediaType()", func() {
+	DescribeTable(
+		"it selects the most acceptable media-type",
+		func(header string, expectMediaType string, expectOK bool) {
+			r, err := http.NewRequest(http.MethodPost, "/", http.NoBody)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			if header != "" {
+				r.Header.Set("Accept", header)
+			}
+
+			mt, ok := NegotiateResponseMediaType(r, "application/json", "application/x-other")
+			Expect(mt).To(Equal(expectMediaType))
+			Expect(ok).To(Equal(expectOK))
+		},
+		Entry("no header", "", "application/json", true),
+		Entry("exact match", "application/x-other", "application/x-other", true),
+		Entry("mixed case", "Application/JSON", "application/json", true),
+		Entry("with parameters", "application/json; charset=utf-8", "application/json", true),
+		Entry("type wildcard", "application/*", "application/json", true),
+		Entry("full wildcard", "*/*", "application/json", true),
+		Entry("higher q-value preferred", "application/json;q=0.5, application/x-other", "application/x-other", true),
+		Entry("equal q-values use order of preference", "application/x-other, application/json", "application/json", true),
+		Entry("more specific range takes precedence", "application/*, application/json;q=0", "application/x-other", true),
+		Entry("zero q-value", "application/json;q=0, application/x-other;q=0", "", false),
+		Entry("no match", "text/html", "", false),
+	)
+})
@@ -0,0 +1,117 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// MaxAuditBodySize is the maximum number of bytes of each HTTP request and
+// response body that are recorded for an AuditSink.
+const MaxAuditBodySize = 1024 * 1024
+
+// AuditSink is a function that is called with the raw bytes of each HTTP
+// request body and the corresponding HTTP response body.
+//
+// It is called after the exchange has completed and the response has been
+// written in full.
+type AuditSink func(ctx context.Context, req, res AuditBody)
+
+// AuditBody is the recorded content of an HTTP request or response body.
+type AuditBody struct {
+	// Data is the raw content of the body, up to MaxAuditBodySize bytes.
+	Data []byte
+
+	// Truncated is true if the body is larger than MaxAuditBodySize, in which
+	// case Data contains only the first MaxAuditBodySize bytes.
+	Truncated bool
+}
+
+// WithAuditSink is a HandlerOption that configures the handler to pass the raw
+// request and response bodies of each exchange to sink.
+//
+// Responses are still streamed to the client as they are produced, however
+// a copy of the response body is buffered in memory until the exchange is
+// complete. No buffering is performed unless an audit sink is configured.
+//
+// At most MaxAuditBodySize bytes of each body are recorded. Any part of the
+// request body that is not read by the handler is read before the sink is
+// called, but only until the limit is reached.
+func WithAuditSink(sink AuditSink) HandlerOption {
+	return func(h *Handler) {
+		h.auditSink = sink
+	}
+}
+
+// auditBuffer records up to MaxAuditBodySize bytes of an HTTP body.
+type auditBuffer struct {
+	buf       bytes.Buffer
+	truncated bool
+}
+
+// record appends data to the buffer, discarding any data beyond the limit.
+func (b *auditBuffer) record(data []byte) {
+	if remaining := MaxAuditBodySize - b.buf.Len(); len(data) > remaining {
+		data = data[:remaining]
+		b.truncated = true
+	}
+
+	b.buf.Write(data)
+}
+
+// recorded returns the recorded content of the body.
+func (b *auditBuffer) recorded() AuditBody {
+	return AuditBody{
+		Data:      b.buf.Bytes(),
+		Truncated: b.truncated,
+	}
+}
+
+// auditRequestBody is an io.ReadCloser that records the bytes read from an
+// HTTP request body.
+type auditRequestBody struct {
+	io.ReadCloser
+	auditBuffer
+}
+
+func (b *auditRequestBody) Read(data []byte) (int, error) {
+	n, err := b.ReadCloser.Read(data)
+	b.record(data[:n])
+	return n, err
+}
+
+// drain reads any remaining data from the request body so that it is captured
+// in the buffer.
+//
+// It reads at most one byte more than can be recorded, which is enough to
+// determine whether the body is truncated.
+func (b *auditRequestBody) drain() {
+	if b.truncated {
+		return
+	}
+
+	n := int64(MaxAuditBodySize - b.buf.Len() + 1)
+	io.CopyN(io.Discard, b, n) // nolint:errcheck // best-effort, body is only being recorded
+}
+
+// auditResponseWriter is an http.ResponseWriter that records the bytes written
+// to the HTTP response body.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	auditBuffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.record(data[:n])
+	return n, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+//
+// It allows http.ResponseController to access features of the underlying
+// writer.
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,239 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dogmatiq/harpy"
+	"github.com/dogmatiq/harpy/internal/jsonx"
+)
+
+// BatchCall is a single call within a batch of calls made via
+// Client.CallBatch().
+type BatchCall struct {
+	// Method is the name of the method to call.
+	Method string
+
+	// Params are the parameters to pass to the method.
+	Params any
+
+	// Result is an optional pointer to the value into which the result is
+	// unmarshaled. If it is nil, the result is only available in its JSON
+	// representation, via BatchResult.Result.
+	Result any
+
+	// Options are the options used to unmarshal the result into Result.
+	Options []harpy.UnmarshalOption
+}
+
+// BatchResult is the outcome of a single call within a batch of calls made via
+// Client.CallBatch().
+type BatchResult struct {
+	// Method is the name of the method that was called.
+	Method string
+
+	// RequestID is the JSON representation of the ID of the request, which is
+	// matched against the ID within the response.
+	RequestID json.RawMessage
+
+	// Result is the JSON representation of the result. It is nil if the call
+	// failed.
+	Result json.RawMessage
+
+	// err is the error that caused the call to fail, if any.
+	err error
+}
+
+// Err returns the error that caused the call to fail, or nil if it succeeded.
+//
+// If the server responded with a JSON-RPC error, the error is a harpy.Error.
+// If the server did not respond to the call at all, the error matches
+// *ProtocolError, as per errors.As().
+func (r BatchResult) Err() error {
+	return r.err
+}
+
+// CallBatch invokes several JSON-RPC methods within a single batch request.
+//
+// It returns one result for each call, in the same order as calls, regardless
+// of the order in which the server produced the responses. The JSON-RPC error
+// produced by an individual call does not cause CallBatch() to return an
+// error; it is available via the Err() method of that call's result.
+//
+// It returns an error if the batch as a whole fails, such as when the request
+// can not be sent, the server rejects the batch, or the server produces a
+// response that violates the protocol.
+//
+// c.OnRequestStart and c.OnRequestEnd are called for each call within the
+// batch. The error passed to c.OnRequestEnd is the error returned by
+// CallBatch(), if any, or otherwise the error of that call's result.
+//
+// It panics if calls is empty, or if any of the requests can not be built.
+func (c *Client) CallBatch(
+	ctx context.Context,
+	calls ...BatchCall,
+) (results []BatchResult, err error) {
+	if len(calls) == 0 {
+		panic("unable to call JSON-RPC methods: batch must contain at least one call")
+	}
+
+	defer c.observeBatch(calls)(&results, &err)
+
+	requests := make([]harpy.Request, len(calls))
+	results = make([]BatchResult, len(calls))
+	indices := make(map[string]int, len(calls))
+
+	for i, call := range calls {
+		req := newCallRequest(c.nextRequestID(), call.Method, call.Params)
+
+		if call.Result != nil && !validateResultParameter(call.Result) {
+			panic(fmt.Sprintf(
+				"unable to call JSON-RPC method (%s): result must be a non-nil pointer",
+				call.Method,
+			))
+		}
+
+		indices[string(req.ID)] = i
+		requests[i] = req
+		results[i] = BatchResult{
+			Method:    call.Method,
+			RequestID: req.ID,
+			err: fmt.Errorf(
+				"unable to process JSON-RPC response (%s): %w",
+				call.Method,
+				protocolError("batch response does not contain a response to this request"),
+			),
+		}
+	}
+
+	httpRes, err := c.postRequest(ctx, requests)
+	if err != nil {
+		return nil, fmt.Errorf("unable to call JSON-RPC methods: %w", err)
+	}
+	defer httpRes.Body.Close()
+
+	rs, err := c.unmarshalResponseSet(httpRes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to process JSON-RPC batch response: %w", err)
+	}
+
+	if !rs.IsBatch {
+		// The server may reject the batch as a whole by responding with a
+		// single error response.
+		if res, ok := rs.Responses[0].(harpy.ErrorResponse); ok {
+			return nil, harpy.NewClientSideError(
+				res.Error.Code,
+				res.Error.Message,
+				res.Error.Data,
+			)
+		}
+
+		return nil, fmt.Errorf(
+			"unable to process JSON-RPC batch response: %w",
+			protocolError("unexpected non-batched JSON-RPC success response"),
+		)
+	}
+
+	if !isSuccessStatus(httpRes.StatusCode) {
+		return nil, fmt.Errorf(
+			"unable to process JSON-RPC batch response: %w",
+			protocolError(
+				"unexpected HTTP %d (%s) status code with JSON-RPC batch response",
+				httpRes.StatusCode,
+				http.StatusText(httpRes.StatusCode),
+			),
+		)
+	}
+
+	responded := make([]bool, len(calls))
+
+	for _, res := range rs.Responses {
+		var id json.RawMessage
+		res.UnmarshalRequestID(&id) // nolint:errcheck // always succeeds for json.RawMessage
+
+		i, ok := indices[string(id)]
+		if !ok || responded[i] {
+			return nil, fmt.Errorf(
+				"unable to process JSON-RPC batch response: %w",
+				protocolError("request ID in response (%s) does not match any request in the batch, or is duplicated", id),
+			)
+		}
+
+		responded[i] = true
+		results[i].err = populateBatchResult(calls[i], &results[i], res)
+	}
+
+	return results, nil
+}
+
+// observeBatch is like observe(), but calls c.OnRequestStart and
+// c.OnRequestEnd for each of the given calls.
+//
+// The returned function must be deferred, and passed pointers to the results
+// and error returned to the caller.
+func (c *Client) observeBatch(calls []BatchCall) func(*[]BatchResult, *error) {
+	if c.OnRequestStart != nil {
+		for _, call := range calls {
+			c.OnRequestStart(call.Method)
+		}
+	}
+
+	start := time.Now()
+
+	return func(results *[]BatchResult, err *error) {
+		if c.OnRequestEnd == nil {
+			return
+		}
+
+		d := time.Since(start)
+
+		if p := recover(); p != nil {
+			for _, call := range calls {
+				c.OnRequestEnd(call.Method, d, fmt.Errorf("panic: %v", p))
+			}
+			panic(p)
+		}
+
+		for i, call := range calls {
+			callErr := *err
+			if callErr == nil {
+				callErr = (*results)[i].err
+			}
+
+			c.OnRequestEnd(call.Method, d, callErr)
+		}
+	}
+}
+
+// populateBatchResult populates r with the result of a call within a batch,
+// based on the response to that call.
+//
+// It returns the error that caused the call to fail, if any.
+func populateBatchResult(
+	call BatchCall,
+	r *BatchResult,
+	res harpy.Response,
+) error {
+	switch res := res.(type) {
+	case harpy.SuccessResponse:
+		r.Result = res.Result
+
+		if call.Result != nil {
+			if err := jsonx.Unmarshal(res.Result, call.Result, call.Options...); err != nil {
+				return fmt.Errorf("unable to process JSON-RPC response (%s): unable to unmarshal result: %w", call.Method, err)
+			}
+		}
+
+	case harpy.ErrorResponse:
+		return harpy.NewClientSideError(
+			res.Error.Code,
+			res.Error.Message,
+			res.Error.Data,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package httptransport_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("func CallBatch()", func() {
+	var (
+		ctx     context.Context
+		cancel  context.CancelFunc
+		handler http.Handler
+		server  *httptest.Server
+		client  *Client
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		handler = NewHandler(
+			harpy.NewRouter(
+				harpy.WithRoute(
+					"echo",
+					func(_ context.Context, params any) (any, error) {
+						return params, nil
+					},
+				),
+				harpy.WithRoute(
+					"error",
+					harpy.NoResult(
+						func(_ context.Context, params any) error {
+							return harpy.NewError(
+								123,
+								harpy.WithMessage("<message>"),
+								harpy.WithData(params),
+							)
+						},
+					),
+				),
+			),
+			WithZapLogger(zap.NewNop()),
+		)
+
+		server = httptest.NewServer(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handler.ServeHTTP(w, r)
+			}),
+		)
+
+		client = &Client{
+			URL: server.URL,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		cancel()
+	})
+
+	It("returns the results in the order that the calls were submitted", func() {
+		var first, third []int
+
+		results, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "echo", Params: []int{1}, Result: &first},
+			BatchCall{Method: "error", Params: []int{2}},
+			BatchCall{Method: "echo", Params: []int{3}, Result: &third},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(results).To(HaveLen(3))
+
+		Expect(results[0].Method).To(Equal("echo"))
+		Expect(results[0].RequestID).To(Equal(json.RawMessage(`1`)))
+		Expect(results[0].Result).To(Equal(json.RawMessage(`[1]`)))
+		Expect(results[0].Err()).ShouldNot(HaveOccurred())
+		Expect(first).To(Equal([]int{1}))
+
+		Expect(results[1].Method).To(Equal("error"))
+		Expect(results[1].RequestID).To(Equal(json.RawMessage(`2`)))
+		Expect(results[1].Result).To(BeNil())
+
+		var rpcErr harpy.Error
+		Expect(errors.As(results[1].Err(), &rpcErr)).To(BeTrue())
+		Expect(rpcErr.Code()).To(BeNumerically("==", 123))
+		Expect(rpcErr.Message()).To(Equal("<message>"))
+
+		Expect(results[2].Method).To(Equal("echo"))
+		Expect(results[2].RequestID).To(Equal(json.RawMessage(`3`)))
+		Expect(results[2].Err()).ShouldNot(HaveOccurred())
+		Expect(third).To(Equal([]int{3}))
+	})
+
+	It("returns an error for a call that the server did not respond to", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"jsonrpc": "2.0", "id": 2, "result": 456}]`))
+		})
+
+		results, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "first"},
+			BatchCall{Method: "second"},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(results).To(HaveLen(2))
+
+		Expect(results[0].Err()).To(MatchError("unable to process JSON-RPC response (first): batch response does not contain a response to this request"))
+
+		var protoErr *ProtocolError
+		Expect(errors.As(results[0].Err(), &protoErr)).To(BeTrue())
+
+		Expect(results[1].Err()).ShouldNot(HaveOccurred())
+		Expect(results[1].Result).To(Equal(json.RawMessage(`456`)))
+	})
+
+	It("returns an error for a call with a result that can not be unmarshaled", func() {
+		var result string
+
+		results, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "echo", Params: []int{1}, Result: &result},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(results[0].Err()).To(MatchError(ContainSubstring("unable to process JSON-RPC response (echo): unable to unmarshal result")))
+		Expect(results[0].Result).To(Equal(json.RawMessage(`[1]`)))
+	})
+
+	It("returns an error if the server rejects the batch as a whole", func() {
+		handler = NewHandler(&harpy.Router{}, WithBatches(false), WithZapLogger(zap.NewNop()))
+
+		_, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "echo"},
+		)
+
+		var rpcErr harpy.Error
+		Expect(errors.As(err, &rpcErr)).To(BeTrue())
+		Expect(rpcErr.Code()).To(Equal(harpy.InvalidRequestCode))
+	})
+
+	It("returns an error if the server responds with an unknown request ID", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"jsonrpc": "2.0", "id": 999, "result": 456}]`))
+		})
+
+		_, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "echo"},
+		)
+		Expect(err).To(MatchError("unable to process JSON-RPC batch response: request ID in response (999) does not match any request in the batch, or is duplicated"))
+
+		var protoErr *ProtocolError
+		Expect(errors.As(err, &protoErr)).To(BeTrue())
+	})
+
+	It("returns an error if the server responds with a non-batched success response", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "result": 456}`))
+		})
+
+		_, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "echo"},
+		)
+		Expect(err).To(MatchError("unable to process JSON-RPC batch response: unexpected non-batched JSON-RPC success response"))
+	})
+
+	It("returns an error if the request can not be sent", func() {
+		server.Close()
+
+		_, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "echo"},
+		)
+		Expect(err).To(MatchError(ContainSubstring("unable to call JSON-RPC methods")))
+	})
+
+	It("panics if there are no calls", func() {
+		Expect(func() {
+			client.CallBatch(ctx)
+		}).To(PanicWith("unable to call JSON-RPC methods: batch must contain at least one call"))
+	})
+
+	It("calls the observer functions for each call within the batch", func() {
+		var (
+			started []string
+			ended   []string
+			errs    []error
+		)
+
+		client.OnRequestStart = func(method string) {
+			started = append(started, method)
+		}
+
+		client.OnRequestEnd = func(method string, _ time.Duration, err error) {
+			ended = append(ended, method)
+			errs = append(errs, err)
+		}
+
+		_, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "echo", Params: []int{1}},
+			BatchCall{Method: "error", Params: []int{2}},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(started).To(Equal([]string{"echo", "error"}))
+		Expect(ended).To(Equal([]string{"echo", "error"}))
+		Expect(errs[0]).ShouldNot(HaveOccurred())
+
+		var rpcErr harpy.Error
+		Expect(errors.As(errs[1], &rpcErr)).To(BeTrue())
+		Expect(rpcErr.Code()).To(BeNumerically("==", 123))
+	})
+
+	It("passes the batch error to the observer function for each call if the batch fails", func() {
+		var errs []error
+		client.OnRequestEnd = func(_ string, _ time.Duration, err error) {
+			errs = append(errs, err)
+		}
+
+		server.Close()
+
+		_, err := client.CallBatch(
+			ctx,
+			BatchCall{Method: "echo"},
+			BatchCall{Method: "echo"},
+		)
+		Expect(err).Should(HaveOccurred())
+		Expect(errs).To(Equal([]error{err, err}))
+	})
+
+	It("passes a panic to the observer function for each call", func() {
+		var errs []error
+		client.OnRequestEnd = func(_ string, _ time.Duration, err error) {
+			errs = append(errs, err)
+		}
+
+		Expect(func() {
+			client.CallBatch(
+				ctx,
+				BatchCall{Method: "echo"},
+				BatchCall{Method: "echo", Result: 123},
+			)
+		}).To(PanicWith("unable to call JSON-RPC method (echo): result must be a non-nil pointer"))
+
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0]).To(MatchError("panic: unable to call JSON-RPC method (echo): result must be a non-nil pointer"))
+	})
+
+	It("panics if a result is not a pointer", func() {
+		Expect(func() {
+			client.CallBatch(
+				ctx,
+				BatchCall{Method: "echo", Result: 123},
+			)
+		}).To(PanicWith("unable to call JSON-RPC method (echo): result must be a non-nil pointer"))
+	})
+})
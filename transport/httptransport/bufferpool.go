@@ -0,0 +1,99 @@
+package httptransport
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPooledBufferSize is the maximum capacity of a buffer that is returned to
+// bufferPool. Larger buffers are discarded so that an occasional large request
+// or response does not permanently increase memory usage.
+const maxPooledBufferSize = 64 << 10
+
+// bufferPool is a pool of buffers used to encode HTTP request bodies, and
+// buffered HTTP response bodies.
+var bufferPool = sync.Pool{
+	New: func() any {
+		return &bytes.Buffer{}
+	},
+}
+
+// getBuffer returns an empty buffer from bufferPool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer resets buf and returns it to bufferPool.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// pooledRequestBody is an HTTP request body that reads from a buffer obtained
+// from bufferPool.
+//
+// The HTTP transport may continue to read from the body, and then close it,
+// after http.Client.Do() has returned. The buffer is therefore only returned to
+// the pool once the body has been closed by the transport and the client has
+// called Release(). If the body is never closed, the buffer is left for the
+// garbage collector.
+type pooledRequestBody struct {
+	*bytes.Reader
+
+	buf    *bytes.Buffer
+	refs   atomic.Int32
+	closed atomic.Bool
+}
+
+// newPooledRequestBody returns a request body that reads the content of buf.
+func newPooledRequestBody(buf *bytes.Buffer) *pooledRequestBody {
+	b := &pooledRequestBody{
+		Reader: bytes.NewReader(buf.Bytes()),
+		buf:    buf,
+	}
+
+	// One reference is held by the transport, and released by Close(). The
+	// other is held by the client, and released by Release().
+	b.refs.Store(2)
+
+	return b
+}
+
+// Close releases the transport's reference to the buffer.
+//
+// The transport may close the body more than once; only the first call has any
+// effect.
+func (b *pooledRequestBody) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		b.release()
+	}
+
+	return nil
+}
+
+// Release releases the client's reference to the buffer. It must be called
+// once http.Client.Do() has returned.
+func (b *pooledRequestBody) Release() {
+	b.release()
+}
+
+// GetBody returns a new reader that reads a copy of the body's content.
+//
+// It is used as the http.Request.GetBody function, which is called when the
+// request is redirected. A copy is returned so that the new reader is not
+// affected when the buffer is returned to the pool.
+func (b *pooledRequestBody) GetBody() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(bytes.Clone(b.buf.Bytes()))), nil
+}
+
+func (b *pooledRequestBody) release() {
+	if b.refs.Add(-1) == 0 {
+		putBuffer(b.buf)
+	}
+}
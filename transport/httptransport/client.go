@@ -6,15 +6,63 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
 	"sync/atomic"
+	"time"
 
 	"github.com/dogmatiq/harpy"
 	"github.com/dogmatiq/harpy/internal/jsonx"
 )
 
+// ErrResponseTooLarge indicates that an HTTP response body exceeded the
+// Client's MaxResponseBytes limit.
+var ErrResponseTooLarge = errors.New("response exceeds the maximum size")
+
+// ProtocolError indicates that the server produced a response that violates
+// the JSON-RPC specification or the HTTP transport's conventions, such as a
+// batch response to a non-batched request, or a response with the wrong
+// request ID.
+//
+// It allows callers to distinguish a misbehaving server from an error that was
+// returned by the server as part of its normal operation, which is represented
+// by a harpy.Error.
+type ProtocolError struct {
+	// Message is a description of the violation.
+	Message string
+
+	// Cause is the error that caused the violation to be detected, if any.
+	Cause error
+}
+
+// Error returns the error message.
+func (e *ProtocolError) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+
+	return e.Message + ": " + e.Cause.Error()
+}
+
+// Unwrap returns the cause of the error.
+func (e *ProtocolError) Unwrap() error {
+	return e.Cause
+}
+
+// protocolError returns a new ProtocolError with a formatted message.
+func protocolError(format string, args ...any) *ProtocolError {
+	return &ProtocolError{
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
 // Client is a HTTP-based JSON-RPC client.
+//
+// If the context passed to Call(), CallRaw() or Notify() has a deadline, the
+// time remaining until the deadline is sent to the server in the
+// X-JSONRPC-Deadline header, unless DisableDeadlinePropagation is true. See
+// WithDeadlinePropagation().
 type Client struct {
 	// HTTPClient is the HTTP client used to make requests. If it is nil,
 	// http.DefaultClient is used.
@@ -23,84 +71,123 @@ type Client struct {
 	// URL is the URL of the JSON-RPC server.
 	URL string
 
+	// FailoverURLs is an optional list of URLs of additional JSON-RPC servers
+	// that are used when a server can not be reached.
+	//
+	// If a request can not be sent due to a connection-level failure, such as
+	// a refused connection, it is sent to the next server in the list, with
+	// URL being the first. A server that responds with an HTTP or JSON-RPC
+	// error does not trigger a failover. The last server that was reached
+	// successfully is tried first by subsequent requests.
+	//
+	// If none of the servers can be reached, or the context is canceled before
+	// a server is reached, the returned error matches *FailoverError, as per
+	// errors.As().
+	FailoverURLs []string
+
+	// Codec is the codec used to encode requests and decode responses. If it
+	// is nil, harpy.JSONCodec is used.
+	Codec harpy.Codec
+
+	// MediaType is the MIME media-type of requests and responses. If it is
+	// empty, "application/json" is used.
+	MediaType string
+
+	// MaxResponseBytes is the maximum size of the body of each HTTP response,
+	// in bytes. If it is zero or negative, there is no limit.
+	//
+	// If a response exceeds the limit, Call(), CallRaw() and Notify() return
+	// an error that matches ErrResponseTooLarge, as per errors.Is().
+	MaxResponseBytes int64
+
+	// DisallowTrailingData causes responses that contain data other than
+	// whitespace after the JSON-RPC response to be rejected, rather than the
+	// trailing data being ignored.
+	DisallowTrailingData bool
+
+	// DisableDeadlinePropagation prevents the time remaining until the
+	// context's deadline from being sent to the server in the
+	// X-JSONRPC-Deadline header.
+	DisableDeadlinePropagation bool
+
+	// DeadlineJitter is the maximum amount of time by which the deadline sent
+	// to the server is shortened.
+	//
+	// The time remaining until the context's deadline is reduced by a random
+	// duration between zero and DeadlineJitter before it is sent. This allows
+	// the server to respond before the client gives up, and staggers the
+	// deadlines of concurrent requests that share a context, such that they
+	// do not all expire on the server at the same time. If it is zero, the
+	// remaining time is sent as-is.
+	DeadlineJitter time.Duration
+
+	// OnRequestStart is an optional function that is called when Call(),
+	// CallRaw() or Notify() begins sending a request for the given method.
+	// CallBatch() calls it once for each call within the batch.
+	OnRequestStart func(method string)
+
+	// OnRequestEnd is an optional function that is called when Call(),
+	// CallRaw() or Notify() returns, including when it panics. CallBatch()
+	// calls it once for each call within the batch.
+	//
+	// d is the time elapsed since the request started. err is the error
+	// returned to the caller, which is nil on success. If the call panics, err
+	// describes the panic value.
+	OnRequestEnd func(method string, d time.Duration, err error)
+
+	// GenerateRequestID is an optional function that returns the ID to use
+	// for each "call" request. The ID must marshal to a JSON string or number.
+	// Fractional numbers are permitted, as per the JSON-RPC specification. If
+	// it is nil, sequential integer IDs are used.
+	//
+	// The request ID within each response must be byte-for-byte identical to
+	// the JSON representation of the ID that was sent. The IDs of the calls
+	// within a batch made via CallBatch() must be distinct, otherwise the
+	// responses can not be matched to the calls.
+	GenerateRequestID func() any
+
 	// prevID is the ID of the last "call" request sent. It is incremented by
 	// one to generate the next request ID.
 	prevID uint32 // atomic
+
+	// preferredURL is the index of the last server that was reached
+	// successfully, where zero is URL and subsequent indices refer to
+	// FailoverURLs.
+	preferredURL uint32 // atomic
 }
 
 // Call invokes a JSON-RPC method.
+//
+// The result of a successful call is unmarshaled into result, which must be a
+// non-nil pointer. If result is nil the result is discarded; Call() still
+// verifies that the server produced a success response, returning an error
+// otherwise. It panics if result is neither nil nor a non-nil pointer.
 func (c *Client) Call(
 	ctx context.Context,
 	method string,
 	params, result any,
 	options ...harpy.UnmarshalOption,
-) error {
-	requestID := atomic.AddUint32(&c.prevID, 1)
-	req, err := harpy.NewCallRequest(
-		requestID,
-		method,
-		params,
-	)
-	if err != nil {
-		panic(fmt.Sprintf(
-			"unable to call JSON-RPC method (%s): %s",
-			method,
-			err,
-		))
-	}
+) (err error) {
+	defer c.observe(method)(&err)
 
-	if err, ok := req.ValidateClientSide(); !ok {
-		panic(fmt.Sprintf(
-			"unable to call JSON-RPC method (%s): %s",
-			method,
-			err.Message(),
-		))
-	}
+	req := newCallRequest(c.nextRequestID(), method, params)
 
-	if !validateResultParameter(result) {
+	if result != nil && !validateResultParameter(result) {
 		panic(fmt.Sprintf(
 			"unable to call JSON-RPC method (%s): result must be a non-nil pointer",
 			method,
 		))
 	}
 
-	httpRes, err := c.postSingleRequest(ctx, req)
-	if err != nil {
-		return fmt.Errorf("unable to call JSON-RPC method (%s): %w", method, err)
-	}
-	defer httpRes.Body.Close()
-
-	res, err := c.unmarshalSingleResponse(httpRes)
+	res, err := c.call(ctx, method, req)
 	if err != nil {
-		return fmt.Errorf("unable to process JSON-RPC response (%s): %w", method, err)
-	}
-
-	var requestIDInResponse uint32
-	if err := res.UnmarshalRequestID(&requestIDInResponse); err != nil {
-		return fmt.Errorf(
-			"unable to process JSON-RPC response (%s): request ID in response is expected to be an integer",
-			method,
-		)
-	}
-
-	if requestIDInResponse != requestID {
-		return fmt.Errorf(
-			"unable to process JSON-RPC response (%s): request ID in response (%d) does not match the actual request ID (%d)",
-			method,
-			requestIDInResponse,
-			requestID,
-		)
+		return err
 	}
 
 	switch res := res.(type) {
 	case harpy.SuccessResponse:
-		if httpRes.StatusCode != http.StatusOK {
-			return fmt.Errorf(
-				"unable to process JSON-RPC response (%s): unexpected HTTP %d (%s) status code with JSON-RPC success response",
-				method,
-				httpRes.StatusCode,
-				http.StatusText(httpRes.StatusCode),
-			)
+		if result == nil {
+			return nil
 		}
 
 		if err := jsonx.Unmarshal(res.Result, result, options...); err != nil {
@@ -118,12 +205,96 @@ func (c *Client) Call(
 	return nil
 }
 
+// CallRaw invokes a JSON-RPC method and returns the JSON-RPC response without
+// unmarshaling its result.
+//
+// The response is either a harpy.SuccessResponse or a harpy.ErrorResponse.
+// Unlike Call(), a JSON-RPC error response is returned as a response rather
+// than as an error. This allows the caller to inspect the response in full,
+// such as to defer unmarshaling the result, or to forward the response
+// without re-marshaling it.
+//
+// It returns an error if the request can not be sent, or if the server
+// produces a response that violates the protocol.
+func (c *Client) CallRaw(
+	ctx context.Context,
+	method string,
+	params any,
+) (res harpy.Response, err error) {
+	defer c.observe(method)(&err)
+
+	req := newCallRequest(c.nextRequestID(), method, params)
+	return c.call(ctx, method, req)
+}
+
+// call sends a "call" request to the server and returns its response.
+func (c *Client) call(
+	ctx context.Context,
+	method string,
+	req harpy.Request,
+) (harpy.Response, error) {
+	httpRes, err := c.postRequest(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to call JSON-RPC method (%s): %w", method, err)
+	}
+	defer httpRes.Body.Close()
+
+	res, err := c.unmarshalSingleResponse(httpRes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to process JSON-RPC response (%s): %w", method, err)
+	}
+
+	if err := c.matchRequestID(req, res); err != nil {
+		return nil, fmt.Errorf("unable to process JSON-RPC response (%s): %w", method, err)
+	}
+
+	if _, ok := res.(harpy.SuccessResponse); ok && !isSuccessStatus(httpRes.StatusCode) {
+		return nil, fmt.Errorf(
+			"unable to process JSON-RPC response (%s): %w",
+			method,
+			protocolError(
+				"unexpected HTTP %d (%s) status code with JSON-RPC success response",
+				httpRes.StatusCode,
+				http.StatusText(httpRes.StatusCode),
+			),
+		)
+	}
+
+	return res, nil
+}
+
+// newCallRequest returns a new "call" request for the given method.
+//
+// It panics if the request can not be built or is invalid.
+func newCallRequest(id any, method string, params any) harpy.Request {
+	req, err := harpy.NewCallRequest(id, method, params)
+	if err != nil {
+		panic(fmt.Sprintf(
+			"unable to call JSON-RPC method (%s): %s",
+			method,
+			err,
+		))
+	}
+
+	if err, ok := req.ValidateClientSide(); !ok {
+		panic(fmt.Sprintf(
+			"unable to call JSON-RPC method (%s): %s",
+			method,
+			err.Message(),
+		))
+	}
+
+	return req
+}
+
 // Notify sends a JSON-RPC notification.
 func (c *Client) Notify(
 	ctx context.Context,
 	method string,
 	params any,
-) error {
+) (err error) {
+	defer c.observe(method)(&err)
+
 	req, err := harpy.NewNotifyRequest(
 		method,
 		params,
@@ -144,7 +315,7 @@ func (c *Client) Notify(
 		))
 	}
 
-	httpRes, err := c.postSingleRequest(ctx, req)
+	httpRes, err := c.postRequest(ctx, req)
 	if err != nil {
 		return fmt.Errorf("unable to send JSON-RPC notification (%s): %w", method, err)
 	}
@@ -161,10 +332,13 @@ func (c *Client) Notify(
 	if httpRes.StatusCode < http.StatusBadRequest ||
 		httpRes.StatusCode >= http.StatusInternalServerError {
 		return fmt.Errorf(
-			"unable to process JSON-RPC response (%s): unexpected HTTP %d (%s) status code in response to JSON-RPC notification",
+			"unable to process JSON-RPC response (%s): %w",
 			method,
-			httpRes.StatusCode,
-			http.StatusText(httpRes.StatusCode),
+			protocolError(
+				"unexpected HTTP %d (%s) status code in response to JSON-RPC notification",
+				httpRes.StatusCode,
+				http.StatusText(httpRes.StatusCode),
+			),
 		)
 	}
 
@@ -177,8 +351,9 @@ func (c *Client) Notify(
 		var requestIDInResponse any
 		if err := res.UnmarshalRequestID(&requestIDInResponse); err != nil || requestIDInResponse != nil {
 			return fmt.Errorf(
-				"unable to process JSON-RPC response (%s): request ID in response is expected to be null",
+				"unable to process JSON-RPC response (%s): %w",
 				method,
+				protocolError("request ID in response is expected to be null"),
 			)
 		}
 
@@ -194,64 +369,244 @@ func (c *Client) Notify(
 	// that a server misbehaving this badly should not be trusted, so we still
 	// produce an error.
 	return fmt.Errorf(
-		"unable to process JSON-RPC response (%s): did not expect a successful JSON-RPC response to a notification, HTTP status code is %d (%s)",
+		"unable to process JSON-RPC response (%s): %w",
 		method,
-		httpRes.StatusCode,
-		http.StatusText(httpRes.StatusCode),
+		protocolError(
+			"did not expect a successful JSON-RPC response to a notification, HTTP status code is %d (%s)",
+			httpRes.StatusCode,
+			http.StatusText(httpRes.StatusCode),
+		),
 	)
 }
 
+// nextRequestID returns the ID to use for the next "call" request.
+func (c *Client) nextRequestID() any {
+	if c.GenerateRequestID != nil {
+		return c.GenerateRequestID()
+	}
+
+	return atomic.AddUint32(&c.prevID, 1)
+}
+
+// matchRequestID returns an error if the request ID in res is not the ID of
+// req.
+//
+// The IDs are compared by their JSON representation, such that IDs of any
+// type, including fractional numbers, are matched exactly.
+func (c *Client) matchRequestID(req harpy.Request, res harpy.Response) error {
+	var requestIDInResponse json.RawMessage
+	if err := res.UnmarshalRequestID(&requestIDInResponse); err == nil &&
+		bytes.Equal(requestIDInResponse, req.ID) {
+		return nil
+	}
+
+	if c.GenerateRequestID == nil {
+		var n uint32
+		if err := res.UnmarshalRequestID(&n); err != nil {
+			return protocolError("request ID in response is expected to be an integer")
+		}
+	}
+
+	return protocolError(
+		"request ID in response (%s) does not match the actual request ID (%s)",
+		requestIDInResponse,
+		req.ID,
+	)
+}
+
+// observe calls c.OnRequestStart and returns a function that calls
+// c.OnRequestEnd. The returned function must be deferred, and passed a pointer
+// to the error returned to the caller.
+//
+// If the caller panics, c.OnRequestEnd is called with an error describing the
+// panic, and then the panic is resumed.
+func (c *Client) observe(method string) func(*error) {
+	if c.OnRequestStart != nil {
+		c.OnRequestStart(method)
+	}
+
+	start := time.Now()
+
+	return func(err *error) {
+		if c.OnRequestEnd == nil {
+			return
+		}
+
+		if p := recover(); p != nil {
+			c.OnRequestEnd(method, time.Since(start), fmt.Errorf("panic: %v", p))
+			panic(p)
+		}
+
+		c.OnRequestEnd(method, time.Since(start), *err)
+	}
+}
+
 // unmarshalSingleResponse unmarshals a single (non-batched) JSON-RPC response
 // from a HTTP response.
 func (c *Client) unmarshalSingleResponse(httpRes *http.Response) (harpy.Response, error) {
-	if ct := httpRes.Header.Get("Content-Type"); ct != mediaType {
-		return nil, fmt.Errorf("unexpected content-type in HTTP response (%s)", ct)
-	}
-
-	rs, err := harpy.UnmarshalResponseSet(httpRes.Body)
+	rs, err := c.unmarshalResponseSet(httpRes)
 	if err != nil {
-		return nil, fmt.Errorf("cannot unmarshal JSON-RPC response: %w", err)
+		return nil, err
 	}
 
 	if rs.IsBatch {
-		return nil, errors.New("unexpected JSON-RPC batch response")
+		return nil, protocolError("unexpected JSON-RPC batch response")
 	}
 
 	return rs.Responses[0], nil
 }
 
-// postSingleRequest sends a single (non-batched) request to the server.
-func (c *Client) postSingleRequest(
+// unmarshalResponseSet unmarshals a JSON-RPC response set from a HTTP
+// response.
+func (c *Client) unmarshalResponseSet(httpRes *http.Response) (harpy.ResponseSet, error) {
+	if ct := httpRes.Header.Get("Content-Type"); ct != mediaTypeOrDefault(c.MediaType) {
+		return harpy.ResponseSet{}, protocolError("unexpected content-type in HTTP response (%s)", ct)
+	}
+
+	var options []harpy.ResponseSetOption
+	if c.Codec != nil {
+		options = append(options, harpy.DecodeResponsesWith(c.Codec))
+	}
+	if c.DisallowTrailingData {
+		options = append(options, harpy.DisallowTrailingResponseData(true))
+	}
+
+	var body io.Reader = httpRes.Body
+	var limited *limitedReader
+
+	if c.MaxResponseBytes > 0 {
+		limited = &limitedReader{
+			Reader:    httpRes.Body,
+			Remaining: c.MaxResponseBytes,
+		}
+		body = limited
+	}
+
+	rs, err := harpy.UnmarshalResponseSet(body, options...)
+
+	// Check if the limit was exceeded before checking err, so that the
+	// truncated response is not reported as a parse error.
+	if limited != nil && limited.Exceeded {
+		return harpy.ResponseSet{}, fmt.Errorf("%w of %d bytes", ErrResponseTooLarge, c.MaxResponseBytes)
+	}
+
+	if err != nil {
+		return harpy.ResponseSet{}, &ProtocolError{
+			Message: "cannot unmarshal JSON-RPC response",
+			Cause:   err,
+		}
+	}
+
+	return rs, nil
+}
+
+// postRequest sends a request, or a batch of requests, to the server.
+//
+// If c.FailoverURLs is non-empty, the request is sent to each server in turn
+// until one of them can be reached.
+func (c *Client) postRequest(
 	ctx context.Context,
-	req harpy.Request,
+	req any,
 ) (*http.Response, error) {
-	body := &bytes.Buffer{}
-	if err := json.NewEncoder(body).Encode(req); err != nil {
+	codec := c.Codec
+	if codec == nil {
+		codec = harpy.JSONCodec
+	}
+
+	buf := getBuffer()
+	if err := codec.NewEncoder(buf).Encode(req); err != nil {
 		// CODE COVERAGE: This should never fail as the request has already been
 		// validated.
 		panic(err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, body)
+	if len(c.FailoverURLs) != 0 {
+		// Each attempt requires its own copy of the body, as the transport may
+		// continue to read from the body of a failed attempt.
+		data := bytes.Clone(buf.Bytes())
+		putBuffer(buf)
+
+		return c.postWithFailover(ctx, data)
+	}
+
+	body := newPooledRequestBody(buf)
+	httpReq := c.newHTTPRequest(ctx, c.URL, body)
+
+	// NewRequestWithContext() only populates these fields for well-known body
+	// types, such as *bytes.Buffer.
+	httpReq.ContentLength = int64(buf.Len())
+	httpReq.GetBody = body.GetBody
+
+	res, err := c.httpClient().Do(httpReq)
+	body.Release()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// newHTTPRequest returns a new HTTP request that posts the given body to url.
+func (c *Client) newHTTPRequest(
+	ctx context.Context,
+	url string,
+	body io.Reader,
+) *http.Request {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
 		// CODE COVERAGE: The main failure case for NewRequestWithContext() is
 		// an invalid HTTP method, but we hardcode it here.
 		panic(err)
 	}
 
-	httpReq.Header.Set("Content-Type", mediaType)
+	httpReq.Header.Set("Content-Type", mediaTypeOrDefault(c.MediaType))
 
-	hc := c.HTTPClient
-	if hc == nil {
-		hc = http.DefaultClient
+	if !c.DisableDeadlinePropagation {
+		setDeadlineHeader(httpReq, c.DeadlineJitter)
 	}
 
-	res, err := hc.Do(httpReq)
-	if err != nil {
-		return nil, err
+	return httpReq
+}
+
+// httpClient returns the HTTP client used to make requests.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// limitedReader is an io.Reader that fails once more than a given number of
+// bytes have been read.
+type limitedReader struct {
+	Reader    io.Reader
+	Remaining int64
+	Exceeded  bool
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.Exceeded {
+		return 0, ErrResponseTooLarge
 	}
 
-	return res, nil
+	// Read one byte more than the limit, so that content that is exactly the
+	// size of the limit can be distinguished from content that exceeds it.
+	if int64(len(p)) > r.Remaining+1 {
+		p = p[:r.Remaining+1]
+	}
+
+	n, err := r.Reader.Read(p)
+
+	if int64(n) > r.Remaining {
+		r.Exceeded = true
+		n = int(r.Remaining)
+		err = ErrResponseTooLarge
+	}
+
+	r.Remaining -= int64(n)
+
+	return n, err
 }
 
 // validateResultParameter returns true if r is a valid variable into which a
@@ -0,0 +1,56 @@
+package httptransport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+)
+
+func BenchmarkClientCall(b *testing.B) {
+	// The transport responds without using the network so that the benchmark
+	// measures the work done by the client itself.
+	client := &Client{
+		URL: "http://localhost",
+		HTTPClient: &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				defer r.Body.Close()
+
+				var req struct {
+					ID json.RawMessage `json:"id"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					return nil, err
+				}
+
+				body := `{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":[1,2,3]}`
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": {"application/json"}},
+					Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+					Request:    r,
+				}, nil
+			}),
+		},
+	}
+
+	ctx := context.Background()
+	params := map[string]any{
+		"name":   "<name>",
+		"values": []int{1, 2, 3},
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		var result []int
+		if err := client.Call(ctx, "<method>", params, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package httptransport_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -14,6 +17,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
 )
 
 var _ = Describe("type Client", func() {
@@ -49,6 +53,7 @@ var _ = Describe("type Client", func() {
 					),
 				),
 			),
+			WithZapLogger(zap.NewNop()),
 		)
 
 		server = httptest.NewServer(
@@ -68,6 +73,30 @@ var _ = Describe("type Client", func() {
 	})
 
 	Describe("func Call()", func() {
+		It("does not limit the nesting depth of the result", func() {
+			result := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+
+			handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "result": ` + result + `}`))
+			})
+
+			var v json.RawMessage
+			err := client.Call(ctx, "echo", nil, &v)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(v).To(Equal(json.RawMessage(result)))
+		})
+
+		It("accepts responses that do not exceed the maximum size", func() {
+			client.MaxResponseBytes = 42 // exact size of the response
+
+			params := []int{1, 2, 3}
+			var result []int
+			err := client.Call(ctx, "echo", params, &result)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result).To(Equal(params))
+		})
+
 		It("returns the JSON-RPC result", func() {
 			params := []int{1, 2, 3}
 			var result []int
@@ -76,6 +105,105 @@ var _ = Describe("type Client", func() {
 			Expect(result).To(Equal(params))
 		})
 
+		It("uses the IDs returned by GenerateRequestID", func() {
+			client.GenerateRequestID = func() any {
+				return "abc"
+			}
+
+			next := handler
+			handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				rs, err := harpy.UnmarshalRequestSet(bytes.NewReader(body))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(rs.Requests[0].ID).To(Equal(json.RawMessage(`"abc"`)))
+
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				next.ServeHTTP(w, r)
+			})
+
+			params := []int{1, 2, 3}
+			var result []int
+			err := client.Call(ctx, "echo", params, &result)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result).To(Equal(params))
+		})
+
+		It("matches fractional request IDs echoed by the server", func() {
+			client.GenerateRequestID = func() any {
+				return json.RawMessage(`1.5`)
+			}
+
+			params := []int{1, 2, 3}
+			var result []int
+			err := client.Call(ctx, "echo", params, &result)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result).To(Equal(params))
+		})
+
+		It("sends the request body when the request is redirected", func() {
+			next := handler
+			handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					http.Redirect(w, r, "/redirected", http.StatusTemporaryRedirect)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			})
+
+			params := []int{1, 2, 3}
+			var result []int
+			err := client.Call(ctx, "echo", params, &result)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result).To(Equal(params))
+		})
+
+		It("does not reuse the request body while it is still being read by the transport", func() {
+			bodies := make(chan string, 2)
+
+			client.HTTPClient = &http.Client{
+				Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					// Respond before the body has been read, then read the
+					// body after the client has sent another request.
+					go func() {
+						defer r.Body.Close()
+						time.Sleep(10 * time.Millisecond)
+
+						data, err := io.ReadAll(r.Body)
+						if err != nil {
+							bodies <- err.Error()
+							return
+						}
+
+						bodies <- string(data)
+					}()
+
+					return &http.Response{
+						StatusCode: http.StatusNoContent,
+						Body:       http.NoBody,
+						Request:    r,
+					}, nil
+				}),
+			}
+
+			err := client.Notify(ctx, "<method-1>", []int{1})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = client.Notify(ctx, "<method-2>", []int{2})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			var body1, body2 string
+			Eventually(bodies).Should(Receive(&body1))
+			Eventually(bodies).Should(Receive(&body2))
+
+			Expect([]string{body1, body2}).To(ConsistOf(
+				MatchJSON(`{"jsonrpc": "2.0", "method": "<method-1>", "params": [1]}`),
+				MatchJSON(`{"jsonrpc": "2.0", "method": "<method-2>", "params": [2]}`),
+			))
+		})
+
 		It("returns the JSON-RPC error produced by the server", func() {
 			params := []int{1, 2, 3}
 			var result any
@@ -89,6 +217,9 @@ var _ = Describe("type Client", func() {
 			Expect(rpcErr.Code()).To(BeNumerically("==", 123))
 			Expect(rpcErr.Message()).To(Equal("<message>"))
 
+			var protoErr *ProtocolError
+			Expect(errors.As(err, &protoErr)).To(BeFalse())
+
 			var data []int
 			ok, err = rpcErr.UnmarshalData(&data)
 			Expect(err).ShouldNot(HaveOccurred())
@@ -96,6 +227,19 @@ var _ = Describe("type Client", func() {
 			Expect(data).To(Equal(params))
 		})
 
+		It("discards the JSON-RPC result if the result variable is nil", func() {
+			err := client.Call(ctx, "echo", []int{1, 2, 3}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("returns the JSON-RPC error produced by the server if the result variable is nil", func() {
+			err := client.Call(ctx, "error", []int{1, 2, 3}, nil)
+
+			var rpcErr harpy.Error
+			Expect(errors.As(err, &rpcErr)).To(BeTrue())
+			Expect(rpcErr.Code()).To(BeNumerically("==", 123))
+		})
+
 		It("returns an error if there is a network error", func() {
 			server.Close()
 
@@ -162,7 +306,6 @@ var _ = Describe("type Client", func() {
 					`unable to call JSON-RPC method (<method>): result must be a non-nil pointer`,
 				))
 			},
-			Entry("nil interface", nil),
 			Entry("nil pointer", (*int)(nil)),
 			Entry("non-pointer", "<string>"),
 		)
@@ -194,6 +337,30 @@ var _ = Describe("type Client", func() {
 				Expect(err).To(MatchError("unable to process JSON-RPC response (echo): cannot unmarshal JSON-RPC response: unexpected EOF"))
 			})
 
+			It("returns an error if the response exceeds the maximum size", func() {
+				client.MaxResponseBytes = 50
+
+				params := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+				var result []int
+				err := client.Call(ctx, "echo", params, &result)
+				Expect(err).To(MatchError(ErrResponseTooLarge))
+				Expect(err).To(MatchError("unable to process JSON-RPC response (echo): response exceeds the maximum size of 50 bytes"))
+			})
+
+			It("returns an error if the JSON-RPC response is followed by trailing data and trailing data is disallowed", func() {
+				handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "result": [1]}{"jsonrpc": "2.0", "id": 1, "result": [2]}`))
+				})
+
+				client.DisallowTrailingData = true
+
+				var result []int
+				err := client.Call(ctx, "echo", []int{1}, &result)
+				Expect(err).To(MatchError("unable to process JSON-RPC response (echo): cannot unmarshal JSON-RPC response: unable to parse response: json: invalid data after top-level value"))
+			})
+
 			It("returns an error if the JSON-RPC response is a batch", func() {
 				handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 					w.Header().Set("Content-Type", "application/json")
@@ -209,6 +376,29 @@ var _ = Describe("type Client", func() {
 				var result []int
 				err := client.Call(ctx, "echo", params, &result)
 				Expect(err).To(MatchError("unable to process JSON-RPC response (echo): unexpected JSON-RPC batch response"))
+
+				var protoErr *ProtocolError
+				Expect(errors.As(err, &protoErr)).To(BeTrue())
+			})
+
+			It("accepts a JSON-RPC success with a HTTP status set by SetSuccessStatus()", func() {
+				handler = NewHandler(
+					harpy.NewRouter(
+						harpy.WithRoute(
+							"create",
+							func(ctx context.Context, params []int) ([]int, error) {
+								SetSuccessStatus(ctx, http.StatusCreated)
+								return params, nil
+							},
+						),
+					),
+					WithZapLogger(zap.NewNop()),
+				)
+
+				var result []int
+				err := client.Call(ctx, "create", []int{1, 2, 3}, &result)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result).To(Equal([]int{1, 2, 3}))
 			})
 
 			It("returns an error if server returns a JSON-RPC success with an unexpected HTTP status", func() {
@@ -249,6 +439,29 @@ var _ = Describe("type Client", func() {
 				))
 			})
 
+			It("returns an error if server returns a request ID that is not byte-for-byte identical to a fractional request ID", func() {
+				client.GenerateRequestID = func() any {
+					return json.RawMessage(`1.5`)
+				}
+
+				handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{
+						"jsonrpc": "2.0",
+						"id": 1.50,
+						"result": {}
+					}`))
+				})
+
+				params := []int{1, 2, 3}
+				var result []int
+				err := client.Call(ctx, "echo", params, &result)
+				Expect(err).To(MatchError(
+					`unable to process JSON-RPC response (echo): request ID in response (1.50) does not match the actual request ID (1.5)`,
+				))
+			})
+
 			It("returns an error if server returns a JSON-RPC success response with a mismatched request ID", func() {
 				handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 					w.Header().Set("Content-Type", "application/json")
@@ -266,10 +479,83 @@ var _ = Describe("type Client", func() {
 				Expect(err).To(MatchError(
 					`unable to process JSON-RPC response (echo): request ID in response (123) does not match the actual request ID (1)`,
 				))
+
+				var protoErr *ProtocolError
+				Expect(errors.As(err, &protoErr)).To(BeTrue())
 			})
 		})
 	})
 
+	Describe("func CallRaw()", func() {
+		It("returns the JSON-RPC success response", func() {
+			res, err := client.CallRaw(ctx, "echo", []int{1, 2, 3})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res).To(Equal(harpy.SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`[1,2,3]`),
+			}))
+		})
+
+		It("returns the JSON-RPC error response without returning an error", func() {
+			res, err := client.CallRaw(ctx, "error", []int{1, 2, 3})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			e, ok := res.(harpy.ErrorResponse)
+			Expect(ok).To(BeTrue())
+			Expect(e.RequestID).To(Equal(json.RawMessage(`1`)))
+			Expect(e.Error.Code).To(BeNumerically("==", 123))
+			Expect(e.Error.Message).To(Equal("<message>"))
+			Expect(e.Error.Data).To(MatchJSON(`[1, 2, 3]`))
+		})
+
+		It("returns an error if the server violates the protocol", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"jsonrpc": "2.0", "id": 2, "result": {}}`))
+			})
+
+			_, err := client.CallRaw(ctx, "echo", []int{1, 2, 3})
+			Expect(err).To(MatchError(ContainSubstring("unable to process JSON-RPC response (echo)")))
+
+			var protoErr *ProtocolError
+			Expect(errors.As(err, &protoErr)).To(BeTrue())
+		})
+
+		It("returns an error if the request can not be sent", func() {
+			server.Close()
+
+			_, err := client.CallRaw(ctx, "echo", []int{1, 2, 3})
+			Expect(err).To(MatchError(ContainSubstring("unable to call JSON-RPC method (echo)")))
+		})
+
+		It("calls the request hooks", func() {
+			var (
+				started string
+				ended   string
+			)
+
+			client.OnRequestStart = func(method string) {
+				started = method
+			}
+			client.OnRequestEnd = func(method string, _ time.Duration, err error) {
+				ended = method
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			_, err := client.CallRaw(ctx, "echo", []int{1, 2, 3})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(started).To(Equal("echo"))
+			Expect(ended).To(Equal("echo"))
+		})
+
+		It("panics if the request can not be built", func() {
+			Expect(func() {
+				client.CallRaw(ctx, "echo", 123)
+			}).To(PanicWith(ContainSubstring("unable to call JSON-RPC method (echo)")))
+		})
+	})
+
 	Describe("func Notify()", func() {
 		It("returns nil on success", func() {
 			called := false
@@ -285,6 +571,7 @@ var _ = Describe("type Client", func() {
 						},
 					),
 				),
+				WithZapLogger(zap.NewNop()),
 			)
 
 			params := []int{1, 2, 3}
@@ -390,6 +677,20 @@ var _ = Describe("type Client", func() {
 				Expect(err).To(MatchError("unable to process JSON-RPC response (echo): cannot unmarshal JSON-RPC response: unexpected EOF"))
 			})
 
+			It("returns an error if the response exceeds the maximum size", func() {
+				handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"jsonrpc": "2.0", "id": null, "error": {"code": 123, "message": "<message>"}}`))
+				})
+
+				client.MaxResponseBytes = 10
+
+				params := []int{1, 2, 3}
+				err := client.Notify(ctx, "echo", params)
+				Expect(err).To(MatchError(ErrResponseTooLarge))
+			})
+
 			It("returns an error if the JSON-RPC response is a batch", func() {
 				handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 					w.Header().Set("Content-Type", "application/json")
@@ -461,4 +762,98 @@ var _ = Describe("type Client", func() {
 			})
 		})
 	})
+
+	Describe("request hooks", func() {
+		var (
+			started  []string
+			ended    []string
+			endedErr error
+			duration time.Duration
+		)
+
+		BeforeEach(func() {
+			started = nil
+			ended = nil
+			endedErr = nil
+			duration = 0
+
+			client.OnRequestStart = func(method string) {
+				started = append(started, method)
+			}
+
+			client.OnRequestEnd = func(method string, d time.Duration, err error) {
+				ended = append(ended, method)
+				duration = d
+				endedErr = err
+			}
+		})
+
+		It("calls the hooks when a call succeeds", func() {
+			var result []int
+			err := client.Call(ctx, "echo", []int{1, 2, 3}, &result)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(started).To(Equal([]string{"echo"}))
+			Expect(ended).To(Equal([]string{"echo"}))
+			Expect(endedErr).ShouldNot(HaveOccurred())
+			Expect(duration).To(BeNumerically(">", 0))
+		})
+
+		It("passes the error returned by Call() to the end hook", func() {
+			var result any
+			err := client.Call(ctx, "error", []int{1, 2, 3}, &result)
+			Expect(err).Should(HaveOccurred())
+			Expect(ended).To(Equal([]string{"error"}))
+			Expect(endedErr).To(Equal(err))
+		})
+
+		It("calls the hooks when a notification is sent", func() {
+			err := client.Notify(ctx, "echo", []int{1, 2, 3})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(started).To(Equal([]string{"echo"}))
+			Expect(ended).To(Equal([]string{"echo"}))
+			Expect(endedErr).ShouldNot(HaveOccurred())
+		})
+
+		It("passes the error returned by Notify() to the end hook", func() {
+			server.Close()
+
+			err := client.Notify(ctx, "echo", []int{1, 2, 3})
+			Expect(err).Should(HaveOccurred())
+			Expect(ended).To(Equal([]string{"echo"}))
+			Expect(endedErr).To(Equal(err))
+		})
+
+		It("calls the end hook if the call panics", func() {
+			Expect(func() {
+				var result any
+				client.Call(ctx, "<method>", 123, &result)
+			}).To(PanicWith(
+				`unable to call JSON-RPC method (<method>): parameters must be an array, an object, or null`,
+			))
+
+			Expect(ended).To(Equal([]string{"<method>"}))
+			Expect(endedErr).To(MatchError(
+				`panic: unable to call JSON-RPC method (<method>): parameters must be an array, an object, or null`,
+			))
+		})
+
+		It("calls the end hook if the notification panics", func() {
+			Expect(func() {
+				client.Notify(ctx, "<method>", make(chan struct{}))
+			}).To(PanicWith(
+				`unable to send JSON-RPC notification (<method>): unable to marshal request parameters: json: unsupported type: chan struct {}`,
+			))
+
+			Expect(started).To(Equal([]string{"<method>"}))
+			Expect(ended).To(Equal([]string{"<method>"}))
+			Expect(endedErr).Should(HaveOccurred())
+		})
+	})
 })
+
+// roundTripperFunc is an http.RoundTripper implemented by a function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
@@ -0,0 +1,129 @@
+package httptransport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// WithTrustedProxies is a HandlerOption that configures the handler to trust
+// the X-Forwarded-For and X-Real-IP headers of requests received from proxies
+// with addresses in the given CIDR ranges (for example, "10.0.0.0/8").
+//
+// The client IP address is made available to the exchanger via
+// RemoteAddrFromContext(). Forwarding headers are ignored unless the immediate
+// peer is a trusted proxy, preventing clients from spoofing their address.
+//
+// It panics if any of the ranges are invalid.
+func WithTrustedProxies(cidrs ...string) HandlerOption {
+	var prefixes []netip.Prefix
+
+	for _, cidr := range cidrs {
+		p, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			panic(fmt.Sprintf("invalid trusted proxy range: %s", err))
+		}
+
+		prefixes = append(prefixes, p.Masked())
+	}
+
+	return func(h *Handler) {
+		h.trustedProxies = append(h.trustedProxies, prefixes...)
+	}
+}
+
+// remoteAddrKey is the context key used to store the client IP address.
+type remoteAddrKey struct{}
+
+// RemoteAddrFromContext returns the IP address of the client that made the
+// HTTP request associated with ctx.
+//
+// If the request was received from a trusted proxy (see WithTrustedProxies())
+// the address is taken from the forwarding headers, otherwise it is the address
+// of the immediate peer.
+//
+// ok is false if ctx is not associated with a request served by a Handler.
+func RemoteAddrFromContext(ctx context.Context) (addr string, ok bool) {
+	addr, ok = ctx.Value(remoteAddrKey{}).(string)
+	return addr, ok
+}
+
+// withRemoteAddr returns a copy of r with the client IP address stored in its
+// context.
+func (h *Handler) withRemoteAddr(r *http.Request) *http.Request {
+	ctx := context.WithValue(
+		r.Context(),
+		remoteAddrKey{},
+		h.clientIP(r),
+	)
+
+	return r.WithContext(ctx)
+}
+
+// clientIP returns the IP address of the client that made the request r.
+func (h *Handler) clientIP(r *http.Request) string {
+	peer, err := parseAddr(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	if !h.isTrustedProxy(peer) {
+		return peer.String()
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) != 0 {
+		// Walk the X-Forwarded-For entries from right to left, as the rightmost
+		// entries were added by the proxies closest to this server. The first
+		// entry that is not a trusted proxy is the client. If an entry can not
+		// be parsed, the last valid entry is used.
+		entries := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(entries) - 1; i >= 0; i-- {
+			addr, err := parseAddr(strings.TrimSpace(entries[i]))
+			if err != nil {
+				break
+			}
+
+			peer = addr
+
+			if !h.isTrustedProxy(addr) {
+				break
+			}
+		}
+
+		return peer.String()
+	}
+
+	if addr, err := parseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.String()
+	}
+
+	return peer.String()
+}
+
+// isTrustedProxy returns true if addr is the address of a trusted proxy.
+func (h *Handler) isTrustedProxy(addr netip.Addr) bool {
+	for _, p := range h.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseAddr parses an IP address, optionally with a port.
+func parseAddr(s string) (netip.Addr, error) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	return addr.Unmap(), nil
+}
@@ -0,0 +1,175 @@
+package httptransport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var _ = Describe("func RemoteAddrFromContext()", func() {
+	var exchanger *ExchangerStub
+
+	BeforeEach(func() {
+		exchanger = &ExchangerStub{}
+	})
+
+	serve := func(
+		remoteAddr string,
+		headers map[string]string,
+		options ...HandlerOption,
+	) (string, bool) {
+		var (
+			addr string
+			ok   bool
+		)
+
+		exchanger.CallFunc = func(
+			ctx context.Context,
+			req harpy.Request,
+		) harpy.Response {
+			addr, ok = RemoteAddrFromContext(ctx)
+			return harpy.NewSuccessResponse(req.ID, nil)
+		}
+
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(`{"jsonrpc": "2.0", "id": 123}`),
+		)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("Content-Type", "application/json")
+
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+
+		options = append([]HandlerOption{WithZapLogger(zap.NewNop())}, options...)
+		handler := NewHandler(exchanger, options...)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		return addr, ok
+	}
+
+	It("returns false if the context is not associated with a request", func() {
+		_, ok := RemoteAddrFromContext(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+
+	DescribeTable(
+		"it returns the client IP address",
+		func(remoteAddr string, headers map[string]string, expect string) {
+			addr, ok := serve(
+				remoteAddr,
+				headers,
+				WithTrustedProxies("10.0.0.0/8", "fd00::/8"),
+			)
+			Expect(ok).To(BeTrue())
+			Expect(addr).To(Equal(expect))
+		},
+		Entry(
+			"direct connection",
+			"192.0.2.1:1234",
+			nil,
+			"192.0.2.1",
+		),
+		Entry(
+			"direct IPv6 connection",
+			"[2001:db8::1]:1234",
+			nil,
+			"2001:db8::1",
+		),
+		Entry(
+			"untrusted peer with spoofed X-Forwarded-For header",
+			"192.0.2.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			"192.0.2.1",
+		),
+		Entry(
+			"untrusted peer with spoofed X-Real-IP header",
+			"192.0.2.1:1234",
+			map[string]string{"X-Real-IP": "198.51.100.1"},
+			"192.0.2.1",
+		),
+		Entry(
+			"trusted proxy with X-Forwarded-For header",
+			"10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			"198.51.100.1",
+		),
+		Entry(
+			"trusted proxy chain with X-Forwarded-For header",
+			"10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "203.0.113.1, 198.51.100.1, 10.0.0.2"},
+			"198.51.100.1",
+		),
+		Entry(
+			"trusted IPv6 proxy with X-Forwarded-For header",
+			"[fd00::1]:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			"198.51.100.1",
+		),
+		Entry(
+			"trusted proxy with X-Real-IP header",
+			"10.0.0.1:1234",
+			map[string]string{"X-Real-IP": "198.51.100.1"},
+			"198.51.100.1",
+		),
+		Entry(
+			"trusted proxy with invalid X-Forwarded-For header",
+			"10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "<invalid>"},
+			"10.0.0.1",
+		),
+		Entry(
+			"trusted proxy without forwarding headers",
+			"10.0.0.1:1234",
+			nil,
+			"10.0.0.1",
+		),
+	)
+
+	It("ignores forwarding headers if there are no trusted proxies", func() {
+		addr, ok := serve(
+			"10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"},
+		)
+		Expect(ok).To(BeTrue())
+		Expect(addr).To(Equal("10.0.0.1"))
+	})
+
+	It("includes the client IP address in log messages", func() {
+		core, logs := observer.New(zapcore.DebugLevel)
+
+		serve(
+			"10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			WithTrustedProxies("10.0.0.0/8"),
+			WithZapLogger(zap.New(core)),
+		)
+
+		Expect(logs.AllUntimed()).ToNot(BeEmpty())
+		for _, entry := range logs.AllUntimed() {
+			Expect(entry.ContextMap()).To(HaveKeyWithValue("client_ip", "198.51.100.1"))
+			Expect(entry.ContextMap()).To(HaveKeyWithValue("remote_addr", "10.0.0.1:1234"))
+		}
+	})
+})
+
+var _ = Describe("func WithTrustedProxies()", func() {
+	It("panics if a range is invalid", func() {
+		Expect(func() {
+			WithTrustedProxies("<invalid>")
+		}).To(PanicWith(MatchRegexp(`^invalid trusted proxy range: `)))
+	})
+})
@@ -0,0 +1,83 @@
+package httptransport
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineHeader is the HTTP header used to convey the time remaining until the
+// client's deadline, in milliseconds.
+//
+// The remaining duration is sent, rather than an absolute time, so that the
+// deadline is unaffected by any difference between the client and server
+// clocks. It does not account for the time taken to transmit the request.
+const DeadlineHeader = "X-JSONRPC-Deadline"
+
+// WithDeadlinePropagation is a HandlerOption that applies the deadline sent by
+// the client in the X-JSONRPC-Deadline header to the context passed to the
+// exchanger.
+//
+// The header is ignored if it is absent or malformed, or if it is too large to
+// be represented as a time.Duration. The client's deadline
+// can only shorten the time available to the exchanger; it never extends a
+// deadline that is already present on the request's context.
+func WithDeadlinePropagation() HandlerOption {
+	return func(h *Handler) {
+		h.propagateDeadlines = true
+	}
+}
+
+// maxDeadlineMilliseconds is the largest value of the DeadlineHeader header
+// that can be represented as a time.Duration.
+const maxDeadlineMilliseconds = math.MaxInt64 / int64(time.Millisecond)
+
+// withDeadlineFromHeader returns a copy of r with a context that has the
+// deadline specified by the client in the DeadlineHeader header.
+//
+// The returned cancel function must be called once the request is complete.
+func withDeadlineFromHeader(r *http.Request) (*http.Request, context.CancelFunc) {
+	header := r.Header.Get(DeadlineHeader)
+	if header == "" {
+		return r, func() {}
+	}
+
+	ms, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || ms < 0 || ms > maxDeadlineMilliseconds {
+		return r, func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(
+		r.Context(),
+		time.Duration(ms)*time.Millisecond,
+	)
+
+	return r.WithContext(ctx), cancel
+}
+
+// setDeadlineHeader sets the DeadlineHeader header on r if its context has a
+// deadline.
+//
+// The time remaining until the deadline is reduced by a random duration of up
+// to jitter.
+func setDeadlineHeader(r *http.Request, jitter time.Duration) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		return
+	}
+
+	remaining := time.Until(deadline)
+	if jitter > 0 {
+		remaining -= rand.N(jitter + 1)
+	}
+
+	ms := remaining.Milliseconds()
+	if ms < 0 {
+		ms = 0
+	}
+
+	r.Header.Set(DeadlineHeader, strconv.FormatInt(ms, 10))
+}
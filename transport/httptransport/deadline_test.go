@@ -0,0 +1,188 @@
+package httptransport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("func WithDeadlinePropagation()", func() {
+	var exchanger *ExchangerStub
+
+	BeforeEach(func() {
+		exchanger = &ExchangerStub{}
+	})
+
+	// serve serves a request with the given deadline header and returns the
+	// context passed to the exchanger.
+	serve := func(header string, options ...HandlerOption) context.Context {
+		var ctx context.Context
+
+		exchanger.CallFunc = func(c context.Context, req harpy.Request) harpy.Response {
+			ctx = c
+			return harpy.NewSuccessResponse(req.ID, nil)
+		}
+
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(`{"jsonrpc": "2.0", "id": 123}`),
+		)
+		r.Header.Set("Content-Type", "application/json")
+
+		if header != "" {
+			r.Header.Set(DeadlineHeader, header)
+		}
+
+		options = append([]HandlerOption{WithZapLogger(zap.NewNop())}, options...)
+		NewHandler(exchanger, options...).ServeHTTP(httptest.NewRecorder(), r)
+
+		return ctx
+	}
+
+	It("applies the deadline sent by the client", func() {
+		start := time.Now()
+		ctx := serve("5000", WithDeadlinePropagation())
+
+		deadline, ok := ctx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(deadline).To(BeTemporally("~", start.Add(5*time.Second), time.Second))
+	})
+
+	It("ignores the deadline header if the option is not used", func() {
+		ctx := serve("5000")
+
+		_, ok := ctx.Deadline()
+		Expect(ok).To(BeFalse())
+	})
+
+	DescribeTable(
+		"it ignores invalid deadline headers",
+		func(header string) {
+			ctx := serve(header, WithDeadlinePropagation())
+
+			_, ok := ctx.Deadline()
+			Expect(ok).To(BeFalse())
+		},
+		Entry("non-numeric", "<invalid>"),
+		Entry("negative", "-1"),
+		Entry("fractional", "1.5"),
+		Entry("too large to represent as a duration", "9223372036855"),
+		Entry("maximum int64", "9223372036854775807"),
+	)
+
+	It("accepts the largest deadline that can be represented as a duration", func() {
+		ctx := serve("9223372036854", WithDeadlinePropagation())
+
+		deadline, ok := ctx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(deadline).To(BeTemporally(">", time.Now().Add(100*365*24*time.Hour)))
+	})
+})
+
+var _ = Describe("type Client (deadline propagation)", func() {
+	var (
+		header string
+		server *httptest.Server
+		client *Client
+	)
+
+	BeforeEach(func() {
+		header = ""
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Get(DeadlineHeader)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		client = &Client{
+			URL: server.URL,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("sends the time remaining until the context deadline", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := client.Notify(ctx, "<method>", []int{})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		ms, err := strconv.Atoi(header)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ms).To(BeNumerically("~", 5000, 1000))
+	})
+
+	It("does not send the header if the context has no deadline", func() {
+		err := client.Notify(context.Background(), "<method>", []int{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(header).To(BeEmpty())
+	})
+
+	It("does not send the header if DisableDeadlinePropagation is true", func() {
+		client.DisableDeadlinePropagation = true
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := client.Notify(ctx, "<method>", []int{})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(header).To(BeEmpty())
+	})
+
+	It("shortens the remaining time by up to DeadlineJitter", func() {
+		client.DeadlineJitter = 2 * time.Second
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		for i := 0; i < 10; i++ {
+			err := client.Notify(ctx, "<method>", []int{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			ms, err := strconv.Atoi(header)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ms).To(BeNumerically(">=", 3000-500))
+			Expect(ms).To(BeNumerically("<=", 5000))
+		}
+	})
+
+	It("propagates the deadline to the exchanger end-to-end", func() {
+		var deadline time.Time
+
+		client = NewInProcessClient(
+			&ExchangerStub{
+				CallFunc: func(ctx context.Context, req harpy.Request) harpy.Response {
+					deadline, _ = ctx.Deadline()
+					return harpy.NewSuccessResponse(req.ID, nil)
+				},
+			},
+			WithZapLogger(zap.NewNop()),
+			WithDeadlinePropagation(),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var result any
+		err := client.Call(ctx, "<method>", []int{}, &result)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		expect, _ := ctx.Deadline()
+		Expect(deadline).To(BeTemporally("~", expect, time.Second))
+	})
+})
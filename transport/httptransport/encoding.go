@@ -0,0 +1,90 @@
+package httptransport
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NegotiateRequestEncoding determines the content-coding of the body of r, as
+// specified by its "Content-Encoding" header.
+//
+// gzip is true if the body is gzip-compressed. ok is false if the body uses a
+// content-coding that is not supported by this package, in which case the
+// request should be rejected.
+func NegotiateRequestEncoding(r *http.Request) (gzip, ok bool) {
+	for _, v := range r.Header.Values("Content-Encoding") {
+		for _, coding := range strings.Split(v, ",") {
+			switch strings.ToLower(strings.TrimSpace(coding)) {
+			case "", "identity":
+			case "gzip", "x-gzip":
+				if gzip {
+					// Multiple gzip encodings are technically possible but
+					// not supported.
+					return false, false
+				}
+				gzip = true
+			default:
+				return false, false
+			}
+		}
+	}
+
+	return gzip, true
+}
+
+// NegotiateResponseEncoding returns true if the response to r may be
+// gzip-compressed, as determined by its "Accept-Encoding" header.
+//
+// Quality values are honored, such that a coding with a q-value of zero is
+// considered unacceptable. The "*" wildcard matches gzip unless gzip is listed
+// explicitly.
+func NegotiateResponseEncoding(r *http.Request) (gzip bool) {
+	var (
+		explicit bool
+		wildcard bool
+	)
+
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, entry := range strings.Split(v, ",") {
+			coding, q := parseQualityValue(entry)
+
+			switch coding {
+			case "gzip", "x-gzip":
+				explicit = true
+				gzip = q > 0
+			case "*":
+				wildcard = q > 0
+			}
+		}
+	}
+
+	if explicit {
+		return gzip
+	}
+
+	return wildcard
+}
+
+// parseQualityValue parses an entry from a header that supports "q" parameters,
+// such as "Accept-Encoding", returning the lower-case value and its quality.
+//
+// Entries without a valid q-value have a quality of 1.
+func parseQualityValue(entry string) (value string, q float64) {
+	value, params, _ := strings.Cut(entry, ";")
+	value = strings.ToLower(strings.TrimSpace(value))
+	q = 1
+
+	for _, p := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(k), "q") {
+			continue
+		}
+
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			q = f
+		}
+	}
+
+	return value, q
+}
@@ -0,0 +1,62 @@
+package httptransport_test
+
+import (
+	"net/http"
+
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func NegotiateRequestEncoding()", func() {
+	DescribeTable(
+		"it determines the content-coding of the request body",
+		func(header string, expectGzip, expectOK bool) {
+			r, err := http.NewRequest(http.MethodPost, "/", http.NoBody)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			if header != "" {
+				r.Header.Set("Content-Encoding", header)
+			}
+
+			gzip, ok := NegotiateRequestEncoding(r)
+			Expect(gzip).To(Equal(expectGzip))
+			Expect(ok).To(Equal(expectOK))
+		},
+		Entry("no header", "", false, true),
+		Entry("identity", "identity", false, true),
+		Entry("gzip", "gzip", true, true),
+		Entry("x-gzip", "x-gzip", true, true),
+		Entry("mixed case", "GZip", true, true),
+		Entry("identity and gzip", "identity, gzip", true, true),
+		Entry("unsupported coding", "br", false, false),
+		Entry("gzip applied more than once", "gzip, gzip", false, false),
+	)
+})
+
+var _ = Describe("func NegotiateResponseEncoding()", func() {
+	DescribeTable(
+		"it determines whether gzip is acceptable",
+		func(header string, expect bool) {
+			r, err := http.NewRequest(http.MethodPost, "/", http.NoBody)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			if header != "" {
+				r.Header.Set("Accept-Encoding", header)
+			}
+
+			Expect(NegotiateResponseEncoding(r)).To(Equal(expect))
+		},
+		Entry("no header", "", false),
+		Entry("gzip", "gzip", true),
+		Entry("gzip amongst other codings", "br, gzip, deflate", true),
+		Entry("gzip with non-zero q-value", "gzip;q=0.5", true),
+		Entry("gzip with zero q-value", "gzip;q=0", false),
+		Entry("gzip with zero q-value and spaces", "gzip ; q=0.000", false),
+		Entry("wildcard", "*", true),
+		Entry("wildcard with zero q-value", "*;q=0", false),
+		Entry("wildcard with gzip explicitly disallowed", "*, gzip;q=0", false),
+		Entry("other codings only", "br, deflate", false),
+	)
+})
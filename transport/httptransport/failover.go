@@ -0,0 +1,79 @@
+package httptransport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// FailoverError indicates that a Client with failover URLs was unable to
+// reach any of its JSON-RPC servers.
+type FailoverError struct {
+	// URLs is the list of URLs that were attempted, in the order that they
+	// were attempted.
+	URLs []string
+
+	// Causes contains the error that caused each attempt to fail, such that
+	// Causes[i] is the error that occurred when attempting URLs[i].
+	Causes []error
+}
+
+// Error returns the error message.
+func (e *FailoverError) Error() string {
+	msg := fmt.Sprintf(
+		"unable to reach any JSON-RPC server (attempted %s)",
+		strings.Join(e.URLs, ", "),
+	)
+
+	if n := len(e.Causes); n != 0 {
+		msg += ": " + e.Causes[n-1].Error()
+	}
+
+	return msg
+}
+
+// Unwrap returns the errors that caused each attempt to fail.
+func (e *FailoverError) Unwrap() []error {
+	return e.Causes
+}
+
+// postWithFailover posts data to each of the client's servers in turn until
+// one of them can be reached.
+//
+// It starts with the last server that was reached successfully. Only errors
+// returned by the HTTP client, which indicate that the server could not be
+// reached, cause the next server to be attempted. No further servers are
+// attempted once ctx is canceled.
+func (c *Client) postWithFailover(
+	ctx context.Context,
+	data []byte,
+) (*http.Response, error) {
+	urls := append([]string{c.URL}, c.FailoverURLs...)
+	start := int(atomic.LoadUint32(&c.preferredURL))
+	failure := &FailoverError{}
+
+	for i := range urls {
+		if i > 0 && ctx.Err() != nil {
+			break
+		}
+
+		index := (start + i) % len(urls)
+		url := urls[index]
+
+		httpReq := c.newHTTPRequest(ctx, url, bytes.NewReader(data))
+		res, err := c.httpClient().Do(httpReq)
+
+		if err == nil {
+			atomic.StoreUint32(&c.preferredURL, uint32(index))
+			return res, nil
+		}
+
+		failure.URLs = append(failure.URLs, url)
+		failure.Causes = append(failure.Causes, err)
+	}
+
+	return nil, failure
+}
@@ -0,0 +1,139 @@
+package httptransport_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+// recordingTransport is an http.RoundTripper that records the URL of each
+// request before forwarding it to http.DefaultTransport.
+type recordingTransport struct {
+	URLs []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.URLs = append(t.URLs, req.URL.String())
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+var _ = Describe("type Client (failover)", func() {
+	var (
+		ctx         context.Context
+		cancel      context.CancelFunc
+		transport   *recordingTransport
+		live        *httptest.Server
+		unreachable string
+		client      *Client
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+
+		live = httptest.NewServer(
+			NewHandler(
+				harpy.NewRouter(
+					harpy.WithRoute(
+						"echo",
+						func(_ context.Context, params any) (any, error) {
+							return params, nil
+						},
+					),
+					harpy.WithRoute(
+						"error",
+						harpy.NoResult(
+							func(context.Context, any) error {
+								return harpy.NewError(123, harpy.WithMessage("<message>"))
+							},
+						),
+					),
+				),
+				WithZapLogger(zap.NewNop()),
+			),
+		)
+
+		closed := httptest.NewServer(http.NotFoundHandler())
+		unreachable = closed.URL
+		closed.Close()
+
+		transport = &recordingTransport{}
+
+		client = &Client{
+			HTTPClient:   &http.Client{Transport: transport},
+			URL:          unreachable,
+			FailoverURLs: []string{live.URL},
+		}
+	})
+
+	AfterEach(func() {
+		live.Close()
+		cancel()
+	})
+
+	It("sends the request to the next server if a server can not be reached", func() {
+		var result []int
+		err := client.Call(ctx, "echo", []int{1, 2, 3}, &result)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result).To(Equal([]int{1, 2, 3}))
+		Expect(transport.URLs).To(Equal([]string{unreachable, live.URL}))
+	})
+
+	It("sends subsequent requests to the last server that was reached", func() {
+		var result []int
+		err := client.Call(ctx, "echo", []int{1, 2, 3}, &result)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		transport.URLs = nil
+
+		err = client.Call(ctx, "echo", []int{4, 5, 6}, &result)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result).To(Equal([]int{4, 5, 6}))
+		Expect(transport.URLs).To(Equal([]string{live.URL}))
+	})
+
+	It("does not fail over if the server responds with a JSON-RPC error", func() {
+		client.URL = live.URL
+		client.FailoverURLs = []string{unreachable}
+
+		var result any
+		err := client.Call(ctx, "error", nil, &result)
+
+		var rpcErr harpy.Error
+		Expect(errors.As(err, &rpcErr)).To(BeTrue())
+		Expect(rpcErr.Code()).To(BeNumerically("==", 123))
+		Expect(transport.URLs).To(Equal([]string{live.URL}))
+	})
+
+	It("returns a FailoverError if none of the servers can be reached", func() {
+		client.FailoverURLs = []string{unreachable + "/other"}
+
+		var result any
+		err := client.Call(ctx, "echo", nil, &result)
+		Expect(err).To(MatchError(ContainSubstring("unable to call JSON-RPC method (echo): unable to reach any JSON-RPC server (attempted " + unreachable + ", " + unreachable + "/other)")))
+
+		var failover *FailoverError
+		Expect(errors.As(err, &failover)).To(BeTrue())
+		Expect(failover.URLs).To(Equal([]string{unreachable, unreachable + "/other"}))
+		Expect(failover.Causes).To(HaveLen(2))
+	})
+
+	It("does not attempt further servers once the context is canceled", func() {
+		cancel()
+
+		var result any
+		err := client.Call(ctx, "echo", nil, &result)
+		Expect(err).To(MatchError(context.Canceled))
+
+		var failover *FailoverError
+		Expect(errors.As(err, &failover)).To(BeTrue())
+		Expect(failover.URLs).To(Equal([]string{unreachable}))
+	})
+})
@@ -1,43 +1,467 @@
 package httptransport
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/netip"
 
 	"github.com/dogmatiq/harpy"
 	"go.uber.org/zap"
 )
 
-// mediaType is the MIME media-type for JSON-RPC requests and responses when
-// delivered over HTTP.
+// mediaType is the default MIME media-type for JSON-RPC requests and responses
+// when delivered over HTTP.
 const mediaType = "application/json"
 
+// mediaTypeOrDefault returns mt, or the default media-type if mt is empty.
+func mediaTypeOrDefault(mt string) string {
+	if mt == "" {
+		return mediaType
+	}
+	return mt
+}
+
+// isJSONCodec returns true if c is nil or harpy.JSONCodec.
+func isJSONCodec(c harpy.Codec) bool {
+	return c == nil || c == harpy.JSONCodec
+}
+
+// serverAtCapacity is the error message to use when a request is canceled while
+// waiting for the number of concurrent requests to drop below the limit.
+//
+// This constant is used by the ResponseWriter implementation to send a
+// more-specific HTTP status code when this error occurs.
+const serverAtCapacity = "the server is handling too many concurrent requests"
+
 // Handler is an implementation of http.Handler that provides an HTTP-based
 // transport for a JSON-RPC server.
 type Handler struct {
-	// Exchanger performs JSON-RPC exchanges.
-	exchanger harpy.Exchanger
+	// server performs JSON-RPC exchanges. Its logger is set separately for
+	// each HTTP request.
+	server harpy.Server
 
 	// newLogger returns the target for log messages about JSON-RPC requests and
 	// responses.
 	//
 	// If it is nil, a harpy.DefaultExchangeLogger is used.
 	newLogger func(*http.Request) harpy.ExchangeLogger
+
+	// auditSink is called with the raw request and response bodies of each
+	// exchange. If it is nil, no auditing is performed.
+	auditSink AuditSink
+
+	// semaphore limits the number of concurrent exchanges. If it is nil, there
+	// is no limit.
+	semaphore chan struct{}
+
+	// disallowBatches controls whether batch requests are rejected.
+	disallowBatches bool
+
+	// maxRequestsPerMethod is the maximum number of requests within a batch
+	// that may target the same method. If it is zero, there is no limit.
+	maxRequestsPerMethod int
+
+	// disallowTrailingData controls whether requests that contain data after
+	// the request set are rejected.
+	disallowTrailingData bool
+
+	// streamBatches controls whether batched responses are flushed to the
+	// client as soon as they are written.
+	streamBatches bool
+
+	// multipleRequestSets controls whether a single HTTP request body may
+	// contain several request sets.
+	multipleRequestSets bool
+
+	// disableHTMLEscaping controls whether HTML characters within JSON strings
+	// are written verbatim.
+	disableHTMLEscaping bool
+
+	// indent is the string used to indent JSON responses.
+	indent string
+
+	// compressResponses controls whether responses are gzip-compressed when
+	// the client accepts it.
+	compressResponses bool
+
+	// minCompressionSize is the minimum size, in bytes, of an unbatched
+	// response that is compressed.
+	minCompressionSize int
+
+	// bufferResponses controls whether unbatched responses are buffered so
+	// that the Content-Length header can be sent.
+	bufferResponses bool
+
+	// errorStatusMap is a map of JSON-RPC error codes to the HTTP status codes
+	// that override the built-in mapping.
+	errorStatusMap map[harpy.ErrorCode]int
+
+	// exposeInternalErrors controls whether the messages of the Go errors that
+	// cause internal server errors are sent to the client.
+	exposeInternalErrors bool
+
+	// trustedProxies is the set of address ranges of proxies that are trusted
+	// to report the client's address via forwarding headers.
+	trustedProxies []netip.Prefix
+
+	// propagateDeadlines controls whether the deadline sent by the client is
+	// applied to the context passed to the exchanger.
+	propagateDeadlines bool
+
+	// traceHeaders controls whether the ID of the OpenTelemetry trace is
+	// included in the HTTP response headers.
+	traceHeaders bool
+
+	// spanIDHeader controls whether the ID of the OpenTelemetry span is also
+	// included in the HTTP response headers.
+	spanIDHeader bool
+
+	// maxParameterSize is the maximum size of the parameters of each request,
+	// in bytes. If it is zero, there is no limit.
+	maxParameterSize int
+
+	// maxNestingDepth is the maximum nesting depth of JSON arrays and objects
+	// within each request set. If it is zero, the default limit is used.
+	maxNestingDepth int
+
+	// maxRequestBodySize is the maximum size of the decompressed request body,
+	// in bytes. If it is zero, only compressed bodies are limited, to
+	// DefaultMaxRequestBodySize.
+	maxRequestBodySize int64
+
+	// codec is the codec used to decode requests and encode responses. If it
+	// is nil, JSON is used.
+	codec harpy.Codec
+
+	// mediaType is the MIME media-type of requests and responses. If it is
+	// empty, "application/json" is used.
+	mediaType string
+
+	// acceptedVersions is the list of JSON-RPC versions, in addition to "2.0",
+	// that are accepted in requests.
+	acceptedVersions []string
+
+	// onParseError is called when a request set can not be parsed. If it is
+	// nil, parse errors are only logged.
+	onParseError func(context.Context, *http.Request, error)
+
+	// healthCheckPath is the URL path at which health checks are served. If it
+	// is empty, health checks are not served.
+	healthCheckPath string
 }
 
 // HandlerOption configures the behavior of a handler.
 type HandlerOption func(*Handler)
 
+// WithMaxConcurrentRequests is a HandlerOption that limits the number of HTTP
+// requests that the handler processes concurrently.
+//
+// Once the limit is reached, additional requests wait until an earlier request
+// completes. If the context of a waiting request is canceled before it can be
+// processed, the handler responds with a JSON-RPC "internal error" and an HTTP
+// 503 (Service Unavailable) status.
+//
+// A limit of zero (the default) means there is no limit.
+func WithMaxConcurrentRequests(n int) HandlerOption {
+	if n < 0 {
+		panic("the concurrent request limit must not be negative")
+	}
+
+	return func(h *Handler) {
+		if n == 0 {
+			h.semaphore = nil
+		} else {
+			h.semaphore = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithBatches is a HandlerOption that controls whether the handler accepts
+// batch requests.
+//
+// By default batches are accepted. If allow is false, each batch request is
+// rejected with a single JSON-RPC "invalid request" error before any of the
+// requests within it are passed to the exchanger. This is useful when the
+// exchanger can not safely handle the requests within a batch concurrently.
+func WithBatches(allow bool) HandlerOption {
+	return func(h *Handler) {
+		h.disallowBatches = !allow
+	}
+}
+
+// WithMaxRequestsPerMethod is a HandlerOption that limits the number of
+// requests within a batch that may target the same method.
+//
+// A batch that exceeds the limit for any method is rejected with a single
+// JSON-RPC "invalid request" error before any of the requests within it are
+// passed to the exchanger. This prevents a single batch from invoking an
+// expensive method an excessive number of times. See harpy.BatchMethodLimiter.
+//
+// A limit of zero (the default) means there is no limit.
+func WithMaxRequestsPerMethod(n int) HandlerOption {
+	if n < 0 {
+		panic("the per-method request limit must not be negative")
+	}
+
+	return func(h *Handler) {
+		h.maxRequestsPerMethod = n
+	}
+}
+
+// WithTrailingData is a HandlerOption that controls whether the handler
+// accepts requests that contain data other than whitespace after the request
+// set.
+//
+// By default any such data is ignored. If allow is false, the request is
+// rejected with a JSON-RPC "parse error" instead. This prevents ambiguity about
+// which content was actually processed, such as when a body contains multiple
+// JSON values.
+func WithTrailingData(allow bool) HandlerOption {
+	return func(h *Handler) {
+		h.disallowTrailingData = !allow
+	}
+}
+
+// WithAcceptedVersions is a HandlerOption that configures the handler to
+// accept requests that specify any of the given values in their "jsonrpc"
+// field, in addition to "2.0". An empty string accepts requests that omit the
+// field altogether.
+//
+// Such requests are handled as though they specified "2.0", and their
+// responses always specify "2.0".
+//
+// WARNING: The JSON-RPC 2.0 specification requires the version to be exactly
+// "2.0"; accepting other versions deviates from the specification. It is
+// intended only to support non-conformant clients, such as during a migration.
+func WithAcceptedVersions(versions ...string) HandlerOption {
+	return func(h *Handler) {
+		h.acceptedVersions = versions
+	}
+}
+
+// WithParseErrorHook is a HandlerOption that configures the handler to call fn
+// whenever a request set can not be parsed, such as when the request body is
+// not valid JSON.
+//
+// fn is called with the HTTP request and the JSON-RPC "parse error" before the
+// error response is written to the client. It is intended for auditing and
+// rate-limiting clients that send malformed requests. The request body has
+// already been consumed when fn is called.
+func WithParseErrorHook(fn func(ctx context.Context, r *http.Request, err error)) HandlerOption {
+	return func(h *Handler) {
+		h.onParseError = fn
+	}
+}
+
+// WithBatchStreaming is a HandlerOption that configures the handler to flush
+// each response within a batch to the client as soon as it is produced, rather
+// than allowing the responses to be buffered.
+//
+// The responses appear within the batch in the order that they are completed,
+// which is not necessarily the order of the requests.
+func WithBatchStreaming() HandlerOption {
+	return func(h *Handler) {
+		h.streamBatches = true
+	}
+}
+
+// WithHTMLEscaping is a HandlerOption that controls whether the characters <,
+// > and & are escaped within the JSON strings of responses.
+//
+// HTML escaping is enabled by default.
+func WithHTMLEscaping(escape bool) HandlerOption {
+	return func(h *Handler) {
+		h.disableHTMLEscaping = !escape
+	}
+}
+
+// WithIndent is a HandlerOption that causes JSON responses to be indented,
+// using the given string for each level of nesting.
+//
+// Responses are not indented by default.
+func WithIndent(indent string) HandlerOption {
+	return func(h *Handler) {
+		h.indent = indent
+	}
+}
+
+// WithResponseCompression is a HandlerOption that enables gzip compression of
+// responses sent to clients that accept it, as per NegotiateResponseEncoding().
+//
+// Whether an unbatched response is compressed is determined by the hint
+// returned by harpy.CompressionHintOf(), such that responses smaller than
+// minSize bytes, or those that are hinted to be incompressible, are sent
+// uncompressed.
+//
+// Batched responses are always compressed, regardless of minSize or any hints,
+// as the size of the batch is not known when the HTTP response headers are
+// written.
+//
+// Responses are not compressed by default. It panics if minSize is negative.
+func WithResponseCompression(minSize int) HandlerOption {
+	if minSize < 0 {
+		panic("the minimum compression size must not be negative")
+	}
+
+	return func(h *Handler) {
+		h.compressResponses = true
+		h.minCompressionSize = minSize
+	}
+}
+
+// WithBufferedResponses is a HandlerOption that causes unbatched responses to
+// be encoded into a buffer before they are sent, such that the HTTP response
+// includes a Content-Length header.
+//
+// Some proxies and clients handle responses of a known length more efficiently
+// than those sent using the "chunked" transfer encoding. Batched responses are
+// always written as they are produced, and are therefore unaffected by this
+// option.
+//
+// Responses are not buffered by default.
+func WithBufferedResponses() HandlerOption {
+	return func(h *Handler) {
+		h.bufferResponses = true
+	}
+}
+
+// WithErrorStatusMap is a HandlerOption that sets the HTTP status codes used
+// for unbatched JSON-RPC error responses with specific error codes, overriding
+// the built-in mapping.
+//
+// This allows applications that use implementation-defined codes, such as
+// those in the reserved "server error" range (-32000 to -32099), to choose an
+// appropriate status. Codes that are not in m use the built-in mapping. The
+// status of a request that was rejected by a harpy.LoadShedder is always 503
+// (Service Unavailable).
+//
+// Multiple uses of this option are combined. It panics if any of the statuses
+// in m is not an HTTP client or server error status, that is, in the range 400
+// to 599.
+func WithErrorStatusMap(m map[harpy.ErrorCode]int) HandlerOption {
+	for _, status := range m {
+		if status < 400 || status > 599 {
+			panic("the error status must be an HTTP client or server error status")
+		}
+	}
+
+	return func(h *Handler) {
+		if h.errorStatusMap == nil {
+			h.errorStatusMap = map[harpy.ErrorCode]int{}
+		}
+
+		for code, status := range m {
+			h.errorStatusMap[code] = status
+		}
+	}
+}
+
+// WithInternalErrorDetails is a HandlerOption that includes the message of
+// the Go error that caused each JSON-RPC "internal server error" in the
+// response sent to the client.
+//
+// By default, these messages are hidden from the client and are only logged.
+// This option may expose sensitive information to the client. It is intended
+// for use during development only.
+func WithInternalErrorDetails() HandlerOption {
+	return func(h *Handler) {
+		h.exposeInternalErrors = true
+	}
+}
+
+// WithMaxNestingDepth is a HandlerOption that sets the maximum depth to which
+// JSON arrays and objects may be nested within a request, including within its
+// parameters.
+//
+// A request that is nested more deeply is rejected with a JSON-RPC "parse
+// error" before it is decoded.
+//
+// The default limit is 64. It panics if n is not positive.
+func WithMaxNestingDepth(n int) HandlerOption {
+	if n <= 0 {
+		panic("the maximum nesting depth must be positive")
+	}
+
+	return func(h *Handler) {
+		h.maxNestingDepth = n
+	}
+}
+
+// WithMaxRequestBodySize is a HandlerOption that sets the maximum size of each
+// HTTP request body, in bytes, after it has been decompressed.
+//
+// A request with a larger body is rejected with a JSON-RPC "invalid request"
+// error and an HTTP 413 (Content Too Large) status.
+//
+// By default, the decompressed content of a gzip-compressed body is limited to
+// DefaultMaxRequestBodySize, and an uncompressed body is not limited. It panics
+// if n is not positive.
+func WithMaxRequestBodySize(n int64) HandlerOption {
+	if n <= 0 {
+		panic("the maximum request body size must be positive")
+	}
+
+	return func(h *Handler) {
+		h.maxRequestBodySize = n
+	}
+}
+
+// WithCodec is a HandlerOption that sets the codec used to decode requests and
+// encode responses, and the MIME media-type that identifies the codec's wire
+// format.
+//
+// Requests must use the given media-type in their Content-Type header. If a
+// request has an Accept header that does not permit the media-type, it is
+// rejected with HTTP 406 (Not Acceptable).
+//
+// By default, requests and responses are encoded as JSON using the
+// "application/json" media-type. When a codec other than harpy.JSONCodec is
+// used, the WithHTMLEscaping() and WithIndent() options have no effect and
+// batched responses are sent together once the entire batch is complete.
+//
+// It panics if mediaType is empty.
+func WithCodec(c harpy.Codec, mediaType string) HandlerOption {
+	if mediaType == "" {
+		panic("the media-type must not be empty")
+	}
+
+	return func(h *Handler) {
+		h.codec = c
+		h.mediaType = mediaType
+	}
+}
+
 // NewHandler returns a new HTTP handler that provides an HTTP-based JSON-RPC
 // transport.
 func NewHandler(e harpy.Exchanger, options ...HandlerOption) http.Handler {
-	h := &Handler{
-		exchanger: e,
-	}
+	h := &Handler{}
 
 	for _, opt := range options {
 		opt(h)
 	}
 
+	if h.multipleRequestSets {
+		if !isJSONCodec(h.codec) {
+			panic("multiple request sets are only supported when using the JSON codec")
+		}
+
+		h.compressResponses = false
+		h.bufferResponses = false
+	}
+
+	if h.maxParameterSize != 0 {
+		e = &parameterSizeLimiter{
+			Next:  e,
+			Limit: h.maxParameterSize,
+		}
+	}
+
+	h.server = harpy.Server{
+		Exchanger:            e,
+		MaxRequestsPerMethod: h.maxRequestsPerMethod,
+	}
+
 	if h.newLogger == nil {
 		logger, err := zap.NewProduction()
 		if err != nil {
@@ -52,11 +476,183 @@ func NewHandler(e harpy.Exchanger, options ...HandlerOption) http.Handler {
 
 // ServeHTTP handles the HTTP request.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	harpy.Exchange( // nolint:errcheck // error already logged, nothing more to do
-		r.Context(),
-		h.exchanger,
-		&RequestSetReader{Request: r},
-		&ResponseWriter{Target: w},
-		h.newLogger(r),
-	)
+	// Health checks are served before any JSON-RPC specific validation is
+	// performed, such as the requirement to use the POST method.
+	if h.isHealthCheck(r) {
+		serveHealthCheck(w, r)
+		return
+	}
+
+	// Headers must be set before the exchange begins, as responses may be
+	// streamed to the client.
+	if h.traceHeaders {
+		h.setTraceHeaders(w, r)
+	}
+
+	if h.auditSink != nil {
+		h.serveAudited(w, r)
+		return
+	}
+
+	h.exchange(w, r)
+}
+
+// exchange performs the JSON-RPC exchange for the HTTP request.
+//
+// The context passed to the exchanger is canceled as soon as any write to the
+// HTTP response fails, such as when the client has disconnected. The cause of
+// the cancelation is the write error.
+func (h *Handler) exchange(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(harpy.WithTransport(r.Context(), "http"))
+	r = h.withRemoteAddr(r)
+	r, successStatus := withSuccessStatus(r)
+	r, responseHeaders := withResponseHeaders(r)
+
+	if h.propagateDeadlines {
+		var cancel context.CancelFunc
+		r, cancel = withDeadlineFromHeader(r)
+		defer cancel()
+	}
+
+	ctx, cancel := context.WithCancelCause(r.Context())
+	defer cancel(nil)
+
+	logger := h.newLogger(r)
+	codec, codecErr := h.negotiateResponseCodec(r)
+	writer := &ResponseWriter{
+		Target: &cancelOnErrorWriter{
+			ResponseWriter: w,
+			Cancel:         cancel,
+		},
+		FlushBatches:         h.streamBatches,
+		DisableHTMLEscaping:  h.disableHTMLEscaping,
+		Indent:               h.indent,
+		ExposeInternalErrors: h.exposeInternalErrors,
+		Codec:                codec.Codec,
+		MediaType:            codec.MediaType,
+		SuccessStatus:        successStatus,
+		ErrorStatusMap:       h.errorStatusMap,
+		Headers:              responseHeaders,
+		Compress:             h.compressResponses && NegotiateResponseEncoding(r),
+		MinCompressionSize:   h.minCompressionSize,
+		BufferResponses:      h.bufferResponses,
+	}
+
+	if codecErr != nil {
+		writeError(ctx, writer, logger, codecErr)
+		return
+	}
+
+	if h.semaphore != nil {
+		select {
+		case h.semaphore <- struct{}{}:
+			defer func() { <-h.semaphore }()
+		case <-ctx.Done():
+			writeError(
+				ctx,
+				writer,
+				logger,
+				harpy.NewErrorWithReservedCode(
+					harpy.InternalErrorCode,
+					harpy.WithMessage(serverAtCapacity),
+					harpy.WithCause(ctx.Err()),
+				),
+			)
+			return
+		}
+	}
+
+	reader := &RequestSetReader{
+		Request:              r,
+		Codec:                h.codec,
+		MediaType:            h.mediaType,
+		MaxNestingDepth:      h.maxNestingDepth,
+		MaxBodySize:          h.maxRequestBodySize,
+		DisallowBatches:      h.disallowBatches,
+		DisallowTrailingData: h.disallowTrailingData,
+		AcceptedVersions:     h.acceptedVersions,
+		OnParseError:         h.onParseError,
+		MultipleRequestSets:  h.multipleRequestSets,
+	}
+
+	server := h.server
+	server.Logger = logger
+
+	if h.multipleRequestSets {
+		exchangeMultiple(ctx, &server, reader, writer)
+		return
+	}
+
+	server.Handle(ctx, reader, writer) // nolint:errcheck // error already logged, nothing more to do
+
+	if writer.Incomplete() {
+		// The HTTP response body is not valid JSON. Abort the response so that
+		// the client observes a failure, such as a truncated body or a reset
+		// connection, rather than a malformed JSON-RPC response.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// writeError writes an error response that is a result of some problem with
+// the HTTP request as a whole, without performing a JSON-RPC exchange.
+func writeError(
+	ctx context.Context,
+	w *ResponseWriter,
+	logger harpy.ExchangeLogger,
+	err error,
+) {
+	res := harpy.NewErrorResponse(nil, err)
+	logger.LogError(ctx, res)
+
+	if err := w.WriteError(res); err != nil {
+		logger.LogWriterError(ctx, err)
+	}
+
+	if err := w.Close(); err != nil {
+		logger.LogWriterError(ctx, err)
+	}
+}
+
+// serveAudited handles the HTTP request, recording the request and response
+// bodies and passing them to the audit sink.
+func (h *Handler) serveAudited(w http.ResponseWriter, r *http.Request) {
+	body := &auditRequestBody{ReadCloser: r.Body}
+	r.Body = body
+	aw := &auditResponseWriter{ResponseWriter: w}
+
+	// The request is audited even if the HTTP response is aborted.
+	defer func() {
+		body.drain()
+
+		h.auditSink(
+			r.Context(),
+			body.recorded(),
+			aw.recorded(),
+		)
+	}()
+
+	h.exchange(aw, r)
+}
+
+// cancelOnErrorWriter is an http.ResponseWriter that cancels a context when a
+// write fails.
+type cancelOnErrorWriter struct {
+	http.ResponseWriter
+	Cancel context.CancelCauseFunc
+}
+
+func (w *cancelOnErrorWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	if err != nil {
+		w.Cancel(fmt.Errorf("unable to write HTTP response: %w", err))
+	}
+	return n, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+//
+// It allows http.ResponseController to access features of the underlying
+// writer.
+func (w *cancelOnErrorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
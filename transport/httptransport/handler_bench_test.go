@@ -0,0 +1,48 @@
+package httptransport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	"go.uber.org/zap"
+)
+
+func BenchmarkHandlerBufferedResponse(b *testing.B) {
+	exchanger := &ExchangerStub{
+		CallFunc: func(_ context.Context, req harpy.Request) harpy.Response {
+			return harpy.SuccessResponse{
+				Version:   "2.0",
+				RequestID: req.ID,
+				Result:    req.Parameters,
+			}
+		},
+	}
+
+	handler := NewHandler(
+		exchanger,
+		WithBufferedResponses(),
+		WithZapLogger(zap.NewNop()),
+	)
+
+	body := `{"jsonrpc":"2.0","id":123,"method":"<method>","params":[1,2,3]}`
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected HTTP status: %d", w.Code)
+		}
+	}
+}
@@ -1,12 +1,21 @@
 package httptransport_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/dogmatiq/harpy"
 	. "github.com/dogmatiq/harpy/internal/fixtures"
@@ -15,6 +24,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
 )
 
 var _ = Describe("type Handler", func() {
@@ -39,7 +49,7 @@ var _ = Describe("type Handler", func() {
 			}
 		}
 
-		handler = NewHandler(exchanger)
+		handler = NewHandler(exchanger, WithZapLogger(zap.NewNop()))
 
 		server = httptest.NewServer(handler)
 
@@ -70,6 +80,87 @@ var _ = Describe("type Handler", func() {
 				"result": [1, 2, 3]
 			}`))
 		})
+
+		It("associates the context with the http transport", func() {
+			exchanger.CallFunc = func(
+				ctx context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				name, _ := harpy.TransportFromContext(ctx)
+				return harpy.NewSuccessResponse(req.ID, name)
+			}
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": "http"}`))
+		})
+	})
+
+	When("the request is a call to a method that does not return a result", func() {
+		It("responds with a null result", func() {
+			server.Config.Handler = NewHandler(
+				harpy.NewRouter(
+					harpy.WithRoute(
+						"<method>",
+						harpy.NoResult(func(context.Context, []int) error {
+							return nil
+						}),
+					),
+				),
+				WithZapLogger(zap.NewNop()),
+			)
+
+			request = strings.NewReader(`{
+				"jsonrpc": "2.0",
+				"id": 123,
+				"method": "<method>",
+				"params": [1, 2, 3]
+			}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			var body map[string]any
+			err = json.NewDecoder(res.Body).Decode(&body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(Equal(map[string]any{
+				"jsonrpc": "2.0",
+				"id":      float64(123),
+				"result":  nil,
+			}))
+			Expect(body).To(HaveKey("result"))
+		})
+	})
+
+	When("the request is a non-batched call with a null request ID", func() {
+		It("responds with a null request ID", func() {
+			request = strings.NewReader(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"params": [1, 2, 3]
+			}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			json, err := io.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(json).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"result": [1, 2, 3]
+			}`))
+		})
 	})
 
 	When("the request is non-batched notification", func() {
@@ -184,6 +275,310 @@ var _ = Describe("type Handler", func() {
 		}`))
 	})
 
+	It("responds with an error if the client does not accept application/json responses", func() {
+		exchanger.CallFunc = func(context.Context, harpy.Request) harpy.Response {
+			Fail("unexpected call")
+			return nil
+		}
+
+		req, err := http.NewRequest(http.MethodPost, server.URL, request)
+		Expect(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/html, application/xml;q=0.9")
+
+		res, err := http.DefaultClient.Do(req)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusNotAcceptable))
+		Expect(res.Header.Get("Content-Type")).To(Equal("application/json"))
+
+		json, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(json).To(MatchJSON(`{
+			"jsonrpc": "2.0",
+			"id": null,
+			"error": {
+				"code": -32600,
+				"message": "JSON-RPC responses are only available in the application/json content type"
+			}
+		}`))
+	})
+
+	It("accepts a request that accepts any media-type", func() {
+		req, err := http.NewRequest(http.MethodPost, server.URL, request)
+		Expect(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/html, */*;q=0.1")
+
+		res, err := http.DefaultClient.Do(req)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		res.Body.Close()
+	})
+
+	It("accepts a content type with a UTF-8 charset parameter", func() {
+		res, err := http.Post(server.URL, "application/json; charset=UTF-8", request)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		res.Body.Close()
+	})
+
+	It("responds with an error if the content type has a charset parameter other than UTF-8", func() {
+		res, err := http.Post(server.URL, "application/json; charset=iso-8859-1", request)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusUnsupportedMediaType))
+
+		json, err := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(json).To(MatchJSON(`{
+			"jsonrpc": "2.0",
+			"id": null,
+			"error": {
+				"code": -32600,
+				"message": "JSON-RPC requests must use the application/json content type"
+			}
+		}`))
+	})
+
+	It("accepts gzip-compressed requests", func() {
+		var body bytes.Buffer
+		w := gzip.NewWriter(&body)
+		_, err := io.Copy(w, request)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+
+		req, err := http.NewRequest(http.MethodPost, server.URL, &body)
+		Expect(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+
+		res, err := http.DefaultClient.Do(req)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+		json, err := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(json).To(MatchJSON(`{
+			"jsonrpc": "2.0",
+			"id": 123,
+			"result": [1, 2, 3]
+		}`))
+	})
+
+	It("responds with an error if the content encoding is not supported", func() {
+		req, err := http.NewRequest(http.MethodPost, server.URL, request)
+		Expect(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "br")
+
+		res, err := http.DefaultClient.Do(req)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusUnsupportedMediaType))
+
+		json, err := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(json).To(MatchJSON(`{
+			"jsonrpc": "2.0",
+			"id": null,
+			"error": {
+				"code": -32600,
+				"message": "JSON-RPC requests must use the gzip or identity content encoding"
+			}
+		}`))
+	})
+
+	It("responds with an error if a gzip-compressed request can not be decompressed", func() {
+		req, err := http.NewRequest(http.MethodPost, server.URL, request)
+		Expect(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+
+		res, err := http.DefaultClient.Do(req)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+		json, err := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(json).To(MatchJSON(`{
+			"jsonrpc": "2.0",
+			"id": null,
+			"error": {
+				"code": -32700,
+				"message": "unable to decompress request: gzip: invalid header"
+			}
+		}`))
+	})
+
+	DescribeTable(
+		"it responds with a parse error if a gzip-compressed request is corrupt",
+		func(corrupt func([]byte) []byte, message string) {
+			var compressed bytes.Buffer
+			w := gzip.NewWriter(&compressed)
+			_, err := io.Copy(w, request)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			req, err := http.NewRequest(
+				http.MethodPost,
+				server.URL,
+				bytes.NewReader(corrupt(compressed.Bytes())),
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", "gzip")
+
+			res, err := http.DefaultClient.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			json, err := io.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(json).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32700,
+					"message": "` + message + `"
+				}
+			}`))
+		},
+		Entry(
+			"empty body",
+			func([]byte) []byte { return nil },
+			"unable to decompress request: EOF",
+		),
+		Entry(
+			"truncated header",
+			func(data []byte) []byte { return data[:5] },
+			"unable to decompress request: unexpected EOF",
+		),
+		Entry(
+			"truncated content",
+			func(data []byte) []byte { return data[:len(data)-10] },
+			"unable to decompress request: unexpected EOF",
+		),
+		Entry(
+			"checksum mismatch",
+			func(data []byte) []byte {
+				data[len(data)-8] ^= 0xff // first byte of the CRC-32 trailer
+				return data
+			},
+			"unable to decompress request: gzip: invalid checksum",
+		),
+	)
+
+	When("the request body exceeds the maximum size", func() {
+		// serve sends a request with the given body and returns the HTTP
+		// response.
+		serve := func(h http.Handler, body []byte, encoding string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			if encoding != "" {
+				r.Header.Set("Content-Encoding", encoding)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			return w
+		}
+
+		// compress returns the gzip-compressed form of data.
+		compress := func(data []byte) []byte {
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			_, err := w.Write(data)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+			return buf.Bytes()
+		}
+
+		expectTooLarge := func(w *httptest.ResponseRecorder) {
+			Expect(w.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32600,
+					"message": "JSON-RPC request body is too large"
+				}
+			}`))
+		}
+
+		It("limits the decompressed size of a gzip-compressed request by default", func() {
+			// The whitespace compresses to a tiny fraction of its size.
+			body := append(
+				bytes.Repeat([]byte(" "), DefaultMaxRequestBodySize),
+				`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`...,
+			)
+			compressed := compress(body)
+			Expect(len(compressed)).To(BeNumerically("<", 16*1024))
+
+			expectTooLarge(serve(handler, compressed, "gzip"))
+		})
+
+		It("does not limit the size of an uncompressed request by default", func() {
+			body := append(
+				bytes.Repeat([]byte(" "), DefaultMaxRequestBodySize),
+				`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`...,
+			)
+
+			w := serve(handler, body, "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+
+		It("applies the limit configured by WithMaxRequestBodySize()", func() {
+			body := []byte(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`)
+
+			h := NewHandler(
+				exchanger,
+				WithMaxRequestBodySize(int64(len(body))),
+				WithZapLogger(zap.NewNop()),
+			)
+
+			w := serve(h, body, "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			w = serve(h, compress(body), "gzip")
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			body = append(body, ' ')
+			expectTooLarge(serve(h, body, ""))
+			expectTooLarge(serve(h, compress(body), "gzip"))
+		})
+
+		It("applies the limit when reading multiple request sets", func() {
+			body := []byte(`{"jsonrpc": "2.0", "id": 1} {"jsonrpc": "2.0", "id": 2}`)
+
+			h := NewHandler(
+				exchanger,
+				WithMultipleRequestSets(),
+				WithMaxRequestBodySize(30),
+				WithZapLogger(zap.NewNop()),
+			)
+
+			w := serve(h, body, "")
+			Expect(w.Body.String()).To(ContainSubstring("JSON-RPC request body is too large"))
+		})
+
+		It("panics if the maximum size is not positive", func() {
+			Expect(func() {
+				WithMaxRequestBodySize(0)
+			}).To(PanicWith("the maximum request body size must be positive"))
+		})
+	})
+
 	It("responds with an error if the request is malformed", func() {
 		request = strings.NewReader(`}`)
 
@@ -251,23 +646,1711 @@ var _ = Describe("type Handler", func() {
 		}`))
 	})
 
-	DescribeTable(
-		"it maps JSON-RPC error codes to the appropriate HTTP status code",
-		func(err error, statusCode int) {
+	When("the number of concurrent requests is limited", func() {
+		var (
+			started chan struct{}
+			release chan struct{}
+		)
+
+		BeforeEach(func() {
+			started = make(chan struct{}, 2)
+			release = make(chan struct{})
+
+			next := exchanger.CallFunc
 			exchanger.CallFunc = func(
-				_ context.Context,
+				ctx context.Context,
 				req harpy.Request,
 			) harpy.Response {
-				return harpy.NewErrorResponse(req.ID, err)
+				started <- struct{}{}
+				<-release
+				return next(ctx, req)
+			}
+
+			handler = NewHandler(
+				exchanger,
+				WithMaxConcurrentRequests(1),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		newRequest := func(ctx context.Context) *http.Request {
+			r := httptest.NewRequest(
+				http.MethodPost,
+				"/",
+				strings.NewReader(`{"jsonrpc":"2.0","id":123,"params":[1,2,3]}`),
+			)
+			r.Header.Set("Content-Type", "application/json")
+			return r.WithContext(ctx)
+		}
+
+		serveInBackground := func(ctx context.Context) (*httptest.ResponseRecorder, <-chan struct{}) {
+			w := httptest.NewRecorder()
+			done := make(chan struct{})
+
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				handler.ServeHTTP(w, newRequest(ctx))
+			}()
+
+			return w, done
+		}
+
+		It("waits for capacity before processing the request", func() {
+			w1, done1 := serveInBackground(context.Background())
+			Eventually(started).Should(Receive())
+
+			w2, done2 := serveInBackground(context.Background())
+			Consistently(started).ShouldNot(Receive())
+
+			close(release)
+			Eventually(done1).Should(BeClosed())
+			Eventually(started).Should(Receive())
+			Eventually(done2).Should(BeClosed())
+
+			Expect(w1.Code).To(Equal(http.StatusOK))
+			Expect(w2.Code).To(Equal(http.StatusOK))
+		})
+
+		It("responds with an error if the request is canceled while waiting for capacity", func() {
+			_, done := serveInBackground(context.Background())
+			Eventually(started).Should(Receive())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newRequest(ctx))
+
+			close(release)
+			Eventually(done).Should(BeClosed())
+
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32603,
+					"message": "the server is handling too many concurrent requests"
+				}
+			}`))
+		})
+
+		It("panics if the limit is negative", func() {
+			Expect(func() {
+				WithMaxConcurrentRequests(-1)
+			}).To(PanicWith("the concurrent request limit must not be negative"))
+		})
+	})
+
+	It("responds with HTTP 503 if the request was rejected by a load shedder", func() {
+		release := make(chan struct{})
+		defer close(release)
+
+		server.Config.Handler = NewHandler(
+			&harpy.LoadShedder{
+				Next: &ExchangerStub{
+					CallFunc: func(_ context.Context, req harpy.Request) harpy.Response {
+						if string(req.ID) == "1" {
+							<-release
+						}
+						return harpy.NewSuccessResponse(req.ID, nil)
+					},
+				},
+				Capacity: 1,
+			},
+			WithZapLogger(zap.NewNop()),
+		)
+
+		// Occupy the only slot with a request that does not complete until the
+		// test ends.
+		go func() {
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 1}`)
+			if res, err := http.Post(server.URL, "application/json", request); err == nil {
+				res.Body.Close()
 			}
+		}()
+
+		Eventually(func() int {
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123}`)
 
 			res, err := http.Post(server.URL, "application/json", request)
 			Expect(err).ShouldNot(HaveOccurred())
-			Expect(res.StatusCode).To(Equal(statusCode))
-		},
-		Entry("method not found", harpy.MethodNotFound(), http.StatusNotImplemented),
-		Entry("invalid parameters", harpy.InvalidParameters(), http.StatusBadRequest),
-		Entry("internal error", harpy.NewErrorWithReservedCode(harpy.InternalErrorCode), http.StatusInternalServerError),
-		Entry("a native JSON-RPC error with an unreserved code", harpy.NewError(123), http.StatusOK),
-	)
-})
+			defer res.Body.Close()
+
+			return res.StatusCode
+		}).Should(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("escapes HTML characters in responses by default", func() {
+		request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": ["<b>&</b>"]}`)
+
+		res, err := http.Post(server.URL, "application/json", request)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(body)).To(ContainSubstring(`\u003cb\u003e\u0026\u003c/b\u003e`))
+	})
+
+	When("HTML escaping is disabled", func() {
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithHTMLEscaping(false),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("does not escape HTML characters in unbatched responses", func() {
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": ["<b>&</b>"]}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"jsonrpc":"2.0","id":123,"result":["<b>&</b>"]}` + "\n"))
+		})
+
+		It("does not escape HTML characters in batched responses", func() {
+			request := strings.NewReader(`[{"jsonrpc": "2.0", "id": 123, "params": ["<b>&</b>"]}]`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(body)).To(Equal(`[{"jsonrpc":"2.0","id":123,"result":["<b>&</b>"]}` + "\n]"))
+		})
+	})
+
+	When("indentation is enabled", func() {
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithIndent("  "),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("indents the response", func() {
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(body)).To(Equal(
+				"{\n" +
+					"  \"jsonrpc\": \"2.0\",\n" +
+					"  \"id\": 123,\n" +
+					"  \"result\": [\n" +
+					"    1,\n" +
+					"    2,\n" +
+					"    3\n" +
+					"  ]\n" +
+					"}\n",
+			))
+		})
+
+		It("indents error responses", func() {
+			res, err := http.Get(server.URL)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(body)).To(HavePrefix("{\n  \"jsonrpc\": \"2.0\",\n"))
+		})
+	})
+
+	When("response compression is enabled", func() {
+		var serve func(body string, acceptEncoding string) *httptest.ResponseRecorder
+
+		BeforeEach(func() {
+			handler = NewHandler(
+				exchanger,
+				WithResponseCompression(10),
+				WithZapLogger(zap.NewNop()),
+			)
+
+			serve = func(body string, acceptEncoding string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+				r.Header.Set("Content-Type", "application/json")
+				if acceptEncoding != "" {
+					r.Header.Set("Accept-Encoding", acceptEncoding)
+				}
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+				return w
+			}
+		})
+
+		decompress := func(w *httptest.ResponseRecorder) []byte {
+			gz, err := gzip.NewReader(w.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			body, err := io.ReadAll(gz)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return body
+		}
+
+		It("compresses responses that are at least the minimum size", func() {
+			w := serve(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3, 4, 5]}`, "gzip")
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Encoding")).To(Equal("gzip"))
+			Expect(w.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+			Expect(decompress(w)).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": [1, 2, 3, 4, 5]}`))
+		})
+
+		It("does not compress responses that are smaller than the minimum size", func() {
+			w := serve(`{"jsonrpc": "2.0", "id": 123, "params": [1]}`, "gzip")
+
+			Expect(w.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(w.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": [1]}`))
+		})
+
+		It("does not compress responses that are hinted to be incompressible", func() {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewSuccessResponse(
+					req.ID,
+					harpy.StreamResult{
+						Reader: incompressibleReader{strings.NewReader(`"<already compressed>"`)},
+					},
+				)
+			}
+
+			w := serve(`{"jsonrpc": "2.0", "id": 123}`, "gzip")
+
+			Expect(w.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(w.Body.Bytes()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": "<already compressed>"}`))
+		})
+
+		It("compresses batched responses", func() {
+			w := serve(`[{"jsonrpc": "2.0", "id": 1, "params": [1]}]`, "gzip")
+
+			Expect(w.Header().Get("Content-Encoding")).To(Equal("gzip"))
+			Expect(decompress(w)).To(MatchJSON(`[{"jsonrpc": "2.0", "id": 1, "result": [1]}]`))
+		})
+
+		It("does not compress responses if the client does not accept gzip", func() {
+			w := serve(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3, 4, 5]}`, "")
+
+			Expect(w.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(w.Header().Get("Vary")).To(BeEmpty())
+			Expect(w.Body.Bytes()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": [1, 2, 3, 4, 5]}`))
+		})
+
+		It("panics if the minimum size is negative", func() {
+			Expect(func() {
+				WithResponseCompression(-1)
+			}).To(PanicWith("the minimum compression size must not be negative"))
+		})
+	})
+
+	When("response buffering is enabled", func() {
+		serve := func(body string, acceptEncoding string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			if acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", acceptEncoding)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		BeforeEach(func() {
+			handler = NewHandler(
+				exchanger,
+				WithBufferedResponses(),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("sets the Content-Length header on success responses", func() {
+			w := serve(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`, "")
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Length")).To(Equal(strconv.Itoa(w.Body.Len())))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": [1, 2, 3]}`))
+		})
+
+		It("sets the Content-Length header on error responses", func() {
+			w := serve(`{"jsonrpc": }`, "")
+
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(w.Header().Get("Content-Length")).To(Equal(strconv.Itoa(w.Body.Len())))
+			Expect(w.Body.Len()).To(BeNumerically(">", 0))
+		})
+
+		It("sets the Content-Length header to the length of the compressed body", func() {
+			handler = NewHandler(
+				exchanger,
+				WithBufferedResponses(),
+				WithResponseCompression(0),
+				WithZapLogger(zap.NewNop()),
+			)
+
+			w := serve(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`, "gzip")
+
+			Expect(w.Header().Get("Content-Encoding")).To(Equal("gzip"))
+			Expect(w.Header().Get("Content-Length")).To(Equal(strconv.Itoa(w.Body.Len())))
+
+			gz, err := gzip.NewReader(w.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			body, err := io.ReadAll(gz)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": [1, 2, 3]}`))
+		})
+
+		It("does not buffer batched responses", func() {
+			w := serve(`[{"jsonrpc": "2.0", "id": 1, "params": [1]}]`, "")
+
+			Expect(w.Header().Get("Content-Length")).To(BeEmpty())
+			Expect(w.Body.Bytes()).To(MatchJSON(`[{"jsonrpc": "2.0", "id": 1, "result": [1]}]`))
+		})
+	})
+
+	When("multiple request sets are enabled", func() {
+		serve := func(body string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w
+		}
+
+		split := func(w *httptest.ResponseRecorder) []string {
+			var values []string
+			dec := json.NewDecoder(w.Body)
+
+			for dec.More() {
+				var v json.RawMessage
+				Expect(dec.Decode(&v)).To(Succeed())
+				values = append(values, string(v))
+			}
+
+			return values
+		}
+
+		BeforeEach(func() {
+			handler = NewHandler(
+				exchanger,
+				WithMultipleRequestSets(),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("writes the responses to each request set followed by a newline", func() {
+			w := serve(
+				`{"jsonrpc": "2.0", "id": 1, "params": [1]}` + "\n" +
+					`[{"jsonrpc": "2.0", "id": 2, "params": [2]}]` +
+					`{"jsonrpc": "2.0", "id": 3, "params": [3]}`,
+			)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring("]\n{"))
+			Expect(w.Body.String()).To(HaveSuffix("\n"))
+
+			l := split(w)
+			Expect(l).To(HaveLen(3))
+			Expect(l[0]).To(MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": [1]}`))
+			Expect(l[1]).To(MatchJSON(`[{"jsonrpc": "2.0", "id": 2, "result": [2]}]`))
+			Expect(l[2]).To(MatchJSON(`{"jsonrpc": "2.0", "id": 3, "result": [3]}`))
+		})
+
+		It("responds with the HTTP status of the first request set that produces a response", func() {
+			w := serve(
+				`{"jsonrpc": "2.0", "method": "<notification>"}` +
+					`{"jsonrpc": "2.0", "id": 1, "params": [1]}`,
+			)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": [1]}`))
+		})
+
+		It("responds with HTTP 204 (No Content) if none of the request sets produce a response", func() {
+			w := serve(
+				`{"jsonrpc": "2.0", "method": "<notification>"}` +
+					`{"jsonrpc": "2.0", "method": "<notification>"}`,
+			)
+
+			Expect(w.Code).To(Equal(http.StatusNoContent))
+			Expect(w.Body.Len()).To(BeZero())
+		})
+
+		It("does not read any further request sets after a parse error", func() {
+			var calls atomic.Int32
+			exchanger.CallFunc = func(_ context.Context, req harpy.Request) harpy.Response {
+				calls.Add(1)
+				return harpy.NewSuccessResponse(req.ID, nil)
+			}
+
+			w := serve(
+				`{"jsonrpc": "2.0", "id": 1}` +
+					`{"jsonrpc": }` +
+					`{"jsonrpc": "2.0", "id": 2}`,
+			)
+
+			Expect(calls.Load()).To(BeNumerically("==", 1))
+
+			l := split(w)
+			Expect(l).To(HaveLen(2))
+			Expect(l[0]).To(MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": null}`))
+			Expect(l[1]).To(ContainSubstring(`"code":-32700`))
+		})
+
+		DescribeTable(
+			"it responds with a parse error if there is data after the last request set",
+			func(trailer string) {
+				w := serve(`{"jsonrpc": "2.0", "id": 1}` + trailer)
+
+				l := split(w)
+				Expect(l).To(HaveLen(2))
+				Expect(l[0]).To(MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": null}`))
+				Expect(l[1]).To(MatchJSON(`{
+					"jsonrpc": "2.0",
+					"id": null,
+					"error": {
+						"code": -32700,
+						"message": "unable to parse request: unexpected data after request set"
+					}
+				}`))
+			},
+			Entry("closing bracket", ` ]]] garbage`),
+			Entry("closing brace", "\n}"),
+		)
+
+		It("ignores trailing whitespace after the last request set", func() {
+			w := serve(`{"jsonrpc": "2.0", "id": 1}` + " \n\t\r\n")
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(split(w)).To(HaveLen(1))
+		})
+
+		It("handles bodies that contain a single request set as usual", func() {
+			w := serve(`{"jsonrpc": "2.0", "id": 1, "params": [1]}`)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": [1]}`))
+		})
+
+		It("panics if used with a codec other than JSON", func() {
+			Expect(func() {
+				NewHandler(
+					exchanger,
+					WithMultipleRequestSets(),
+					WithCodec(HexCodec{}, "application/x-hex-json"),
+				)
+			}).To(PanicWith("multiple request sets are only supported when using the JSON codec"))
+		})
+	})
+
+	It("ignores subsequent request sets by default", func() {
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(
+				`{"jsonrpc": "2.0", "id": 1, "params": [1]}`+
+					`{"jsonrpc": "2.0", "id": 2, "params": [2]}`,
+			),
+		)
+		r.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Body.Bytes()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": [1]}`))
+	})
+
+	It("does not buffer responses by default", func() {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`))
+		r.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Header().Get("Content-Length")).To(BeEmpty())
+	})
+
+	It("does not compress responses by default", func() {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept-Encoding", "gzip")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(w.Body.Bytes()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": [1, 2, 3]}`))
+	})
+
+	When("the parameter size is limited", func() {
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithMaxParameterSize(10),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("rejects calls with parameters that exceed the limit", func() {
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3, 4, 5]}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": 123,
+				"error": {
+					"code": -32602,
+					"message": "parameters must not exceed 10 bytes"
+				}
+			}`))
+		})
+
+		It("rejects only the offending requests within a batch", func() {
+			request := strings.NewReader(`[
+				{"jsonrpc": "2.0", "id": 1, "params": [1, 2, 3]},
+				{"jsonrpc": "2.0", "id": 2, "params": [1, 2, 3, 4, 5]}
+			]`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			var responses []map[string]any
+			err = json.NewDecoder(res.Body).Decode(&responses)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(responses).To(ConsistOf(
+				map[string]any{
+					"jsonrpc": "2.0",
+					"id":      float64(1),
+					"result":  []any{float64(1), float64(2), float64(3)},
+				},
+				map[string]any{
+					"jsonrpc": "2.0",
+					"id":      float64(2),
+					"error": map[string]any{
+						"code":    float64(-32602),
+						"message": "parameters must not exceed 10 bytes",
+					},
+				},
+			))
+		})
+
+		It("does not pass notifications with parameters that exceed the limit to the exchanger", func() {
+			exchanger.NotifyFunc = func(context.Context, harpy.Request) error {
+				panic("unexpected call")
+			}
+
+			request := strings.NewReader(`{"jsonrpc": "2.0", "params": [1, 2, 3, 4, 5]}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusNoContent))
+		})
+	})
+
+	When("the nesting depth is limited", func() {
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithMaxNestingDepth(3),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("accepts requests within the limit", func() {
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": [[1]]}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("rejects requests that exceed the limit", func() {
+			exchanger.CallFunc = func(context.Context, harpy.Request) harpy.Response {
+				panic("unexpected call")
+			}
+
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": [[[1]]]}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32700,
+					"message": "unable to parse request: json: exceeded maximum nesting depth of 3"
+				}
+			}`))
+		})
+
+		It("panics if the limit is not positive", func() {
+			Expect(func() {
+				WithMaxNestingDepth(0)
+			}).To(PanicWith("the maximum nesting depth must be positive"))
+		})
+	})
+
+	When("an error status map is specified", func() {
+		BeforeEach(func() {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewErrorResponse(
+					req.ID,
+					harpy.NewErrorWithReservedCode(-32001, harpy.WithMessage("<message>")),
+				)
+			}
+
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithErrorStatusMap(map[harpy.ErrorCode]int{
+					-32001: http.StatusBadRequest,
+				}),
+				WithErrorStatusMap(map[harpy.ErrorCode]int{
+					harpy.MethodNotFoundCode: http.StatusNotFound,
+				}),
+			)
+		})
+
+		It("uses the mapped status for unbatched responses", func() {
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("overrides the built-in mapping", func() {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewErrorResponse(req.ID, harpy.MethodNotFound())
+			}
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("falls back to the built-in mapping for unmapped codes", func() {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewErrorResponse(
+					req.ID,
+					harpy.NewErrorWithReservedCode(-32002),
+				)
+			}
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("uses the mapped status for errors with the request set as a whole", func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithErrorStatusMap(map[harpy.ErrorCode]int{
+					harpy.ParseErrorCode: http.StatusUnprocessableEntity,
+				}),
+			)
+
+			res, err := http.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc": }`))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		})
+
+		DescribeTable(
+			"it panics if a status is not an HTTP client or server error status",
+			func(status int) {
+				Expect(func() {
+					WithErrorStatusMap(map[harpy.ErrorCode]int{
+						-32001: status,
+					})
+				}).To(PanicWith("the error status must be an HTTP client or server error status"))
+			},
+			Entry("informational", http.StatusContinue),
+			Entry("success", http.StatusOK),
+			Entry("redirection", http.StatusFound),
+			Entry("out of range", 1000),
+		)
+	})
+
+	When("internal error details are enabled", func() {
+		BeforeEach(func() {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewErrorResponse(req.ID, errors.New("<error>"))
+			}
+
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithInternalErrorDetails(),
+			)
+		})
+
+		It("includes the message of the causal error in the response", func() {
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": 123,
+				"error": {
+					"code": -32603,
+					"message": "<error>"
+				}
+			}`))
+		})
+
+		It("does not include the message by default", func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+			)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": 123,
+				"error": {
+					"code": -32603,
+					"message": "internal server error"
+				}
+			}`))
+		})
+
+		It("includes the message within batched responses", func() {
+			request = strings.NewReader(`[
+				{"jsonrpc": "2.0", "id": 1},
+				{"jsonrpc": "2.0", "id": 2}
+			]`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			var responses []json.RawMessage
+			err = json.NewDecoder(res.Body).Decode(&responses)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(responses).To(ConsistOf(
+				MatchJSON(`{"jsonrpc": "2.0", "id": 1, "error": {"code": -32603, "message": "<error>"}}`),
+				MatchJSON(`{"jsonrpc": "2.0", "id": 2, "error": {"code": -32603, "message": "<error>"}}`),
+			))
+		})
+	})
+
+	When("the number of requests per method is limited", func() {
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithMaxRequestsPerMethod(1),
+			)
+		})
+
+		It("accepts batches that do not exceed the limit", func() {
+			request := strings.NewReader(`[
+				{"jsonrpc": "2.0", "id": 1, "method": "<a>", "params": [1]},
+				{"jsonrpc": "2.0", "id": 2, "method": "<b>", "params": [2]}
+			]`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("rejects batches that exceed the limit without calling the exchanger", func() {
+			exchanger.CallFunc = func(context.Context, harpy.Request) harpy.Response {
+				panic("unexpected call")
+			}
+
+			request := strings.NewReader(`[
+				{"jsonrpc": "2.0", "id": 1, "method": "<a>", "params": [1]},
+				{"jsonrpc": "2.0", "id": 2, "method": "<a>", "params": [2]}
+			]`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32600,
+					"message": "batch contains more than 1 requests to the \"\u003ca\u003e\" method"
+				}
+			}`))
+		})
+	})
+
+	When("batches are disallowed", func() {
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithBatches(false),
+			)
+		})
+
+		It("accepts non-batched requests", func() {
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("rejects batch requests without calling the exchanger", func() {
+			exchanger.CallFunc = func(context.Context, harpy.Request) harpy.Response {
+				panic("unexpected call")
+			}
+
+			request := strings.NewReader(`[
+				{"jsonrpc": "2.0", "id": 1, "params": [1]},
+				{"jsonrpc": "2.0", "id": 2, "params": [2]}
+			]`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32600,
+					"message": "batch requests are not supported"
+				}
+			}`))
+		})
+	})
+
+	When("trailing data is disallowed", func() {
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithTrailingData(false),
+			)
+		})
+
+		It("accepts requests that are followed by whitespace", func() {
+			request := strings.NewReader("{\"jsonrpc\": \"2.0\", \"id\": 123, \"params\": [1, 2, 3]}\n")
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("rejects requests that are followed by other data without calling the exchanger", func() {
+			exchanger.CallFunc = func(context.Context, harpy.Request) harpy.Response {
+				panic("unexpected call")
+			}
+
+			request := strings.NewReader(
+				`{"jsonrpc": "2.0", "id": 1, "params": [1]}{"jsonrpc": "2.0", "id": 2, "params": [2]}`,
+			)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32700,
+					"message": "unable to parse request: json: invalid data after top-level value"
+				}
+			}`))
+		})
+	})
+
+	When("other versions are accepted", func() {
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithAcceptedVersions("2", ""),
+			)
+		})
+
+		DescribeTable(
+			"it handles requests with an accepted version and responds with version 2.0",
+			func(request string) {
+				res, err := http.Post(server.URL, "application/json", strings.NewReader(request))
+				Expect(err).ShouldNot(HaveOccurred())
+				defer res.Body.Close()
+
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+				body, err := io.ReadAll(res.Body)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(body).To(MatchJSON(`{
+					"jsonrpc": "2.0",
+					"id": 123,
+					"result": [1, 2, 3]
+				}`))
+			},
+			Entry("legacy version", `{"jsonrpc": "2", "id": 123, "params": [1, 2, 3]}`),
+			Entry("absent version", `{"id": 123, "params": [1, 2, 3]}`),
+			Entry("standard version", `{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`),
+		)
+
+		It("rejects requests with other versions", func() {
+			request := strings.NewReader(`{"jsonrpc": "1.0", "id": 123, "params": [1, 2, 3]}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	When("a parse error hook is specified", func() {
+		type parseError struct {
+			Path string
+			Err  error
+		}
+
+		var errs chan parseError
+
+		BeforeEach(func() {
+			errs = make(chan parseError, 1)
+
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithParseErrorHook(func(ctx context.Context, r *http.Request, err error) {
+					Expect(ctx).NotTo(BeNil())
+					errs <- parseError{r.URL.Path, err}
+				}),
+			)
+		})
+
+		It("calls the hook when the request can not be parsed", func() {
+			request := strings.NewReader(`{"jsonrpc": }`)
+
+			res, err := http.Post(server.URL+"/<path>", "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			var pe parseError
+			Expect(errs).To(Receive(&pe))
+			Expect(pe.Path).To(Equal("/<path>"))
+
+			var nerr harpy.Error
+			Expect(errors.As(pe.Err, &nerr)).To(BeTrue())
+			Expect(nerr.Code()).To(Equal(harpy.ParseErrorCode))
+		})
+
+		It("does not call the hook for requests that are parsed successfully", func() {
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(errs).NotTo(Receive())
+		})
+
+		It("does not call the hook for other errors", func() {
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123}`)
+
+			res, err := http.Post(server.URL, "text/plain", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusUnsupportedMediaType))
+			Expect(errs).NotTo(Receive())
+		})
+	})
+
+	When("a codec is specified", func() {
+		const hexMediaType = "application/x-hex-json"
+
+		// decodeHex decodes a hex-encoded HTTP response body.
+		decodeHex := func(res *http.Response) []byte {
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			data, err := hex.DecodeString(string(body))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			return data
+		}
+
+		BeforeEach(func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithCodec(HexCodec{}, hexMediaType),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("uses the codec for requests and responses", func() {
+			request := strings.NewReader(hex.EncodeToString([]byte(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`)))
+
+			res, err := http.Post(server.URL, hexMediaType, request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal(hexMediaType))
+			Expect(decodeHex(res)).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": 123,
+				"result": [1, 2, 3]
+			}`))
+		})
+
+		It("encodes batched responses together", func() {
+			request := strings.NewReader(hex.EncodeToString([]byte(`[
+				{"jsonrpc": "2.0", "id": 1, "params": [1]},
+				{"jsonrpc": "2.0", "id": 2, "params": [2]}
+			]`)))
+
+			res, err := http.Post(server.URL, hexMediaType, request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			var responses []map[string]any
+			err = json.Unmarshal(decodeHex(res), &responses)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(responses).To(ConsistOf(
+				map[string]any{
+					"jsonrpc": "2.0",
+					"id":      float64(1),
+					"result":  []any{float64(1)},
+				},
+				map[string]any{
+					"jsonrpc": "2.0",
+					"id":      float64(2),
+					"result":  []any{float64(2)},
+				},
+			))
+		})
+
+		It("responds with an error if the request does not use the codec's media-type", func() {
+			request := strings.NewReader(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusUnsupportedMediaType))
+			Expect(decodeHex(res)).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32600,
+					"message": "JSON-RPC requests must use the application/x-hex-json content type"
+				}
+			}`))
+		})
+
+		It("responds with a parse error if the request can not be decoded", func() {
+			request := strings.NewReader(`<not hex>`)
+
+			res, err := http.Post(server.URL, hexMediaType, request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+			var response map[string]any
+			err = json.Unmarshal(decodeHex(res), &response)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(response).To(HaveKeyWithValue("error", HaveKeyWithValue("code", float64(-32700))))
+		})
+
+		It("panics if the media-type is empty", func() {
+			Expect(func() {
+				WithCodec(HexCodec{}, "")
+			}).To(PanicWith("the media-type must not be empty"))
+		})
+	})
+
+	When("batch streaming is enabled", func() {
+		BeforeEach(func() {
+			handler = NewHandler(
+				exchanger,
+				WithBatchStreaming(),
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("sends each batched response to the client as soon as it is written", func() {
+			server.Config.Handler = handler
+			release := make(chan struct{})
+			defer func() {
+				select {
+				case <-release:
+				default:
+					close(release)
+				}
+			}()
+
+			next := exchanger.CallFunc
+			exchanger.CallFunc = func(
+				ctx context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				if string(req.ID) == "2" {
+					<-release
+				}
+				return next(ctx, req)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(
+				ctx,
+				http.MethodPost,
+				server.URL,
+				strings.NewReader(`[
+					{"jsonrpc": "2.0", "id": 1, "params": [1]},
+					{"jsonrpc": "2.0", "id": 2, "params": [2]}
+				]`),
+			)
+			Expect(err).ShouldNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+
+			res, err := http.DefaultClient.Do(req)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			// Read the first response before the second one is produced.
+			var body []byte
+			buf := make([]byte, 1024)
+			for !bytes.Contains(body, []byte(`"id":1`)) {
+				n, err := res.Body.Read(buf)
+				Expect(err).ShouldNot(HaveOccurred())
+				body = append(body, buf[:n]...)
+			}
+
+			close(release)
+
+			rest, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			body = append(body, rest...)
+
+			Expect(body).To(MatchJSON(`[
+				{"jsonrpc": "2.0", "id": 1, "result": [1]},
+				{"jsonrpc": "2.0", "id": 2, "result": [2]}
+			]`))
+		})
+
+		It("does not fail if the target does not support flushing", func() {
+			r := httptest.NewRequest(
+				http.MethodPost,
+				"/",
+				strings.NewReader(`[
+					{"jsonrpc": "2.0", "id": 1, "params": [1]}
+				]`),
+			)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := &nonFlushingResponseWriter{httptest.NewRecorder()}
+			handler.ServeHTTP(w, r)
+
+			Expect(w.Recorder.Code).To(Equal(http.StatusOK))
+			Expect(w.Recorder.Body.Bytes()).To(MatchJSON(`[
+				{"jsonrpc": "2.0", "id": 1, "result": [1]}
+			]`))
+		})
+	})
+
+	When("the result is streamed", func() {
+		var closed *atomic.Bool
+
+		BeforeEach(func() {
+			closed = &atomic.Bool{}
+
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewSuccessResponse(
+					req.ID,
+					harpy.StreamResult{
+						Reader: &readCloser{
+							Reader: bytes.NewReader(req.Parameters),
+							Closed: closed,
+						},
+					},
+				)
+			}
+
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+			)
+		})
+
+		It("copies the result into the response", func() {
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(body)).To(Equal(
+				`{"jsonrpc":"2.0","id":123,"result":[1, 2, 3]}` + "\n",
+			))
+			Expect(closed.Load()).To(BeTrue())
+		})
+
+		It("copies the result into batched responses", func() {
+			request = strings.NewReader(`[
+				{"jsonrpc": "2.0", "id": 1, "params": [1]},
+				{"jsonrpc": "2.0", "id": 2, "params": [2]}
+			]`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			var responses []json.RawMessage
+			err = json.NewDecoder(res.Body).Decode(&responses)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(responses).To(ConsistOf(
+				MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": [1]}`),
+				MatchJSON(`{"jsonrpc": "2.0", "id": 2, "result": [2]}`),
+			))
+		})
+
+		It("buffers the result if indentation is enabled", func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithIndent("  "),
+			)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring("\n  \"result\": [\n    1,"))
+			Expect(closed.Load()).To(BeTrue())
+		})
+
+		It("buffers the result if a codec is specified", func() {
+			server.Config.Handler = NewHandler(
+				exchanger,
+				WithZapLogger(zap.NewNop()),
+				WithCodec(HexCodec{}, "application/x-hex-json"),
+			)
+
+			request := strings.NewReader(hex.EncodeToString([]byte(`{"jsonrpc": "2.0", "id": 123, "params": [1, 2, 3]}`)))
+
+			res, err := http.Post(server.URL, "application/x-hex-json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			data, err := hex.DecodeString(string(body))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(data).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": 123,
+				"result": [1, 2, 3]
+			}`))
+		})
+	})
+
+	When("the response can not be written", func() {
+		It("cancels the context passed to the exchanger", func() {
+			var cause error
+
+			exchanger.CallFunc = func(
+				ctx context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				if string(req.ID) == "1" {
+					return harpy.NewSuccessResponse(req.ID, nil)
+				}
+
+				<-ctx.Done()
+				cause = context.Cause(ctx)
+
+				return harpy.NewErrorResponse(req.ID, ctx.Err())
+			}
+
+			r := httptest.NewRequest(
+				http.MethodPost,
+				"/",
+				strings.NewReader(`[
+					{"jsonrpc": "2.0", "id": 1, "params": []},
+					{"jsonrpc": "2.0", "id": 2, "params": []}
+				]`),
+			)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := &disconnectedResponseWriter{httptest.NewRecorder()}
+			Expect(func() {
+				handler.ServeHTTP(w, r)
+			}).To(PanicWith(http.ErrAbortHandler))
+
+			Expect(cause).To(MatchError(syscall.EPIPE))
+		})
+
+		It("does not close the batch after a response is partially written", func() {
+			r := httptest.NewRequest(
+				http.MethodPost,
+				"/",
+				strings.NewReader(`[
+					{"jsonrpc": "2.0", "id": 1, "params": []},
+					{"jsonrpc": "2.0", "id": 2, "params": []}
+				]`),
+			)
+			r.Header.Set("Content-Type", "application/json")
+
+			rec := httptest.NewRecorder()
+			w := &failingResponseWriter{
+				ResponseRecorder: rec,
+				Remaining:        2, // the opening bracket and the first response
+			}
+
+			Expect(func() {
+				handler.ServeHTTP(w, r)
+			}).To(PanicWith(http.ErrAbortHandler))
+
+			Expect(rec.Body.String()).To(HavePrefix("["))
+			Expect(rec.Body.String()).NotTo(HaveSuffix("]"))
+		})
+
+		It("aborts the HTTP response if a result stream fails part way through a batch", func() {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewSuccessResponse(
+					req.ID,
+					harpy.StreamResult{
+						Reader: io.MultiReader(
+							strings.NewReader(`{"partial":`),
+							iotest.NewFailer(nil, nil),
+						),
+					},
+				)
+			}
+
+			server.Config.Handler = NewHandler(exchanger, WithZapLogger(zap.NewNop()))
+
+			request := strings.NewReader(`[
+				{"jsonrpc": "2.0", "id": 1, "params": []},
+				{"jsonrpc": "2.0", "id": 2, "params": []}
+			]`)
+
+			res, err := http.Post(server.URL, "application/json", request)
+			if err == nil {
+				defer res.Body.Close()
+				_, err = io.ReadAll(res.Body)
+			}
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("an audit sink is configured", func() {
+		var (
+			auditedRequest    []byte
+			auditedResponse   []byte
+			requestTruncated  bool
+			responseTruncated bool
+		)
+
+		BeforeEach(func() {
+			auditedRequest = nil
+			auditedResponse = nil
+			requestTruncated = false
+			responseTruncated = false
+
+			handler = NewHandler(
+				exchanger,
+				WithAuditSink(func(_ context.Context, req, res AuditBody) {
+					auditedRequest = req.Data
+					auditedResponse = res.Data
+					requestTruncated = req.Truncated
+					responseTruncated = res.Truncated
+				}),
+				WithZapLogger(zap.NewNop()),
+			)
+
+			server.Config.Handler = handler
+		})
+
+		It("passes the raw request and response bodies to the sink", func() {
+			body := `{"jsonrpc":"2.0","id":123,"params":[1,2,3]}`
+
+			res, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			data, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(string(auditedRequest)).To(Equal(body))
+			Expect(requestTruncated).To(BeFalse())
+			Expect(auditedResponse).To(Equal(data))
+			Expect(responseTruncated).To(BeFalse())
+			Expect(auditedResponse).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": 123,
+				"result": [1, 2, 3]
+			}`))
+		})
+
+		It("passes the complete batch response to the sink", func() {
+			body := `[{"jsonrpc":"2.0","id":123,"params":[1,2,3]},{"jsonrpc":"2.0","id":456,"params":[4,5,6]}]`
+
+			res, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			data, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(string(auditedRequest)).To(Equal(body))
+			Expect(auditedResponse).To(Equal(data))
+		})
+
+		It("captures trailing request data that is not parsed", func() {
+			body := `{"jsonrpc":"2.0","id":123,"params":[1,2,3]}` + strings.Repeat(" ", 10000) + "<trailing>"
+
+			res, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+
+			Expect(string(auditedRequest)).To(Equal(body))
+		})
+
+		It("truncates request and response bodies that exceed the maximum size", func() {
+			// The exchanger echoes the parameters, so the response is also
+			// larger than the limit.
+			params := `["` + strings.Repeat("x", MaxAuditBodySize) + `"]`
+			body := `{"jsonrpc":"2.0","id":123,"params":` + params + `}`
+
+			res, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			data, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(string(auditedRequest)).To(Equal(body[:MaxAuditBodySize]))
+			Expect(requestTruncated).To(BeTrue())
+			Expect(auditedResponse).To(Equal(data[:MaxAuditBodySize]))
+			Expect(responseTruncated).To(BeTrue())
+		})
+
+		It("does not read unparsed request data beyond the maximum size", func() {
+			body := &countingReader{
+				Reader: strings.NewReader(strings.Repeat(" ", 4*MaxAuditBodySize)),
+			}
+
+			// The request is rejected before the body is read, because it does
+			// not use the POST method.
+			r := httptest.NewRequest(http.MethodPut, "/", body)
+			r.Header.Set("Content-Type", "application/json")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			Expect(auditedRequest).To(HaveLen(MaxAuditBodySize))
+			Expect(requestTruncated).To(BeTrue())
+			Expect(body.Count).To(Equal(MaxAuditBodySize + 1))
+		})
+	})
+
+	DescribeTable(
+		"it maps JSON-RPC error codes to the appropriate HTTP status code",
+		func(err error, statusCode int) {
+			exchanger.CallFunc = func(
+				_ context.Context,
+				req harpy.Request,
+			) harpy.Response {
+				return harpy.NewErrorResponse(req.ID, err)
+			}
+
+			res, err := http.Post(server.URL, "application/json", request)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res.StatusCode).To(Equal(statusCode))
+		},
+		Entry("method not found", harpy.MethodNotFound(), http.StatusNotImplemented),
+		Entry("invalid parameters", harpy.InvalidParameters(), http.StatusBadRequest),
+		Entry("internal error", harpy.NewErrorWithReservedCode(harpy.InternalErrorCode), http.StatusInternalServerError),
+		Entry("a native JSON-RPC error with an unreserved code", harpy.NewError(123), http.StatusOK),
+		Entry("canceled", context.Canceled, http.StatusServiceUnavailable),
+		Entry("deadline exceeded", context.DeadlineExceeded, http.StatusGatewayTimeout),
+	)
+})
+
+// countingReader is an io.Reader that counts the number of bytes read.
+type countingReader struct {
+	io.Reader
+	Count int
+}
+
+func (r *countingReader) Read(data []byte) (int, error) {
+	n, err := r.Reader.Read(data)
+	r.Count += n
+	return n, err
+}
+
+// disconnectedResponseWriter is an http.ResponseWriter that fails all writes as
+// though the client has disconnected.
+type disconnectedResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w *disconnectedResponseWriter) Write([]byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+// failingResponseWriter is an http.ResponseWriter that fails once a number of
+// writes have succeeded.
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+	Remaining int
+}
+
+func (w *failingResponseWriter) Write(data []byte) (int, error) {
+	if w.Remaining == 0 {
+		return 0, syscall.EPIPE
+	}
+
+	w.Remaining--
+	return w.ResponseRecorder.Write(data)
+}
+
+// nonFlushingResponseWriter is an http.ResponseWriter that does not implement
+// http.Flusher.
+type nonFlushingResponseWriter struct {
+	Recorder *httptest.ResponseRecorder
+}
+
+func (w *nonFlushingResponseWriter) Header() http.Header {
+	return w.Recorder.Header()
+}
+
+func (w *nonFlushingResponseWriter) Write(data []byte) (int, error) {
+	return w.Recorder.Write(data)
+}
+
+func (w *nonFlushingResponseWriter) WriteHeader(code int) {
+	w.Recorder.WriteHeader(code)
+}
+
+// readCloser is an io.ReadCloser that records whether it has been closed.
+type readCloser struct {
+	io.Reader
+	Closed *atomic.Bool
+}
+
+func (r *readCloser) Close() error {
+	r.Closed.Store(true)
+	return nil
+}
+
+// incompressibleReader is an io.Reader that hints that its content should not
+// be compressed.
+type incompressibleReader struct {
+	io.Reader
+}
+
+func (incompressibleReader) CompressionHint() harpy.CompressionHint {
+	return harpy.CompressionHint{Size: -1, Incompressible: true}
+}
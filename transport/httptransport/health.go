@@ -0,0 +1,51 @@
+package httptransport
+
+import (
+	"net/http"
+)
+
+// DefaultHealthCheckPath is the URL path at which health checks are served
+// when WithHealthCheck() is used with an empty path.
+const DefaultHealthCheckPath = "/healthz"
+
+// healthCheckBody is the HTTP response body sent in response to health checks.
+const healthCheckBody = "ok\n"
+
+// WithHealthCheck is a HandlerOption that configures the handler to respond to
+// GET and HEAD requests for the given URL path with HTTP 200 (OK), such that it
+// may be used as a liveness probe by load balancers and orchestrators.
+//
+// Health checks bypass JSON-RPC entirely; the exchanger is never invoked. All
+// other requests, including POST requests to the same path, are handled as
+// JSON-RPC requests.
+//
+// If path is empty, DefaultHealthCheckPath is used.
+func WithHealthCheck(path string) HandlerOption {
+	if path == "" {
+		path = DefaultHealthCheckPath
+	}
+
+	return func(h *Handler) {
+		h.healthCheckPath = path
+	}
+}
+
+// isHealthCheck returns true if r is a health check request.
+func (h *Handler) isHealthCheck(r *http.Request) bool {
+	if h.healthCheckPath == "" || r.URL.Path != h.healthCheckPath {
+		return false
+	}
+
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// serveHealthCheck responds to a health check request.
+func serveHealthCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method != http.MethodHead {
+		w.Write([]byte(healthCheckBody)) // nolint:errcheck // nothing to do if the probe has gone away
+	}
+}
@@ -0,0 +1,90 @@
+package httptransport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("func WithHealthCheck()", func() {
+	var (
+		exchanger *ExchangerStub
+		handler   http.Handler
+	)
+
+	BeforeEach(func() {
+		exchanger = &ExchangerStub{
+			CallFunc: func(_ context.Context, req harpy.Request) harpy.Response {
+				return harpy.NewSuccessResponse(req.ID, "<result>")
+			},
+		}
+
+		handler = NewHandler(
+			exchanger,
+			WithZapLogger(zap.NewNop()),
+			WithHealthCheck(""),
+		)
+	})
+
+	// serve serves an HTTP request and returns the HTTP response.
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	It("responds to GET requests for the health check path", func() {
+		exchanger.CallFunc = func(context.Context, harpy.Request) harpy.Response {
+			Fail("unexpected call")
+			return nil
+		}
+
+		w := serve(http.MethodGet, DefaultHealthCheckPath, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+		Expect(w.Body.String()).To(Equal("ok\n"))
+	})
+
+	It("responds to HEAD requests for the health check path without a body", func() {
+		w := serve(http.MethodHead, DefaultHealthCheckPath, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.Len()).To(BeZero())
+	})
+
+	It("uses the given path", func() {
+		handler = NewHandler(
+			exchanger,
+			WithZapLogger(zap.NewNop()),
+			WithHealthCheck("/live"),
+		)
+
+		w := serve(http.MethodGet, "/live", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = serve(http.MethodGet, DefaultHealthCheckPath, "")
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("handles POST requests to the health check path as JSON-RPC requests", func() {
+		w := serve(http.MethodPost, DefaultHealthCheckPath, `{"jsonrpc": "2.0", "id": 1}`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": "<result>"}`))
+	})
+
+	It("handles GET requests to other paths as JSON-RPC requests", func() {
+		w := serve(http.MethodGet, "/", "")
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,115 @@
+package httptransport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// NewInProcessClient returns a client that sends requests directly to an
+// HTTP handler that uses e to perform JSON-RPC exchanges, without using the
+// network.
+//
+// The options are applied to the handler, as per NewHandler(). The client uses
+// the same codec as the handler, if one is specified by WithCodec(). It is
+// intended for use in tests, where it avoids the need to start an HTTP server.
+func NewInProcessClient(e harpy.Exchanger, options ...HandlerOption) *Client {
+	h := NewHandler(e, options...).(*Handler)
+
+	return &Client{
+		HTTPClient: &http.Client{
+			Transport: &handlerRoundTripper{
+				Handler: h,
+			},
+		},
+		URL:       "http://in-process/",
+		Codec:     h.codec,
+		MediaType: h.mediaType,
+	}
+}
+
+// handlerRoundTripper is an implementation of http.RoundTripper that serves
+// requests using an http.Handler within the same process.
+type handlerRoundTripper struct {
+	Handler http.Handler
+}
+
+// RoundTrip executes a single HTTP transaction.
+func (t *handlerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.RemoteAddr = "in-process"
+	r.RequestURI = req.URL.RequestURI()
+
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	defer r.Body.Close()
+
+	w := &bufferedResponseWriter{
+		header: http.Header{},
+	}
+
+	if err := serve(t.Handler, w, r); err != nil {
+		return nil, err
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          io.NopCloser(&w.body),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// serve calls h.ServeHTTP(), returning an error if the handler aborts the
+// response by panicking with http.ErrAbortHandler.
+func serve(h http.Handler, w http.ResponseWriter, r *http.Request) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			if p != http.ErrAbortHandler {
+				panic(p)
+			}
+
+			err = errors.New("the HTTP handler aborted the response")
+		}
+	}()
+
+	h.ServeHTTP(w, r)
+	return nil
+}
+
+// bufferedResponseWriter is an implementation of http.ResponseWriter that
+// buffers the response in memory.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
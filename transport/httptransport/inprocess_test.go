@@ -0,0 +1,120 @@
+package httptransport_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	"github.com/dogmatiq/iago/iotest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("func NewInProcessClient()", func() {
+	var (
+		ctx      context.Context
+		notified []int
+		client   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		notified = nil
+
+		client = NewInProcessClient(
+			harpy.NewRouter(
+				harpy.WithRoute(
+					"echo",
+					func(_ context.Context, params []int) ([]int, error) {
+						return params, nil
+					},
+				),
+				harpy.WithRoute(
+					"notify",
+					harpy.NoResult(
+						func(_ context.Context, params []int) error {
+							notified = params
+							return nil
+						},
+					),
+				),
+				harpy.WithRoute(
+					"error",
+					harpy.NoResult(
+						func(context.Context, []int) error {
+							return harpy.NewError(123, harpy.WithMessage("<message>"))
+						},
+					),
+				),
+			),
+			WithZapLogger(zap.NewNop()),
+		)
+	})
+
+	It("returns a client that can make calls without a network", func() {
+		var result []int
+		err := client.Call(ctx, "echo", []int{1, 2, 3}, &result)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("returns a client that can send notifications without a network", func() {
+		err := client.Notify(ctx, "notify", []int{1, 2, 3})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(notified).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("returns JSON-RPC errors produced by the exchanger", func() {
+		var result any
+		err := client.Call(ctx, "error", []int{}, &result)
+
+		var rpcErr harpy.Error
+		Expect(errors.As(err, &rpcErr)).To(BeTrue())
+		Expect(rpcErr.Code()).To(BeEquivalentTo(123))
+		Expect(rpcErr.Message()).To(Equal("<message>"))
+	})
+
+	It("returns an error if the handler aborts the response", func() {
+		client = NewInProcessClient(
+			&ExchangerStub{
+				CallFunc: func(_ context.Context, req harpy.Request) harpy.Response {
+					return harpy.NewSuccessResponse(
+						req.ID,
+						harpy.StreamResult{Reader: iotest.NewFailer(nil, nil)},
+					)
+				},
+			},
+			WithZapLogger(zap.NewNop()),
+		)
+
+		var result any
+		err := client.Call(ctx, "echo", []int{}, &result)
+		Expect(err).To(MatchError(ContainSubstring("the HTTP handler aborted the response")))
+	})
+
+	It("returns a client that uses the same codec as the handler", func() {
+		client = NewInProcessClient(
+			harpy.NewRouter(
+				harpy.WithRoute(
+					"echo",
+					func(_ context.Context, params []int) ([]int, error) {
+						return params, nil
+					},
+				),
+			),
+			WithZapLogger(zap.NewNop()),
+			WithCodec(HexCodec{}, "application/x-hex-json"),
+		)
+
+		Expect(client.Codec).To(Equal(HexCodec{}))
+		Expect(client.MediaType).To(Equal("application/x-hex-json"))
+
+		var result []int
+		err := client.Call(ctx, "echo", []int{1, 2, 3}, &result)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result).To(Equal([]int{1, 2, 3}))
+	})
+})
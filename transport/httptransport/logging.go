@@ -9,13 +9,25 @@ import (
 
 // WithZapLogger is a HandlerOption that configures the handler to use a
 // harpy.ZapExchangeLogger for logging requests and responses.
-func WithZapLogger(logger *zap.Logger) HandlerOption {
+//
+// Each log message includes the address of the immediate peer and the IP
+// address of the client, as returned by RemoteAddrFromContext().
+//
+// The options are passed to harpy.NewZapExchangeLogger().
+func WithZapLogger(logger *zap.Logger, options ...harpy.ExchangeLoggerOption) HandlerOption {
 	return func(h *Handler) {
 		h.newLogger = func(r *http.Request) harpy.ExchangeLogger {
+			fields := []zap.Field{
+				zap.String("remote_addr", r.RemoteAddr),
+			}
+
+			if ip, ok := RemoteAddrFromContext(r.Context()); ok {
+				fields = append(fields, zap.String("client_ip", ip))
+			}
+
 			return harpy.NewZapExchangeLogger(
-				logger.With(
-					zap.String("remote_addr", r.RemoteAddr),
-				),
+				logger.With(fields...),
+				options...,
 			)
 		}
 	}
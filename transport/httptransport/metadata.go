@@ -0,0 +1,111 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DeprecationHeader is the HTTP response header used by SetDeprecation() to
+// indicate that the called method is deprecated.
+const DeprecationHeader = "X-API-Deprecation"
+
+// responseHeadersKey is the context key used to store the HTTP response
+// headers added by AddResponseHeader().
+type responseHeadersKey struct{}
+
+// responseHeaders is a set of HTTP response headers that may be added to
+// concurrently.
+type responseHeaders struct {
+	m      sync.Mutex
+	header http.Header
+}
+
+// AddResponseHeader adds an HTTP header to the response to the JSON-RPC
+// request associated with ctx.
+//
+// It allows handlers to send metadata that is not part of the JSON-RPC result,
+// such as the server version, without altering the response body. Headers
+// that are managed by the transport itself, namely Content-Type,
+// Content-Length, Content-Encoding and Transfer-Encoding, can not be added.
+//
+// The header is sent regardless of whether the call succeeds. Within a batch,
+// the headers added while handling each request are combined. They are only
+// sent if they are added before the HTTP response headers are written, which
+// may occur as soon as the first response within the batch is produced.
+//
+// It returns false if ctx is not associated with a request served by a
+// Handler, or if name is a header that is managed by the transport.
+func AddResponseHeader(ctx context.Context, name, value string) bool {
+	if isManagedHeader(name) {
+		return false
+	}
+
+	h, ok := ctx.Value(responseHeadersKey{}).(*responseHeaders)
+	if ok {
+		h.m.Lock()
+		h.header.Add(name, value)
+		h.m.Unlock()
+	}
+
+	return ok
+}
+
+// isManagedHeader returns true if the HTTP response header with the given
+// name is managed by the ResponseWriter, and therefore must not be set by
+// AddResponseHeader() or ResponseWriter.Headers.
+func isManagedHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding":
+		return true
+	default:
+		return false
+	}
+}
+
+// SetDeprecation indicates that the method being called via ctx is deprecated
+// by adding a DeprecationHeader to the response, with the given message as its
+// value.
+//
+// See AddResponseHeader() for details about when the header is sent. It
+// returns false if ctx is not associated with a request served by a Handler.
+func SetDeprecation(ctx context.Context, message string) bool {
+	return AddResponseHeader(ctx, DeprecationHeader, message)
+}
+
+// AddWarning adds a "Warning" header to the response containing the given
+// message, such as to alert the client to a problem that did not prevent the
+// call from succeeding.
+//
+// The header uses the 299 ("miscellaneous persistent warning") warn-code, as
+// per RFC 7234.
+//
+// See AddResponseHeader() for details about when the header is sent. It
+// returns false if ctx is not associated with a request served by a Handler.
+func AddWarning(ctx context.Context, message string) bool {
+	return AddResponseHeader(ctx, "Warning", `299 - `+quoteString(message))
+}
+
+// quoteString returns s as an HTTP quoted-string.
+func quoteString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// withResponseHeaders returns a copy of r with a location in which HTTP
+// response headers can be stored by AddResponseHeader().
+//
+// It returns a function that returns a copy of the stored headers.
+func withResponseHeaders(r *http.Request) (*http.Request, func() http.Header) {
+	h := &responseHeaders{
+		header: http.Header{},
+	}
+	ctx := context.WithValue(r.Context(), responseHeadersKey{}, h)
+
+	return r.WithContext(ctx), func() http.Header {
+		h.m.Lock()
+		defer h.m.Unlock()
+
+		return h.header.Clone()
+	}
+}
@@ -0,0 +1,132 @@
+package httptransport_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("func AddResponseHeader()", func() {
+	var exchanger *ExchangerStub
+
+	BeforeEach(func() {
+		exchanger = &ExchangerStub{
+			CallFunc: func(ctx context.Context, req harpy.Request) harpy.Response {
+				ok := AddResponseHeader(ctx, "X-Server-Version", "1.2.3")
+				Expect(ok).To(BeTrue())
+
+				return harpy.NewSuccessResponse(req.ID, nil)
+			},
+		}
+	})
+
+	// serve serves a request with the given body and returns the HTTP
+	// response.
+	serve := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(body),
+		)
+		r.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		NewHandler(exchanger, WithZapLogger(zap.NewNop())).ServeHTTP(w, r)
+
+		return w
+	}
+
+	It("adds the header to a successful response without altering the body", func() {
+		w := serve(`{"jsonrpc": "2.0", "id": 123}`)
+		Expect(w.Header().Get("X-Server-Version")).To(Equal("1.2.3"))
+		Expect(w.Body.String()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": null}`))
+	})
+
+	It("adds the header to an error response", func() {
+		exchanger.CallFunc = func(ctx context.Context, req harpy.Request) harpy.Response {
+			AddResponseHeader(ctx, "X-Server-Version", "1.2.3")
+			return harpy.NewErrorResponse(req.ID, errors.New("<error>"))
+		}
+
+		w := serve(`{"jsonrpc": "2.0", "id": 123}`)
+		Expect(w.Header().Get("X-Server-Version")).To(Equal("1.2.3"))
+	})
+
+	It("adds the header to the response to a notification", func() {
+		exchanger.NotifyFunc = func(ctx context.Context, req harpy.Request) error {
+			AddResponseHeader(ctx, "X-Server-Version", "1.2.3")
+			return nil
+		}
+
+		w := serve(`{"jsonrpc": "2.0"}`)
+		Expect(w.Code).To(Equal(http.StatusNoContent))
+		Expect(w.Header().Get("X-Server-Version")).To(Equal("1.2.3"))
+	})
+
+	DescribeTable(
+		"it does not add headers that are managed by the transport",
+		func(name, value string) {
+			exchanger.CallFunc = func(ctx context.Context, req harpy.Request) harpy.Response {
+				ok := AddResponseHeader(ctx, name, value)
+				Expect(ok).To(BeFalse())
+				return harpy.NewSuccessResponse(req.ID, nil)
+			}
+
+			w := serve(`{"jsonrpc": "2.0", "id": 123}`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Values("Content-Type")).To(Equal([]string{"application/json"}))
+			Expect(w.Header().Values("Content-Encoding")).To(BeEmpty())
+			Expect(w.Header().Values("Content-Length")).To(BeEmpty())
+			Expect(w.Header().Values("Transfer-Encoding")).To(BeEmpty())
+			Expect(w.Body.String()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": null}`))
+		},
+		Entry("Content-Type", "Content-Type", "text/plain"),
+		Entry("Content-Length", "Content-Length", "1"),
+		Entry("Content-Encoding", "Content-Encoding", "gzip"),
+		Entry("Transfer-Encoding", "Transfer-Encoding", "identity"),
+		Entry("non-canonical name", "content-encoding", "gzip"),
+	)
+
+	It("returns false if the context is not associated with a handler", func() {
+		ok := AddResponseHeader(context.Background(), "X-Server-Version", "1.2.3")
+		Expect(ok).To(BeFalse())
+	})
+
+	Describe("func SetDeprecation()", func() {
+		It("adds the deprecation header", func() {
+			exchanger.CallFunc = func(ctx context.Context, req harpy.Request) harpy.Response {
+				SetDeprecation(ctx, "use <other> instead")
+				return harpy.NewSuccessResponse(req.ID, nil)
+			}
+
+			w := serve(`{"jsonrpc": "2.0", "id": 123}`)
+			Expect(w.Header().Get(DeprecationHeader)).To(Equal("use <other> instead"))
+		})
+	})
+
+	Describe("func AddWarning()", func() {
+		It("adds a warning header with a quoted message", func() {
+			exchanger.CallFunc = func(ctx context.Context, req harpy.Request) harpy.Response {
+				AddWarning(ctx, `the "limit" parameter was ignored`)
+				AddWarning(ctx, `results are stale`)
+				return harpy.NewSuccessResponse(req.ID, nil)
+			}
+
+			w := serve(`{"jsonrpc": "2.0", "id": 123}`)
+			Expect(w.Header().Values("Warning")).To(Equal([]string{
+				`299 - "the \"limit\" parameter was ignored"`,
+				`299 - "results are stale"`,
+			}))
+		})
+	})
+})
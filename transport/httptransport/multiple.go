@@ -0,0 +1,144 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// WithMultipleRequestSets is a HandlerOption that allows a single HTTP request
+// body to contain several independent request sets, one after the other.
+//
+// Each request set is read and exchanged in turn until the end of the body is
+// reached. The responses to each request set are written to the HTTP response
+// body in the same order, each followed by a newline. The HTTP status code is
+// determined by the first request set that produces a response. A parse error
+// ends the exchange, as the remainder of the body can not be split into
+// request sets. Anything other than whitespace after the last request set is
+// also a parse error.
+//
+// This is a non-standard extension to the JSON-RPC specification. Request
+// bodies that contain a single request set are handled as usual. Response
+// compression and buffering, as enabled by WithResponseCompression() and
+// WithBufferedResponses(), are not performed when this option is used.
+//
+// Multiple request sets are not permitted by default. NewHandler() panics if
+// this option is used with a codec other than harpy.JSONCodec.
+func WithMultipleRequestSets() HandlerOption {
+	return func(h *Handler) {
+		h.multipleRequestSets = true
+	}
+}
+
+// exchangeMultiple performs a JSON-RPC exchange for each of the request sets
+// within the HTTP request body.
+func exchangeMultiple(
+	ctx context.Context,
+	server *harpy.Server,
+	reader *RequestSetReader,
+	writer *ResponseWriter,
+) {
+	target := &delimitedResponseWriter{
+		ResponseWriter: writer.Target,
+	}
+
+	writer.Reset(target)
+
+	for {
+		server.Handle(ctx, reader, writer) // nolint:errcheck // error already logged, nothing more to do
+
+		if writer.Incomplete() {
+			// The HTTP response body is not valid JSON. See Handler.exchange().
+			panic(http.ErrAbortHandler)
+		}
+
+		if !reader.More() {
+			break
+		}
+
+		if err := target.Delimit(); err != nil {
+			server.Logger.LogWriterError(ctx, err)
+			panic(http.ErrAbortHandler)
+		}
+
+		writer.Reset(target)
+
+		// The additional headers have already been added to the HTTP
+		// response, and must not be added again.
+		writer.Headers = nil
+	}
+
+	target.Close()
+}
+
+// delimitedResponseWriter is an http.ResponseWriter that allows the responses
+// to several request sets to be written to the same HTTP response.
+//
+// Only the first HTTP status code is sent. If the first request set does not
+// produce any responses, its HTTP 204 (No Content) status is deferred until
+// Close() is called, as the responses to subsequent request sets require a
+// body.
+type delimitedResponseWriter struct {
+	http.ResponseWriter
+
+	wroteHeader bool
+	noContent   bool
+	last        byte
+}
+
+// WriteHeader sends an HTTP response header with the given status code, unless
+// a header has already been sent.
+func (w *delimitedResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	if status == http.StatusNoContent {
+		w.noContent = true
+		return
+	}
+
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes data to the HTTP response body.
+func (w *delimitedResponseWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+
+	n, err := w.ResponseWriter.Write(data)
+	if n > 0 {
+		w.last = data[n-1]
+	}
+
+	return n, err
+}
+
+// Delimit writes a newline to the HTTP response body, unless the body is
+// empty or already ends with a newline.
+func (w *delimitedResponseWriter) Delimit() error {
+	if !w.wroteHeader || w.last == '\n' {
+		return nil
+	}
+
+	_, err := w.Write([]byte("\n"))
+	return err
+}
+
+// Close sends the deferred HTTP 204 (No Content) status if none of the request
+// sets produced a response.
+func (w *delimitedResponseWriter) Close() {
+	if !w.wroteHeader && w.noContent {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+//
+// It allows http.ResponseController to access features of the underlying
+// writer.
+func (w *delimitedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
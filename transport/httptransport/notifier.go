@@ -0,0 +1,229 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// eventStreamMediaType is the MIME media-type used for server-sent events.
+const eventStreamMediaType = "text/event-stream"
+
+// Notifier is an implementation of http.Handler that pushes server-originated
+// JSON-RPC notifications to clients using server-sent events (SSE).
+//
+// Clients connect with a long-lived HTTP GET request. Each notification is sent
+// as a single event with a "data" field containing the JSON-RPC request object
+// and an "id" field that clients may use to resume the stream via the
+// "Last-Event-ID" header after reconnecting.
+type Notifier struct {
+	// historySize is the maximum number of events that are retained in order
+	// to replay them to reconnecting clients.
+	historySize int
+
+	// bufferSize is the number of events that are buffered for each client.
+	bufferSize int
+
+	m       sync.Mutex
+	lastID  uint64
+	history []notifierEvent
+	clients map[chan notifierEvent]struct{}
+}
+
+// NotifierOption configures the behavior of a notifier.
+type NotifierOption func(*Notifier)
+
+// WithEventHistory is a NotifierOption that sets the number of recent events
+// that are retained so that they can be replayed to clients that reconnect
+// with a "Last-Event-ID" header.
+//
+// The default is 100. A value of zero disables resumption.
+func WithEventHistory(size int) NotifierOption {
+	if size < 0 {
+		panic("event history size must not be negative")
+	}
+
+	return func(n *Notifier) {
+		n.historySize = size
+	}
+}
+
+// WithClientBufferSize is a NotifierOption that sets the number of events that
+// are buffered for each client.
+//
+// A client that falls further behind is disconnected. It may reconnect and
+// resume from its last event, provided that the event is still within the
+// notifier's history.
+//
+// The default is 16.
+func WithClientBufferSize(size int) NotifierOption {
+	if size <= 0 {
+		panic("client buffer size must be positive")
+	}
+
+	return func(n *Notifier) {
+		n.bufferSize = size
+	}
+}
+
+// NewNotifier returns a new notifier.
+func NewNotifier(options ...NotifierOption) *Notifier {
+	n := &Notifier{
+		historySize: 100,
+		bufferSize:  16,
+		clients:     map[chan notifierEvent]struct{}{},
+	}
+
+	for _, opt := range options {
+		opt(n)
+	}
+
+	return n
+}
+
+// notifierEvent is a single event sent by a notifier.
+type notifierEvent struct {
+	ID   uint64
+	Data []byte
+}
+
+// Notify broadcasts a JSON-RPC notification to all connected clients.
+func (n *Notifier) Notify(method string, params any) error {
+	req, err := harpy.NewNotifyRequest(method, params)
+	if err != nil {
+		return err
+	}
+
+	if err, ok := req.ValidateClientSide(); !ok {
+		return err
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("unable to marshal JSON-RPC notification: %w", err)
+	}
+
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	n.lastID++
+	ev := notifierEvent{n.lastID, data}
+
+	if n.historySize > 0 {
+		if len(n.history) == n.historySize {
+			n.history = n.history[1:]
+		}
+		n.history = append(n.history, ev)
+	}
+
+	for ch := range n.clients {
+		select {
+		case ch <- ev:
+		default:
+			// The client is not keeping up, disconnect it.
+			delete(n.clients, ch)
+			close(ch)
+		}
+	}
+
+	return nil
+}
+
+// ServeHTTP streams notifications to the client until the request's context
+// is canceled.
+func (n *Notifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "notification streams must be requested using the GET method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	ch, missed := n.subscribe(r.Header.Get("Last-Event-ID"))
+	defer n.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", eventStreamMediaType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for _, ev := range missed {
+		if err := writeEvent(w, ev); err != nil {
+			return
+		}
+	}
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe registers a new client.
+//
+// lastEventID is the value of the client's "Last-Event-ID" header, if any. It
+// returns the channel on which new events are delivered, along with any
+// retained events that the client has missed.
+func (n *Notifier) subscribe(lastEventID string) (chan notifierEvent, []notifierEvent) {
+	ch := make(chan notifierEvent, n.bufferSize)
+
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	n.clients[ch] = struct{}{}
+
+	if lastEventID == "" {
+		return ch, nil
+	}
+
+	id, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil {
+		return ch, nil
+	}
+
+	for i, ev := range n.history {
+		if ev.ID > id {
+			return ch, append([]notifierEvent(nil), n.history[i:]...)
+		}
+	}
+
+	return ch, nil
+}
+
+// unsubscribe removes a client that was registered by subscribe().
+func (n *Notifier) unsubscribe(ch chan notifierEvent) {
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	if _, ok := n.clients[ch]; ok {
+		delete(n.clients, ch)
+		close(ch)
+	}
+}
+
+// writeEvent writes ev to w in the server-sent events format.
+func writeEvent(w http.ResponseWriter, ev notifierEvent) error {
+	_, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.ID, ev.Data)
+	return err
+}
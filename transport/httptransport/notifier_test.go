@@ -0,0 +1,210 @@
+package httptransport_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type Notifier", func() {
+	var (
+		ctx      context.Context
+		cancel   context.CancelFunc
+		notifier *Notifier
+		server   *httptest.Server
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+		notifier = NewNotifier()
+		server = httptest.NewServer(notifier)
+	})
+
+	AfterEach(func() {
+		cancel()
+		server.Close()
+	})
+
+	connect := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		Expect(err).ShouldNot(HaveOccurred())
+		return res, bufio.NewReader(res.Body)
+	}
+
+	readEvent := func(r *bufio.Reader) string {
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			Expect(err).ShouldNot(HaveOccurred())
+
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return strings.Join(lines, "\n")
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	Describe("func ServeHTTP()", func() {
+		It("streams notifications as server-sent events", func() {
+			res, r := connect("")
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+			Expect(res.Header.Get("Cache-Control")).To(Equal("no-cache"))
+
+			err := notifier.Notify("update", []int{1, 2, 3})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = notifier.Notify("update", []int{4, 5, 6})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(readEvent(r)).To(Equal(
+				`id: 1` + "\n" +
+					`data: {"jsonrpc":"2.0","method":"update","params":[1,2,3]}`,
+			))
+			Expect(readEvent(r)).To(Equal(
+				`id: 2` + "\n" +
+					`data: {"jsonrpc":"2.0","method":"update","params":[4,5,6]}`,
+			))
+		})
+
+		It("replays missed notifications when the client provides a last event ID", func() {
+			Expect(notifier.Notify("update", []int{1})).To(Succeed())
+			Expect(notifier.Notify("update", []int{2})).To(Succeed())
+			Expect(notifier.Notify("update", []int{3})).To(Succeed())
+
+			res, r := connect("1")
+			defer res.Body.Close()
+
+			Expect(readEvent(r)).To(Equal(
+				`id: 2` + "\n" +
+					`data: {"jsonrpc":"2.0","method":"update","params":[2]}`,
+			))
+			Expect(readEvent(r)).To(Equal(
+				`id: 3` + "\n" +
+					`data: {"jsonrpc":"2.0","method":"update","params":[3]}`,
+			))
+		})
+
+		It("does not replay notifications that are no longer retained", func() {
+			notifier = NewNotifier(WithEventHistory(1))
+			server.Config.Handler = notifier
+
+			Expect(notifier.Notify("update", []int{1})).To(Succeed())
+			Expect(notifier.Notify("update", []int{2})).To(Succeed())
+			Expect(notifier.Notify("update", []int{3})).To(Succeed())
+
+			res, r := connect("1")
+			defer res.Body.Close()
+
+			Expect(readEvent(r)).To(Equal(
+				`id: 3` + "\n" +
+					`data: {"jsonrpc":"2.0","method":"update","params":[3]}`,
+			))
+		})
+
+		It("disconnects clients that do not keep up", func() {
+			notifier = NewNotifier(WithClientBufferSize(1))
+
+			w := &blockingResponseWriter{
+				ResponseRecorder: httptest.NewRecorder(),
+				Written:          make(chan struct{}),
+				Unblock:          make(chan struct{}),
+			}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Last-Event-ID", "0")
+
+			// Send the first event before the client connects so that it is
+			// replayed immediately after the client subscribes.
+			Expect(notifier.Notify("update", []int{1})).To(Succeed())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				notifier.ServeHTTP(w, r)
+			}()
+
+			// Wait for the handler to block while writing the first event.
+			Eventually(w.Written).Should(BeClosed())
+
+			Expect(notifier.Notify("update", []int{2})).To(Succeed()) // buffered
+			Expect(notifier.Notify("update", []int{3})).To(Succeed()) // overflows
+
+			close(w.Unblock)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("stops streaming when the client disconnects", func() {
+			reqCtx, cancelReq := context.WithCancel(ctx)
+			r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+			w := httptest.NewRecorder()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				notifier.ServeHTTP(w, r)
+			}()
+
+			cancelReq()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("responds with an HTTP 405 status for non-GET requests", func() {
+			res, err := http.Post(server.URL, "text/plain", strings.NewReader(""))
+			Expect(err).ShouldNot(HaveOccurred())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+			Expect(res.Header.Get("Allow")).To(Equal(http.MethodGet))
+		})
+	})
+
+	Describe("func Notify()", func() {
+		It("returns an error if the parameters can not be marshaled", func() {
+			err := notifier.Notify("update", func() {})
+			Expect(err).To(MatchError(ContainSubstring("unable to marshal request parameters")))
+		})
+
+		It("returns an error if the notification is invalid", func() {
+			err := notifier.Notify("update", 123)
+			Expect(err).To(MatchError(ContainSubstring("parameters must be an array, an object, or null")))
+		})
+	})
+})
+
+// blockingResponseWriter is an http.ResponseWriter that blocks on the first
+// write of an event until it is unblocked.
+type blockingResponseWriter struct {
+	*httptest.ResponseRecorder
+
+	Written chan struct{}
+	Unblock chan struct{}
+	blocked bool
+}
+
+func (w *blockingResponseWriter) Write(data []byte) (int, error) {
+	if !w.blocked {
+		w.blocked = true
+		close(w.Written)
+		<-w.Unblock
+	}
+
+	return w.ResponseRecorder.Write(data)
+}
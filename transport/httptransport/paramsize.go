@@ -0,0 +1,65 @@
+package httptransport
+
+import (
+	"context"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// WithMaxParameterSize is a HandlerOption that limits the size of the
+// parameters of each individual JSON-RPC request, in bytes.
+//
+// A request with larger parameters is rejected with a JSON-RPC "invalid
+// parameters" error without being passed to the exchanger. Within a batch, only
+// the offending requests are rejected; the other requests are processed as
+// usual.
+//
+// A limit of zero (the default) means there is no limit.
+func WithMaxParameterSize(n int) HandlerOption {
+	if n < 0 {
+		panic("the parameter size limit must not be negative")
+	}
+
+	return func(h *Handler) {
+		h.maxParameterSize = n
+	}
+}
+
+// parameterSizeLimiter is an implementation of harpy.Exchanger that rejects
+// requests with parameters that exceed a maximum size.
+type parameterSizeLimiter struct {
+	Next  harpy.Exchanger
+	Limit int
+}
+
+// Call handles a call request and returns the response.
+func (l *parameterSizeLimiter) Call(ctx context.Context, req harpy.Request) harpy.Response {
+	if err, ok := l.validate(req); !ok {
+		return harpy.NewErrorResponse(req.ID, err)
+	}
+
+	return l.Next.Call(ctx, req)
+}
+
+// Notify handles a notification request.
+func (l *parameterSizeLimiter) Notify(ctx context.Context, req harpy.Request) error {
+	if err, ok := l.validate(req); !ok {
+		return err
+	}
+
+	return l.Next.Notify(ctx, req)
+}
+
+// validate returns an error if the parameters of req are too large.
+func (l *parameterSizeLimiter) validate(req harpy.Request) (harpy.Error, bool) {
+	if len(req.Parameters) <= l.Limit {
+		return harpy.Error{}, true
+	}
+
+	return harpy.InvalidParameters(
+		harpy.WithMessage(
+			"parameters must not exceed %d bytes",
+			l.Limit,
+		),
+	), false
+}
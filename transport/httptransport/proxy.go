@@ -0,0 +1,78 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// Proxy is an implementation of harpy.Exchanger that forwards requests to a
+// remote JSON-RPC server using a Client.
+//
+// It can be used to forward requests from a gateway to an upstream server, for
+// example as the exchanger for methods that are not handled locally.
+//
+// The Client generates its own IDs for upstream requests. The responses
+// returned by Call() always carry the ID of the original request.
+type Proxy struct {
+	// Client is the client used to send requests to the remote server.
+	Client *Client
+}
+
+var _ harpy.Exchanger = (*Proxy)(nil)
+
+// Call handles a call request and returns the response.
+//
+// If the remote server responds with a JSON-RPC error, the returned
+// ErrorResponse contains the same error code, message and data. Any other
+// failure, such as a network error, is reported as an internal error.
+func (p *Proxy) Call(ctx context.Context, req harpy.Request) harpy.Response {
+	var result json.RawMessage
+	err := p.Client.Call(ctx, req.Method, forwardedParams(req), &result)
+
+	if err == nil {
+		return harpy.SuccessResponse{
+			Version:   req.Version,
+			RequestID: req.ID,
+			Result:    result,
+		}
+	}
+
+	if rpcErr, ok := err.(harpy.Error); ok {
+		// CODE COVERAGE: The data of a client-side error is already JSON, so
+		// it can not fail to marshal.
+		data, _, _ := rpcErr.MarshalData()
+
+		return harpy.ErrorResponse{
+			Version:   req.Version,
+			RequestID: req.ID,
+			Error: harpy.ErrorInfo{
+				Code:    rpcErr.Code(),
+				Message: rpcErr.Message(),
+				Data:    data,
+			},
+			ServerError: err,
+		}
+	}
+
+	return harpy.NewErrorResponse(req.ID, err)
+}
+
+// Notify handles a notification request.
+//
+// It returns an error if the notification can not be sent to the remote
+// server, or if the server responds with an error.
+func (p *Proxy) Notify(ctx context.Context, req harpy.Request) error {
+	return p.Client.Notify(ctx, req.Method, forwardedParams(req))
+}
+
+// forwardedParams returns the parameters of req that are forwarded to the
+// remote server.
+func forwardedParams(req harpy.Request) any {
+	if len(req.Parameters) == 0 {
+		return nil
+	}
+
+	return req.Parameters
+}
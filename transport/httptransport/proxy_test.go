@@ -0,0 +1,216 @@
+package httptransport_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("type Proxy", func() {
+	var (
+		ctx      context.Context
+		server   *httptest.Server
+		notified chan json.RawMessage
+		proxy    *Proxy
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		notified = make(chan json.RawMessage, 1)
+		ch := notified
+
+		server = httptest.NewServer(
+			NewHandler(
+				harpy.NewRouter(
+					harpy.WithRoute(
+						"echo",
+						func(_ context.Context, params json.RawMessage) (json.RawMessage, error) {
+							return params, nil
+						},
+					),
+					harpy.WithRoute(
+						"error",
+						harpy.NoResult(
+							func(_ context.Context, params []int) error {
+								return harpy.NewError(
+									123,
+									harpy.WithMessage("<message>"),
+									harpy.WithData(params),
+								)
+							},
+						),
+					),
+					harpy.WithRoute(
+						"notify",
+						harpy.NoResult(
+							func(_ context.Context, params json.RawMessage) error {
+								ch <- params
+								return nil
+							},
+						),
+					),
+				),
+				WithZapLogger(zap.NewNop()),
+			),
+		)
+
+		proxy = &Proxy{
+			Client: &Client{
+				URL: server.URL,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("func Call()", func() {
+		It("returns the result from the remote server with the original request ID", func() {
+			res := proxy.Call(ctx, harpy.Request{
+				Version:    "2.0",
+				ID:         json.RawMessage(`"<id>"`),
+				Method:     "echo",
+				Parameters: json.RawMessage(`[1, 2, 3]`),
+			})
+
+			Expect(res).To(BeAssignableToTypeOf(harpy.SuccessResponse{}))
+
+			successRes := res.(harpy.SuccessResponse)
+			Expect(successRes.Version).To(Equal("2.0"))
+			Expect(successRes.RequestID).To(Equal(json.RawMessage(`"<id>"`)))
+			Expect(successRes.Result).To(MatchJSON(`[1, 2, 3]`))
+		})
+
+		It("forwards requests without parameters", func() {
+			res := proxy.Call(ctx, harpy.Request{
+				Version: "2.0",
+				ID:      json.RawMessage(`123`),
+				Method:  "echo",
+			})
+
+			Expect(res).To(BeAssignableToTypeOf(harpy.SuccessResponse{}))
+			Expect(res.(harpy.SuccessResponse).Result).To(MatchJSON(`null`))
+		})
+
+		It("returns the JSON-RPC error produced by the remote server", func() {
+			res := proxy.Call(ctx, harpy.Request{
+				Version:    "2.0",
+				ID:         json.RawMessage(`123`),
+				Method:     "error",
+				Parameters: json.RawMessage(`[1, 2, 3]`),
+			})
+
+			Expect(res).To(BeAssignableToTypeOf(harpy.ErrorResponse{}))
+
+			errorRes := res.(harpy.ErrorResponse)
+			Expect(errorRes.RequestID).To(Equal(json.RawMessage(`123`)))
+			Expect(errorRes.Error.Code).To(BeNumerically("==", 123))
+			Expect(errorRes.Error.Message).To(Equal("<message>"))
+			Expect(errorRes.Error.Data).To(MatchJSON(`[1, 2, 3]`))
+		})
+
+		It("returns the reserved JSON-RPC errors produced by the remote server", func() {
+			res := proxy.Call(ctx, harpy.Request{
+				Version: "2.0",
+				ID:      json.RawMessage(`123`),
+				Method:  "<unknown>",
+			})
+
+			Expect(res).To(BeAssignableToTypeOf(harpy.ErrorResponse{}))
+
+			errorRes := res.(harpy.ErrorResponse)
+			Expect(errorRes.RequestID).To(Equal(json.RawMessage(`123`)))
+			Expect(errorRes.Error.Code).To(Equal(harpy.MethodNotFoundCode))
+		})
+
+		It("returns an internal error if the remote server can not be reached", func() {
+			server.Close()
+
+			res := proxy.Call(ctx, harpy.Request{
+				Version: "2.0",
+				ID:      json.RawMessage(`123`),
+				Method:  "echo",
+			})
+
+			Expect(res).To(BeAssignableToTypeOf(harpy.ErrorResponse{}))
+
+			errorRes := res.(harpy.ErrorResponse)
+			Expect(errorRes.RequestID).To(Equal(json.RawMessage(`123`)))
+			Expect(errorRes.Error.Code).To(Equal(harpy.InternalErrorCode))
+			Expect(errorRes.Error.Message).To(Equal("internal server error"))
+			Expect(errorRes.ServerError).Should(HaveOccurred())
+		})
+
+		It("returns an internal error if the remote server misbehaves", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte(`<not json>`))
+			})
+
+			res := proxy.Call(ctx, harpy.Request{
+				Version: "2.0",
+				ID:      json.RawMessage(`123`),
+				Method:  "echo",
+			})
+
+			Expect(res).To(BeAssignableToTypeOf(harpy.ErrorResponse{}))
+
+			errorRes := res.(harpy.ErrorResponse)
+			Expect(errorRes.Error.Code).To(Equal(harpy.InternalErrorCode))
+			Expect(errorRes.ServerError).To(MatchError(
+				"unable to process JSON-RPC response (echo): unexpected content-type in HTTP response (text/plain)",
+			))
+		})
+	})
+
+	Describe("func Notify()", func() {
+		It("forwards the notification to the remote server", func() {
+			err := proxy.Notify(ctx, harpy.Request{
+				Version:    "2.0",
+				Method:     "notify",
+				Parameters: json.RawMessage(`[1, 2, 3]`),
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notified).To(Receive(MatchJSON(`[1, 2, 3]`)))
+		})
+
+		It("returns an error if the remote server can not be reached", func() {
+			server.Close()
+
+			err := proxy.Notify(ctx, harpy.Request{
+				Version: "2.0",
+				Method:  "notify",
+			})
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	It("can be used as the exchanger for a handler", func() {
+		gateway := httptest.NewServer(NewHandler(proxy, WithZapLogger(zap.NewNop())))
+		defer gateway.Close()
+
+		client := &Client{
+			URL: gateway.URL,
+		}
+
+		var result []int
+		err := client.Call(ctx, "echo", []int{1, 2, 3}, &result)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(result).To(Equal([]int{1, 2, 3}))
+
+		err = client.Call(ctx, "error", []int{1, 2, 3}, &result)
+
+		var rpcErr harpy.Error
+		Expect(errors.As(err, &rpcErr)).To(BeTrue())
+		Expect(rpcErr.Code()).To(BeNumerically("==", 123))
+	})
+})
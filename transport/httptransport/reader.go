@@ -1,9 +1,17 @@
 package httptransport
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/dogmatiq/harpy"
 )
@@ -12,6 +20,86 @@ import (
 // JSON-RPC request set from an HTTP request.
 type RequestSetReader struct {
 	Request *http.Request
+
+	// Codec is the codec used to decode the request set. If it is nil,
+	// harpy.JSONCodec is used.
+	Codec harpy.Codec
+
+	// MediaType is the MIME media-type that requests must use. If it is empty,
+	// "application/json" is used.
+	MediaType string
+
+	// MaxNestingDepth is the maximum depth to which JSON arrays and objects
+	// may be nested within the request set. If it is zero, the default limit
+	// of harpy.MaxRequestSetNestingDepth() is used.
+	MaxNestingDepth int
+
+	// DisallowBatches causes batch request sets to be rejected with a
+	// JSON-RPC "invalid request" error, such that none of the requests within
+	// the batch are processed.
+	DisallowBatches bool
+
+	// DisallowTrailingData causes request sets that are followed by data other
+	// than whitespace to be rejected with a JSON-RPC "parse error".
+	DisallowTrailingData bool
+
+	// AcceptedVersions is a list of JSON-RPC version strings, in addition to
+	// "2.0", that are accepted in the "jsonrpc" field of each request. See
+	// harpy.AcceptVersions().
+	//
+	// Accepting other versions deviates from the JSON-RPC specification.
+	AcceptedVersions []string
+
+	// OnParseError is an optional function that is called when the request
+	// set can not be parsed, before the error is returned by Read().
+	//
+	// err is the JSON-RPC "parse error" returned by Read(). Its cause, if any,
+	// describes the underlying problem with the request body.
+	OnParseError func(ctx context.Context, r *http.Request, err error)
+
+	// MaxBodySize is the maximum size of the HTTP request body, in bytes,
+	// after it has been decompressed. A request with a larger body is rejected
+	// with a JSON-RPC "invalid request" error.
+	//
+	// If it is zero, the decompressed content of a gzip-compressed body is
+	// limited to DefaultMaxRequestBodySize, and an uncompressed body is not
+	// limited.
+	MaxBodySize int64
+
+	// MultipleRequestSets causes successive request sets to be read from the
+	// HTTP request body, such that each call to Read() returns the next
+	// request set. Use More() to determine whether there is another request
+	// set to read. DisallowTrailingData has no effect in this mode.
+	//
+	// Multiple request sets within a single HTTP request are not part of the
+	// JSON-RPC specification. It is only supported when using the JSON codec.
+	MultipleRequestSets bool
+
+	// decoder is the decoder used to split the HTTP request body into
+	// successive request sets when MultipleRequestSets is true. It is nil
+	// until the first request set is read.
+	decoder *json.Decoder
+
+	// body is the reader of the (possibly decompressed) HTTP request body
+	// used by decoder.
+	body io.Reader
+
+	// gzip is the reader used to decompress the HTTP request body. It is nil
+	// if the body is not compressed, or has not been opened.
+	gzip *gzipBody
+
+	// failed is true if a request set could not be read when
+	// MultipleRequestSets is true, such that no further request sets can be
+	// read.
+	failed bool
+
+	// trailingData is true if More() found data after the last request set
+	// that can not be the start of another request set.
+	trailingData bool
+
+	// trailingErr is the error that occurred when More() attempted to read
+	// the remainder of the HTTP request body.
+	trailingErr error
 }
 
 const (
@@ -22,36 +110,399 @@ const (
 	// more-specific HTTP status code when this error occurs.
 	incorrectHTTPMethod = "JSON-RPC requests must use the POST method"
 
-	// incorrectMediaType is the error message to use when a request is received
-	// that does not use the expected MIME media-type.
+	// incorrectMediaTypeFormat is the format of the error message to use when
+	// a request is received that does not use the expected MIME media-type.
+	//
+	// This constant is used by the ResponseWriter implementation to send a
+	// more-specific HTTP status code when this error occurs.
+	incorrectMediaTypeFormat = "JSON-RPC requests must use the %s content type"
+
+	// unsupportedContentEncoding is the error message to use when a request is
+	// received that uses an unsupported content-coding.
+	//
+	// This constant is used by the ResponseWriter implementation to send a
+	// more-specific HTTP status code when this error occurs.
+	unsupportedContentEncoding = "JSON-RPC requests must use the gzip or identity content encoding"
+
+	// batchesNotSupported is the error message to use when a batch request is
+	// received by a handler that does not allow batches.
+	batchesNotSupported = "batch requests are not supported"
+
+	// requestBodyTooLarge is the error message to use when a request is
+	// received with a body that exceeds the maximum size.
 	//
 	// This constant is used by the ResponseWriter implementation to send a
 	// more-specific HTTP status code when this error occurs.
-	incorrectMediaType = "JSON-RPC requests must use the application/json content type"
+	requestBodyTooLarge = "JSON-RPC request body is too large"
 )
 
+// DefaultMaxRequestBodySize is the default maximum size, in bytes, of the
+// decompressed content of a gzip-compressed HTTP request body.
+//
+// A limit is always applied to compressed bodies because a small amount of
+// compressed data can expand to a very large amount of decompressed data.
+const DefaultMaxRequestBodySize = 4 * 1024 * 1024
+
 // Read reads the next RequestSet that is to be processed.
 //
 // It returns ctx.Err() if ctx is canceled while waiting to read the next
 // request set. If request set data is read but cannot be parsed a native
 // JSON-RPC Error is returned. Any other error indicates an IO error.
-func (r *RequestSetReader) Read(_ context.Context) (harpy.RequestSet, error) {
+func (r *RequestSetReader) Read(ctx context.Context) (harpy.RequestSet, error) {
+	var (
+		rs  harpy.RequestSet
+		err error
+	)
+
+	if r.MultipleRequestSets {
+		rs, err = r.readNext()
+		r.failed = err != nil
+	} else {
+		rs, err = r.read()
+	}
+
+	if r.OnParseError != nil {
+		var nerr harpy.Error
+		if errors.As(err, &nerr) && nerr.Code() == harpy.ParseErrorCode {
+			r.OnParseError(ctx, r.Request, err)
+		}
+	}
+
+	return rs, err
+}
+
+// More returns true if there may be another request set to read from the HTTP
+// request body.
+//
+// It always returns false unless MultipleRequestSets is true and a request set
+// has already been read successfully.
+func (r *RequestSetReader) More() bool {
+	if r.decoder == nil || r.failed {
+		return false
+	}
+
+	if r.decoder.More() {
+		return true
+	}
+
+	// json.Decoder.More() also returns false if the next character is a
+	// closing bracket or brace, or if the body can not be read. Any such data
+	// is reported as an error by the next call to Read(), rather than being
+	// silently discarded.
+	r.trailingData, r.trailingErr = r.readTrailingData()
+	return r.trailingData || r.trailingErr != nil
+}
+
+// readTrailingData reads the remainder of the HTTP request body after the
+// last request set. It returns true if it contains anything other than
+// whitespace.
+func (r *RequestSetReader) readTrailingData() (bool, error) {
+	buffered, _ := io.ReadAll(r.decoder.Buffered()) // never fails
+	if len(bytes.TrimSpace(buffered)) != 0 {
+		return true, nil
+	}
+
+	var buf [512]byte
+	for {
+		n, err := r.body.Read(buf[:])
+		if len(bytes.TrimSpace(buf[:n])) != 0 {
+			return true, nil
+		}
+
+		if err == io.EOF {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// read reads the RequestSet from the HTTP request.
+func (r *RequestSetReader) read() (harpy.RequestSet, error) {
+	body, err := r.open()
+	if err != nil {
+		return harpy.RequestSet{}, err
+	}
+
+	if r.gzip != nil {
+		defer r.gzip.Close()
+	}
+
+	options := r.requestSetOptions()
+	if r.DisallowTrailingData {
+		options = append(options, harpy.DisallowTrailingData(true))
+	}
+
+	// The body is read in full before it is unmarshaled, which allows the use
+	// of harpy.UnmarshalRequestSetBytes(), and ensures that the gzip checksum
+	// of a compressed body is verified even if the request set ends before the
+	// compressed data does.
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return harpy.RequestSet{}, r.bodyError(err)
+	}
+
+	rs, err := harpy.UnmarshalRequestSetBytes(data, options...)
+	if err != nil {
+		return harpy.RequestSet{}, err
+	}
+
+	return r.validate(rs)
+}
+
+// readNext reads the next RequestSet from the HTTP request when
+// MultipleRequestSets is true.
+func (r *RequestSetReader) readNext() (harpy.RequestSet, error) {
+	if r.decoder == nil {
+		body, err := r.open()
+		if err != nil {
+			return harpy.RequestSet{}, err
+		}
+
+		r.body = body
+		r.decoder = json.NewDecoder(body)
+	}
+
+	if r.trailingData {
+		return harpy.RequestSet{}, harpy.NewErrorWithReservedCode(
+			harpy.ParseErrorCode,
+			harpy.WithCause(errors.New("unable to parse request: unexpected data after request set")),
+		)
+	}
+
+	if r.trailingErr != nil {
+		return harpy.RequestSet{}, r.bodyError(r.trailingErr)
+	}
+
+	var data json.RawMessage
+	if err := r.decoder.Decode(&data); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return harpy.RequestSet{}, harpy.NewErrorWithReservedCode(
+				harpy.ParseErrorCode,
+				harpy.WithCause(fmt.Errorf("unable to parse request: %w", err)),
+			)
+		}
+
+		return harpy.RequestSet{}, r.bodyError(err)
+	}
+
+	rs, err := harpy.UnmarshalRequestSetBytes(data, r.requestSetOptions()...)
+	if err != nil {
+		return harpy.RequestSet{}, err
+	}
+
+	return r.validate(rs)
+}
+
+// bodyError returns the error to return when reading the HTTP request body
+// fails with err.
+//
+// It returns a native JSON-RPC error if the body is not valid gzip-compressed
+// data or exceeds the maximum size. Otherwise, it returns err unchanged.
+func (r *RequestSetReader) bodyError(err error) error {
+	if r.gzip != nil && r.gzip.err != nil {
+		return newDecompressionError(r.gzip.err)
+	}
+
+	if err == errRequestBodyTooLarge {
+		return newRequestBodyTooLargeError()
+	}
+
+	return err
+}
+
+// validate returns an error if rs is not permitted by the reader's
+// configuration.
+func (r *RequestSetReader) validate(rs harpy.RequestSet) (harpy.RequestSet, error) {
+	if rs.IsBatch && r.DisallowBatches {
+		return harpy.RequestSet{}, harpy.NewErrorWithReservedCode(
+			harpy.InvalidRequestCode,
+			harpy.WithMessage(batchesNotSupported),
+		)
+	}
+
+	return rs, nil
+}
+
+// open validates the HTTP request and returns a reader of its body,
+// decompressing it if necessary.
+func (r *RequestSetReader) open() (io.Reader, error) {
 	// Check HTTP method is POST.
 	if r.Request.Method != http.MethodPost {
-		return harpy.RequestSet{}, harpy.NewErrorWithReservedCode(
+		return nil, harpy.NewErrorWithReservedCode(
 			harpy.InvalidRequestCode,
 			harpy.WithMessage(incorrectHTTPMethod),
 		)
 	}
 
-	// Validate the "content-type" HTTP header.
-	mt, _, err := mime.ParseMediaType(r.Request.Header.Get("Content-Type"))
-	if err != nil || mt != mediaType {
-		return harpy.RequestSet{}, harpy.NewErrorWithReservedCode(
+	// Validate the "content-type" HTTP header. JSON is always UTF-8 encoded,
+	// so a charset parameter is permitted only if it specifies UTF-8.
+	expected := mediaTypeOrDefault(r.MediaType)
+	mt, params, err := mime.ParseMediaType(r.Request.Header.Get("Content-Type"))
+	if err != nil || mt != expected || !isUTF8Charset(params) {
+		return nil, harpy.NewErrorWithReservedCode(
+			harpy.InvalidRequestCode,
+			harpy.WithMessage(fmt.Sprintf(incorrectMediaTypeFormat, expected)),
+		)
+	}
+
+	// Validate the "content-encoding" HTTP header.
+	isGzip, ok := NegotiateRequestEncoding(r.Request)
+	if !ok {
+		return nil, harpy.NewErrorWithReservedCode(
 			harpy.InvalidRequestCode,
-			harpy.WithMessage(incorrectMediaType),
+			harpy.WithMessage(unsupportedContentEncoding),
 		)
 	}
 
-	return harpy.UnmarshalRequestSet(r.Request.Body)
+	var body io.Reader = r.Request.Body
+	limit := r.MaxBodySize
+
+	if isGzip {
+		gz, err := gzip.NewReader(r.Request.Body)
+		if err != nil {
+			if isGzipFormatError(err) {
+				return nil, newDecompressionError(err)
+			}
+
+			return nil, err
+		}
+
+		r.gzip = &gzipBody{Reader: gz}
+		body = r.gzip
+
+		if limit == 0 {
+			limit = DefaultMaxRequestBodySize
+		}
+	}
+
+	if limit > 0 {
+		body = &limitedBody{Reader: body, remaining: limit}
+	}
+
+	return body, nil
+}
+
+// errRequestBodyTooLarge is returned by limitedBody.Read() when the body
+// exceeds the maximum size.
+var errRequestBodyTooLarge = errors.New("request body is too large")
+
+// limitedBody is an io.Reader that returns errRequestBodyTooLarge if more than
+// a given number of bytes are read from the underlying reader.
+type limitedBody struct {
+	io.Reader
+
+	// remaining is the number of bytes that may still be read.
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, errRequestBodyTooLarge
+	}
+
+	// Read at most one byte more than is permitted, so that a body that is
+	// exactly the maximum size is not rejected.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.Reader.Read(p)
+	b.remaining -= int64(n)
+
+	if b.remaining < 0 {
+		return n - 1, errRequestBodyTooLarge
+	}
+
+	return n, err
+}
+
+// newRequestBodyTooLargeError returns a JSON-RPC "invalid request" error
+// indicating that the request body exceeds the maximum size.
+func newRequestBodyTooLargeError() error {
+	return harpy.NewErrorWithReservedCode(
+		harpy.InvalidRequestCode,
+		harpy.WithMessage(requestBodyTooLarge),
+	)
+}
+
+// gzipBody is an io.Reader that decompresses a gzip-compressed HTTP request
+// body.
+type gzipBody struct {
+	*gzip.Reader
+
+	// err is the error that occurred if the body is not valid gzip-compressed
+	// data.
+	err error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err != nil && err != io.EOF && isGzipFormatError(err) {
+		b.err = err
+	}
+	return n, err
+}
+
+// newDecompressionError returns a JSON-RPC "parse error" caused by the failure
+// to decompress a gzip-compressed request body.
+func newDecompressionError(cause error) error {
+	return harpy.NewErrorWithReservedCode(
+		harpy.ParseErrorCode,
+		harpy.WithCause(fmt.Errorf("unable to decompress request: %w", cause)),
+	)
+}
+
+// isGzipFormatError returns true if err indicates that gzip-compressed data is
+// malformed or truncated.
+func isGzipFormatError(err error) bool {
+	var corrupt flate.CorruptInputError
+
+	return errors.Is(err, gzip.ErrHeader) ||
+		errors.Is(err, gzip.ErrChecksum) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &corrupt)
+}
+
+// requestSetOptions returns the options used to unmarshal each request set,
+// excluding those that depend on whether multiple request sets are read.
+func (r *RequestSetReader) requestSetOptions() []harpy.RequestSetOption {
+	var options []harpy.RequestSetOption
+	if r.Codec != nil {
+		options = append(options, harpy.DecodeWith(r.Codec))
+	}
+	if r.MaxNestingDepth != 0 {
+		options = append(options, harpy.MaxRequestSetNestingDepth(r.MaxNestingDepth))
+	}
+	if len(r.AcceptedVersions) != 0 {
+		options = append(options, harpy.AcceptVersions(r.AcceptedVersions...))
+	}
+	return options
+}
+
+// isUTF8Charset returns true if the "charset" parameter within the given
+// media-type parameters is either absent or specifies UTF-8.
+func isUTF8Charset(params map[string]string) bool {
+	charset, ok := params["charset"]
+	return !ok || strings.EqualFold(charset, "utf-8")
+}
+
+// isIncorrectMediaTypeMessage returns true if m is an error message produced
+// using incorrectMediaTypeFormat.
+func isIncorrectMediaTypeMessage(m string) bool {
+	return matchesMessageFormat(m, incorrectMediaTypeFormat)
+}
+
+// matchesMessageFormat returns true if m is an error message produced by
+// substituting a non-empty value into format, which must contain a single %s
+// verb.
+func matchesMessageFormat(m, format string) bool {
+	prefix, suffix, _ := strings.Cut(format, "%s")
+	return len(m) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(m, prefix) &&
+		strings.HasSuffix(m, suffix)
 }
@@ -0,0 +1,58 @@
+package httptransport
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// successStatusKey is the context key used to store the HTTP status code of a
+// successful response.
+type successStatusKey struct{}
+
+// SetSuccessStatus sets the HTTP status code that is used if the JSON-RPC call
+// associated with ctx succeeds, such as http.StatusCreated.
+//
+// It is intended to be called by a handler while processing a call. The status
+// code is only used if the call is not part of a batch, as the responses
+// within a batch may have different outcomes; batched responses always use
+// HTTP 200 (OK). It has no effect if the call fails.
+//
+// It returns false if ctx is not associated with a request served by a
+// Handler. It panics if code is not a 2xx status code, or if it is 204 (No
+// Content), as every JSON-RPC response to a call has a body.
+func SetSuccessStatus(ctx context.Context, code int) bool {
+	if !isSuccessStatus(code) {
+		panic("success status must be a 2xx status code other than 204 (No Content)")
+	}
+
+	s, ok := ctx.Value(successStatusKey{}).(*atomic.Int32)
+	if ok {
+		s.Store(int32(code))
+	}
+
+	return ok
+}
+
+// withSuccessStatus returns a copy of r with a location in which the HTTP
+// status code of a successful response can be stored by SetSuccessStatus().
+//
+// It returns a function that returns the stored status code, or zero if none
+// has been set.
+func withSuccessStatus(r *http.Request) (*http.Request, func() int) {
+	s := &atomic.Int32{}
+	ctx := context.WithValue(r.Context(), successStatusKey{}, s)
+
+	return r.WithContext(ctx), func() int {
+		return int(s.Load())
+	}
+}
+
+// isSuccessStatus returns true if code is a HTTP status code that may be used
+// for a response to a JSON-RPC call that succeeded.
+//
+// Any 2xx status code other than 204 (No Content) is permitted, as every
+// JSON-RPC response to a call has a body.
+func isSuccessStatus(code int) bool {
+	return code >= 200 && code <= 299 && code != http.StatusNoContent
+}
@@ -0,0 +1,96 @@
+package httptransport_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("func SetSuccessStatus()", func() {
+	var exchanger *ExchangerStub
+
+	BeforeEach(func() {
+		exchanger = &ExchangerStub{
+			CallFunc: func(ctx context.Context, req harpy.Request) harpy.Response {
+				ok := SetSuccessStatus(ctx, http.StatusCreated)
+				Expect(ok).To(BeTrue())
+
+				return harpy.NewSuccessResponse(req.ID, nil)
+			},
+		}
+	})
+
+	// serve serves a request with the given body and returns the HTTP
+	// response.
+	serve := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(body),
+		)
+		r.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		NewHandler(exchanger, WithZapLogger(zap.NewNop())).ServeHTTP(w, r)
+
+		return w
+	}
+
+	It("sets the HTTP status of a successful unbatched response", func() {
+		w := serve(`{"jsonrpc": "2.0", "id": 123}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Body.String()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 123, "result": null}`))
+	})
+
+	It("uses HTTP 200 if the status is not set", func() {
+		exchanger.CallFunc = func(_ context.Context, req harpy.Request) harpy.Response {
+			return harpy.NewSuccessResponse(req.ID, nil)
+		}
+
+		w := serve(`{"jsonrpc": "2.0", "id": 123}`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("does not affect error responses", func() {
+		exchanger.CallFunc = func(ctx context.Context, req harpy.Request) harpy.Response {
+			SetSuccessStatus(ctx, http.StatusCreated)
+			return harpy.NewErrorResponse(req.ID, errors.New("<error>"))
+		}
+
+		w := serve(`{"jsonrpc": "2.0", "id": 123}`)
+		Expect(w.Code).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("does not affect batched responses", func() {
+		w := serve(`[{"jsonrpc": "2.0", "id": 1}, {"jsonrpc": "2.0", "id": 2}]`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("returns false if the context is not associated with a handler", func() {
+		ok := SetSuccessStatus(context.Background(), http.StatusCreated)
+		Expect(ok).To(BeFalse())
+	})
+
+	DescribeTable(
+		"it panics if the status code is not a valid success status",
+		func(code int) {
+			Expect(func() {
+				SetSuccessStatus(context.Background(), code)
+			}).To(PanicWith("success status must be a 2xx status code other than 204 (No Content)"))
+		},
+		Entry("informational", http.StatusContinue),
+		Entry("no content", http.StatusNoContent),
+		Entry("redirect", http.StatusFound),
+		Entry("client error", http.StatusBadRequest),
+	)
+})
@@ -0,0 +1,51 @@
+package httptransport
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceIDHeader is the HTTP response header that contains the ID of the
+	// OpenTelemetry trace associated with the request.
+	TraceIDHeader = "X-Trace-Id"
+
+	// SpanIDHeader is the HTTP response header that contains the ID of the
+	// OpenTelemetry span associated with the request.
+	SpanIDHeader = "X-Span-Id"
+)
+
+// WithTraceHeaders is a HandlerOption that adds the X-Trace-Id header to each
+// HTTP response, allowing clients to correlate their requests with the
+// server's telemetry.
+//
+// The trace ID is obtained from the OpenTelemetry span in the request's
+// context, which is typically created by HTTP instrumentation middleware that
+// wraps the handler. If spanID is true, the X-Span-Id header is also added.
+//
+// The headers are omitted if the request's context does not contain a valid
+// span context.
+func WithTraceHeaders(spanID bool) HandlerOption {
+	return func(h *Handler) {
+		h.traceHeaders = true
+		h.spanIDHeader = spanID
+	}
+}
+
+// setTraceHeaders sets the tracing-related headers on w based on the span in
+// the context of r.
+//
+// It must be called before the response body is written.
+func (h *Handler) setTraceHeaders(w http.ResponseWriter, r *http.Request) {
+	sc := trace.SpanContextFromContext(r.Context())
+	if !sc.IsValid() {
+		return
+	}
+
+	w.Header().Set(TraceIDHeader, sc.TraceID().String())
+
+	if h.spanIDHeader {
+		w.Header().Set(SpanIDHeader, sc.SpanID().String())
+	}
+}
@@ -0,0 +1,79 @@
+package httptransport_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("func WithTraceHeaders()", func() {
+	var request *http.Request
+
+	BeforeEach(func() {
+		request = httptest.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(`{"jsonrpc": "2.0", "id": 123}`),
+		)
+		request.Header.Set("Content-Type", "application/json")
+
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+			SpanID:     trace.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18},
+			TraceFlags: trace.FlagsSampled,
+		})
+
+		request = request.WithContext(
+			trace.ContextWithSpanContext(request.Context(), sc),
+		)
+	})
+
+	// serve serves the request and returns the HTTP response headers.
+	serve := func(options ...HandlerOption) http.Header {
+		options = append([]HandlerOption{WithZapLogger(zap.NewNop())}, options...)
+		handler := NewHandler(&ExchangerStub{}, options...)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request)
+
+		return w.Result().Header
+	}
+
+	It("adds the trace ID header", func() {
+		header := serve(WithTraceHeaders(false))
+		Expect(header.Get(TraceIDHeader)).To(Equal("0102030405060708090a0b0c0d0e0f10"))
+		Expect(header.Values(SpanIDHeader)).To(BeEmpty())
+	})
+
+	It("adds the span ID header if requested", func() {
+		header := serve(WithTraceHeaders(true))
+		Expect(header.Get(TraceIDHeader)).To(Equal("0102030405060708090a0b0c0d0e0f10"))
+		Expect(header.Get(SpanIDHeader)).To(Equal("1112131415161718"))
+	})
+
+	It("omits the headers if the option is not used", func() {
+		header := serve()
+		Expect(header.Values(TraceIDHeader)).To(BeEmpty())
+		Expect(header.Values(SpanIDHeader)).To(BeEmpty())
+	})
+
+	It("omits the headers if there is no span in the context", func() {
+		request = httptest.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(`{"jsonrpc": "2.0", "id": 123}`),
+		)
+		request.Header.Set("Content-Type", "application/json")
+
+		header := serve(WithTraceHeaders(true))
+		Expect(header.Values(TraceIDHeader)).To(BeEmpty())
+		Expect(header.Values(SpanIDHeader)).To(BeEmpty())
+	})
+})
@@ -1,8 +1,13 @@
 package httptransport
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/dogmatiq/harpy"
 )
@@ -13,18 +18,123 @@ type ResponseWriter struct {
 	// Target is the writer used to send JSON-RPC responses.
 	Target http.ResponseWriter
 
+	// FlushBatches controls whether each batched response is flushed to the
+	// client as soon as it is written.
+	//
+	// Flushing is only performed if Target supports it. Note that the
+	// responses within a batch are written in the order that they are
+	// produced, which is not necessarily the order of the requests.
+	FlushBatches bool
+
+	// DisableHTMLEscaping controls whether the characters <, > and & are
+	// written verbatim within JSON strings, rather than being escaped.
+	//
+	// By default these characters are escaped, as per json.Encoder.
+	DisableHTMLEscaping bool
+
+	// Indent is the string used to indent each level of nesting within the
+	// JSON responses. If it is empty, responses are not indented.
+	Indent string
+
+	// Codec is the codec used to encode responses. If it is nil, responses are
+	// encoded as JSON according to DisableHTMLEscaping and Indent.
+	//
+	// When a codec other than harpy.JSONCodec is used, batched responses are
+	// buffered and encoded together when the writer is closed, and
+	// FlushBatches has no effect.
+	Codec harpy.Codec
+
+	// MediaType is the MIME media-type sent in the Content-Type header. If it
+	// is empty, "application/json" is used.
+	MediaType string
+
+	// ExposeInternalErrors controls whether the message of each "internal
+	// server error" response is replaced with the message of the Go error
+	// that caused it, as per the response's ServerError field.
+	//
+	// This may expose sensitive information to the client. It is intended
+	// for use during development only.
+	ExposeInternalErrors bool
+
+	// SuccessStatus is a function that returns the HTTP status code to use
+	// when writing an unbatched SuccessResponse. If it is nil or returns zero,
+	// HTTP 200 (OK) is used. It is not used for batched responses.
+	//
+	// The Handler uses this field to apply the status code set by
+	// SetSuccessStatus().
+	SuccessStatus func() int
+
+	// ErrorStatusMap is a map of JSON-RPC error codes to the HTTP status codes
+	// used when writing error responses with those codes, overriding the
+	// built-in mapping. Codes that are not in the map use the built-in
+	// mapping. Each status must be in the range 400 to 599. It is not used
+	// for batched responses, nor for requests that were rejected by a
+	// harpy.LoadShedder.
+	ErrorStatusMap map[harpy.ErrorCode]int
+
+	// Headers is a function that returns additional HTTP headers to include
+	// in the response. If it is nil, no additional headers are sent. Headers
+	// that are managed by the writer, namely Content-Type, Content-Length,
+	// Content-Encoding and Transfer-Encoding, are ignored.
+	//
+	// The Handler uses this field to apply the headers added by
+	// AddResponseHeader().
+	Headers func() http.Header
+
+	// Compress controls whether responses may be gzip-compressed.
+	//
+	// It must only be enabled if the client accepts gzip-compressed responses,
+	// as per NegotiateResponseEncoding(). Unbatched responses are compressed
+	// if the hint returned by harpy.CompressionHintOf() indicates that they
+	// should be, given MinCompressionSize. Batched responses are always
+	// compressed, as their size is not known when the HTTP response headers
+	// are written.
+	Compress bool
+
+	// MinCompressionSize is the minimum size, in bytes, of an unbatched
+	// response that is compressed when Compress is true.
+	MinCompressionSize int
+
+	// BufferResponses controls whether unbatched responses are encoded into
+	// a buffer before they are written, such that the Content-Length header
+	// can be sent.
+	//
+	// By default, responses are written directly to Target as they are
+	// encoded, which typically results in a "chunked" transfer encoding. It
+	// does not apply to batched responses.
+	BufferResponses bool
+
+	// buffer is the buffer into which an unbatched response is written when
+	// BufferResponses is true. It is nil if the response is written directly
+	// to Target.
+	buffer *bytes.Buffer
+
+	// gzip is the writer used to compress the HTTP response body. It is nil
+	// if the body is not compressed.
+	gzip *gzip.Writer
+
 	// hasResponse is true if any kind of response has been written.
 	hasResponse bool
 
+	// incomplete is true if a response could not be written in full, such
+	// that the HTTP response body is not valid JSON.
+	incomplete bool
+
 	// arrayOpen indicates whether the JSON opening array bracket has been
 	// written as part of a batch response.
 	arrayOpen bool
+
+	// batch contains the batched responses that have not yet been encoded. It
+	// is only used when Codec is a codec other than harpy.JSONCodec.
+	batch []harpy.Response
 }
 
 var (
 	openArray  = []byte(`[`)
 	closeArray = []byte(`]`)
 	comma      = []byte(`,`)
+	resultKey  = []byte(`"result":`)
+	nullResult = []byte(`"result":null`)
 )
 
 // WriteError writes an error response that is a result of some problem with
@@ -36,13 +146,15 @@ var (
 // status code is set to the most appropriate equivalent, otherwise it is set to
 // 500 (Internal Server Error).
 func (w *ResponseWriter) WriteError(res harpy.ErrorResponse) error {
-	status := httpStatusFromError(res.Error)
-	if status == http.StatusOK {
-		status = http.StatusInternalServerError
+	status, ok := w.mappedErrorStatus(res)
+	if !ok {
+		status = httpStatusFromErrorResponse(res)
+		if status == http.StatusOK {
+			status = http.StatusInternalServerError
+		}
 	}
 
-	w.writeHeaders(status)
-	return w.writeResponse(res)
+	return w.writeUnbatched(status, w.exposeInternalError(res))
 }
 
 // WriteUnbatched writes a response to an individual request that was not part
@@ -54,15 +166,28 @@ func (w *ResponseWriter) WriteError(res harpy.ErrorResponse) error {
 // specification the HTTP status code is set to the most appropriate equivalent.
 //
 // Application-defined JSON-RPC errors always result in a HTTP 200 (OK), as they
-// considered part of normal operation of the transport.
+// considered part of normal operation of the transport. The exception is a
+// request that was rejected by a harpy.LoadShedder, which results in a HTTP 503
+// (Service Unavailable).
+//
+// If res is a SuccessResponse, the HTTP status code is the one returned by
+// w.SuccessStatus, if any.
 func (w *ResponseWriter) WriteUnbatched(res harpy.Response) error {
 	status := http.StatusOK
 	if e, ok := res.(harpy.ErrorResponse); ok {
-		status = httpStatusFromError(e.Error)
+		if s, ok := w.mappedErrorStatus(e); ok {
+			status = s
+		} else {
+			status = httpStatusFromErrorResponse(e)
+		}
+		res = w.exposeInternalError(e)
+	} else if w.SuccessStatus != nil {
+		if s := w.SuccessStatus(); s != 0 {
+			status = s
+		}
 	}
 
-	w.writeHeaders(status)
-	return w.writeResponse(res)
+	return w.writeUnbatched(status, res)
 }
 
 // WriteBatched writes a response to an individual request that was part of a
@@ -75,58 +200,384 @@ func (w *ResponseWriter) WriteUnbatched(res harpy.Response) error {
 // The HTTP status code is always 200 (OK), as even if res is an ErrorResponse,
 // other responses in the batch may indicate a success.
 func (w *ResponseWriter) WriteBatched(res harpy.Response) error {
+	if e, ok := res.(harpy.ErrorResponse); ok {
+		res = w.exposeInternalError(e)
+	}
+
+	if !isJSONCodec(w.Codec) {
+		w.batch = append(w.batch, res)
+		return nil
+	}
+
 	separator := comma
 
 	if !w.arrayOpen {
-		w.writeHeaders(http.StatusOK)
+		w.writeHeaders(http.StatusOK, w.Compress)
 		w.arrayOpen = true
 		separator = openArray
 	}
 
-	if _, err := w.Target.Write(separator); err != nil {
-		return err
+	if _, err := w.body().Write(separator); err != nil {
+		return w.check(err)
+	}
+
+	if err := w.writeResponse(res); err != nil {
+		return w.check(err)
+	}
+
+	if w.FlushBatches {
+		return w.flush()
 	}
 
-	return w.writeResponse(res)
+	return nil
 }
 
 // Close is called to signal that there are no more responses to be sent.
 //
 // If batched responses have been written, it writes the closing bracket of the
-// array that encapsulates the responses.
+// array that encapsulates the responses. The closing bracket is not written if
+// a previous response could not be written in full, as the HTTP response body
+// can not be made into valid JSON. See Incomplete().
+//
+// If the HTTP response body is compressed, it finishes the compressed stream.
 func (w *ResponseWriter) Close() error {
-	if w.arrayOpen {
-		_, err := w.Target.Write(closeArray)
-		return err
+	if w.incomplete {
+		return nil
 	}
 
-	if !w.hasResponse {
+	if len(w.batch) != 0 {
+		w.writeHeaders(http.StatusOK, w.Compress)
+		if err := w.check(w.writeResponse(w.batch)); err != nil {
+			return err
+		}
+	} else if w.arrayOpen {
+		if _, err := w.body().Write(closeArray); err != nil {
+			return err
+		}
+	} else if !w.hasResponse {
+		w.addHeaders()
 		w.Target.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	if w.gzip != nil {
+		if err := w.gzip.Close(); err != nil {
+			return w.check(err)
+		}
+	}
+
+	if w.arrayOpen && w.FlushBatches {
+		return w.flush()
 	}
 
 	return nil
 }
 
+// Reset prepares the writer to write the responses to another request set to
+// target.
+//
+// It discards any state from previous use of the writer, such that a batch
+// written after the call to Reset() is encapsulated in a new array. The
+// writer's configuration is retained. This allows the same writer to be used
+// for several exchanges, provided that Close() has been called, or the writer
+// has been abandoned, before each call to Reset().
+func (w *ResponseWriter) Reset(target http.ResponseWriter) {
+	w.Target = target
+	w.buffer = nil
+	w.gzip = nil
+	w.hasResponse = false
+	w.incomplete = false
+	w.arrayOpen = false
+	w.batch = nil
+}
+
+// Incomplete returns true if a response could not be written in full, such
+// that the HTTP response body written so far is not valid JSON.
+//
+// This may occur if writing to the target fails, or if the result stream of a
+// response can not be read. The HTTP handler responds to this condition by
+// aborting the HTTP response, such that the client observes a failure rather
+// than receiving a malformed body.
+func (w *ResponseWriter) Incomplete() bool {
+	return w.incomplete
+}
+
+// check marks the response as incomplete if err is non-nil, then returns err.
+func (w *ResponseWriter) check(err error) error {
+	if err != nil {
+		w.incomplete = true
+	}
+	return err
+}
+
+// mappedErrorStatus returns the HTTP status code for res as per
+// w.ErrorStatusMap. ok is false if the error code is not in the map.
+func (w *ResponseWriter) mappedErrorStatus(res harpy.ErrorResponse) (status int, ok bool) {
+	if errors.Is(res.ServerError, harpy.ErrServerBusy) {
+		return 0, false
+	}
+
+	status, ok = w.ErrorStatusMap[res.Error.Code]
+	return status, ok
+}
+
+// exposeInternalError returns res with the message of its ServerError as the
+// JSON-RPC error message, if ExposeInternalErrors is enabled and res is an
+// internal server error.
+//
+// It must be called after the HTTP status code has been determined, as the
+// status may depend on the original message.
+func (w *ResponseWriter) exposeInternalError(res harpy.ErrorResponse) harpy.ErrorResponse {
+	if w.ExposeInternalErrors &&
+		res.Error.Code == harpy.InternalErrorCode &&
+		res.ServerError != nil {
+		res.Error.Message = res.ServerError.Error()
+	}
+
+	return res
+}
+
+// shouldCompress returns true if res should be compressed.
+func (w *ResponseWriter) shouldCompress(res harpy.Response) bool {
+	if !w.Compress {
+		return false
+	}
+
+	return harpy.CompressionHintOf(res).ShouldCompress(w.MinCompressionSize)
+}
+
+// writeUnbatched writes the HTTP response headers and an unbatched response
+// with the given HTTP status code.
+//
+// If w.BufferResponses is true, the response is encoded into a buffer so that
+// the Content-Length header can be sent before the body.
+func (w *ResponseWriter) writeUnbatched(status int, res harpy.Response) error {
+	compress := w.shouldCompress(res)
+
+	if !w.BufferResponses {
+		w.writeHeaders(status, compress)
+		return w.check(w.writeResponse(res))
+	}
+
+	w.buffer = getBuffer()
+	defer func() {
+		putBuffer(w.buffer)
+		w.buffer = nil
+	}()
+
+	w.prepareHeaders(compress)
+
+	if err := w.writeResponse(res); err != nil {
+		return w.check(err)
+	}
+
+	if w.gzip != nil {
+		if err := w.gzip.Close(); err != nil {
+			// CODE COVERAGE: Closing the gzip writer only fails if writing to
+			// the buffer fails, which never occurs.
+			return w.check(err)
+		}
+		w.gzip = nil
+	}
+
+	w.Target.Header().Set("Content-Length", strconv.Itoa(w.buffer.Len()))
+	w.Target.WriteHeader(status)
+
+	_, err := w.Target.Write(w.buffer.Bytes())
+	return w.check(err)
+}
+
 // writeHeaders writes the HTTP response headers.
-func (w *ResponseWriter) writeHeaders(status int) {
-	w.Target.Header().Set("Content-Type", mediaType)
+//
+// If compress is true, the HTTP response body is gzip-compressed.
+func (w *ResponseWriter) writeHeaders(status int, compress bool) {
+	w.prepareHeaders(compress)
 	w.Target.WriteHeader(status)
 }
 
-// writeResponse writes a JSON-RPC response to the HTTP response body.
-func (w *ResponseWriter) writeResponse(res harpy.Response) error {
+// prepareHeaders sets the HTTP response headers without writing them.
+//
+// If compress is true, the HTTP response body is gzip-compressed.
+func (w *ResponseWriter) prepareHeaders(compress bool) {
+	w.addHeaders()
+
+	header := w.Target.Header()
+	header.Set("Content-Type", mediaTypeOrDefault(w.MediaType))
+
+	if w.Compress {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		w.gzip = gzip.NewWriter(w.body())
+	}
+}
+
+// body returns the writer to which the HTTP response body is written.
+func (w *ResponseWriter) body() io.Writer {
+	if w.gzip != nil {
+		return w.gzip
+	}
+	if w.buffer != nil {
+		return w.buffer
+	}
+	return w.Target
+}
+
+// addHeaders adds the headers returned by w.Headers to the HTTP response
+// headers.
+func (w *ResponseWriter) addHeaders() {
+	if w.Headers == nil {
+		return
+	}
+
+	target := w.Target.Header()
+	for name, values := range w.Headers() {
+		if isManagedHeader(name) {
+			continue
+		}
+
+		for _, v := range values {
+			target.Add(name, v)
+		}
+	}
+}
+
+// writeResponse writes a JSON-RPC response, or batch of responses, to the HTTP
+// response body.
+//
+// If res is a harpy.SuccessResponse with a result stream, the result is copied
+// directly to the HTTP response body when possible. Otherwise, the stream is
+// read into memory before the response is encoded.
+func (w *ResponseWriter) writeResponse(res any) error {
 	w.hasResponse = true
-	enc := json.NewEncoder(w.Target)
+
+	switch r := res.(type) {
+	case harpy.SuccessResponse:
+		if r.ResultStream != nil {
+			if isJSONCodec(w.Codec) && w.Indent == "" {
+				return w.writeStreamedResponse(r)
+			}
+
+			var err error
+			if res, err = r.BufferResult(); err != nil {
+				return err
+			}
+		}
+	case []harpy.Response:
+		for i, x := range r {
+			if x, ok := x.(harpy.SuccessResponse); ok {
+				var err error
+				if r[i], err = x.BufferResult(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if !isJSONCodec(w.Codec) {
+		return w.Codec.NewEncoder(w.body()).Encode(res)
+	}
+
+	enc := json.NewEncoder(w.body())
+	enc.SetEscapeHTML(!w.DisableHTMLEscaping)
+	enc.SetIndent("", w.Indent)
 	return enc.Encode(res)
 }
 
+// writeStreamedResponse writes a success response to the HTTP response body,
+// copying the result directly from the response's result stream.
+func (w *ResponseWriter) writeStreamedResponse(res harpy.SuccessResponse) (err error) {
+	stream := res.ResultStream
+	if c, ok := stream.(io.Closer); ok {
+		defer func() {
+			err = errors.Join(err, c.Close())
+		}()
+	}
+
+	// Encode the response without the result, then write the result in place
+	// of the null value.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!w.DisableHTMLEscaping)
+	if err := enc.Encode(res); err != nil {
+		return err
+	}
+
+	// The result is the last property of the encoded response, so searching
+	// from the end avoids matching any similar text within the request ID.
+	encoded := buf.Bytes()
+	i := bytes.LastIndex(encoded, nullResult)
+	if i == -1 {
+		// CODE COVERAGE: This branch can not be reached, as a success
+		// response with a nil Result always encodes the result as null.
+		return errors.New("unable to locate result within encoded response")
+	}
+
+	head := encoded[:i+len(resultKey)]
+	tail := encoded[i+len(nullResult):]
+
+	if _, err := w.body().Write(head); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w.body(), stream); err != nil {
+		return err
+	}
+
+	_, err = w.body().Write(tail)
+	return err
+}
+
+// flush sends any buffered data to the client, if supported by the target.
+//
+// If the HTTP response body is compressed, the data compressed so far is
+// flushed first.
+func (w *ResponseWriter) flush() error {
+	if w.gzip != nil {
+		if err := w.gzip.Flush(); err != nil {
+			return err
+		}
+	}
+
+	err := http.NewResponseController(w.Target).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+// httpStatusFromErrorResponse returns the appropriate HTTP status code to send
+// in response to a JSON-RPC error response.
+//
+// Requests that were rejected by a harpy.LoadShedder result in a HTTP 503
+// (Service Unavailable) status, regardless of the JSON-RPC error code.
+func httpStatusFromErrorResponse(res harpy.ErrorResponse) int {
+	if errors.Is(res.ServerError, harpy.ErrServerBusy) {
+		return http.StatusServiceUnavailable
+	}
+
+	return httpStatusFromError(res.Error)
+}
+
 // httpStatusFromError returns the appropriate HTTP status code to send in
 // response to a specific JSON-RPC error code.
 //
 // Application-defined error codes, that is, error codes that are not reserved
 // by the JSON-RPC specification, result in a HTTP status of "200 OK", as they
-// are considered part of standard operation of the server.
+// are considered part of standard operation of the server. The exceptions are
+// harpy.CanceledCode and harpy.DeadlineExceededCode, which indicate that the
+// server did not complete the request.
 func httpStatusFromError(err harpy.ErrorInfo) int {
+	switch err.Code {
+	case harpy.CanceledCode:
+		return http.StatusServiceUnavailable
+
+	case harpy.DeadlineExceededCode:
+		return http.StatusGatewayTimeout
+	}
+
 	if !err.Code.IsReserved() {
 		return http.StatusOK
 	}
@@ -141,8 +592,13 @@ func httpStatusFromError(err harpy.ErrorInfo) int {
 		// this package.
 		if err.Message == incorrectHTTPMethod {
 			return http.StatusMethodNotAllowed
-		} else if err.Message == incorrectMediaType {
+		} else if isIncorrectMediaTypeMessage(err.Message) ||
+			err.Message == unsupportedContentEncoding {
 			return http.StatusUnsupportedMediaType
+		} else if isUnacceptableMediaTypeMessage(err.Message) {
+			return http.StatusNotAcceptable
+		} else if err.Message == requestBodyTooLarge {
+			return http.StatusRequestEntityTooLarge
 		}
 
 		return http.StatusBadRequest
@@ -153,6 +609,13 @@ func httpStatusFromError(err harpy.ErrorInfo) int {
 	case harpy.MethodNotFoundCode:
 		return http.StatusNotImplemented
 
+	case harpy.InternalErrorCode:
+		if err.Message == serverAtCapacity {
+			return http.StatusServiceUnavailable
+		}
+
+		return http.StatusInternalServerError
+
 	default:
 		return http.StatusInternalServerError
 	}
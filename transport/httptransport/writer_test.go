@@ -0,0 +1,78 @@
+package httptransport_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/transport/httptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type ResponseWriter", func() {
+	Describe("func Reset()", func() {
+		It("allows the writer to be used to write another batch", func() {
+			first := httptest.NewRecorder()
+			writer := &ResponseWriter{
+				Target: first,
+			}
+
+			err := writer.WriteBatched(harpy.SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`"<first>"`),
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = writer.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			second := httptest.NewRecorder()
+			writer.Reset(second)
+
+			err = writer.WriteBatched(harpy.SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`2`),
+				Result:    json.RawMessage(`"<second>"`),
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = writer.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(first.Body.String()).To(MatchJSON(`[{"jsonrpc": "2.0", "id": 1, "result": "<first>"}]`))
+			Expect(second.Body.String()).To(MatchJSON(`[{"jsonrpc": "2.0", "id": 2, "result": "<second>"}]`))
+		})
+
+		It("retains the writer's configuration", func() {
+			writer := &ResponseWriter{
+				Target:    httptest.NewRecorder(),
+				MediaType: "application/x-custom",
+			}
+
+			err := writer.WriteBatched(harpy.SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`null`),
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = writer.Close()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			rec := httptest.NewRecorder()
+			writer.Reset(rec)
+
+			err = writer.WriteUnbatched(harpy.SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`2`),
+				Result:    json.RawMessage(`null`),
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(rec.Header().Get("Content-Type")).To(Equal("application/x-custom"))
+			Expect(rec.Body.String()).To(MatchJSON(`{"jsonrpc": "2.0", "id": 2, "result": null}`))
+		})
+	})
+})
@@ -0,0 +1,184 @@
+package localtransport
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/dogmatiq/harpy"
+	"github.com/dogmatiq/harpy/internal/jsonx"
+	"go.uber.org/zap"
+)
+
+// Client is a JSON-RPC client that sends requests directly to an exchanger
+// within the same process.
+type Client struct {
+	// Exchanger is the exchanger that handles requests made by the client.
+	Exchanger harpy.Exchanger
+
+	// Logger is the target for log messages about JSON-RPC requests and
+	// responses. If it is nil, no logging is performed.
+	Logger harpy.ExchangeLogger
+
+	// prevID is the ID of the last "call" request sent. It is incremented by
+	// one to generate the next request ID.
+	prevID uint32 // atomic
+}
+
+// Call invokes a JSON-RPC method.
+//
+// The result of a successful call is unmarshaled into result, which must be a
+// non-nil pointer. If result is nil the result is discarded; Call() still
+// verifies that the exchanger produced a success response, returning an error
+// otherwise. It panics if result is neither nil nor a non-nil pointer.
+func (c *Client) Call(
+	ctx context.Context,
+	method string,
+	params, result any,
+	options ...harpy.UnmarshalOption,
+) error {
+	requestID := atomic.AddUint32(&c.prevID, 1)
+	req, err := harpy.NewCallRequest(
+		requestID,
+		method,
+		params,
+	)
+	if err != nil {
+		panic(fmt.Sprintf(
+			"unable to call JSON-RPC method (%s): %s",
+			method,
+			err,
+		))
+	}
+
+	if err, ok := req.ValidateClientSide(); !ok {
+		panic(fmt.Sprintf(
+			"unable to call JSON-RPC method (%s): %s",
+			method,
+			err.Message(),
+		))
+	}
+
+	if result != nil && !validateResultParameter(result) {
+		panic(fmt.Sprintf(
+			"unable to call JSON-RPC method (%s): result must be a non-nil pointer",
+			method,
+		))
+	}
+
+	res, err := c.exchange(ctx, req)
+	if err != nil {
+		return fmt.Errorf("unable to call JSON-RPC method (%s): %w", method, err)
+	}
+
+	switch res := res.(type) {
+	case harpy.SuccessResponse:
+		if result == nil {
+			return nil
+		}
+
+		if err := jsonx.Unmarshal(res.Result, result, options...); err != nil {
+			return fmt.Errorf("unable to process JSON-RPC response (%s): unable to unmarshal result: %w", method, err)
+		}
+
+	case harpy.ErrorResponse:
+		return harpy.NewClientSideError(
+			res.Error.Code,
+			res.Error.Message,
+			res.Error.Data,
+		)
+
+	default:
+		return fmt.Errorf("unable to process JSON-RPC response (%s): exchanger did not produce a response", method)
+	}
+
+	return nil
+}
+
+// Notify sends a JSON-RPC notification.
+func (c *Client) Notify(
+	ctx context.Context,
+	method string,
+	params any,
+) error {
+	req, err := harpy.NewNotifyRequest(
+		method,
+		params,
+	)
+	if err != nil {
+		panic(fmt.Sprintf(
+			"unable to send JSON-RPC notification (%s): %s",
+			method,
+			err,
+		))
+	}
+
+	if err, ok := req.ValidateClientSide(); !ok {
+		panic(fmt.Sprintf(
+			"unable to send JSON-RPC notification (%s): %s",
+			method,
+			err.Message(),
+		))
+	}
+
+	res, err := c.exchange(ctx, req)
+	if err != nil {
+		return fmt.Errorf("unable to send JSON-RPC notification (%s): %w", method, err)
+	}
+
+	if res, ok := res.(harpy.ErrorResponse); ok {
+		return harpy.NewClientSideError(
+			res.Error.Code,
+			res.Error.Message,
+			res.Error.Data,
+		)
+	}
+
+	return nil
+}
+
+// exchange performs a JSON-RPC exchange for a single request.
+//
+// It returns the response written by the exchange, which is nil if req is a
+// notification.
+func (c *Client) exchange(ctx context.Context, req harpy.Request) (harpy.Response, error) {
+	logger := c.Logger
+	if logger == nil {
+		logger = harpy.NewZapExchangeLogger(zap.NewNop())
+	}
+
+	w := &responseWriter{}
+
+	if err := harpy.Exchange(
+		harpy.WithTransport(ctx, "local"),
+		c.Exchanger,
+		&requestSetReader{
+			RequestSet: harpy.RequestSet{
+				Requests: []harpy.Request{req},
+			},
+		},
+		w,
+		logger,
+	); err != nil {
+		return nil, err
+	}
+
+	return w.Response, nil
+}
+
+// validateResultParameter returns true if r is a valid variable into which a
+// JSON-RPC result value can be written.
+func validateResultParameter(v any) bool {
+	if v == nil {
+		return false
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Ptr {
+		return false
+	}
+
+	return !rv.IsNil()
+}
@@ -0,0 +1,218 @@
+package localtransport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/localtransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type Client", func() {
+	var (
+		ctx      context.Context
+		notified []int
+		client   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		notified = nil
+
+		client = &Client{
+			Exchanger: harpy.NewRouter(
+				harpy.WithRoute(
+					"echo",
+					func(_ context.Context, params any) (any, error) {
+						return params, nil
+					},
+				),
+				harpy.WithRoute(
+					"stream",
+					func(_ context.Context, params json.RawMessage) (any, error) {
+						return harpy.StreamResult{
+							Reader: bytes.NewReader(params),
+						}, nil
+					},
+				),
+				harpy.WithRoute(
+					"notify",
+					harpy.NoResult(
+						func(_ context.Context, params []int) error {
+							notified = params
+							return nil
+						},
+					),
+				),
+				harpy.WithRoute(
+					"error",
+					harpy.NoResult(
+						func(_ context.Context, params any) error {
+							return harpy.NewError(
+								123,
+								harpy.WithMessage("<message>"),
+								harpy.WithData(params),
+							)
+						},
+					),
+				),
+			),
+		}
+	})
+
+	Describe("func Call()", func() {
+		It("returns the JSON-RPC result", func() {
+			params := []int{1, 2, 3}
+			var result []int
+			err := client.Call(ctx, "echo", params, &result)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result).To(Equal(params))
+		})
+
+		It("returns streamed JSON-RPC results", func() {
+			params := []int{1, 2, 3}
+			var result []int
+			err := client.Call(ctx, "stream", params, &result)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result).To(Equal(params))
+		})
+
+		It("discards the JSON-RPC result if the result variable is nil", func() {
+			err := client.Call(ctx, "echo", []int{1, 2, 3}, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("returns the JSON-RPC error produced by the exchanger if the result variable is nil", func() {
+			err := client.Call(ctx, "error", []int{1, 2, 3}, nil)
+
+			var rpcErr harpy.Error
+			Expect(errors.As(err, &rpcErr)).To(BeTrue())
+			Expect(rpcErr.Code()).To(BeNumerically("==", 123))
+		})
+
+		It("returns the JSON-RPC error produced by the exchanger", func() {
+			params := []int{1, 2, 3}
+			var result any
+			err := client.Call(ctx, "error", params, &result)
+			Expect(err).Should(HaveOccurred())
+			Expect(result).To(BeNil())
+
+			var rpcErr harpy.Error
+			ok := errors.As(err, &rpcErr)
+			Expect(ok).To(BeTrue())
+			Expect(rpcErr.Code()).To(BeNumerically("==", 123))
+			Expect(rpcErr.Message()).To(Equal("<message>"))
+
+			var data []int
+			ok, err = rpcErr.UnmarshalData(&data)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(data).To(Equal(params))
+		})
+
+		It("associates the context with the local transport", func() {
+			var (
+				name string
+				ok   bool
+			)
+
+			client.Exchanger = &ExchangerStub{
+				CallFunc: func(ctx context.Context, req harpy.Request) harpy.Response {
+					name, ok = harpy.TransportFromContext(ctx)
+					return harpy.NewSuccessResponse(req.ID, nil)
+				},
+			}
+
+			var result any
+			err := client.Call(ctx, "echo", []int{}, &result)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(name).To(Equal("local"))
+		})
+
+		It("returns an error if the exchanger does not produce a response", func() {
+			client.Exchanger = &ExchangerStub{}
+
+			var result any
+			err := client.Call(ctx, "echo", []int{}, &result)
+			Expect(err).To(MatchError("unable to process JSON-RPC response (echo): exchanger did not produce a response"))
+		})
+
+		It("panics if the JSON-RPC request can not be built", func() {
+			Expect(func() {
+				var result any
+				client.Call(
+					ctx,
+					"<method>",
+					make(chan struct{}),
+					&result,
+				)
+			}).To(PanicWith(
+				`unable to call JSON-RPC method (<method>): unable to marshal request parameters: json: unsupported type: chan struct {}`,
+			))
+		})
+
+		It("panics if the JSON-RPC request can not be validated", func() {
+			Expect(func() {
+				var result any
+				client.Call(
+					ctx,
+					"<method>",
+					123,
+					&result,
+				)
+			}).To(PanicWith(
+				`unable to call JSON-RPC method (<method>): parameters must be an array, an object, or null`,
+			))
+		})
+
+		DescribeTable(
+			"it panics if the result variable is not a non-nil pointer",
+			func(result any) {
+				Expect(func() {
+					client.Call(
+						ctx,
+						"<method>",
+						[]int{1, 2, 3},
+						result,
+					)
+				}).To(PanicWith(
+					`unable to call JSON-RPC method (<method>): result must be a non-nil pointer`,
+				))
+			},
+			Entry("nil pointer", (*int)(nil)),
+			Entry("non-pointer", "<string>"),
+		)
+	})
+
+	Describe("func Notify()", func() {
+		It("passes the notification to the exchanger", func() {
+			err := client.Notify(ctx, "notify", []int{1, 2, 3})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(notified).To(Equal([]int{1, 2, 3}))
+		})
+
+		It("does not return errors produced by the exchanger", func() {
+			err := client.Notify(ctx, "error", []int{1, 2, 3})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("panics if the JSON-RPC request can not be built", func() {
+			Expect(func() {
+				client.Notify(
+					ctx,
+					"<method>",
+					make(chan struct{}),
+				)
+			}).To(PanicWith(
+				`unable to send JSON-RPC notification (<method>): unable to marshal request parameters: json: unsupported type: chan struct {}`,
+			))
+		})
+	})
+})
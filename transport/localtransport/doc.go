@@ -0,0 +1,8 @@
+// Package localtransport provides a JSON-RPC transport that connects a client
+// directly to a harpy.Exchanger within the same process.
+//
+// Requests are passed to the exchanger via harpy.Exchange() without the use of
+// a network or any other IO. It is useful for embedding a JSON-RPC API within
+// the same process as its clients, and for testing or benchmarking the core
+// exchange pipeline without network overhead.
+package localtransport
@@ -0,0 +1,56 @@
+package localtransport
+
+import (
+	"context"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// requestSetReader is an implementation of harpy.RequestSetReader that returns
+// a pre-built request set.
+type requestSetReader struct {
+	RequestSet harpy.RequestSet
+}
+
+func (r *requestSetReader) Read(context.Context) (harpy.RequestSet, error) {
+	return r.RequestSet, nil
+}
+
+// responseWriter is an implementation of harpy.ResponseWriter that retains the
+// response in memory.
+type responseWriter struct {
+	Response harpy.Response
+}
+
+func (w *responseWriter) WriteError(res harpy.ErrorResponse) error {
+	w.Response = res
+	return nil
+}
+
+func (w *responseWriter) WriteUnbatched(res harpy.Response) error {
+	return w.write(res)
+}
+
+func (w *responseWriter) WriteBatched(res harpy.Response) error {
+	return w.write(res)
+}
+
+// write retains res in memory.
+//
+// If res has a streamed result, the stream is read into memory, as it can not
+// be consumed after the exchange is complete.
+func (w *responseWriter) write(res harpy.Response) error {
+	if r, ok := res.(harpy.SuccessResponse); ok {
+		var err error
+		if res, err = r.BufferResult(); err != nil {
+			return err
+		}
+	}
+
+	w.Response = res
+	return nil
+}
+
+func (w *responseWriter) Close() error {
+	return nil
+}
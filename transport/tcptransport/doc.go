@@ -0,0 +1,7 @@
+// Package tcptransport provides a JSON-RPC transport for stream-oriented
+// network connections, such as TCP connections.
+//
+// Each request set and response set is sent as a discrete message within the
+// stream. Messages are delimited either by a Content-Length header, as used by
+// the Language Server Protocol, or by a newline character.
+package tcptransport
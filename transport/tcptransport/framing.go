@@ -0,0 +1,22 @@
+package tcptransport
+
+// Framing is a method of delimiting JSON-RPC messages within a stream.
+type Framing int
+
+const (
+	// ContentLengthFraming precedes each message with a header that specifies
+	// its length, as per harpy.UnmarshalRequestSetFramed(). For example:
+	//
+	//	Content-Length: 63\r\n
+	//	\r\n
+	//	{"jsonrpc": "2.0", "id": 1, "method": "<method>", "params": []}
+	//
+	// It is the default framing.
+	ContentLengthFraming Framing = iota
+
+	// NewlineFraming terminates each message with a newline character, such
+	// that each message occupies a single line. Messages must not contain
+	// literal newlines; this is always the case for compact JSON, as newlines
+	// within strings are escaped. Blank lines are ignored.
+	NewlineFraming
+)
@@ -0,0 +1,209 @@
+package tcptransport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// DefaultMaxMessageSize is the default maximum size of each message read from
+// a connection, in bytes.
+const DefaultMaxMessageSize = 4 * 1024 * 1024
+
+// RequestSetReader is an implementation of harpy.RequestSetReader that reads
+// JSON-RPC request sets from a network connection.
+//
+// Each call to Read() reads a single message from the connection, allowing the
+// same reader to be used with successive calls to harpy.Exchange().
+type RequestSetReader struct {
+	// Conn is the connection from which requests are read.
+	Conn net.Conn
+
+	// Framing is the method used to delimit messages within the stream.
+	Framing Framing
+
+	// MaxMessageSize is the maximum size of each message, in bytes, excluding
+	// any Content-Length header or terminating newline. If it is zero,
+	// DefaultMaxMessageSize is used. If it is negative, there is no limit.
+	//
+	// A message that exceeds the limit is discarded without being buffered,
+	// and a JSON-RPC "parse error" is returned. Subsequent messages can still
+	// be read from the connection.
+	MaxMessageSize int
+
+	// buf is a buffered reader over Conn. It is retained between calls to
+	// Read(), as it may contain data that belongs to the next message.
+	buf *bufio.Reader
+}
+
+// Read reads the next RequestSet that is to be processed.
+//
+// It returns ctx.Err() if ctx is canceled while waiting to read the next
+// request set. Any partially-read message is discarded, such that the
+// connection can not be used to read further requests.
+//
+// If the connection is closed before any part of the message is read, it
+// returns io.EOF. If it is closed part way through a message, it returns
+// io.ErrUnexpectedEOF. If request set data is read but cannot be parsed a
+// native JSON-RPC Error is returned. Any other error indicates an IO error.
+func (r *RequestSetReader) Read(ctx context.Context) (harpy.RequestSet, error) {
+	var rs harpy.RequestSet
+
+	err := r.withContext(ctx, func() error {
+		var err error
+
+		if r.Framing == NewlineFraming {
+			rs, err = r.readLine()
+		} else {
+			var options []harpy.RequestSetOption
+			if max := r.maxMessageSize(); max > 0 {
+				options = append(options, harpy.MaxFrameSize(int64(max)))
+			}
+
+			rs, err = harpy.UnmarshalRequestSetFramed(r.reader(), options...)
+		}
+
+		return err
+	})
+
+	return rs, err
+}
+
+// wait blocks until the next message is available to be read, without
+// consuming any of its data.
+//
+// It returns io.EOF if the connection is closed before any data is available.
+func (r *RequestSetReader) wait(ctx context.Context) error {
+	return r.withContext(ctx, func() error {
+		_, err := r.reader().Peek(1)
+		return err
+	})
+}
+
+// readLine reads a single newline-delimited message.
+func (r *RequestSetReader) readLine() (harpy.RequestSet, error) {
+	for {
+		line, err := readLimitedLine(r.reader(), r.maxMessageSize())
+
+		if err == errMessageTooLarge {
+			return harpy.RequestSet{}, harpy.NewErrorWithReservedCode(
+				harpy.ParseErrorCode,
+				harpy.WithCause(fmt.Errorf(
+					"unable to parse request: message exceeds the maximum size of %d bytes",
+					r.maxMessageSize(),
+				)),
+			)
+		} else if err == io.EOF {
+			if len(bytes.TrimSpace(line)) == 0 {
+				return harpy.RequestSet{}, io.EOF
+			}
+			return harpy.RequestSet{}, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return harpy.RequestSet{}, err
+		}
+
+		if len(bytes.TrimSpace(line)) != 0 {
+			return harpy.UnmarshalRequestSet(bytes.NewReader(line))
+		}
+	}
+}
+
+// maxMessageSize returns the maximum size of each message, or a non-positive
+// value if there is no limit.
+func (r *RequestSetReader) maxMessageSize() int {
+	if r.MaxMessageSize == 0 {
+		return DefaultMaxMessageSize
+	}
+	return r.MaxMessageSize
+}
+
+// errMessageTooLarge indicates that a newline-delimited message exceeds the
+// maximum message size.
+var errMessageTooLarge = errors.New("message too large")
+
+// readLimitedLine reads a single line from r, including the terminating
+// newline.
+//
+// If max is positive and the line, excluding the newline, is longer than max
+// bytes, the remainder of the line is discarded without being buffered and
+// errMessageTooLarge is returned.
+func readLimitedLine(r *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+
+	for {
+		chunk, err := r.ReadSlice('\n')
+
+		size := len(line) + len(chunk)
+		if err == nil {
+			size-- // exclude the newline
+		}
+
+		if max > 0 && size > max {
+			for err == bufio.ErrBufferFull {
+				_, err = r.ReadSlice('\n')
+			}
+
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			} else if err != nil {
+				return nil, err
+			}
+
+			return nil, errMessageTooLarge
+		}
+
+		line = append(line, chunk...)
+
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// reader returns the buffered reader used to read from the connection.
+func (r *RequestSetReader) reader() *bufio.Reader {
+	if r.buf == nil {
+		r.buf = bufio.NewReader(r.Conn)
+	}
+	return r.buf
+}
+
+// withContext calls fn, interrupting any blocking read from the connection
+// when ctx is canceled.
+//
+// If ctx is canceled before fn returns, and fn fails, it returns ctx.Err().
+func (r *RequestSetReader) withContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(interrupted)
+		// A deadline in the past causes any blocking read to fail immediately.
+		r.Conn.SetReadDeadline(time.Unix(1, 0)) // nolint:errcheck
+	})
+
+	err := fn()
+
+	if !stop() {
+		// The deadline was (or is being) set by the function above. Wait for
+		// it to finish before clearing the deadline so that subsequent reads
+		// are not affected.
+		<-interrupted
+		r.Conn.SetReadDeadline(time.Time{}) // nolint:errcheck
+
+		if err != nil {
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
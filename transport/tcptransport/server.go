@@ -0,0 +1,122 @@
+package tcptransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/dogmatiq/harpy"
+	"go.uber.org/zap"
+)
+
+// Server serves JSON-RPC requests received over network connections.
+type Server struct {
+	// Exchanger is the exchanger that handles the requests.
+	Exchanger harpy.Exchanger
+
+	// Framing is the method used to delimit messages within the stream.
+	Framing Framing
+
+	// MaxMessageSize is the maximum size of each message, in bytes. If it is
+	// zero, DefaultMaxMessageSize is used. If it is negative, there is no
+	// limit. See RequestSetReader.MaxMessageSize.
+	MaxMessageSize int
+
+	// IdleTimeout is the maximum amount of time to wait for the next request
+	// set to arrive on a connection. If it is zero, there is no limit.
+	IdleTimeout time.Duration
+
+	// AcknowledgeNotifications causes an acknowledgement to be written for
+	// each notification once it has been handled. Acknowledgements are not
+	// part of the JSON-RPC specification. See
+	// ResponseWriter.AcknowledgeNotifications.
+	AcknowledgeNotifications bool
+
+	// Logger is the target for log messages about JSON-RPC requests and
+	// responses. If it is nil, no logging is performed.
+	Logger harpy.ExchangeLogger
+}
+
+// ServeConn serves JSON-RPC requests received on conn, writing the responses
+// back to conn.
+//
+// Each request set is handled by a separate call to harpy.Exchange(), in the
+// order that they are received.
+//
+// It returns nil if the connection is closed or reset by the peer, or if it
+// remains idle for longer than s.IdleTimeout. It returns ctx.Err() if ctx is
+// canceled. conn is always closed before ServeConn() returns.
+func (s *Server) ServeConn(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+
+	ctx = harpy.WithTransport(ctx, "tcp")
+
+	logger := s.Logger
+	if logger == nil {
+		logger = harpy.NewZapExchangeLogger(zap.NewNop())
+	}
+
+	r := &RequestSetReader{
+		Conn:           conn,
+		Framing:        s.Framing,
+		MaxMessageSize: s.MaxMessageSize,
+	}
+
+	w := &ResponseWriter{
+		Target:                   conn,
+		Framing:                  s.Framing,
+		AcknowledgeNotifications: s.AcknowledgeNotifications,
+	}
+
+	for {
+		// Wait for the next message to arrive before starting the exchange, so
+		// that a connection closed by the peer between messages is not
+		// reported as an error.
+		if ok, err := s.wait(ctx, r); !ok {
+			return err
+		}
+
+		if err := harpy.Exchange(ctx, s.Exchanger, r, w, logger); err != nil {
+			return err
+		}
+	}
+}
+
+// wait blocks until the next message is available to be read from r.
+//
+// It returns false if no further messages can be read, along with the error
+// that ServeConn() should return.
+func (s *Server) wait(ctx context.Context, r *RequestSetReader) (bool, error) {
+	waitCtx := ctx
+	if s.IdleTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, s.IdleTimeout)
+		defer cancel()
+	}
+
+	err := r.wait(waitCtx)
+
+	switch {
+	case err == nil:
+		return true, nil
+	case ctx.Err() != nil:
+		return false, ctx.Err()
+	case err == waitCtx.Err():
+		// The idle timeout has elapsed.
+		return false, nil
+	case isClosedByPeer(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// isClosedByPeer returns true if err indicates that the remote end of a
+// connection was closed.
+func isClosedByPeer(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
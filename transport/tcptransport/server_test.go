@@ -0,0 +1,293 @@
+package tcptransport_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/harpy"
+	. "github.com/dogmatiq/harpy/internal/fixtures"
+	. "github.com/dogmatiq/harpy/transport/tcptransport"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("type Server", func() {
+	var (
+		ctx      context.Context
+		cancel   context.CancelFunc
+		client   net.Conn
+		conn     net.Conn
+		incoming *bufio.Reader
+		server   *Server
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+
+		client, conn = net.Pipe()
+		incoming = bufio.NewReader(client)
+
+		server = &Server{
+			Exchanger: &ExchangerStub{
+				CallFunc: func(_ context.Context, req harpy.Request) harpy.Response {
+					return harpy.NewSuccessResponse(req.ID, req.Method)
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		client.Close()
+		cancel()
+	})
+
+	// serve starts the server in the background and returns a channel that
+	// receives the result of ServeConn().
+	serve := func() <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			result <- server.ServeConn(ctx, conn)
+		}()
+		return result
+	}
+
+	// send writes a Content-Length framed message to the server.
+	send := func(message string) {
+		_, err := fmt.Fprintf(client, "Content-Length: %d\r\n\r\n%s", len(message), message)
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	// receive reads a Content-Length framed response set from the server.
+	receive := func() harpy.ResponseSet {
+		rs, err := harpy.UnmarshalResponseSetFramed(incoming)
+		Expect(err).ShouldNot(HaveOccurred())
+		return rs
+	}
+
+	Describe("func ServeConn()", func() {
+		It("serves successive requests received on the same connection", func() {
+			result := serve()
+
+			send(`{"jsonrpc": "2.0", "id": 1, "method": "first"}`)
+			rs := receive()
+			Expect(rs.Responses).To(HaveLen(1))
+			Expect(rs.Responses[0]).To(Equal(harpy.SuccessResponse{
+				Version:   "2.0",
+				RequestID: json.RawMessage(`1`),
+				Result:    json.RawMessage(`"first"`),
+			}))
+
+			send(`{"jsonrpc": "2.0", "id": 2, "method": "second"}`)
+			rs = receive()
+			Expect(rs.Responses).To(HaveLen(1))
+			Expect(rs.Responses[0].(harpy.SuccessResponse).RequestID).To(Equal(json.RawMessage(`2`)))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("associates the context with the tcp transport", func() {
+			server.Exchanger = &ExchangerStub{
+				CallFunc: func(ctx context.Context, req harpy.Request) harpy.Response {
+					name, _ := harpy.TransportFromContext(ctx)
+					return harpy.NewSuccessResponse(req.ID, name)
+				},
+			}
+
+			result := serve()
+
+			send(`{"jsonrpc": "2.0", "id": 1, "method": "<method>"}`)
+			rs := receive()
+			Expect(rs.Responses).To(HaveLen(1))
+			Expect(rs.Responses[0].(harpy.SuccessResponse).Result).To(Equal(json.RawMessage(`"tcp"`)))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("writes batched responses as a single message", func() {
+			result := serve()
+
+			send(`[
+				{"jsonrpc": "2.0", "id": 1, "method": "first"},
+				{"jsonrpc": "2.0", "method": "notification"},
+				{"jsonrpc": "2.0", "id": 2, "method": "second"}
+			]`)
+
+			rs := receive()
+			Expect(rs.IsBatch).To(BeTrue())
+			Expect(rs.Responses).To(HaveLen(2))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("does not write a response to a notification", func() {
+			result := serve()
+
+			send(`{"jsonrpc": "2.0", "method": "notification"}`)
+			send(`{"jsonrpc": "2.0", "id": 1, "method": "call"}`)
+
+			rs := receive()
+			Expect(rs.Responses[0].(harpy.SuccessResponse).RequestID).To(Equal(json.RawMessage(`1`)))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("acknowledges notifications if configured to do so", func() {
+			server.Framing = NewlineFraming
+			server.AcknowledgeNotifications = true
+			result := serve()
+
+			_, err := io.WriteString(client, `{"jsonrpc": "2.0", "method": "notification"}`+"\n")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			line, err := incoming.ReadString('\n')
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(line).To(MatchJSON(`{"jsonrpc": "2.0", "ack": "notification"}`))
+
+			_, err = io.WriteString(client, `[{"jsonrpc": "2.0", "method": "notification"}, {"jsonrpc": "2.0", "id": 1, "method": "call"}]`+"\n")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			line, err = incoming.ReadString('\n')
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(line).To(Or(
+				MatchJSON(`[{"jsonrpc": "2.0", "ack": "notification"}, {"jsonrpc": "2.0", "id": 1, "result": "call"}]`),
+				MatchJSON(`[{"jsonrpc": "2.0", "id": 1, "result": "call"}, {"jsonrpc": "2.0", "ack": "notification"}]`),
+			))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("continues serving requests after a parse error", func() {
+			result := serve()
+
+			send(`{"jsonrpc": "2.0", "id": 1, "method": }`)
+			rs := receive()
+			res := rs.Responses[0].(harpy.ErrorResponse)
+			Expect(res.Error.Code).To(Equal(harpy.ParseErrorCode))
+
+			send(`{"jsonrpc": "2.0", "id": 1, "method": "call"}`)
+			rs = receive()
+			Expect(rs.Responses[0].(harpy.SuccessResponse).RequestID).To(Equal(json.RawMessage(`1`)))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("discards messages that exceed the maximum size and continues serving requests", func() {
+			server.MaxMessageSize = 64
+			result := serve()
+
+			send(`{"jsonrpc": "2.0", "id": 1, "method": "call", "params": ["` + strings.Repeat("x", 10000) + `"]}`)
+			rs := receive()
+			res := rs.Responses[0].(harpy.ErrorResponse)
+			Expect(res.Error.Code).To(Equal(harpy.ParseErrorCode))
+			Expect(res.Error.Message).To(Equal("unable to parse request: frame content exceeds the maximum size of 64 bytes"))
+
+			send(`{"jsonrpc": "2.0", "id": 2, "method": "call"}`)
+			rs = receive()
+			Expect(rs.Responses[0].(harpy.SuccessResponse).RequestID).To(Equal(json.RawMessage(`2`)))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("discards newline-framed messages that exceed the maximum size and continues serving requests", func() {
+			server.Framing = NewlineFraming
+			server.MaxMessageSize = 64
+			result := serve()
+
+			_, err := io.WriteString(client, `{"jsonrpc": "2.0", "id": 1, "method": "call", "params": ["`+strings.Repeat("x", 10000)+`"]}`+"\n")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			line, err := incoming.ReadString('\n')
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(line).To(MatchJSON(`{
+				"jsonrpc": "2.0",
+				"id": null,
+				"error": {
+					"code": -32700,
+					"message": "unable to parse request: message exceeds the maximum size of 64 bytes"
+				}
+			}`))
+
+			_, err = io.WriteString(client, `{"jsonrpc": "2.0", "id": 2, "method": "call"}`+"\n")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			line, err = incoming.ReadString('\n')
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(line).To(MatchJSON(`{"jsonrpc": "2.0", "id": 2, "result": "call"}`))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("supports newline framing", func() {
+			server.Framing = NewlineFraming
+			result := serve()
+
+			_, err := io.WriteString(client, "\n{\"jsonrpc\": \"2.0\", \"id\": 1, \"method\": \"call\"}\n")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			line, err := incoming.ReadString('\n')
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(line).To(HaveSuffix("\n"))
+			Expect(line).To(MatchJSON(`{"jsonrpc": "2.0", "id": 1, "result": "call"}`))
+
+			client.Close()
+			Expect(<-result).To(Succeed())
+		})
+
+		It("returns an error if the connection is closed part way through a message", func() {
+			result := serve()
+
+			_, err := io.WriteString(client, "Content-Length: 100\r\n\r\n{")
+			Expect(err).ShouldNot(HaveOccurred())
+			client.Close()
+
+			Expect(<-result).To(MatchError(io.ErrUnexpectedEOF))
+		})
+
+		It("returns nil if the connection is idle for longer than the idle timeout", func() {
+			server.IdleTimeout = 10 * time.Millisecond
+			result := serve()
+
+			Expect(<-result).To(Succeed())
+		})
+
+		It("returns the context error if the context is canceled while idle", func() {
+			result := serve()
+
+			cancel()
+			Expect(<-result).To(Equal(context.Canceled))
+		})
+
+		It("returns the context error if the context is canceled while reading a request", func() {
+			result := serve()
+
+			_, err := io.WriteString(client, "Content-Length: 100\r\n\r\n{")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			cancel()
+			Expect(<-result).To(Equal(context.Canceled))
+		})
+
+		It("closes the connection", func() {
+			server.IdleTimeout = 10 * time.Millisecond
+			result := serve()
+			Expect(<-result).To(Succeed())
+
+			_, err := conn.Read(make([]byte, 1))
+			Expect(err).To(MatchError(io.ErrClosedPipe))
+		})
+	})
+})
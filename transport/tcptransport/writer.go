@@ -0,0 +1,165 @@
+package tcptransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/dogmatiq/harpy"
+)
+
+// ResponseWriter is an implementation of harpy.ResponseWriter that writes
+// JSON-RPC responses to a stream, such as a network connection.
+//
+// Each response set is written as a single message. Batched responses are
+// buffered in memory and written when the writer is closed, allowing the
+// same writer to be used with successive calls to harpy.Exchange().
+type ResponseWriter struct {
+	// Target is the writer to which responses are written.
+	Target io.Writer
+
+	// Framing is the method used to delimit messages within the stream.
+	Framing Framing
+
+	// AcknowledgeNotifications causes an acknowledgement to be written for
+	// each notification once it has been handled.
+	//
+	// Acknowledgements are an extension provided by this transport; they are
+	// NOT part of the JSON-RPC specification, and clients must be prepared to
+	// receive them. Each acknowledgement is a JSON object containing the
+	// "jsonrpc" version and the method name of the notification in the "ack"
+	// property, for example:
+	//
+	//	{"jsonrpc":"2.0","ack":"<method>"}
+	//
+	// Within a batch, acknowledgements are written in place of responses.
+	AcknowledgeNotifications bool
+
+	// batch contains the batched responses and acknowledgements that have not
+	// yet been written.
+	batch []any
+}
+
+var _ harpy.NotificationAcknowledger = (*ResponseWriter)(nil)
+
+// acknowledgement is the message written to acknowledge a notification.
+type acknowledgement struct {
+	Version string `json:"jsonrpc"`
+	Method  string `json:"ack"`
+}
+
+// newAcknowledgement returns the acknowledgement of req.
+func newAcknowledgement(req harpy.Request) acknowledgement {
+	return acknowledgement{
+		Version: "2.0",
+		Method:  req.Method,
+	}
+}
+
+// WriteError writes an error response that is a result of some problem with
+// the request set as a whole.
+func (w *ResponseWriter) WriteError(res harpy.ErrorResponse) error {
+	return w.writeMessage(res)
+}
+
+// WriteUnbatched writes a response to an individual request that was not part
+// of a batch.
+func (w *ResponseWriter) WriteUnbatched(res harpy.Response) error {
+	return w.writeMessage(res)
+}
+
+// WriteBatched writes a response to an individual request that was part of a
+// batch.
+//
+// The response is not written to the stream until Close() is called.
+func (w *ResponseWriter) WriteBatched(res harpy.Response) error {
+	w.batch = append(w.batch, res)
+	return nil
+}
+
+// AcknowledgeUnbatched acknowledges a notification that was not part of a
+// batch.
+//
+// It does nothing unless w.AcknowledgeNotifications is true.
+func (w *ResponseWriter) AcknowledgeUnbatched(req harpy.Request) error {
+	if !w.AcknowledgeNotifications {
+		return nil
+	}
+
+	return w.writeMessage(newAcknowledgement(req))
+}
+
+// AcknowledgeBatched acknowledges a notification that was part of a batch.
+//
+// It does nothing unless w.AcknowledgeNotifications is true. The
+// acknowledgement is not written to the stream until Close() is called.
+func (w *ResponseWriter) AcknowledgeBatched(req harpy.Request) error {
+	if w.AcknowledgeNotifications {
+		w.batch = append(w.batch, newAcknowledgement(req))
+	}
+
+	return nil
+}
+
+// Close is called to signal that there are no more responses to be sent.
+//
+// If batched responses or acknowledgements have been written, it writes them
+// to the stream as a single message. It does not close Target.
+func (w *ResponseWriter) Close() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+
+	batch := w.batch
+	w.batch = nil
+
+	return w.writeMessage(batch)
+}
+
+// writeMessage writes a JSON-RPC response, or batch of responses, as a single
+// framed message.
+//
+// Any result streams are read into memory, as the length of the message must
+// be known before it is written.
+func (w *ResponseWriter) writeMessage(res any) error {
+	switch r := res.(type) {
+	case harpy.SuccessResponse:
+		var err error
+		if res, err = r.BufferResult(); err != nil {
+			return err
+		}
+	case []any:
+		for i, x := range r {
+			if x, ok := x.(harpy.SuccessResponse); ok {
+				var err error
+				if r[i], err = x.BufferResult(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	if w.Framing == NewlineFraming {
+		buf.Grow(len(data) + 1)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	} else {
+		buf.WriteString("Content-Length: ")
+		buf.WriteString(strconv.Itoa(len(data)))
+		buf.WriteString("\r\n\r\n")
+		buf.Write(data)
+	}
+
+	// The message is written with a single call so that it is not interleaved
+	// with other writes to the same stream.
+	_, err = w.Target.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,24 @@
+package harpy_test
+
+import (
+	"context"
+
+	. "github.com/dogmatiq/harpy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func TransportFromContext()", func() {
+	It("returns the transport associated with the context", func() {
+		ctx := WithTransport(context.Background(), "<transport>")
+
+		name, ok := TransportFromContext(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("<transport>"))
+	})
+
+	It("returns false if the context is not associated with a transport", func() {
+		_, ok := TransportFromContext(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+})